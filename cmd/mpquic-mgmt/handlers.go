@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -262,7 +263,13 @@ func (h *APIHandler) HandleTunnelRoutes(w http.ResponseWriter, r *http.Request)
 		h.handleTunnelMetrics(w, r, name)
 	case "logs":
 		h.handleTunnelLogs(w, r, name)
+	case "paths":
+		h.handleTunnelPaths(w, r, name, "")
 	default:
+		if pathName, ok := strings.CutPrefix(action, "paths/"); ok {
+			h.handleTunnelPaths(w, r, name, pathName)
+			return
+		}
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("unknown action: %s", action)})
 	}
 }
@@ -491,6 +498,57 @@ func (h *APIHandler) handleTunnelLogs(w http.ResponseWriter, r *http.Request, na
 	})
 }
 
+// GET/POST /api/v1/tunnels/{name}/paths
+// DELETE     /api/v1/tunnels/{name}/paths/{path}
+// POST       /api/v1/tunnels/{name}/paths/{path}/drain
+// POST       /api/v1/tunnels/{name}/paths/{path}/qlog
+// Proxied to the tunnel's control API (runtime path add/remove/drain/qlog).
+func (h *APIHandler) handleTunnelPaths(w http.ResponseWriter, r *http.Request, name, sub string) {
+	target := "/paths"
+	var allowed []string
+	if sub == "" {
+		allowed = []string{http.MethodGet, http.MethodPost}
+	} else {
+		pathName, action, _ := strings.Cut(sub, "/")
+		if !validName.MatchString(pathName) || (action != "" && action != "drain" && action != "qlog") {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("unknown action: paths/%s", sub)})
+			return
+		}
+		target += "/" + pathName
+		allowed = []string{http.MethodDelete}
+		if action != "" {
+			target += "/" + action
+			allowed = []string{http.MethodPost}
+		}
+	}
+	if !slices.Contains(allowed, r.Method) {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64*1024))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if r.Method != http.MethodGet {
+		log.Printf("API: %s %s tunnel=%s remote=%s", r.Method, target, name, r.RemoteAddr)
+	}
+
+	status, data, err := h.mgr.ControlAPI(name, r.Method, target, body)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"error":  err.Error(),
+			"tunnel": name,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
 // ─── Aggregated Metrics ───────────────────────────────────────────────────
 
 func (h *APIHandler) HandleMetricsAll(w http.ResponseWriter, r *http.Request) {
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/netip"
@@ -57,6 +59,7 @@ type TunnelConfig struct {
 	StripeFECType         string              `yaml:"stripe_fec_type,omitempty" json:"stripe_fec_type,omitempty"`
 	StripeFECWindow       int                 `yaml:"stripe_fec_window,omitempty" json:"stripe_fec_window,omitempty"`
 	StripeFECInterleave   int                 `yaml:"stripe_fec_interleave,omitempty" json:"stripe_fec_interleave,omitempty"`
+	StripePipeScheduler   string              `yaml:"stripe_pipe_scheduler,omitempty" json:"stripe_pipe_scheduler,omitempty"`
 	StripeEnabled         bool                `yaml:"stripe_enabled,omitempty" json:"stripe_enabled,omitempty"`
 	MetricsListen         string              `yaml:"metrics_listen,omitempty" json:"metrics_listen,omitempty"`
 }
@@ -100,6 +103,7 @@ var paramClassification = map[string]ParamCategory{
 	"stripe_fec_window":     CatB_Restart,
 	"stripe_fec_interleave": CatB_Restart,
 	"stripe_disable_gso":    CatB_Restart,
+	"stripe_pipe_scheduler": CatB_Restart,
 	"detect_starlink":       CatB_Restart,
 	"starlink_default_pipes": CatB_Restart,
 	"starlink_transport":    CatB_Restart,
@@ -474,6 +478,49 @@ func (im *InstanceManager) FetchAllMetrics() (map[string]json.RawMessage, error)
 	return result, nil
 }
 
+// ─── Control API Proxy ────────────────────────────────────────────────────
+
+// ControlAPI forwards a request to a running tunnel's local control API
+// (control_api_listen), adding its bearer token. Returns the tunnel's
+// status code and JSON body unchanged.
+func (im *InstanceManager) ControlAPI(name, method, path string, body []byte) (int, json.RawMessage, error) {
+	inst, err := im.GetInstance(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	if inst.Status != "running" {
+		return 0, nil, fmt.Errorf("tunnel %q not running", name)
+	}
+	base := resolveControlAPIURL(&inst.Config)
+	if base == "" {
+		return 0, nil, fmt.Errorf("tunnel %q has no control_api_listen", name)
+	}
+
+	req, err := http.NewRequest(method, base+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if inst.Config.ControlAPIAuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+inst.Config.ControlAPIAuthToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("control api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&data); err != nil {
+		return 0, nil, fmt.Errorf("control api decode: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
 // ─── Logs ──────────────────────────────────────────────────────────────────
 
 // FetchLogs retrieves recent journal entries for a tunnel instance.
@@ -617,6 +664,19 @@ func resolveMetricsURL(cfg *TunnelConfig) string {
 	return "http://" + listen + "/api/v1/stats"
 }
 
+// resolveControlAPIURL returns the base URL of the tunnel's control API.
+// A wildcard listen address is reached through loopback.
+func resolveControlAPIURL(cfg *TunnelConfig) string {
+	host, port, err := net.SplitHostPort(cfg.ControlAPIListen)
+	if err != nil {
+		return ""
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
 func formatDuration(sec int64) string {
 	if sec <= 0 {
 		return "-"
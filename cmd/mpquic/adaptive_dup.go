@@ -0,0 +1,117 @@
+package main
+
+import (
+	"time"
+)
+
+// ─── Adaptive duplication ─────────────────────────────────────────────────
+//
+// duplicate_mode: adaptive duplicates a class only while its primary path
+// (the one selectBestPath would pick for the class) is degraded: measured
+// loss at or above duplicate_loss_pct, or effective RTT at or above
+// duplicate_rtt_ms. Duplication switches off again once both measurements
+// fall below adaptiveDupHysteresis × threshold and it has been on for at
+// least duplicate_min_on_ms, so a path hovering around the threshold does
+// not flap between one and N copies.
+//
+// The decision is re-evaluated by qualityLoop right after path quality is
+// refreshed; the send path only reads the resulting flag.
+
+const (
+	// Fraction of the on-threshold below which duplication may switch off.
+	adaptiveDupHysteresis = 0.7
+
+	defaultDuplicateLossPct = 2.0
+	defaultDuplicateMinOnMs = 10000
+)
+
+// adaptiveDupState tracks one class's adaptive duplication. Guarded by
+// multipathConn.mu, like the trafficClassCounters it lives in.
+type adaptiveDupState struct {
+	active      bool
+	since       time.Time
+	activeTotal time.Duration // completed on-periods
+	activations uint64
+}
+
+// activeFor returns the total time duplication has been on, including the
+// current on-period.
+func (s *adaptiveDupState) activeFor(now time.Time) time.Duration {
+	d := s.activeTotal
+	if s.active {
+		d += now.Sub(s.since)
+	}
+	return d
+}
+
+// step advances the state machine with the primary path's quality.
+// Returns true when the state changed.
+func (s *adaptiveDupState) step(policy DataplaneClassPolicy, q pathQuality, now time.Time) bool {
+	lossOn := policy.DuplicateLossPct / 100
+	rttOn := time.Duration(policy.DuplicateRTTMs) * time.Millisecond
+
+	loss := q.lossRate
+	// Only a measured RTT counts: pathQualityUnknownRTT must not trigger
+	// duplication on a path that has not been sampled yet.
+	var rtt time.Duration
+	if q.srtt > 0 {
+		rtt = q.effectiveRTT()
+	}
+
+	degraded := (lossOn > 0 && loss >= lossOn) || (rttOn > 0 && rtt >= rttOn)
+	if !s.active {
+		if !degraded {
+			return false
+		}
+		s.active = true
+		s.since = now
+		s.activations++
+		return true
+	}
+
+	recovered := (lossOn <= 0 || loss < lossOn*adaptiveDupHysteresis) &&
+		(rttOn <= 0 || float64(rtt) < float64(rttOn)*adaptiveDupHysteresis)
+	minOn := time.Duration(max(policy.DuplicateMinOnMs, 0)) * time.Millisecond // -1: no minimum
+	if !recovered || now.Sub(s.since) < minOn {
+		return false
+	}
+	s.active = false
+	s.activeTotal += now.Sub(s.since)
+	return true
+}
+
+// evaluateAdaptiveDuplication steps the state of every adaptive class
+// against its current primary path. Caller holds m.mu.
+func (m *multipathConn) evaluateAdaptiveDuplication(now time.Time) {
+	for className, policy := range m.dataplane.classes {
+		if !policy.Duplicate || policy.DuplicateMode != "adaptive" {
+			continue
+		}
+		idx := m.selectBestPathLocked(policy, nil, now)
+		if idx < 0 {
+			continue
+		}
+		c := m.classTx[className]
+		if c == nil {
+			c = &trafficClassCounters{}
+			m.classTx[className] = c
+		}
+		p := m.paths[idx]
+		if c.adaptive.step(policy, p.quality, now) {
+			m.logger.Infof("adaptive duplication class=%s active=%t primary=%s srtt=%s loss=%.1f%%",
+				className, c.adaptive.active, p.cfg.Name, p.quality.srtt.Round(time.Millisecond), p.quality.lossRate*100)
+		}
+	}
+}
+
+// duplicationActive reports whether a class with Duplicate set should send
+// duplicate copies right now.
+func (m *multipathConn) duplicationActive(className string, policy DataplaneClassPolicy) bool {
+	if policy.DuplicateMode != "adaptive" {
+		return true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c := m.classTx[className]
+	return c != nil && c.adaptive.active
+}
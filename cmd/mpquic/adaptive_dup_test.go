@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func adaptivePolicy() DataplaneClassPolicy {
+	return DataplaneClassPolicy{
+		Duplicate:        true,
+		DuplicateMode:    "adaptive",
+		DuplicateLossPct: 2,
+		DuplicateRTTMs:   200,
+		DuplicateMinOnMs: 5000,
+	}
+}
+
+func TestAdaptiveDup_LossTriggers(t *testing.T) {
+	var s adaptiveDupState
+	now := time.Now()
+	if s.step(adaptivePolicy(), pathQuality{lossRate: 0.01}, now) || s.active {
+		t.Fatal("1% loss must not trigger a 2% threshold")
+	}
+	if !s.step(adaptivePolicy(), pathQuality{lossRate: 0.03}, now) || !s.active {
+		t.Fatal("3% loss must trigger duplication")
+	}
+	if s.activations != 1 {
+		t.Errorf("activations = %d, want 1", s.activations)
+	}
+}
+
+func TestAdaptiveDup_RTTTriggersOnlyWhenMeasured(t *testing.T) {
+	var s adaptiveDupState
+	now := time.Now()
+	policy := adaptivePolicy()
+	policy.DuplicateRTTMs = 50 // below pathQualityUnknownRTT
+	if s.step(policy, pathQuality{}, now) {
+		t.Fatal("an unsampled path must not trigger on the unknown-RTT default")
+	}
+	q := pathQuality{srtt: 40 * time.Millisecond, rttVar: 10 * time.Millisecond} // effective 60 ms
+	if !s.step(policy, q, now) {
+		t.Fatal("effective RTT over budget must trigger duplication")
+	}
+}
+
+func TestAdaptiveDup_MinOnTimeAndHysteresis(t *testing.T) {
+	var s adaptiveDupState
+	start := time.Now()
+	policy := adaptivePolicy()
+	s.step(policy, pathQuality{lossRate: 0.05}, start)
+
+	// Recovered, but inside the minimum on-time.
+	if s.step(policy, pathQuality{}, start.Add(2*time.Second)) || !s.active {
+		t.Fatal("duplication switched off before duplicate_min_on_ms")
+	}
+	// Past the on-time, but loss is between the off- and on-thresholds.
+	if s.step(policy, pathQuality{lossRate: 0.015}, start.Add(6*time.Second)) || !s.active {
+		t.Fatal("duplication switched off above the hysteresis threshold")
+	}
+	// Clearly recovered.
+	if !s.step(policy, pathQuality{lossRate: 0.005}, start.Add(7*time.Second)) || s.active {
+		t.Fatal("duplication still on after recovery")
+	}
+	if got := s.activeFor(start.Add(time.Hour)); got != 7*time.Second {
+		t.Errorf("activeFor = %s, want 7s", got)
+	}
+
+	// duplicate_min_on_ms: -1 switches off as soon as the path recovers.
+	policy.DuplicateMinOnMs = -1
+	s.step(policy, pathQuality{lossRate: 0.05}, start)
+	if !s.step(policy, pathQuality{}, start.Add(time.Millisecond)) || s.active {
+		t.Fatal("duplication kept on with no minimum on-time")
+	}
+}
+
+func TestNormalizeDataplaneConfig_AdaptiveDuplicate(t *testing.T) {
+	dp := DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"voip":   {DuplicateMode: " Adaptive "},
+			"always": {Duplicate: true},
+			"game":   {DuplicateMode: "adaptive", DuplicateMinOnMs: -1},
+		},
+	}
+	normalizeDataplaneConfig(&dp, "priority")
+	voip := dp.Classes["voip"]
+	if !voip.Duplicate || voip.DuplicateMode != "adaptive" || voip.DuplicateCopies != 2 {
+		t.Errorf("voip = %+v, want duplicate adaptive with 2 copies", voip)
+	}
+	if voip.DuplicateLossPct != defaultDuplicateLossPct || voip.DuplicateMinOnMs != defaultDuplicateMinOnMs {
+		t.Errorf("voip thresholds = %v%%/%dms, want defaults", voip.DuplicateLossPct, voip.DuplicateMinOnMs)
+	}
+	if minOn := dp.Classes["game"].DuplicateMinOnMs; minOn != -1 {
+		t.Errorf("duplicate_min_on_ms -1 normalized to %d", minOn)
+	}
+	if mode := dp.Classes["always"].DuplicateMode; mode != "always" {
+		t.Errorf("duplicate: true mode = %q, want always", mode)
+	}
+
+	dp.DefaultClass = "voip"
+	bad := dp.Classes["voip"]
+	bad.DuplicateMode = "sometimes"
+	dp.Classes["voip"] = bad
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for unknown duplicate_mode")
+	}
+}
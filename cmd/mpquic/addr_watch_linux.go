@@ -0,0 +1,92 @@
+//go:build linux
+
+package main
+
+// addr_watch_linux.go — interface address change notifications.
+//
+// A NETLINK_ROUTE socket joined to the IPv4/IPv6 address groups receives an
+// RTM_NEWADDR/RTM_DELADDR message whenever an address is added to or removed
+// from any interface. Only the events of the watched interface are passed on;
+// the interface is matched by name, so a PPP/WWAN interface that is torn
+// down and recreated with a new index is still followed.
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchInterfaceAddrs returns a channel that receives a value whenever an
+// address of interface ifName is added or removed. Bursts of events are
+// coalesced. The channel is closed when ctx is done.
+func watchInterfaceAddrs(ctx context.Context, ifName string) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	sa := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR}
+	if err := unix.Bind(fd, sa); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	// Wake up once a second to notice ctx cancellation.
+	tv := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("netlink timeout: %w", err)
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		defer close(events)
+		defer unix.Close(fd)
+		buf := make([]byte, 64*1024)
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+					continue
+				}
+				// ENOBUFS: events were lost, report a change to be safe.
+				if !errors.Is(err, unix.ENOBUFS) {
+					return
+				}
+			} else if !addrEventFor(buf[:n], ifName) {
+				continue
+			}
+			select {
+			case events <- struct{}{}:
+			default: // one pending notification is enough
+			}
+		}
+	}()
+	return events, nil
+}
+
+// addrEventFor reports whether a netlink datagram carries an address
+// message for interface ifName.
+func addrEventFor(b []byte, ifName string) bool {
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return false
+	}
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWADDR && m.Header.Type != unix.RTM_DELADDR {
+			continue
+		}
+		// struct ifaddrmsg: family, prefixlen, flags, scope (1 byte each), index (u32)
+		if len(m.Data) < unix.SizeofIfAddrmsg {
+			continue
+		}
+		index := int(binary.NativeEndian.Uint32(m.Data[4:8]))
+		if iface, err := net.InterfaceByIndex(index); err == nil && iface.Name == ifName {
+			return true
+		}
+	}
+	return false
+}
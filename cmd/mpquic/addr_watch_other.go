@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchInterfaceAddrs is not supported on non-Linux platforms: the client
+// reconnects when the address of its bind interface changes.
+func watchInterfaceAddrs(_ context.Context, _ string) (<-chan struct{}, error) {
+	return nil, errors.New("interface address events not supported on this platform")
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ─── Packet-level bonding ─────────────────────────────────────────────────
+//
+// With scheduler_policy=bonding the sender spreads the packets of a single
+// flow across all alive QUIC paths, in proportion to each path's estimated
+// capacity (cwnd / sRTT, from the connection tracer). Each bonded packet
+// carries a small header with a per-sender sequence number and the
+// receiver restores the original order in a bondReorderBuffer before the
+// packet reaches the TUN, so a single TCP download sees the combined
+// bandwidth of all WANs instead of being pinned to one path.
+//
+// Wire format (prepended to the inner IP packet):
+//
+//	[0]    magic 0xB5   (high nibble 0xB: never a valid IPv4/IPv6 version)
+//	[1]    version 1
+//	[2:4]  reserved (0)
+//	[4:8]  sequence number (uint32, big-endian, wraps)
+//
+// The server needs no configuration: a peer group switches to bonding as
+// soon as it receives a bonded packet, and from then on the return
+// direction is bonded across that peer's QUIC paths as well.
+//
+// Bonding adds bondHdrLen bytes to every datagram: lower tun_mtu by 8 when
+// the MTU is already at the QUIC datagram limit.
+
+const (
+	bondMagic   = 0xB5
+	bondVersion = 1
+	bondHdrLen  = 8
+
+	defaultBondReorderHold = 60 * time.Millisecond
+	defaultBondReorderMax  = 1024
+
+	// Capacity assumed per unit of path weight when no cwnd/sRTT sample is
+	// available yet (bytes/s, ~1 Mbit/s).
+	bondFallbackCapacityPerWeight = 125_000
+)
+
+// isBondedPacket reports whether pkt starts with a bonding header.
+func isBondedPacket(pkt []byte) bool {
+	return len(pkt) > bondHdrLen && pkt[0] == bondMagic && pkt[1] == bondVersion
+}
+
+// encodeBondPacket returns a new buffer holding the bonding header
+// followed by pkt.
+func encodeBondPacket(seq uint32, pkt []byte) []byte {
+	out := make([]byte, bondHdrLen+len(pkt))
+	out[0] = bondMagic
+	out[1] = bondVersion
+	binary.BigEndian.PutUint32(out[4:8], seq)
+	copy(out[bondHdrLen:], pkt)
+	return out
+}
+
+// decodeBondPacket splits a bonded datagram into sequence and inner packet.
+func decodeBondPacket(pkt []byte) (uint32, []byte, bool) {
+	if !isBondedPacket(pkt) {
+		return 0, nil, false
+	}
+	return binary.BigEndian.Uint32(pkt[4:8]), pkt[bondHdrLen:], true
+}
+
+// bondSeqLess compares sequence numbers with wraparound (RFC 1982 style).
+func bondSeqLess(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// bondCapacity estimates a QUIC path's capacity in bytes/s as cwnd / sRTT.
+// Falls back to weight × bondFallbackCapacityPerWeight before the first
+// RTT sample.
+func bondCapacity(st *quicPathStats, weight int) int64 {
+	if weight < 1 {
+		weight = 1
+	}
+	if st != nil {
+		srtt := atomic.LoadInt64(&st.smoothedRTT)
+		cwnd := atomic.LoadInt64(&st.cwnd)
+		if srtt > 0 && cwnd > 0 {
+			if c := cwnd * int64(time.Second) / srtt; c > 0 {
+				return c
+			}
+		}
+	}
+	return int64(weight) * bondFallbackCapacityPerWeight
+}
+
+// bondPick runs one round of smooth weighted round-robin (nginx-style) over
+// the candidates and returns the chosen index into weights. current holds
+// the running per-candidate counters and is updated in place; it must be
+// the same length as weights. Returns -1 when there are no candidates.
+//
+// Weights are capacities in bytes/s; they are scaled to KB/s to keep the
+// counters small. Smooth WRR interleaves paths instead of sending bursts to
+// one path, which keeps the reorder depth at the receiver low.
+func bondPick(weights []int64, current []int64) int {
+	best := -1
+	var total int64
+	for i, w := range weights {
+		w /= 1000
+		if w < 1 {
+			w = 1
+		}
+		current[i] += w
+		total += w
+		if best < 0 || current[i] > current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		current[best] -= total
+	}
+	return best
+}
+
+// ─── Receiver-side reorder buffer ─────────────────────────────────────────
+
+// bondReorderStats are cumulative reorder buffer counters.
+type bondReorderStats struct {
+	InOrder   uint64 `json:"in_order"`
+	Reordered uint64 `json:"reordered"`
+	Late      uint64 `json:"late"`
+	GapSkips  uint64 `json:"gap_skips"`
+	Overflows uint64 `json:"overflows"`
+	Resyncs   uint64 `json:"resyncs"`
+	Pending   int    `json:"pending"`
+}
+
+type bondPending struct {
+	pkt     []byte
+	arrived time.Time
+}
+
+// bondReorderBuffer restores sender order for bonded packets received over
+// several paths. Packets ahead of the expected sequence are held for at
+// most hold; when the oldest held packet exceeds it (or more than max
+// packets are held) the gap is declared lost and delivery resumes from the
+// next held sequence. Packets arriving after their gap was skipped are
+// delivered immediately: the inner transport (TCP) copes with a late
+// segment far better than with a loss.
+//
+// deliver is called with the buffer lock held, so deliveries are strictly
+// serialized even though push is called from one goroutine per path.
+type bondReorderBuffer struct {
+	mu      sync.Mutex
+	hold    time.Duration
+	max     int
+	deliver func([]byte)
+
+	started bool
+	next    uint32
+	pending map[uint32]bondPending
+	timer   *time.Timer
+	closed  bool
+
+	stats bondReorderStats
+}
+
+func newBondReorderBuffer(hold time.Duration, max int, deliver func([]byte)) *bondReorderBuffer {
+	if hold <= 0 {
+		hold = defaultBondReorderHold
+	}
+	if max <= 0 {
+		max = defaultBondReorderMax
+	}
+	return &bondReorderBuffer{
+		hold:    hold,
+		max:     max,
+		deliver: deliver,
+		pending: make(map[uint32]bondPending),
+	}
+}
+
+// push hands a bonded packet to the buffer. pkt must not be reused by the
+// caller afterwards.
+func (b *bondReorderBuffer) push(seq uint32, pkt []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	if !b.started {
+		b.started = true
+		b.next = seq
+	}
+
+	switch {
+	case seq == b.next:
+		b.stats.InOrder++
+		b.deliver(pkt)
+		b.next++
+		b.flushLocked()
+	case bondSeqLess(seq, b.next):
+		if b.next-seq > uint32(b.max)*4 {
+			// Far behind: the sender restarted its sequence space (client
+			// restart while the server still holds the peer group).
+			b.stats.Resyncs++
+			for len(b.pending) > 0 {
+				b.skipGapLocked()
+			}
+			b.deliver(pkt)
+			b.next = seq + 1
+			return
+		}
+		b.stats.Late++
+		b.deliver(pkt)
+	default:
+		if _, dup := b.pending[seq]; dup {
+			return
+		}
+		b.pending[seq] = bondPending{pkt: pkt, arrived: time.Now()}
+		if len(b.pending) > b.max {
+			b.stats.Overflows++
+			b.skipGapLocked()
+		}
+	}
+	b.armLocked()
+}
+
+// flushLocked delivers consecutive held packets starting at b.next.
+func (b *bondReorderBuffer) flushLocked() {
+	for {
+		p, ok := b.pending[b.next]
+		if !ok {
+			return
+		}
+		delete(b.pending, b.next)
+		b.stats.Reordered++
+		b.deliver(p.pkt)
+		b.next++
+	}
+}
+
+// skipGapLocked gives up on the missing sequence(s) before the lowest held
+// packet and resumes delivery from there.
+func (b *bondReorderBuffer) skipGapLocked() {
+	if len(b.pending) == 0 {
+		return
+	}
+	first := true
+	var lowest uint32
+	for seq := range b.pending {
+		if first || bondSeqLess(seq, lowest) {
+			lowest = seq
+			first = false
+		}
+	}
+	b.stats.GapSkips++
+	b.next = lowest
+	b.flushLocked()
+}
+
+// armLocked schedules expiry of the oldest held packet.
+func (b *bondReorderBuffer) armLocked() {
+	if len(b.pending) == 0 || b.timer != nil {
+		return
+	}
+	var oldest time.Time
+	for _, p := range b.pending {
+		if oldest.IsZero() || p.arrived.Before(oldest) {
+			oldest = p.arrived
+		}
+	}
+	delay := b.hold - time.Since(oldest)
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+	b.timer = time.AfterFunc(delay, b.expire)
+}
+
+// expire skips gaps whose held packets have waited longer than hold.
+func (b *bondReorderBuffer) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timer = nil
+	if b.closed {
+		return
+	}
+	now := time.Now()
+	for len(b.pending) > 0 {
+		oldest := now
+		for _, p := range b.pending {
+			if p.arrived.Before(oldest) {
+				oldest = p.arrived
+			}
+		}
+		if now.Sub(oldest) < b.hold {
+			break
+		}
+		b.skipGapLocked()
+	}
+	b.armLocked()
+}
+
+// snapshot returns a copy of the counters.
+func (b *bondReorderBuffer) snapshot() bondReorderStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stats
+	s.Pending = len(b.pending)
+	return s
+}
+
+// close stops the expiry timer and drops held packets.
+func (b *bondReorderBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.pending = nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ─── bonding header tests ─────────────────────────────────────────────────
+
+func TestBondPacket_RoundTrip(t *testing.T) {
+	inner := []byte{0x45, 0x00, 0x00, 0x14, 0xDE, 0xAD}
+	frame := encodeBondPacket(0xFFFFFFFE, inner)
+	if len(frame) != bondHdrLen+len(inner) {
+		t.Fatalf("frame len = %d, want %d", len(frame), bondHdrLen+len(inner))
+	}
+	seq, payload, ok := decodeBondPacket(frame)
+	if !ok {
+		t.Fatal("decodeBondPacket failed on a bonded frame")
+	}
+	if seq != 0xFFFFFFFE {
+		t.Errorf("seq = %#x, want 0xFFFFFFFE", seq)
+	}
+	if string(payload) != string(inner) {
+		t.Errorf("payload = %x, want %x", payload, inner)
+	}
+}
+
+func TestBondPacket_PlainIPNotBonded(t *testing.T) {
+	v4 := []byte{0x45, 0x00, 0x00, 0x14, 0, 0, 0, 0, 64, 17}
+	v6 := []byte{0x60, 0x00, 0x00, 0x00, 0, 0, 17, 64, 0, 0}
+	if isBondedPacket(v4) || isBondedPacket(v6) {
+		t.Error("plain IPv4/IPv6 packets must not be detected as bonded")
+	}
+	if isBondedPacket([]byte{0x7f, 0, 0, 1}) {
+		t.Error("4-byte registration must not be detected as bonded")
+	}
+}
+
+func TestBondSeqLess_Wraparound(t *testing.T) {
+	if !bondSeqLess(0xFFFFFFF0, 0x00000010) {
+		t.Error("0xFFFFFFF0 should precede 0x10 across wraparound")
+	}
+	if bondSeqLess(0x10, 0xFFFFFFF0) {
+		t.Error("0x10 should follow 0xFFFFFFF0 across wraparound")
+	}
+}
+
+// ─── capacity-weighted scheduling tests ───────────────────────────────────
+
+func TestBondPick_ProportionalToCapacity(t *testing.T) {
+	weights := []int64{300_000, 100_000} // 3:1
+	current := make([]int64, 2)
+	counts := make([]int, 2)
+	for i := 0; i < 400; i++ {
+		counts[bondPick(weights, current)]++
+	}
+	if counts[0] != 300 || counts[1] != 100 {
+		t.Errorf("counts = %v, want [300 100]", counts)
+	}
+}
+
+func TestBondPick_Interleaves(t *testing.T) {
+	weights := []int64{100_000, 100_000}
+	current := make([]int64, 2)
+	prev := bondPick(weights, current)
+	for i := 0; i < 10; i++ {
+		next := bondPick(weights, current)
+		if next == prev {
+			t.Fatalf("equal-capacity paths should alternate, got %d twice", next)
+		}
+		prev = next
+	}
+}
+
+func TestBondCapacity(t *testing.T) {
+	st := &quicPathStats{
+		smoothedRTT: int64(50 * time.Millisecond),
+		cwnd:        500_000,
+	}
+	// 500 kB per 50 ms = 10 MB/s
+	if c := bondCapacity(st, 1); c != 10_000_000 {
+		t.Errorf("capacity = %d, want 10000000", c)
+	}
+	if c := bondCapacity(nil, 4); c != 4*bondFallbackCapacityPerWeight {
+		t.Errorf("fallback capacity = %d, want %d", c, 4*bondFallbackCapacityPerWeight)
+	}
+}
+
+// ─── reorder buffer tests ─────────────────────────────────────────────────
+
+type bondSink struct {
+	mu  sync.Mutex
+	got []byte
+}
+
+func (s *bondSink) deliver(pkt []byte) {
+	s.mu.Lock()
+	s.got = append(s.got, pkt[0])
+	s.mu.Unlock()
+}
+
+func (s *bondSink) order() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.got...)
+}
+
+func TestBondReorder_RestoresOrder(t *testing.T) {
+	sink := &bondSink{}
+	b := newBondReorderBuffer(time.Second, 64, sink.deliver)
+	defer b.close()
+
+	for _, seq := range []uint32{10, 12, 13, 11, 14} {
+		b.push(seq, []byte{byte(seq)})
+	}
+	if got := sink.order(); string(got) != string([]byte{10, 11, 12, 13, 14}) {
+		t.Errorf("delivery order = %v, want [10 11 12 13 14]", got)
+	}
+	st := b.snapshot()
+	if st.Reordered != 2 || st.Pending != 0 {
+		t.Errorf("stats = %+v, want reordered=2 pending=0", st)
+	}
+}
+
+func TestBondReorder_HoldTimeoutSkipsGap(t *testing.T) {
+	sink := &bondSink{}
+	b := newBondReorderBuffer(20*time.Millisecond, 64, sink.deliver)
+	defer b.close()
+
+	b.push(1, []byte{1})
+	b.push(3, []byte{3}) // 2 is lost
+	if got := sink.order(); len(got) != 1 {
+		t.Fatalf("delivered %v before hold expiry, want only [1]", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && len(sink.order()) < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sink.order(); string(got) != string([]byte{1, 3}) {
+		t.Fatalf("delivery order = %v, want [1 3] after hold expiry", got)
+	}
+	if st := b.snapshot(); st.GapSkips != 1 {
+		t.Errorf("gap_skips = %d, want 1", st.GapSkips)
+	}
+
+	// The lost packet shows up late: delivered immediately, not dropped.
+	b.push(2, []byte{2})
+	if got := sink.order(); string(got) != string([]byte{1, 3, 2}) {
+		t.Errorf("delivery order = %v, want [1 3 2]", got)
+	}
+	if st := b.snapshot(); st.Late != 1 {
+		t.Errorf("late = %d, want 1", st.Late)
+	}
+}
+
+func TestBondReorder_OverflowSkipsGap(t *testing.T) {
+	sink := &bondSink{}
+	b := newBondReorderBuffer(time.Hour, 4, sink.deliver)
+	defer b.close()
+
+	b.push(0, []byte{0})
+	for seq := uint32(2); seq <= 6; seq++ { // 1 missing, 5 held > max 4
+		b.push(seq, []byte{byte(seq)})
+	}
+	if got := sink.order(); string(got) != string([]byte{0, 2, 3, 4, 5, 6}) {
+		t.Errorf("delivery order = %v, want [0 2 3 4 5 6]", got)
+	}
+	if st := b.snapshot(); st.Overflows != 1 {
+		t.Errorf("overflows = %d, want 1", st.Overflows)
+	}
+}
+
+func TestBondReorder_SenderRestartResyncs(t *testing.T) {
+	sink := &bondSink{}
+	b := newBondReorderBuffer(time.Hour, 8, sink.deliver)
+	defer b.close()
+
+	b.push(100000, []byte{1})
+	b.push(0, []byte{2}) // sender restarted
+	b.push(2, []byte{4})
+	b.push(1, []byte{3})
+	if got := sink.order(); string(got) != string([]byte{1, 2, 3, 4}) {
+		t.Errorf("delivery order = %v, want [1 2 3 4]", got)
+	}
+	if st := b.snapshot(); st.Resyncs != 1 {
+		t.Errorf("resyncs = %d, want 1", st.Resyncs)
+	}
+}
+
+// ─── server bonded dispatch ───────────────────────────────────────────────
+
+func TestConnectionTable_BondedDispatchSpreadsFlow(t *testing.T) {
+	ct := newConnectionTable()
+	peer := netip.MustParseAddr("10.200.1.1")
+	now := time.Now()
+	grp := &connGroup{
+		peerIP:  peer,
+		bonding: true,
+		paths: []*pathConn{
+			{remoteAddr: "wan1", sendCh: make(chan []byte, 64), lastRecv: now},
+			{remoteAddr: "wan2", sendCh: make(chan []byte, 64), lastRecv: now},
+		},
+	}
+	ct.byIP[peer] = grp
+
+	// Minimal IPv4/UDP packet: one flow (10.0.0.1:443 → 10.200.1.1:50000)
+	pkt := make([]byte, 28)
+	pkt[0] = 0x45
+	pkt[9] = 17
+	copy(pkt[12:16], []byte{10, 0, 0, 1})
+	copy(pkt[16:20], []byte{10, 200, 1, 1})
+	pkt[20], pkt[21] = 0x01, 0xBB
+	pkt[22], pkt[23] = 0xC3, 0x50
+	for i := 0; i < 10; i++ {
+		if !ct.dispatch(peer, append([]byte(nil), pkt...)) {
+			t.Fatalf("dispatch %d failed", i)
+		}
+	}
+
+	if len(grp.paths[0].sendCh) != 5 || len(grp.paths[1].sendCh) != 5 {
+		t.Errorf("queued = %d/%d, want 5/5 (one flow spread over both paths)",
+			len(grp.paths[0].sendCh), len(grp.paths[1].sendCh))
+	}
+	// Sequence numbers are contiguous across paths.
+	seen := make(map[uint32]bool)
+	for _, pc := range grp.paths {
+		for len(pc.sendCh) > 0 {
+			seq, inner, ok := decodeBondPacket(<-pc.sendCh)
+			if !ok || len(inner) != len(pkt) {
+				t.Fatal("dispatched packet is not a bonded frame of the original")
+			}
+			seen[seq] = true
+		}
+	}
+	for seq := uint32(0); seq < 10; seq++ {
+		if !seen[seq] {
+			t.Errorf("missing seq %d", seq)
+		}
+	}
+}
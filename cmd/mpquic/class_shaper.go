@@ -0,0 +1,266 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ─── Per-class rate limiting ──────────────────────────────────────────────
+//
+// classShaper enforces the per-class max_rate_mbps / min_rate_mbps /
+// burst_bytes of a dataplane with token buckets, in the spirit of HTB:
+//
+//   - total_rate_mbps is the root bucket: the capacity shared by all classes.
+//   - min_rate_mbps is a class's guaranteed bucket. Traffic within the
+//     guarantee always passes (it still debits the root, so the aggregate
+//     stays near total_rate_mbps).
+//   - Above its guarantee a class borrows from the root. Classes with a
+//     higher borrow_priority borrow first: a class may only borrow while the
+//     root keeps the burst of every higher-priority class in reserve, so
+//     unused capacity flows to critical traffic before bulk.
+//   - max_rate_mbps is a hard ceiling, whether sending on the guarantee or
+//     borrowed capacity.
+//
+// Packets over the limit are dropped (policing), not delayed: the client's
+// TUN reader and the server's dispatcher are single goroutines shared by
+// all classes, so delaying one packet would delay every class behind it.
+// TCP reacts to the drops by backing off to the allowed rate.
+
+const (
+	// Default bucket depth when burst_bytes is 0: 50 ms at the bucket rate,
+	// never less than ~10 full-size packets.
+	classShaperBurstWindow = 50 * time.Millisecond
+	classShaperMinBurst    = 15000
+)
+
+// tokenBucket is a byte token bucket refilled lazily on access.
+// Not thread-safe: callers hold classShaper.mu.
+type tokenBucket struct {
+	rateBPS float64 // bytes/second
+	burst   float64
+	tokens  float64 // may go negative down to -burst (root only)
+	last    time.Time
+}
+
+func newTokenBucket(rateMbps float64, burstBytes int, now time.Time) *tokenBucket {
+	if rateMbps <= 0 {
+		return nil
+	}
+	rate := rateMbps * 1e6 / 8
+	burst := float64(burstBytes)
+	if burst <= 0 {
+		burst = rate * classShaperBurstWindow.Seconds()
+		if burst < classShaperMinBurst {
+			burst = classShaperMinBurst
+		}
+	}
+	return &tokenBucket{rateBPS: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rateBPS
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+func (b *tokenBucket) has(n float64) bool {
+	return b == nil || b.tokens >= n
+}
+
+// take consumes n tokens. The root bucket may be driven negative by
+// guaranteed traffic; floor bounds how far, so a burst of guaranteed
+// traffic cannot lock out borrowers for longer than one burst window.
+func (b *tokenBucket) take(n float64) {
+	if b == nil {
+		return
+	}
+	b.tokens -= n
+	if b.tokens < -b.burst {
+		b.tokens = -b.burst
+	}
+}
+
+type shapedClass struct {
+	guar    *tokenBucket // min_rate_mbps (nil = no guarantee)
+	ceil    *tokenBucket // max_rate_mbps (nil = no ceiling)
+	reserve float64      // root tokens left for higher borrow_priority classes
+
+	maxRateMbps float64
+	minRateMbps float64
+
+	passed   uint64
+	borrowed uint64
+	policed  uint64
+}
+
+// ClassShapingStats is the per-class shaper view exposed by /api/v1/stats.
+type ClassShapingStats struct {
+	Class       string  `json:"class"`
+	MaxRateMbps float64 `json:"max_rate_mbps,omitempty"`
+	MinRateMbps float64 `json:"min_rate_mbps,omitempty"`
+	Passed      uint64  `json:"passed_pkts"`
+	Borrowed    uint64  `json:"borrowed_pkts"`
+	Policed     uint64  `json:"policed_pkts"`
+}
+
+type classShaper struct {
+	mu           sync.Mutex
+	root         *tokenBucket // total_rate_mbps (nil = no shared capacity limit)
+	classes      map[string]*shapedClass
+	defaultClass string
+}
+
+// newClassShaper builds a shaper for dp, or returns nil when dp sets no
+// rates at all (the common case: zero overhead on the send path).
+func newClassShaper(dp compiledDataplane) *classShaper {
+	if !dp.hasRateLimits() {
+		return nil
+	}
+	now := time.Now()
+	s := &classShaper{
+		root:         newTokenBucket(dp.totalRateMbps, 0, now),
+		classes:      make(map[string]*shapedClass, len(dp.classes)),
+		defaultClass: dp.defaultClass,
+	}
+	for name, policy := range dp.classes {
+		c := &shapedClass{
+			guar:        newTokenBucket(policy.MinRateMbps, policy.BurstBytes, now),
+			ceil:        newTokenBucket(policy.MaxRateMbps, policy.BurstBytes, now),
+			maxRateMbps: policy.MaxRateMbps,
+			minRateMbps: policy.MinRateMbps,
+		}
+		// Reserve the burst of every class that borrows before this one.
+		for otherName, other := range dp.classes {
+			if otherName == name || other.BorrowPriority <= policy.BorrowPriority {
+				continue
+			}
+			c.reserve += classBurst(other)
+		}
+		s.classes[name] = c
+	}
+	return s
+}
+
+// classBurst is the burst a class may borrow in one go.
+func classBurst(policy DataplaneClassPolicy) float64 {
+	if policy.BurstBytes > 0 {
+		return float64(policy.BurstBytes)
+	}
+	rate := policy.MaxRateMbps
+	if rate <= 0 {
+		rate = policy.MinRateMbps
+	}
+	burst := rate * 1e6 / 8 * classShaperBurstWindow.Seconds()
+	if burst < classShaperMinBurst {
+		burst = classShaperMinBurst
+	}
+	return burst
+}
+
+// allow reports whether a packet of size bytes in className may be sent now,
+// and charges the buckets if so. A nil shaper allows everything.
+func (s *classShaper) allow(className string, size int) bool {
+	if s == nil {
+		return true
+	}
+	n := float64(size)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.classes[className]
+	if c == nil {
+		c = s.classes[s.defaultClass]
+	}
+	if c == nil {
+		return true
+	}
+	c.guar.refill(now)
+	c.ceil.refill(now)
+	s.root.refill(now)
+
+	if !c.ceil.has(n) {
+		c.policed++
+		return false
+	}
+	if c.guar != nil && c.guar.has(n) {
+		c.guar.take(n)
+		c.ceil.take(n)
+		s.root.take(n)
+		c.passed++
+		return true
+	}
+	if s.root == nil {
+		c.ceil.take(n)
+		c.passed++
+		return true
+	}
+	if s.root.tokens-n >= c.reserve {
+		s.root.take(n)
+		c.ceil.take(n)
+		c.passed++
+		c.borrowed++
+		return true
+	}
+	c.policed++
+	return false
+}
+
+// snapshot returns per-class counters sorted by class name.
+func (s *classShaper) snapshot() []ClassShapingStats {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ClassShapingStats, 0, len(s.classes))
+	for name, c := range s.classes {
+		out = append(out, ClassShapingStats{
+			Class:       name,
+			MaxRateMbps: c.maxRateMbps,
+			MinRateMbps: c.minRateMbps,
+			Passed:      c.passed,
+			Borrowed:    c.borrowed,
+			Policed:     c.policed,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Class < out[j].Class })
+	return out
+}
+
+// mergeClassShapingStats sums per-class counters of several shapers
+// (the server keeps one shaper per peer).
+func mergeClassShapingStats(snapshots ...[]ClassShapingStats) []ClassShapingStats {
+	byClass := make(map[string]*ClassShapingStats)
+	for _, snap := range snapshots {
+		for _, st := range snap {
+			agg := byClass[st.Class]
+			if agg == nil {
+				copySt := st
+				byClass[st.Class] = &copySt
+				continue
+			}
+			agg.Passed += st.Passed
+			agg.Borrowed += st.Borrowed
+			agg.Policed += st.Policed
+		}
+	}
+	if len(byClass) == 0 {
+		return nil
+	}
+	out := make([]ClassShapingStats, 0, len(byClass))
+	for _, st := range byClass {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Class < out[j].Class })
+	return out
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// Rates in these tests are low enough that refill during a test loop
+// (microseconds) is well below one packet.
+
+func mustCompileDataplane(t *testing.T, dp DataplaneConfig) compiledDataplane {
+	t.Helper()
+	normalizeDataplaneConfig(&dp, "priority")
+	if err := validateDataplaneConfig(dp, nil); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	out, err := compileDataplaneConfig(dp)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return out
+}
+
+func countAllowed(s *classShaper, class string, size, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if s.allow(class, size) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestClassShaper_NilWithoutRates(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{"default": {}},
+	})
+	if dp.shaper != nil {
+		t.Fatal("shaper built for a dataplane without rates")
+	}
+	if !dp.shaper.allow("default", 1500) {
+		t.Error("nil shaper must allow every packet")
+	}
+}
+
+func TestClassShaper_MaxRateCapsBurst(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"default": {MaxRateMbps: 1, BurstBytes: 15000},
+		},
+	})
+	if got := countAllowed(dp.shaper, "default", 1500, 20); got != 10 {
+		t.Errorf("allowed = %d, want 10 (burst_bytes / packet size)", got)
+	}
+	st := dp.shaper.snapshot()
+	if len(st) != 1 || st[0].Passed != 10 || st[0].Policed != 10 {
+		t.Errorf("stats = %+v, want passed=10 policed=10", st)
+	}
+}
+
+func TestClassShaper_MinGuaranteeSurvivesBulk(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		TotalRateMbps: 10, // root burst 62500 bytes
+		Classes: map[string]DataplaneClassPolicy{
+			"default":  {},
+			"critical": {MinRateMbps: 1, BurstBytes: 15000, BorrowPriority: 1},
+		},
+	})
+	s := dp.shaper
+
+	// Bulk drains what it may borrow, leaving critical's burst in reserve.
+	bulk := countAllowed(s, "default", 1500, 100)
+	if bulk == 0 || bulk == 100 {
+		t.Fatalf("bulk allowed = %d, want policed at the shared rate", bulk)
+	}
+	if s.root.tokens < 15000-1500 {
+		t.Errorf("root tokens = %.0f, bulk borrowed into critical's reserve", s.root.tokens)
+	}
+
+	// Critical still gets its full guarantee.
+	if got := countAllowed(s, "critical", 1500, 10); got != 10 {
+		t.Errorf("critical allowed = %d, want 10 despite bulk saturation", got)
+	}
+	// And bulk stays policed.
+	if s.allow("default", 1500) {
+		t.Error("bulk allowed after root exhausted")
+	}
+}
+
+func TestClassShaper_PriorityClassBorrowsIdleCapacity(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		TotalRateMbps: 10,
+		Classes: map[string]DataplaneClassPolicy{
+			"default":  {MinRateMbps: 5},
+			"critical": {MinRateMbps: 1, MaxRateMbps: 8, BorrowPriority: 1},
+		},
+	})
+	s := dp.shaper
+
+	// default is idle: critical goes past its 10-packet guarantee on
+	// borrowed capacity.
+	got := countAllowed(s, "critical", 1500, 30)
+	if got <= 10 {
+		t.Fatalf("critical allowed = %d, want > 10 with default idle", got)
+	}
+	var borrowed uint64
+	for _, st := range s.snapshot() {
+		if st.Class == "critical" {
+			borrowed = st.Borrowed
+		}
+	}
+	if borrowed != uint64(got-10) {
+		t.Errorf("borrowed = %d, want %d", borrowed, got-10)
+	}
+}
+
+func TestClassShaper_UnknownClassUsesDefault(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"default": {MaxRateMbps: 1, BurstBytes: 3000},
+		},
+	})
+	if got := countAllowed(dp.shaper, "ghost", 1500, 5); got != 2 {
+		t.Errorf("allowed = %d, want 2 (default class cap)", got)
+	}
+}
+
+func TestMergeClassShapingStats(t *testing.T) {
+	merged := mergeClassShapingStats(
+		[]ClassShapingStats{{Class: "default", Passed: 3, Policed: 1}},
+		[]ClassShapingStats{{Class: "critical", Passed: 2}, {Class: "default", Passed: 4, Borrowed: 2}},
+	)
+	if len(merged) != 2 || merged[0].Class != "critical" {
+		t.Fatalf("merged = %+v, want [critical default]", merged)
+	}
+	if d := merged[1]; d.Passed != 7 || d.Borrowed != 2 || d.Policed != 1 {
+		t.Errorf("default = %+v, want passed=7 borrowed=2 policed=1", d)
+	}
+}
+
+func TestConnectionTable_DispatchPolicesPerPeer(t *testing.T) {
+	dp := mustCompileDataplane(t, DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"default": {MaxRateMbps: 1, BurstBytes: 3000},
+		},
+	})
+	ct := newConnectionTable()
+	ct.dataplane = &dp
+	peers := []netip.Addr{netip.MustParseAddr("10.200.1.1"), netip.MustParseAddr("10.200.1.2")}
+	for _, peer := range peers {
+		ct.byIP[peer] = &connGroup{
+			peerIP: peer,
+			paths:  []*pathConn{{remoteAddr: peer.String(), sendCh: make(chan []byte, 64), lastRecv: time.Now()}},
+		}
+	}
+
+	pkt := make([]byte, 1500)
+	pkt[0] = 0x45
+	for _, peer := range peers {
+		for i := 0; i < 5; i++ {
+			if !ct.dispatch(peer, pkt) {
+				t.Fatalf("dispatch to %s reported failure for a policed packet", peer)
+			}
+		}
+		if q := len(ct.byIP[peer].paths[0].sendCh); q != 2 {
+			t.Errorf("peer %s queued = %d, want 2 (own 3000-byte burst)", peer, q)
+		}
+	}
+	if st := snapshotClassShapingStats(ct); len(st) != 1 || st[0].Passed != 4 || st[0].Policed != 6 {
+		t.Errorf("stats = %+v, want passed=4 policed=6", st)
+	}
+}
@@ -9,9 +9,20 @@ import (
 )
 
 type compiledDataplane struct {
-	defaultClass string
-	classes      map[string]DataplaneClassPolicy
-	classifiers  []compiledClassifierRule
+	defaultClass  string
+	classes       map[string]DataplaneClassPolicy
+	classifiers   []compiledClassifierRule
+	totalRateMbps float64
+	shaper        *classShaper // nil when no class sets a rate
+	// domains is the runtime DNS-learned cache (see dns_snoop.go). It is
+	// not part of the compiled policy: the owner sets it after compiling so
+	// that it survives dataplane reloads.
+	domains        *domainCache
+	hasDomainRules bool
+	// sniFlows is the runtime per-flow SNI memory (see sni.go), owned and
+	// set like domains.
+	sniFlows    *sniFlowTable
+	hasSNIRules bool
 }
 
 type compiledClassifierRule struct {
@@ -23,6 +34,8 @@ type compiledClassifierRule struct {
 	srcPorts  []portRange
 	dstPorts  []portRange
 	dscp      map[uint8]struct{}
+	domains   []string
+	sni       []string
 }
 
 type portRange struct {
@@ -38,18 +51,25 @@ type packetMeta struct {
 	dstPort  uint16
 	hasPorts bool
 	dscp     uint8
+	domains  []string // DNS names bound to the remote address (domain rules only)
+	sni      string   // TLS/QUIC server name of the flow (sni rules only)
+	l4Offset int      // start of the TCP/UDP header in the packet
 }
 
 type trafficClassCounters struct {
 	txPackets    uint64
 	txErrors     uint64
 	txDuplicates uint64
+	txDupBytes   uint64 // bytes sent as extra copies
+	txPoliced    uint64
+	adaptive     adaptiveDupState
 }
 
 func compileDataplaneConfig(dp DataplaneConfig) (compiledDataplane, error) {
 	out := compiledDataplane{
-		defaultClass: dp.DefaultClass,
-		classes:      make(map[string]DataplaneClassPolicy, len(dp.Classes)),
+		defaultClass:  dp.DefaultClass,
+		classes:       make(map[string]DataplaneClassPolicy, len(dp.Classes)),
+		totalRateMbps: dp.TotalRateMbps,
 	}
 
 	for className, policy := range dp.Classes {
@@ -88,12 +108,84 @@ func compileDataplaneConfig(dp DataplaneConfig) (compiledDataplane, error) {
 			srcPorts:  srcPorts,
 			dstPorts:  dstPorts,
 			dscp:      dscp,
+			domains:   rule.Domains,
+			sni:       rule.SNI,
 		})
+		if len(rule.Domains) > 0 {
+			out.hasDomainRules = true
+		}
+		if len(rule.SNI) > 0 {
+			out.hasSNIRules = true
+		}
 	}
 
+	out.shaper = newClassShaper(out)
 	return out, nil
 }
 
+// hasRateLimits reports whether any rate is configured, i.e. whether the
+// dataplane needs a classShaper.
+func (dp compiledDataplane) hasRateLimits() bool {
+	if dp.totalRateMbps > 0 {
+		return true
+	}
+	for _, policy := range dp.classes {
+		if policy.MaxRateMbps > 0 || policy.MinRateMbps > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the class of pkt: the first matching classifier, else
+// the default class.
+func (dp compiledDataplane) resolve(pkt []byte) (string, DataplaneClassPolicy) {
+	return dp.resolveDir(pkt, false)
+}
+
+// resolveReturn classifies a packet sent towards the client with a policy
+// written from the client's side: source and destination are swapped, so a
+// rule for upstream traffic (dst_ports 5060) matches its replies.
+func (dp compiledDataplane) resolveReturn(pkt []byte) (string, DataplaneClassPolicy) {
+	return dp.resolveDir(pkt, true)
+}
+
+func (dp compiledDataplane) resolveDir(pkt []byte, reverse bool) (string, DataplaneClassPolicy) {
+	meta, ok := parsePacketMeta(pkt)
+	if ok {
+		if reverse {
+			meta.srcAddr, meta.dstAddr = meta.dstAddr, meta.srcAddr
+			meta.srcPort, meta.dstPort = meta.dstPort, meta.srcPort
+		}
+		if dp.hasDomainRules && dp.domains != nil {
+			// Client TX: the remote end is the destination. Fall back to the
+			// source for return traffic.
+			meta.domains = dp.domains.lookup(meta.dstAddr)
+			if len(meta.domains) == 0 {
+				meta.domains = dp.domains.lookup(meta.srcAddr)
+			}
+		}
+		if dp.hasSNIRules {
+			meta.sni = dp.sniFlows.lookupOrLearn(pkt, meta)
+		}
+		for _, rule := range dp.classifiers {
+			if rule.matches(meta) {
+				if classPolicy, found := dp.classes[rule.className]; found {
+					return rule.className, classPolicy
+				}
+			}
+		}
+	}
+
+	className := dp.defaultClass
+	classPolicy, found := dp.classes[className]
+	if !found {
+		className = "default"
+		classPolicy = DataplaneClassPolicy{SchedulerPolicy: "priority"}
+	}
+	return className, classPolicy
+}
+
 func parseCIDRs(values []string) ([]netip.Prefix, error) {
 	if len(values) == 0 {
 		return nil, nil
@@ -174,6 +266,12 @@ func (r compiledClassifierRule) matches(meta packetMeta) bool {
 			return false
 		}
 	}
+	if len(r.domains) > 0 && !matchDomainPatterns(meta.domains, r.domains) {
+		return false
+	}
+	if len(r.sni) > 0 && (meta.sni == "" || !matchDomainPatterns([]string{meta.sni}, r.sni)) {
+		return false
+	}
 	return true
 }
 
@@ -226,6 +324,7 @@ func parsePacketMeta(pkt []byte) (packetMeta, bool) {
 			meta.srcPort = binary.BigEndian.Uint16(pkt[ihl : ihl+2])
 			meta.dstPort = binary.BigEndian.Uint16(pkt[ihl+2 : ihl+4])
 			meta.hasPorts = true
+			meta.l4Offset = ihl
 		}
 		return meta, true
 
@@ -248,6 +347,7 @@ func parsePacketMeta(pkt []byte) (packetMeta, bool) {
 			meta.srcPort = binary.BigEndian.Uint16(pkt[40:42])
 			meta.dstPort = binary.BigEndian.Uint16(pkt[42:44])
 			meta.hasPorts = true
+			meta.l4Offset = 40
 		}
 		return meta, true
 	}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go"
@@ -22,6 +24,7 @@ type multipathPathState struct {
 	conn             quic.Connection
 	dc               datagramConn
 	stripeConn       *stripeClientConn   // non-nil for stripe transport paths
+	mpPath           *quic.Path          // non-nil for additional quic-mp paths (see quic_mp.go)
 	alive            bool
 	reconnecting     bool
 	consecutiveFails int
@@ -32,6 +35,25 @@ type multipathPathState struct {
 	rxErrors         uint64
 	lastUp           time.Time
 	lastDown         time.Time
+	quicStats        *quicPathStats // tracer-fed RTT/loss, nil for stripe paths
+	quality          pathQuality
+	bondCurrent      int64 // smooth-WRR counter for scheduler_policy=bonding
+
+	// Runtime path management (see client_paths.go). Removed paths keep
+	// their slot so path indices held by goroutines and the flow table stay
+	// valid; they are skipped everywhere.
+	ctx           context.Context // cancelled when the path is removed
+	cancel        context.CancelFunc
+	draining      bool
+	drainDeadline time.Time
+	removed       bool
+
+	probe *probeState // nil when probing is disabled for the path
+
+	// Peer hello (see peer_dataplane.go): the connection it was last sent
+	// on and when, so a reconnected path sends it again at once.
+	ctlDC     datagramConn
+	ctlSentAt time.Time
 }
 
 type multipathConn struct {
@@ -45,7 +67,26 @@ type multipathConn struct {
 	cfg     *Config
 	dataplane compiledDataplane
 	classTx   map[string]*trafficClassCounters
+	domains   *domainCache // DNS-learned addr → name bindings for domain rules
+	sniFlows  *sniFlowTable // per-flow TLS/QUIC server names for sni rules
 	baseCtx context.Context
+	bondTxSeq uint32             // atomic: next bonding sequence number
+	bondRx    *bondReorderBuffer // restores order of bonded packets from the server
+	closed    chan struct{}      // closed by closeAll; unblocks bondRx delivery
+	closeOnce sync.Once
+	// Flow affinity (see client_flow.go): flow hash → path index.
+	flowPaths  map[uint32]int
+	flowPrev   map[uint32]int
+	flowRepins uint64
+	// Return-direction policy (see peer_dataplane.go).
+	tunAddrs   []netip.Addr
+	peerPolicy [][]byte       // pushed dataplane datagrams, nil unless dataplane_push
+	rxDup      *packetDedup   // drops the extra copies of duplicated return packets
+	// Connection shared by the transport: quic-mp paths (see quic_mp.go).
+	quicMP quicMPSession
+	// Coupled congestion control of the pipes of a path (see coupled_cc.go).
+	lia liaGroups
+	qlog *qlogTraces // nil without qlog_dir (see qlog.go)
 }
 
 func runClientLoop(ctx context.Context, cfg *Config, logger *Logger) error {
@@ -77,9 +118,10 @@ func runClientOnce(ctx context.Context, cfg *Config, logger *Logger) error {
 	if err != nil {
 		return err
 	}
-	defer udpConn.Close()
+	wan := newWANMigrator(cfg, bindIP, udpConn, logger)
+	defer wan.close()
 
-	remoteUDP, err := net.ResolveUDPAddr("udp", net.JoinHostPort(cfg.RemoteAddr, fmt.Sprintf("%d", cfg.RemotePort)))
+	remoteUDP, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(cfg.RemoteAddr, fmt.Sprintf("%d", cfg.RemotePort)))
 	if err != nil {
 		return err
 	}
@@ -87,14 +129,17 @@ func runClientOnce(ctx context.Context, cfg *Config, logger *Logger) error {
 	if err != nil {
 		return err
 	}
+	qlogs, err := newQlogTraces(cfg, logger)
+	if err != nil {
+		return err
+	}
 
-	transport := quic.Transport{Conn: udpConn}
-	conn, err := transport.Dial(ctx, remoteUDP, tlsConf, &quic.Config{
-		EnableDatagrams:     true,
-		KeepAlivePeriod:     15 * time.Second,
-		MaxIdleTimeout:      60 * time.Second,
-		CongestionAlgorithm: cfg.CongestionAlgorithm,
-	})
+	conn, err := dialEarly(ctx, wan.transport, remoteUDP, tlsConf, withCongestionControl(&quic.Config{
+		EnableDatagrams: true,
+		KeepAlivePeriod: 15 * time.Second,
+		MaxIdleTimeout:  60 * time.Second,
+		Tracer:          qlogs.tracer("tunnel", "client", false, nil),
+	}, cfg.CongestionAlgorithm, nil))
 	if err != nil {
 		return err
 	}
@@ -102,6 +147,12 @@ func runClientOnce(ctx context.Context, cfg *Config, logger *Logger) error {
 
 	logger.Infof("connected local=%s remote=%s tun=%s", udpConn.LocalAddr(), remoteUDP.String(), cfg.TunName)
 
+	// Follow WAN address changes by migrating the connection (wan_migration.go).
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	wan.conn = conn
+	wan.watch(watchCtx)
+
 	var dc datagramConn
 	if cfg.TransportMode == "reliable" {
 		sc, err := openStreamConn(ctx, conn)
@@ -144,6 +195,15 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 	if err != nil {
 		return nil, err
 	}
+	domains := newDomainCache(defaultDomainCacheMax)
+	sniFlows := newSNIFlowTable(defaultSNIFlowMax)
+	dpRuntime.domains = domains
+	dpRuntime.sniFlows = sniFlows
+
+	qlogs, err := newQlogTraces(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Expand pipes: paths with pipes > 1 become N internal path entries
 	expandedPaths := expandMultipathPipes(cfg.MultipathPaths, cfg, logger)
@@ -154,9 +214,29 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 		logger:  logger,
 		cfg:     cfg,
 		dataplane: dpRuntime,
+		domains:   domains,
+		sniFlows:  sniFlows,
 		classTx: make(map[string]*trafficClassCounters),
 		baseCtx: ctx,
-	}
+		closed:  make(chan struct{}),
+		flowPaths: make(map[uint32]int),
+		rxDup:     newPacketDedup(1024),
+		qlog:      qlogs,
+	}
+	mp.quicMP.qlog = qlogs
+	mp.tunAddrs = cfg.tunAddrs()
+	mp.setPeerPolicyLocked(cfg.Dataplane)
+	mp.bondRx = newBondReorderBuffer(
+		time.Duration(cfg.BondingReorderHoldMs)*time.Millisecond,
+		cfg.BondingReorderMax,
+		func(pkt []byte) {
+			select {
+			case <-ctx.Done():
+			case <-mp.closed:
+			case mp.recvCh <- pkt:
+			}
+		},
+	)
 	registerMetricsClient(mp)
 
 	for className := range dpRuntime.classes {
@@ -167,6 +247,10 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 
 	for _, p := range expandedPaths {
 		state := &multipathPathState{cfg: p}
+		state.ctx, state.cancel = context.WithCancel(ctx)
+		if interval, mult := probeSettings(p, cfg); interval > 0 {
+			state.probe = newProbeState(interval, mult)
+		}
 		mp.paths = append(mp.paths, state)
 
 		effectiveTransport := resolvePathTransport(p, cfg, logger)
@@ -179,7 +263,7 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 				state.reconnecting = true
 				continue
 			}
-			keys, err := stripeNegotiateKey(ctx, cfg, p, sessionID, logger)
+			keys, err := stripeNegotiateKey(ctx, cfg, p, sessionID, mp.qlog, logger)
 			if err != nil {
 				logger.Errorf("stripe key exchange failed name=%s err=%v", p.Name, err)
 				state.reconnecting = true
@@ -193,6 +277,7 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 			}
 			state.dc = sc
 			state.stripeConn = sc
+			state.quality.reset()
 			state.alive = true
 			state.reconnecting = false
 			state.lastUp = time.Now()
@@ -201,6 +286,17 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 			continue
 		}
 
+		// ── QUIC multipath transport (one shared connection) ──────
+		if effectiveTransport == "quic-mp" {
+			if err := mp.connectQUICMP(ctx, len(mp.paths)-1, p); err != nil {
+				logger.Errorf("quic-mp path init failed name=%s err=%v", p.Name, err)
+				state.reconnecting = true
+				continue
+			}
+			aliveCount++
+			continue
+		}
+
 		// ── QUIC transport (default) ──────────────────────────────
 		bindIP, err := resolveBindIP(p.BindIP)
 		if err != nil {
@@ -217,7 +313,7 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 			continue
 		}
 
-		remoteUDP, err := net.ResolveUDPAddr("udp", net.JoinHostPort(p.RemoteAddr, fmt.Sprintf("%d", p.RemotePort)))
+		remoteUDP, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(p.RemoteAddr, fmt.Sprintf("%d", p.RemotePort)))
 		if err != nil {
 			_ = udpConn.Close()
 			logger.Errorf("path init failed name=%s step=remote-resolve err=%v", p.Name, err)
@@ -234,12 +330,13 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 		}
 
 		transport := quic.Transport{Conn: udpConn}
-		conn, err := transport.Dial(ctx, remoteUDP, tlsConf, &quic.Config{
-			EnableDatagrams:     true,
-			KeepAlivePeriod:     15 * time.Second,
-			MaxIdleTimeout:      60 * time.Second,
-			CongestionAlgorithm: cfg.CongestionAlgorithm,
-		})
+		qstats := &quicPathStats{}
+		conn, err := dialEarly(ctx, &transport, remoteUDP, tlsConf, withCongestionControl(&quic.Config{
+			EnableDatagrams: true,
+			KeepAlivePeriod: 15 * time.Second,
+			MaxIdleTimeout:  60 * time.Second,
+			Tracer:          mp.qlog.tracer(p.Name, "client", p.Qlog, newQUICPathTracer(qstats)),
+		}, pathCongestionAlgorithm(p, cfg), mp.lia.forPath(p).newSender))
 		if err != nil {
 			_ = udpConn.Close()
 			logger.Errorf("path init failed name=%s step=dial err=%v", p.Name, err)
@@ -250,6 +347,8 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 		state.udpConn = udpConn
 		state.transport = &transport
 		state.conn = conn
+		state.quicStats = qstats
+		state.quality.reset()
 		if cfg.TransportMode == "reliable" {
 			sc, err := openStreamConn(ctx, conn)
 			if err != nil {
@@ -275,14 +374,20 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 		return nil, fmt.Errorf("multipath: no initial path available")
 	}
 
-	for idx := range mp.paths {
-		go mp.recvLoop(ctx, idx)
-		if !mp.paths[idx].alive && mp.paths[idx].reconnecting {
-			go mp.reconnectLoop(ctx, idx)
+	for idx, p := range mp.paths {
+		go mp.recvLoop(p.ctx, idx)
+		if !p.alive && p.reconnecting {
+			go mp.reconnectLoop(p.ctx, idx)
+		}
+		if p.probe != nil {
+			go mp.probeLoop(p.ctx, idx, p.probe)
 		}
 	}
 
 	go mp.telemetryLoop(ctx)
+	go mp.qualityLoop(ctx)
+	go mp.flowGCLoop(ctx)
+	go mp.peerCtlLoop(ctx)
 
 	return mp, nil
 }
@@ -290,13 +395,38 @@ func newMultipathConn(ctx context.Context, cfg *Config, logger *Logger) (*multip
 func (m *multipathConn) SendDatagram(pkt []byte) error {
 	className, classPolicy := m.resolvePacketClass(pkt)
 
-	if classPolicy.Duplicate {
+	// Over its rate: drop silently. Returning an error would tear down the
+	// tunnel; the inner transport sees a loss and backs off.
+	if !m.dataplane.shaper.allow(className, len(pkt)) {
+		m.markClassPoliced(className)
+		return nil
+	}
+
+	if classPolicy.Duplicate && m.duplicationActive(className, classPolicy) {
 		return m.sendDuplicate(pkt, className, classPolicy)
 	}
+	if classPolicy.SchedulerPolicy == "bonding" {
+		return m.sendBonded(pkt, className, classPolicy)
+	}
+	return m.sendBestPath(pkt, className, classPolicy)
+}
+
+func (m *multipathConn) sendBestPath(pkt []byte, className string, classPolicy DataplaneClassPolicy) error {
+	var flow uint32
+	var pinFlow bool
+	if m.flowAffinity(classPolicy) {
+		flow, pinFlow = flowHash(pkt)
+	}
 
 	deadline := time.Now().Add(1200 * time.Millisecond)
 	for {
-		idx, conn := m.selectBestPath(classPolicy, nil)
+		var idx int
+		var conn datagramConn
+		if pinFlow {
+			idx, conn = m.selectFlowPath(flow, classPolicy)
+		} else {
+			idx, conn = m.selectBestPath(classPolicy, nil)
+		}
 		if idx < 0 || conn == nil {
 			if time.Now().After(deadline) {
 				m.markClassError(className)
@@ -355,20 +485,138 @@ func (m *multipathConn) sendDuplicate(pkt []byte, className string, classPolicy
 
 	m.markClassTx(className)
 	if sent > 1 {
-		m.markClassDuplicate(className, uint64(sent-1))
+		m.markClassDuplicate(className, uint64(sent-1), uint64(sent-1)*uint64(len(pkt)))
 	}
 	return nil
 }
 
-func (m *multipathConn) selectBestPath(classPolicy DataplaneClassPolicy, skip map[int]struct{}) (int, datagramConn) {
+// sendBonded sends pkt with a bonding header on the next QUIC path chosen by
+// capacity-weighted round-robin. The sequence number is only allocated once
+// a path is found, so falling back to unbonded best-path scheduling (no QUIC
+// path alive, e.g. stripe-only) never leaves a gap at the receiver.
+func (m *multipathConn) sendBonded(pkt []byte, className string, classPolicy DataplaneClassPolicy) error {
+	var frame []byte
+	var skip map[int]struct{}
+	for {
+		idx, conn := m.selectBondPath(classPolicy, skip)
+		if idx < 0 || conn == nil {
+			if frame == nil {
+				fallback := classPolicy
+				fallback.SchedulerPolicy = "priority"
+				return m.sendBestPath(pkt, className, fallback)
+			}
+			m.markClassError(className)
+			return fmt.Errorf("multipath: no active path available for bonded send")
+		}
+		if frame == nil {
+			frame = encodeBondPacket(atomic.AddUint32(&m.bondTxSeq, 1)-1, pkt)
+		}
+
+		if err := conn.SendDatagram(frame); err != nil {
+			m.markTxError(idx, err)
+			if skip == nil {
+				skip = make(map[int]struct{}, len(m.paths))
+			}
+			skip[idx] = struct{}{}
+			continue
+		}
+
+		m.markTxSuccess(idx)
+		m.markClassTx(className)
+		return nil
+	}
+}
+
+// selectBondPath picks a QUIC path for a bonded packet, in proportion to the
+// estimated capacity of each eligible path. Stripe paths are not bonded: they
+// already spread packets over their own pipes with FEC.
+func (m *multipathConn) selectBondPath(classPolicy DataplaneClassPolicy, skip map[int]struct{}) (int, datagramConn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
-	if len(m.paths) == 0 {
+	excluded := make(map[string]struct{}, len(classPolicy.ExcludedPaths))
+	for _, name := range classPolicy.ExcludedPaths {
+		excluded[name] = struct{}{}
+	}
+	preferred := make(map[string]struct{}, len(classPolicy.PreferredPaths))
+	for _, name := range classPolicy.PreferredPaths {
+		preferred[name] = struct{}{}
+	}
+
+	var candArr, prefArr [16]int
+	cand := candArr[:0]
+	pref := prefArr[:0]
+	for idx, p := range m.paths {
+		if skip != nil {
+			if _, blocked := skip[idx]; blocked {
+				continue
+			}
+		}
+		if _, blocked := excluded[p.cfg.Name]; blocked {
+			continue
+		}
+		if p.cfg.BasePath != "" {
+			if _, blocked := excluded[p.cfg.BasePath]; blocked {
+				continue
+			}
+		}
+		if !p.alive || p.dc == nil || p.stripeConn != nil || now.Before(p.cooldownUntil) {
+			continue
+		}
+		if p.draining || p.removed || p.probe.down() {
+			continue
+		}
+		cand = append(cand, idx)
+		_, nameOk := preferred[p.cfg.Name]
+		_, baseOk := preferred[p.cfg.BasePath]
+		if nameOk || baseOk {
+			pref = append(pref, idx)
+		}
+	}
+	if len(pref) > 0 {
+		cand = pref
+	}
+	if len(cand) == 0 {
+		return -1, nil
+	}
+
+	var weightArr, curArr [16]int64
+	weights := weightArr[:0]
+	current := curArr[:0]
+	for _, idx := range cand {
+		p := m.paths[idx]
+		weights = append(weights, bondCapacity(p.quicStats, p.cfg.Weight))
+		current = append(current, p.bondCurrent)
+	}
+	pick := bondPick(weights, current)
+	for i, idx := range cand {
+		m.paths[idx].bondCurrent = current[i]
+	}
+	idx := cand[pick]
+	return idx, m.paths[idx].dc
+}
+
+func (m *multipathConn) selectBestPath(classPolicy DataplaneClassPolicy, skip map[int]struct{}) (int, datagramConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bestIdx := m.selectBestPathLocked(classPolicy, skip, time.Now())
+	if bestIdx < 0 {
 		return -1, nil
 	}
 
+	m.rr = (bestIdx + 1) % len(m.paths)
+	return bestIdx, m.paths[bestIdx].dc
+}
+
+// selectBestPathLocked returns the index of the path classPolicy would send
+// on, or -1. It does not advance the round-robin cursor. Caller holds m.mu.
+func (m *multipathConn) selectBestPathLocked(classPolicy DataplaneClassPolicy, skip map[int]struct{}, now time.Time) int {
+	if len(m.paths) == 0 {
+		return -1
+	}
+
 	bestIdx := -1
 	bestScore := int(^uint(0) >> 1)
 	start := m.rr % len(m.paths)
@@ -379,6 +627,7 @@ func (m *multipathConn) selectBestPath(classPolicy DataplaneClassPolicy, skip ma
 	if policy == "" {
 		policy = "priority"
 	}
+	latencyBudget := time.Duration(classPolicy.LatencyBudgetMs) * time.Millisecond
 
 	excluded := make(map[string]struct{}, len(classPolicy.ExcludedPaths))
 	for _, name := range classPolicy.ExcludedPaths {
@@ -426,7 +675,16 @@ func (m *multipathConn) selectBestPath(classPolicy DataplaneClassPolicy, skip ma
 			if now.Before(p.cooldownUntil) {
 				continue
 			}
-			score := pathPolicyScore(policy, p)
+			// Draining paths keep their pinned flows but take no new ones.
+			if p.draining || p.removed || p.probe.down() {
+				continue
+			}
+			var score int
+			if isQualityPolicy(policy) {
+				score = pathQualityScore(policy, p, latencyBudget)
+			} else {
+				score = pathPolicyScore(policy, p)
+			}
 			if score < bestScore {
 				bestScore = score
 				bestIdx = idx
@@ -441,15 +699,14 @@ func (m *multipathConn) selectBestPath(classPolicy DataplaneClassPolicy, skip ma
 		}
 	}
 
-	if bestIdx < 0 {
-		return -1, nil
-	}
-
-	m.rr = (bestIdx + 1) % len(m.paths)
-	return bestIdx, m.paths[bestIdx].dc
+	return bestIdx
 }
 
 func pathPolicyScore(policy string, p *multipathPathState) int {
+	if isQualityPolicy(policy) {
+		return pathQualityScore(policy, p, defaultLatencyBudget)
+	}
+
 	base := p.cfg.Priority * 1000
 	failPenalty := p.consecutiveFails * 100
 
@@ -472,24 +729,7 @@ func pathPolicyScore(policy string, p *multipathPathState) int {
 }
 
 func (m *multipathConn) resolvePacketClass(pkt []byte) (string, DataplaneClassPolicy) {
-	meta, ok := parsePacketMeta(pkt)
-	if ok {
-		for _, rule := range m.dataplane.classifiers {
-			if rule.matches(meta) {
-				if classPolicy, found := m.dataplane.classes[rule.className]; found {
-					return rule.className, classPolicy
-				}
-			}
-		}
-	}
-
-	className := m.dataplane.defaultClass
-	classPolicy, found := m.dataplane.classes[className]
-	if !found {
-		className = "default"
-		classPolicy = DataplaneClassPolicy{SchedulerPolicy: "priority"}
-	}
-	return className, classPolicy
+	return m.dataplane.resolve(pkt)
 }
 
 func (m *multipathConn) markClassTx(className string) {
@@ -514,7 +754,18 @@ func (m *multipathConn) markClassError(className string) {
 	c.txErrors++
 }
 
-func (m *multipathConn) markClassDuplicate(className string, duplicates uint64) {
+func (m *multipathConn) markClassPoliced(className string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.classTx[className]
+	if c == nil {
+		c = &trafficClassCounters{}
+		m.classTx[className] = c
+	}
+	c.txPoliced++
+}
+
+func (m *multipathConn) markClassDuplicate(className string, duplicates, dupBytes uint64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	c := m.classTx[className]
@@ -523,6 +774,7 @@ func (m *multipathConn) markClassDuplicate(className string, duplicates uint64)
 		m.classTx[className] = c
 	}
 	c.txDuplicates += duplicates
+	c.txDupBytes += dupBytes
 }
 
 func (m *multipathConn) markTxSuccess(idx int) {
@@ -546,6 +798,11 @@ func (m *multipathConn) markTxError(idx int, err error) {
 	p.cooldownUntil = time.Now().Add(time.Duration(p.consecutiveFails) * time.Second)
 	p.alive = false
 	p.dc = nil
+	p.quicStats = nil
+	if p.mpPath != nil {
+		_ = p.mpPath.Close()
+		p.mpPath = nil
+	}
 	if p.conn != nil {
 		_ = p.conn.CloseWithError(0, "tx-error")
 		p.conn = nil
@@ -555,15 +812,19 @@ func (m *multipathConn) markTxError(idx int, err error) {
 		p.udpConn = nil
 	}
 	name := p.cfg.Name
-	needReconnect := !p.reconnecting
+	needReconnect := !p.reconnecting && !p.removed
 	if needReconnect {
 		p.reconnecting = true
 	}
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = m.baseCtx
+	}
 	m.mu.Unlock()
 
 	m.logger.Errorf("path tx failed name=%s err=%v", name, err)
 	if needReconnect {
-		go m.reconnectLoop(m.baseCtx, idx)
+		go m.reconnectLoop(ctx, idx)
 	}
 }
 
@@ -574,6 +835,7 @@ func (m *multipathConn) ReceiveDatagram(ctx context.Context) ([]byte, error) {
 	case err := <-m.errCh:
 		return nil, err
 	case pkt := <-m.recvCh:
+		m.domains.observe(pkt)
 		return pkt, nil
 	}
 }
@@ -609,6 +871,20 @@ func (m *multipathConn) recvLoop(ctx context.Context, idx int) {
 		m.onPathSuccess(idx)
 
 		copyPkt := append([]byte(nil), pkt...)
+		if isProbePacket(copyPkt) {
+			m.onProbeReply(idx, copyPkt)
+			continue
+		}
+		if inner, ok := decodePeerDup(copyPkt); ok {
+			if m.rxDup.isDuplicate(inner) {
+				continue
+			}
+			copyPkt = inner
+		}
+		if seq, inner, ok := decodeBondPacket(copyPkt); ok {
+			m.bondRx.push(seq, inner)
+			continue
+		}
 		select {
 		case <-ctx.Done():
 			return
@@ -654,11 +930,14 @@ func (m *multipathConn) onPathError(ctx context.Context, idx int, err error) {
 	p.cooldownUntil = time.Now().Add(time.Duration(p.consecutiveFails) * time.Second)
 	oldStripe := p.stripeConn
 	oldConn := p.conn
+	oldMPPath := p.mpPath
 	oldUDP := p.udpConn
 	p.dc = nil
 	p.stripeConn = nil
 	p.conn = nil
+	p.mpPath = nil
 	p.udpConn = nil
+	p.quicStats = nil
 	name := p.cfg.Name
 	needReconnect := !p.reconnecting
 	if needReconnect {
@@ -673,6 +952,9 @@ func (m *multipathConn) onPathError(ctx context.Context, idx int, err error) {
 	if oldConn != nil {
 		_ = oldConn.CloseWithError(0, "rx-error")
 	}
+	if oldMPPath != nil {
+		_ = oldMPPath.Close()
+	}
 	if oldUDP != nil {
 		_ = oldUDP.Close()
 	}
@@ -718,7 +1000,7 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 				}
 				continue
 			}
-			keys, err := stripeNegotiateKey(ctx, m.cfg, pcfg, sessionID, m.logger)
+			keys, err := stripeNegotiateKey(ctx, m.cfg, pcfg, sessionID, m.qlog, m.logger)
 			if err != nil {
 				if ctx.Err() != nil {
 					return
@@ -745,10 +1027,16 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 				continue
 			}
 			m.mu.Lock()
+			if m.paths[idx].removed {
+				m.mu.Unlock()
+				_ = sc.Close()
+				return
+			}
 			if idx >= 0 && idx < len(m.paths) {
 				p := m.paths[idx]
 				p.dc = sc
 				p.stripeConn = sc
+				p.quality.reset()
 				p.alive = true
 				p.reconnecting = false
 				p.lastUp = time.Now()
@@ -761,6 +1049,23 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 			return
 		}
 
+		// ── QUIC multipath reconnect ──────────────────────────────
+		if effectiveTransport == "quic-mp" {
+			if err := m.connectQUICMP(ctx, idx, pcfg); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				m.logger.Errorf("quic-mp path redial failed name=%s err=%v", pcfg.Name, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(2 * time.Second):
+				}
+				continue
+			}
+			return
+		}
+
 		// ── QUIC reconnect (existing logic) ───────────────────────
 		bindIP, err := resolveBindIP(pcfg.BindIP)
 		if err != nil {
@@ -785,7 +1090,7 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 			continue
 		}
 
-		remoteUDP, err := net.ResolveUDPAddr("udp", net.JoinHostPort(pcfg.RemoteAddr, fmt.Sprintf("%d", pcfg.RemotePort)))
+		remoteUDP, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(pcfg.RemoteAddr, fmt.Sprintf("%d", pcfg.RemotePort)))
 		if err != nil {
 			_ = udpConn.Close()
 			m.logger.Errorf("path redial remote resolve failed name=%s err=%v", pcfg.Name, err)
@@ -810,13 +1115,14 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 		}
 
 		transport := quic.Transport{Conn: udpConn}
+		qstats := &quicPathStats{}
 		dialCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
-		conn, err := transport.Dial(dialCtx, remoteUDP, tlsConf, &quic.Config{
-			EnableDatagrams:     true,
-			KeepAlivePeriod:     15 * time.Second,
-			MaxIdleTimeout:      60 * time.Second,
-			CongestionAlgorithm: m.cfg.CongestionAlgorithm,
-		})
+		conn, err := dialEarly(dialCtx, &transport, remoteUDP, tlsConf, withCongestionControl(&quic.Config{
+			EnableDatagrams: true,
+			KeepAlivePeriod: 15 * time.Second,
+			MaxIdleTimeout:  60 * time.Second,
+			Tracer:          m.qlog.tracer(pcfg.Name, "client", pcfg.Qlog, newQUICPathTracer(qstats)),
+		}, pathCongestionAlgorithm(pcfg, m.cfg), m.lia.forPath(pcfg).newSender))
 		cancel()
 		if err != nil {
 			_ = udpConn.Close()
@@ -849,12 +1155,20 @@ func (m *multipathConn) reconnectLoop(ctx context.Context, idx int) {
 		}
 
 		m.mu.Lock()
+		if m.paths[idx].removed {
+			m.mu.Unlock()
+			_ = conn.CloseWithError(0, "path-removed")
+			_ = udpConn.Close()
+			return
+		}
 		if idx >= 0 && idx < len(m.paths) {
 			p := m.paths[idx]
 			p.conn = conn
 			p.dc = dc
 			p.udpConn = udpConn
 			p.transport = &transport
+			p.quicStats = qstats
+			p.quality.reset()
 			p.alive = true
 			p.reconnecting = false
 			p.lastUp = time.Now()
@@ -893,14 +1207,21 @@ func (m *multipathConn) logTelemetrySnapshot() {
 		alive, total                 int
 	}
 	agg := make(map[string]*baseAgg)
+	flowCounts := m.flowCountsLocked()
 
-	for _, p := range m.paths {
+	for i, p := range m.paths {
+		if p.removed {
+			continue
+		}
 		state := "down"
-		if p.alive && (p.conn != nil || p.stripeConn != nil) {
+		if p.alive && (p.conn != nil || p.stripeConn != nil || p.mpPath != nil) {
 			state = "up"
 		}
+		if p.draining {
+			state = "draining"
+		}
 		m.logger.Infof(
-			"path telemetry name=%s state=%s tx_pkts=%d rx_pkts=%d tx_err=%d rx_err=%d fails=%d cooldown_until=%s last_up=%s last_down=%s",
+			"path telemetry name=%s state=%s tx_pkts=%d rx_pkts=%d tx_err=%d rx_err=%d fails=%d srtt=%s rttvar=%s loss=%.2f%% flows=%d cooldown_until=%s last_up=%s last_down=%s",
 			p.cfg.Name,
 			state,
 			p.txPackets,
@@ -908,15 +1229,25 @@ func (m *multipathConn) logTelemetrySnapshot() {
 			p.txErrors,
 			p.rxErrors,
 			p.consecutiveFails,
+			p.quality.srtt.Round(time.Millisecond),
+			p.quality.rttVar.Round(time.Millisecond),
+			p.quality.lossRate*100,
+			flowCounts[i],
 			formatTime(p.cooldownUntil),
 			formatTime(p.lastUp),
 			formatTime(p.lastDown),
 		)
 
+		if p.probe != nil {
+			ps := p.probe.snapshot()
+			m.logger.Infof("probe telemetry name=%s state=%s rtt=%.1fms jitter=%.1fms loss=%.1f%% sent=%d lost=%d",
+				p.cfg.Name, ps.State, ps.RTTMs, ps.JitterMs, ps.LossPct, ps.Sent, ps.Lost)
+		}
+
 		// Log stripe security metrics if available
 		if p.stripeConn != nil {
-			if df := p.stripeConn.SecurityStats(); df > 0 {
-				m.logger.Infof("stripe security name=%s decrypt_fail=%d", p.cfg.Name, df)
+			if df, rd := p.stripeConn.SecurityStats(); df > 0 || rd > 0 {
+				m.logger.Infof("stripe security name=%s decrypt_fail=%d replay_drop=%d", p.cfg.Name, df, rd)
 			}
 		}
 
@@ -935,7 +1266,7 @@ func (m *multipathConn) logTelemetrySnapshot() {
 		a.txErr += p.txErrors
 		a.rxErr += p.rxErrors
 		a.total++
-		if p.alive && (p.conn != nil || p.stripeConn != nil) {
+		if p.alive && (p.conn != nil || p.stripeConn != nil || p.mpPath != nil) {
 			a.alive++
 		}
 	}
@@ -950,6 +1281,10 @@ func (m *multipathConn) logTelemetrySnapshot() {
 		}
 	}
 
+	if m.flowRepins > 0 || len(m.flowPaths) > 0 {
+		m.logger.Infof("flow affinity flows=%d prev=%d repins=%d", len(m.flowPaths), len(m.flowPrev), m.flowRepins)
+	}
+
 	classes := make([]string, 0, len(m.classTx))
 	for className := range m.classTx {
 		classes = append(classes, className)
@@ -961,13 +1296,26 @@ func (m *multipathConn) logTelemetrySnapshot() {
 			continue
 		}
 		m.logger.Infof(
-			"class telemetry class=%s tx_pkts=%d tx_err=%d tx_dups=%d",
+			"class telemetry class=%s tx_pkts=%d tx_err=%d tx_dups=%d dup_bytes=%d dup_active=%t dup_active_sec=%.0f tx_policed=%d",
 			className,
 			c.txPackets,
 			c.txErrors,
 			c.txDuplicates,
+			c.txDupBytes,
+			c.adaptive.active,
+			c.adaptive.activeFor(time.Now()).Seconds(),
+			c.txPoliced,
 		)
 	}
+
+	if m.bondRx != nil {
+		if b := m.bondRx.snapshot(); b.InOrder+b.Late+b.Reordered > 0 {
+			m.logger.Infof(
+				"bonding telemetry in_order=%d reordered=%d late=%d gap_skips=%d overflows=%d pending=%d",
+				b.InOrder, b.Reordered, b.Late, b.GapSkips, b.Overflows, b.Pending,
+			)
+		}
+	}
 }
 
 func formatTime(t time.Time) string {
@@ -985,6 +1333,7 @@ func (m *multipathConn) closeAll(code quic.ApplicationErrorCode, reason string)
 	type toClose struct {
 		stripe *stripeClientConn
 		conn   quic.Connection
+		mpPath *quic.Path
 		udp    *net.UDPConn
 	}
 	var items []toClose
@@ -992,11 +1341,13 @@ func (m *multipathConn) closeAll(code quic.ApplicationErrorCode, reason string)
 		items = append(items, toClose{
 			stripe: p.stripeConn,
 			conn:   p.conn,
+			mpPath: p.mpPath,
 			udp:    p.udpConn,
 		})
 		// Nil out refs so onPathError won't double-close
 		p.stripeConn = nil
 		p.conn = nil
+		p.mpPath = nil
 		p.dc = nil
 		p.udpConn = nil
 		p.alive = false
@@ -1008,6 +1359,9 @@ func (m *multipathConn) closeAll(code quic.ApplicationErrorCode, reason string)
 		if item.stripe != nil {
 			_ = item.stripe.Close()
 		}
+		if item.mpPath != nil {
+			_ = item.mpPath.Close()
+		}
 		if item.conn != nil {
 			_ = item.conn.CloseWithError(code, reason)
 		}
@@ -1015,6 +1369,11 @@ func (m *multipathConn) closeAll(code quic.ApplicationErrorCode, reason string)
 			_ = item.udp.Close()
 		}
 	}
+
+	m.closeOnce.Do(func() { close(m.closed) })
+	if m.bondRx != nil {
+		m.bondRx.close()
+	}
 }
 
 func (m *multipathConn) snapshotDataplaneConfig() DataplaneConfig {
@@ -1025,17 +1384,20 @@ func (m *multipathConn) snapshotDataplaneConfig() DataplaneConfig {
 
 func (m *multipathConn) applyDataplaneConfig(dp DataplaneConfig) error {
 	normalizeDataplaneConfig(&dp, m.cfg.MultipathPolicy)
-	if err := validateDataplaneConfig(dp, m.cfg.MultipathPaths); err != nil {
+	if err := validateDataplaneConfig(dp, m.knownPaths()); err != nil {
 		return err
 	}
 	compiled, err := compileDataplaneConfig(dp)
 	if err != nil {
 		return err
 	}
+	compiled.domains = m.domains
+	compiled.sniFlows = m.sniFlows
 
 	m.mu.Lock()
 	m.cfg.Dataplane = cloneDataplaneConfig(dp)
 	m.dataplane = compiled
+	m.setPeerPolicyLocked(m.cfg.Dataplane)
 	for className := range compiled.classes {
 		if _, ok := m.classTx[className]; !ok {
 			m.classTx[className] = &trafficClassCounters{}
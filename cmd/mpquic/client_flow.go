@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// ─── Client flow affinity ─────────────────────────────────────────────────
+//
+// With scheduler_policy=balanced selectBestPath spreads packets over the
+// equal-score paths by rotating m.rr, so consecutive packets of one TCP
+// flow would leave on different WANs and arrive reordered. The client
+// therefore pins each flow (5-tuple hash, see flowHash) to the path its
+// first packet was sent on, like the server's connGroup.flowPaths:
+//
+//   - New flows take the path selectBestPath picks, so flows are still
+//     distributed across paths.
+//   - A flow is re-pinned when its path dies, enters cooldown or is
+//     excluded for the class after a dataplane reload.
+//   - Generational GC: every clientFlowGCInterval flowPaths becomes
+//     flowPrev and a fresh map starts; active flows are promoted on their
+//     next packet, idle ones disappear after two ticks.
+//
+// Policies that pick a single best path (priority, failover, quality
+// policies) need no pinning: all flows already follow the best path, and
+// pinning would delay failback to a recovered primary.
+
+const clientFlowGCInterval = 30 * time.Second
+
+// flowAffinity reports whether packets of classPolicy are pinned per flow.
+func (m *multipathConn) flowAffinity(classPolicy DataplaneClassPolicy) bool {
+	policy := classPolicy.SchedulerPolicy
+	if policy == "" {
+		policy = m.cfg.MultipathPolicy
+	}
+	return policy == "balanced"
+}
+
+// selectFlowPath returns the path flow h is pinned to, pinning it to the
+// best path for classPolicy if it is new or its path is no longer usable.
+func (m *multipathConn) selectFlowPath(h uint32, classPolicy DataplaneClassPolicy) (int, datagramConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	idx, pinned := m.flowPaths[h]
+	if !pinned && m.flowPrev != nil {
+		idx, pinned = m.flowPrev[h]
+		if pinned {
+			m.flowPaths[h] = idx // promote to current generation
+		}
+	}
+	if pinned && idx < len(m.paths) && m.pathUsableLocked(m.paths[idx], classPolicy, now) {
+		return idx, m.paths[idx].dc
+	}
+
+	best := m.selectBestPathLocked(classPolicy, nil, now)
+	if best < 0 {
+		return -1, nil
+	}
+	m.rr = (best + 1) % len(m.paths)
+	m.flowPaths[h] = best
+	if pinned {
+		m.flowRepins++
+	}
+	return best, m.paths[best].dc
+}
+
+// pathUsableLocked reports whether p can carry traffic of classPolicy right
+// now. Caller holds m.mu.
+func (m *multipathConn) pathUsableLocked(p *multipathPathState, classPolicy DataplaneClassPolicy, now time.Time) bool {
+	if !p.alive || p.dc == nil || p.removed || p.probe.down() || now.Before(p.cooldownUntil) {
+		return false
+	}
+	for _, name := range classPolicy.ExcludedPaths {
+		if name == p.cfg.Name || (p.cfg.BasePath != "" && name == p.cfg.BasePath) {
+			return false
+		}
+	}
+	return true
+}
+
+// flowGCTick rotates the flow table generations.
+func (m *multipathConn) flowGCTick() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.flowPaths) > 0 {
+		m.flowPrev = m.flowPaths
+		m.flowPaths = make(map[uint32]int, min(len(m.flowPrev), 64))
+	} else {
+		m.flowPrev = nil
+	}
+}
+
+func (m *multipathConn) flowGCLoop(ctx context.Context) {
+	ticker := time.NewTicker(clientFlowGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.flowGCTick()
+		}
+	}
+}
+
+// flowCountsLocked returns the number of pinned flows per path index,
+// counting both generations (a flow promoted from flowPrev counts once).
+// Caller holds m.mu.
+func (m *multipathConn) flowCountsLocked() []int {
+	counts := make([]int, len(m.paths))
+	for _, idx := range m.flowPaths {
+		if idx < len(counts) {
+			counts[idx]++
+		}
+	}
+	for h, idx := range m.flowPrev {
+		if _, promoted := m.flowPaths[h]; promoted {
+			continue
+		}
+		if idx < len(counts) {
+			counts[idx]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// newFlowTestConn builds a multipathConn with n equal, alive paths backed
+// by mockDC.
+func newFlowTestConn(policy string, n int) (*multipathConn, []*mockDC) {
+	m := &multipathConn{
+		cfg:       &Config{MultipathPolicy: policy},
+		classTx:   make(map[string]*trafficClassCounters),
+		flowPaths: make(map[uint32]int),
+	}
+	dcs := make([]*mockDC, n)
+	for i := range dcs {
+		dcs[i] = &mockDC{}
+		m.paths = append(m.paths, &multipathPathState{
+			cfg:   MultipathPathConfig{Name: "wan" + string(rune('1'+i)), Priority: 1, Weight: 1},
+			dc:    dcs[i],
+			alive: true,
+		})
+	}
+	return m, dcs
+}
+
+// tcpFlowPacket returns an IPv4 TCP packet of the flow identified by srcPort.
+func tcpFlowPacket(srcPort uint16) []byte {
+	pkt := ipv4Packet(6, tcpSegment([]byte("x")))
+	binary.BigEndian.PutUint16(pkt[20:22], srcPort)
+	return pkt
+}
+
+func TestClientFlowAffinity_BalancedPinsFlow(t *testing.T) {
+	m, dcs := newFlowTestConn("balanced", 2)
+
+	for i := 0; i < 10; i++ {
+		if err := m.sendBestPath(tcpFlowPacket(40000), "default", DataplaneClassPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a, b := len(dcs[0].sent), len(dcs[1].sent); a != 10 && b != 10 {
+		t.Fatalf("flow split across paths: wan1=%d wan2=%d", a, b)
+	}
+
+	// A second flow is placed on the other path by the round-robin.
+	if err := m.sendBestPath(tcpFlowPacket(40001), "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dcs[0].sent) == 0 || len(dcs[1].sent) == 0 {
+		t.Fatalf("new flow not balanced: wan1=%d wan2=%d", len(dcs[0].sent), len(dcs[1].sent))
+	}
+	counts := m.flowCountsLocked()
+	if counts[0] != 1 || counts[1] != 1 {
+		t.Errorf("flow counts = %v, want [1 1]", counts)
+	}
+}
+
+func TestClientFlowAffinity_OnlyForBalanced(t *testing.T) {
+	m, _ := newFlowTestConn("priority", 2)
+	if err := m.sendBestPath(tcpFlowPacket(40000), "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.flowPaths) != 0 {
+		t.Errorf("priority policy pinned %d flows, want 0", len(m.flowPaths))
+	}
+
+	// A class-level balanced policy enables pinning for that class only.
+	if err := m.sendBestPath(tcpFlowPacket(40000), "bulk", DataplaneClassPolicy{SchedulerPolicy: "balanced"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.flowPaths) != 1 {
+		t.Errorf("balanced class pinned %d flows, want 1", len(m.flowPaths))
+	}
+}
+
+func TestClientFlowAffinity_RepinsWhenPathDies(t *testing.T) {
+	m, dcs := newFlowTestConn("balanced", 2)
+	pkt := tcpFlowPacket(40000)
+	if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	h, _ := flowHash(pkt)
+	pinned := m.flowPaths[h]
+	m.paths[pinned].alive = false
+
+	if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	other := 1 - pinned
+	if m.flowPaths[h] != other || len(dcs[other].sent) != 1 {
+		t.Fatalf("flow not re-pinned to %s: pinned=%d sent=%d", m.paths[other].cfg.Name, m.flowPaths[h], len(dcs[other].sent))
+	}
+	if m.flowRepins != 1 {
+		t.Errorf("flowRepins = %d, want 1", m.flowRepins)
+	}
+
+	// The flow stays on its new path after the old one recovers.
+	m.paths[pinned].alive = true
+	if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if m.flowPaths[h] != other {
+		t.Error("flow moved back to the recovered path")
+	}
+}
+
+func TestClientFlowGCTick_EvictsIdleFlows(t *testing.T) {
+	m, _ := newFlowTestConn("balanced", 2)
+	active, idle := tcpFlowPacket(40000), tcpFlowPacket(40001)
+	for _, pkt := range [][]byte{active, idle} {
+		if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ha, _ := flowHash(active)
+	hi, _ := flowHash(idle)
+	pinned := m.flowPaths[ha]
+
+	m.flowGCTick()
+	if len(m.flowPaths) != 0 || len(m.flowPrev) != 2 {
+		t.Fatalf("tick 1: cur=%d prev=%d, want 0/2", len(m.flowPaths), len(m.flowPrev))
+	}
+	if got := m.flowCountsLocked(); got[0]+got[1] != 2 {
+		t.Errorf("tick 1: flow counts = %v, want 2 flows", got)
+	}
+
+	// The active flow is promoted on its next packet, keeping its path.
+	if err := m.sendBestPath(active, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if idx, ok := m.flowPaths[ha]; !ok || idx != pinned {
+		t.Fatalf("active flow not promoted to its path: idx=%d ok=%t", idx, ok)
+	}
+
+	m.flowGCTick()
+	if _, ok := m.flowPrev[ha]; !ok {
+		t.Error("tick 2: active flow evicted")
+	}
+	if _, ok := m.flowPrev[hi]; ok {
+		t.Error("tick 2: idle flow survived two ticks")
+	}
+}
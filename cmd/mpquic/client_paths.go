@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ─── Runtime path management ──────────────────────────────────────────────
+//
+// The control API can add, remove and drain multipath paths while the
+// tunnel runs (GET/POST /paths, DELETE /paths/{name},
+// POST /paths/{name}/drain, POST /paths/{name}/qlog):
+//
+//   - add: the path (expanded into pipes like at startup) gets a new slot
+//     and is dialled by reconnectLoop, so a modem that is not up yet is
+//     retried instead of failing the request.
+//   - remove: the path is closed at once; flows pinned to it re-pin on
+//     their next packet.
+//   - drain: the path takes no new flows (selectBestPath skips it) but
+//     pinned flows keep using it until they go idle or the drain timeout
+//     expires, then it is removed.
+//   - qlog: switches the qlog trace of the path's connections (qlog.go).
+//
+// Slots are never reused or compacted: goroutines and the flow table refer
+// to paths by index. Runtime changes are not written back to the config
+// file; a full client restart goes back to multipath_paths.
+
+const (
+	defaultPathDrainTimeout = 30 * time.Second
+	maxPathDrainTimeout     = 10 * time.Minute
+	pathDrainPoll           = time.Second
+)
+
+var (
+	errPathNotFound = errors.New("path not found")
+	errPathExists   = errors.New("path already exists")
+	errPathLast     = errors.New("cannot remove the last active path")
+	errQlogDisabled = errors.New("qlog disabled: qlog_dir not set")
+)
+
+// pathInfo is the control API view of one path (one pipe for expanded
+// QUIC paths).
+type pathInfo struct {
+	Name       string `json:"name"`
+	BasePath   string `json:"base_path"`
+	BindIP     string `json:"bind_ip"`
+	RemoteAddr string `json:"remote_addr"`
+	RemotePort int    `json:"remote_port"`
+	Transport  string `json:"transport"`
+	Priority   int    `json:"priority"`
+	Weight     int    `json:"weight"`
+	State      string `json:"state"` // up | down | connecting | draining
+	FlowCount  int    `json:"flow_count"`
+	// Seconds left before a draining path is removed.
+	DrainRemainingSec int `json:"drain_remaining_sec,omitempty"`
+	Qlog              bool `json:"qlog"` // connections traced to qlog_dir
+}
+
+// snapshotPaths returns the live (not removed) paths.
+func (m *multipathConn) snapshotPaths() []pathInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	flowCounts := m.flowCountsLocked()
+	out := make([]pathInfo, 0, len(m.paths))
+	for i, p := range m.paths {
+		if p.removed {
+			continue
+		}
+		info := pathInfo{
+			Name:       p.cfg.Name,
+			BasePath:   p.cfg.BasePath,
+			BindIP:     p.cfg.BindIP,
+			RemoteAddr: p.cfg.RemoteAddr,
+			RemotePort: p.cfg.RemotePort,
+			Transport:  "quic",
+			Priority:   p.cfg.Priority,
+			Weight:     p.cfg.Weight,
+			FlowCount:  flowCounts[i],
+			Qlog:       m.qlog.tracing(qlogPathName(p.cfg), p.cfg.Qlog),
+		}
+		switch {
+		case p.stripeConn != nil || p.cfg.Transport == "stripe":
+			info.Transport = "stripe"
+		case p.cfg.Transport == "quic-mp":
+			info.Transport = "quic-mp"
+		}
+		switch {
+		case p.probe.down():
+			info.State = "down"
+		case p.draining:
+			info.State = "draining"
+			info.DrainRemainingSec = int(p.drainDeadline.Sub(now).Round(time.Second) / time.Second)
+		case p.alive && p.dc != nil:
+			info.State = "up"
+		case p.reconnecting:
+			info.State = "connecting"
+		default:
+			info.State = "down"
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// knownPaths returns the configured paths plus those added at runtime, the
+// names dataplane preferred_paths/excluded_paths may refer to. Removed
+// paths stay valid names so a dataplane written for the full set still
+// applies during maintenance.
+func (m *multipathConn) knownPaths() []MultipathPathConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]MultipathPathConfig, 0, len(m.cfg.MultipathPaths)+len(m.paths))
+	out = append(out, m.cfg.MultipathPaths...)
+	for _, p := range m.paths {
+		out = append(out, p.cfg)
+	}
+	return out
+}
+
+// pathIndicesLocked returns the live slots of path name, matching the pipe
+// name or the base path name. Caller holds m.mu.
+func (m *multipathConn) pathIndicesLocked(name string) []int {
+	var idxs []int
+	for i, p := range m.paths {
+		if p.removed {
+			continue
+		}
+		if p.cfg.Name == name || p.cfg.BasePath == name {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs
+}
+
+// othersActiveLocked reports whether a path outside idxs can still take new
+// flows. Caller holds m.mu.
+func (m *multipathConn) othersActiveLocked(idxs []int) bool {
+	skip := make(map[int]struct{}, len(idxs))
+	for _, i := range idxs {
+		skip[i] = struct{}{}
+	}
+	for i, p := range m.paths {
+		if _, own := skip[i]; own || p.removed || p.draining {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// addPath adds p at runtime and starts dialling it. Returns the names of
+// the new slots (pipes for an expanded QUIC path).
+func (m *multipathConn) addPath(p MultipathPathConfig) ([]string, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("name required")
+	}
+	if err := normalizeMultipathPath(&p); err != nil {
+		return nil, err
+	}
+	if p.Qlog && m.qlog == nil {
+		return nil, fmt.Errorf("qlog requires qlog_dir")
+	}
+	// Pipe expansion may probe the interface (starlink detection): keep it
+	// outside the lock.
+	expanded := expandMultipathPipes([]MultipathPathConfig{p}, m.cfg, m.logger)
+
+	m.mu.Lock()
+	for _, existing := range m.paths {
+		if existing.removed {
+			continue
+		}
+		conflict := existing.cfg.Name == p.Name || existing.cfg.BasePath == p.Name
+		for _, e := range expanded {
+			conflict = conflict || existing.cfg.Name == e.Name
+		}
+		if conflict {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", errPathExists, existing.cfg.Name)
+		}
+	}
+	names := make([]string, 0, len(expanded))
+	start := len(m.paths)
+	for _, e := range expanded {
+		state := &multipathPathState{cfg: e, reconnecting: true}
+		state.ctx, state.cancel = context.WithCancel(m.baseCtx)
+		if interval, mult := probeSettings(e, m.cfg); interval > 0 {
+			state.probe = newProbeState(interval, mult)
+		}
+		m.paths = append(m.paths, state)
+		names = append(names, e.Name)
+	}
+	added := m.paths[start:]
+	m.mu.Unlock()
+
+	for i, state := range added {
+		go m.recvLoop(state.ctx, start+i)
+		go m.reconnectLoop(state.ctx, start+i)
+		if state.probe != nil {
+			go m.probeLoop(state.ctx, start+i, state.probe)
+		}
+	}
+	m.logger.Infof("path added name=%s pipes=%d bind=%s remote=%s:%d", p.Name, len(names), p.BindIP, p.RemoteAddr, p.RemotePort)
+	return names, nil
+}
+
+// removePath closes path name (all its pipes) immediately.
+func (m *multipathConn) removePath(name string) error {
+	m.mu.Lock()
+	idxs := m.pathIndicesLocked(name)
+	if len(idxs) == 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", errPathNotFound, name)
+	}
+	if !m.othersActiveLocked(idxs) {
+		m.mu.Unlock()
+		return errPathLast
+	}
+	m.mu.Unlock()
+
+	m.retirePaths(idxs)
+	m.logger.Infof("path removed name=%s pipes=%d", name, len(idxs))
+	return nil
+}
+
+// drainPath stops assigning new flows to path name and removes it once its
+// pinned flows are gone or timeout expires.
+func (m *multipathConn) drainPath(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultPathDrainTimeout
+	}
+	if timeout > maxPathDrainTimeout {
+		timeout = maxPathDrainTimeout
+	}
+
+	m.mu.Lock()
+	idxs := m.pathIndicesLocked(name)
+	if len(idxs) == 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", errPathNotFound, name)
+	}
+	if !m.othersActiveLocked(idxs) {
+		m.mu.Unlock()
+		return errPathLast
+	}
+	deadline := time.Now().Add(timeout)
+	for _, i := range idxs {
+		m.paths[i].draining = true
+		m.paths[i].drainDeadline = deadline
+	}
+	m.mu.Unlock()
+
+	m.logger.Infof("path draining name=%s pipes=%d timeout=%s", name, len(idxs), timeout)
+	go m.drainLoop(m.baseCtx, name, idxs, deadline)
+	return nil
+}
+
+// setPathQlog switches the qlog trace of the connections of path name,
+// the live ones and those of later reconnects.
+func (m *multipathConn) setPathQlog(name string, on bool) error {
+	if m.qlog == nil {
+		return errQlogDisabled
+	}
+	m.mu.RLock()
+	idxs := m.pathIndicesLocked(name)
+	traceNames := make(map[string]struct{}, len(idxs))
+	for _, i := range idxs {
+		traceNames[qlogPathName(m.paths[i].cfg)] = struct{}{}
+	}
+	m.mu.RUnlock()
+	if len(idxs) == 0 {
+		return fmt.Errorf("%w: %s", errPathNotFound, name)
+	}
+	for n := range traceNames {
+		m.qlog.set(n, on)
+	}
+	return nil
+}
+
+func (m *multipathConn) drainLoop(ctx context.Context, name string, idxs []int, deadline time.Time) {
+	ticker := time.NewTicker(pathDrainPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.RLock()
+			counts := m.flowCountsLocked()
+			flows := 0
+			for _, i := range idxs {
+				flows += counts[i]
+			}
+			m.mu.RUnlock()
+
+			if flows > 0 && now.Before(deadline) {
+				continue
+			}
+			m.retirePaths(idxs)
+			m.logger.Infof("path drained name=%s flows_left=%d", name, flows)
+			return
+		}
+	}
+}
+
+// retirePaths marks the slots removed, stops their goroutines and closes
+// their connections. Flows pinned to them re-pin on their next packet.
+func (m *multipathConn) retirePaths(idxs []int) {
+	type toClose struct {
+		stripe *stripeClientConn
+		conn   quic.Connection
+		mpPath *quic.Path
+		udp    *net.UDPConn
+	}
+	var items []toClose
+
+	m.mu.Lock()
+	for _, i := range idxs {
+		p := m.paths[i]
+		if p.removed {
+			continue
+		}
+		items = append(items, toClose{stripe: p.stripeConn, conn: p.conn, mpPath: p.mpPath, udp: p.udpConn})
+		p.removed = true
+		p.draining = false
+		p.alive = false
+		p.dc = nil
+		p.stripeConn = nil
+		p.conn = nil
+		p.mpPath = nil
+		p.udpConn = nil
+		p.quicStats = nil
+		p.lastDown = time.Now()
+		if p.cancel != nil {
+			p.cancel()
+		}
+	}
+	m.mu.Unlock()
+
+	// Close outside the lock, as in closeAll: this unblocks recvLoop.
+	for _, item := range items {
+		if item.stripe != nil {
+			_ = item.stripe.Close()
+		}
+		if item.mpPath != nil {
+			_ = item.mpPath.Close()
+		}
+		if item.conn != nil {
+			_ = item.conn.CloseWithError(0, "path-removed")
+		}
+		if item.udp != nil {
+			_ = item.udp.Close()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newPathTestConn is newFlowTestConn with the fields runtime path
+// management needs. The base context is already cancelled so added paths
+// are never dialled.
+func newPathTestConn(t *testing.T, n int) (*multipathConn, []*mockDC) {
+	m, dcs := newFlowTestConn("balanced", n)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.baseCtx = ctx
+	m.logger = newLogger("error")
+	for _, p := range m.paths {
+		p.cfg.BasePath = p.cfg.Name
+	}
+	return m, dcs
+}
+
+func TestRemovePath_RepinsFlowsAndRefusesLast(t *testing.T) {
+	m, dcs := newPathTestConn(t, 2)
+	pkt := tcpFlowPacket(40000)
+	if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	h, _ := flowHash(pkt)
+	pinned := m.flowPaths[h]
+	other := 1 - pinned
+
+	if err := m.removePath(m.paths[pinned].cfg.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.sendBestPath(pkt, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dcs[other].sent) != 1 || m.flowPaths[h] != other {
+		t.Fatalf("flow not moved off the removed path: sent=%d pinned=%d", len(dcs[other].sent), m.flowPaths[h])
+	}
+	if got := len(m.snapshotPaths()); got != 1 {
+		t.Errorf("snapshotPaths returned %d paths, want 1", got)
+	}
+
+	if err := m.removePath(m.paths[other].cfg.Name); !errors.Is(err, errPathLast) {
+		t.Errorf("removing the last path: err=%v, want errPathLast", err)
+	}
+	if err := m.removePath("nope"); !errors.Is(err, errPathNotFound) {
+		t.Errorf("removing an unknown path: err=%v, want errPathNotFound", err)
+	}
+}
+
+func TestDrainPath_KeepsPinnedFlowsTakesNoNewOnes(t *testing.T) {
+	m, dcs := newPathTestConn(t, 2)
+	m.baseCtx = context.Background() // drainLoop must run
+	old := tcpFlowPacket(40000)
+	if err := m.sendBestPath(old, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	h, _ := flowHash(old)
+	draining := m.flowPaths[h]
+
+	if err := m.drainPath(m.paths[draining].cfg.Name, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// The pinned flow stays; every new flow avoids the draining path.
+	if err := m.sendBestPath(old, "default", DataplaneClassPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	for port := uint16(41000); port < 41010; port++ {
+		if err := m.sendBestPath(tcpFlowPacket(port), "default", DataplaneClassPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(dcs[draining].sent); got != 2 {
+		t.Errorf("draining path sent %d packets, want only the 2 of the pinned flow", got)
+	}
+
+	// Once the pinned flow is gone the path is removed.
+	m.mu.Lock()
+	delete(m.flowPaths, h)
+	m.mu.Unlock()
+	deadline := time.Now().Add(3 * pathDrainPoll)
+	for {
+		m.mu.RLock()
+		removed := m.paths[draining].removed
+		m.mu.RUnlock()
+		if removed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("drained path not removed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestAddPath_ExpandsPipesAndRejectsDuplicates(t *testing.T) {
+	m, _ := newPathTestConn(t, 1)
+
+	names, err := m.addPath(MultipathPathConfig{
+		Name: "lte", BindIP: "127.0.0.1", RemoteAddr: "127.0.0.1", RemotePort: 45017, Pipes: 2, Transport: "quic",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "lte.0" || names[1] != "lte.1" {
+		t.Fatalf("names = %v, want [lte.0 lte.1]", names)
+	}
+	if got := len(m.snapshotPaths()); got != 3 {
+		t.Errorf("snapshotPaths returned %d paths, want 3", got)
+	}
+
+	_, err = m.addPath(MultipathPathConfig{Name: "lte", BindIP: "127.0.0.1", RemoteAddr: "127.0.0.1", RemotePort: 45017})
+	if !errors.Is(err, errPathExists) {
+		t.Errorf("duplicate add: err=%v, want errPathExists", err)
+	}
+	if _, err := m.addPath(MultipathPathConfig{Name: "bad", BindIP: "127.0.0.1", RemoteAddr: "127.0.0.1"}); err == nil {
+		t.Error("path without remote_port accepted")
+	}
+
+	// A removed path's name can be added again in a new slot.
+	if err := m.removePath("lte"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.addPath(MultipathPathConfig{Name: "lte", BindIP: "127.0.0.1", RemoteAddr: "127.0.0.1", RemotePort: 45017}); err != nil {
+		t.Errorf("re-adding a removed path: %v", err)
+	}
+}
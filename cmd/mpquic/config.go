@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,6 +17,7 @@ import (
 type Config struct {
 	Role                  string                `yaml:"role"`
 	BindIP                string                `yaml:"bind_ip"`
+	BindIP6               string                `yaml:"bind_ip6"` // server: extra IPv6 listen address next to an IPv4 bind_ip
 	RemoteAddr            string                `yaml:"remote_addr"`
 	RemotePort            int                   `yaml:"remote_port"`
 	MultiConnEnabled      bool                  `yaml:"multi_conn_enabled"`
@@ -26,6 +28,7 @@ type Config struct {
 	MultipathPaths        []MultipathPathConfig `yaml:"multipath_paths"`
 	TunName               string                `yaml:"tun_name"`
 	TunCIDR               string                `yaml:"tun_cidr"`
+	TunCIDR6              string                `yaml:"tun_cidr6"` // optional IPv6 prefix next to an IPv4 tun_cidr
 	TunMTU                int                   `yaml:"tun_mtu"`
 	LogLevel              string                `yaml:"log_level"`
 	TLSCertFile           string                `yaml:"tls_cert_file"`
@@ -33,6 +36,7 @@ type Config struct {
 	TLSCAFile             string                `yaml:"tls_ca_file"`
 	TLSServerName         string                `yaml:"tls_server_name"`
 	TLSInsecureSkipVerify bool                  `yaml:"tls_insecure_skip_verify"`
+	TLS0RTT               bool                  `yaml:"tls_0rtt"` // server: accept 0-RTT from resuming clients (multi-conn)
 	ControlAPIListen      string                `yaml:"control_api_listen"`
 	ControlAPIAuthToken   string                `yaml:"control_api_auth_token"`
 	CongestionAlgorithm   string                `yaml:"congestion_algorithm"`
@@ -50,8 +54,29 @@ type Config struct {
 	StripeFECType         string                `yaml:"stripe_fec_type"`    // "rs" (default), "xor" (legacy), "rlc"
 	StripeFECWindow       int                   `yaml:"stripe_fec_window"`  // Sliding-window size W (default 10, used by xor/rlc)
 	StripeFECInterleave   int                   `yaml:"stripe_fec_interleave"` // RS interleave depth (0=block RS, >0=interleaved, default 4)
+	StripePipeScheduler   string                `yaml:"stripe_pipe_scheduler"` // "rr" (default), "weighted" (see stripe_scheduler.go)
 	StripeEnabled         bool                  `yaml:"stripe_enabled"`
+	// Stripe key epochs (see stripe_crypto.go): each direction switches key
+	// after this time or this much traffic, whichever comes first (-1 = never).
+	StripeRekeyIntervalSec int `yaml:"stripe_rekey_interval_sec"` // default 3600
+	StripeRekeyMB          int `yaml:"stripe_rekey_mb"`           // default 65536
 	MetricsListen         string                `yaml:"metrics_listen"` // e.g. "10.200.17.254:9090" — bind to tunnel IP only
+	BondingReorderHoldMs  int                   `yaml:"bonding_reorder_hold_ms"` // max wait for a missing bonded packet (default 60)
+	BondingReorderMax     int                   `yaml:"bonding_reorder_max"`     // max packets held for reordering (default 1024)
+	ProbeIntervalMs       int                   `yaml:"probe_interval_ms"`       // active path probing interval (0 = disabled)
+	ProbeDetectMultiplier int                   `yaml:"probe_detect_multiplier"` // missed probes before a path is down (default 3)
+	// qlog traces of the QUIC connections (see qlog.go).
+	QlogDir               string                `yaml:"qlog_dir"`         // empty = qlog disabled
+	QlogEnabled           bool                  `yaml:"qlog_enabled"`     // trace all connections from the start
+	QlogMaxFileMB         int                   `yaml:"qlog_max_file_mb"` // rotate a trace file at this size (default 16)
+	QlogMaxDirMB          int                   `yaml:"qlog_max_dir_mb"`  // delete the oldest traces above this total (default 256)
+	// Return-direction policy per peer (see peer_dataplane.go).
+	DataplanePush         bool                       `yaml:"dataplane_push"`  // client: push dataplane to the server
+	PeerDataplanes        map[string]DataplaneConfig `yaml:"peer_dataplanes"` // server: peer TUN IP → dataplane
+
+	// unknownCongestionAlgorithm is the congestion_algorithm loadConfig
+	// replaced with cubic, logged by main.
+	unknownCongestionAlgorithm string
 }
 
 type MultipathPathConfig struct {
@@ -63,13 +88,26 @@ type MultipathPathConfig struct {
 	Weight     int    `yaml:"weight"`
 	Pipes      int    `yaml:"pipes"`
 	BasePath   string `yaml:"-"`        // original path name before pipe expansion
-	Transport  string `yaml:"transport"` // "quic" (default), "stripe", or "auto"
+	Transport  string `yaml:"transport"` // "quic" (default), "quic-mp", "stripe", or "auto"
+	// Per-path overrides of probe_interval_ms / probe_detect_multiplier
+	// (0 = inherit; probe_interval_ms -1 disables probing on this path).
+	ProbeIntervalMs       int `yaml:"probe_interval_ms"`
+	ProbeDetectMultiplier int `yaml:"probe_detect_multiplier"`
+	// Per-path override of congestion_algorithm for transport quic
+	// (empty = inherit).
+	CongestionAlgorithm string `yaml:"congestion_algorithm"`
+	// Qlog traces the path's connections from the start (needs qlog_dir).
+	Qlog bool `yaml:"qlog"`
 }
 
 type DataplaneConfig struct {
 	DefaultClass string                          `yaml:"default_class"`
 	Classes      map[string]DataplaneClassPolicy `yaml:"classes"`
 	Classifiers  []DataplaneClassifierRule       `yaml:"classifiers"`
+	// TotalRateMbps is the capacity shared by all classes, the root of the
+	// class rate hierarchy (see class_shaper.go). Required when any class
+	// sets min_rate_mbps; 0 disables the shared limit.
+	TotalRateMbps float64 `yaml:"total_rate_mbps"`
 }
 
 type DataplaneClassPolicy struct {
@@ -78,6 +116,26 @@ type DataplaneClassPolicy struct {
 	ExcludedPaths   []string `yaml:"excluded_paths"`
 	Duplicate       bool     `yaml:"duplicate"`
 	DuplicateCopies int      `yaml:"duplicate_copies"`
+	// DuplicateMode is "always" (default) or "adaptive": duplicate only
+	// while the primary path breaks DuplicateLossPct or DuplicateRTTMs
+	// (see adaptive_dup.go). "adaptive" implies duplicate: true.
+	DuplicateMode    string  `yaml:"duplicate_mode"`
+	DuplicateLossPct float64 `yaml:"duplicate_loss_pct"`
+	DuplicateRTTMs   int     `yaml:"duplicate_rtt_ms"`
+	// DuplicateMinOnMs: 0 selects defaultDuplicateMinOnMs, -1 = no minimum.
+	DuplicateMinOnMs int `yaml:"duplicate_min_on_ms"`
+	// LatencyBudgetMs is the effective-RTT ceiling (sRTT + 2·rttvar) used by
+	// scheduler_policy=latency_budget. 0 selects defaultLatencyBudget.
+	LatencyBudgetMs int `yaml:"latency_budget_ms"`
+	// MaxRateMbps caps the class; 0 = no cap.
+	MaxRateMbps float64 `yaml:"max_rate_mbps"`
+	// MinRateMbps is guaranteed to the class out of total_rate_mbps; 0 = none.
+	MinRateMbps float64 `yaml:"min_rate_mbps"`
+	// BurstBytes is the token bucket depth; 0 = 50 ms at the class rate.
+	BurstBytes int `yaml:"burst_bytes"`
+	// BorrowPriority orders classes borrowing unused capacity above their
+	// guarantee: higher borrows first.
+	BorrowPriority int `yaml:"borrow_priority"`
 }
 
 type DataplaneClassifierRule struct {
@@ -89,6 +147,14 @@ type DataplaneClassifierRule struct {
 	SrcPorts  []string `yaml:"src_ports"`
 	DstPorts  []string `yaml:"dst_ports"`
 	DSCP      []int    `yaml:"dscp"`
+	// Domains matches DNS names learned by snooping DNS responses
+	// (see dns_snoop.go): "example.com" exactly, "*.example.com" for
+	// subdomains.
+	Domains []string `yaml:"domains"`
+	// SNI matches the server name of TLS ClientHello / QUIC Initial
+	// packets, remembered per flow (see sni.go). Same pattern syntax as
+	// Domains.
+	SNI []string `yaml:"sni"`
 }
 
 func loadConfig(path string) (*Config, error) {
@@ -107,9 +173,42 @@ func loadConfig(path string) (*Config, error) {
 	if cfg.Role != "client" && cfg.Role != "server" {
 		return nil, fmt.Errorf("role must be client or server")
 	}
+	cfg.CongestionAlgorithm = strings.ToLower(strings.TrimSpace(cfg.CongestionAlgorithm))
+	if cfg.CongestionAlgorithm == "" {
+		cfg.CongestionAlgorithm = "cubic"
+	}
+	if !isValidCongestionAlgorithm(cfg.CongestionAlgorithm) {
+		// older releases ran unknown values as cubic: keep configs loading
+		cfg.unknownCongestionAlgorithm = cfg.CongestionAlgorithm
+		cfg.CongestionAlgorithm = "cubic"
+	}
 	if cfg.BindIP == "" && !(cfg.Role == "client" && cfg.MultipathEnabled) {
 		return nil, fmt.Errorf("bind_ip required")
 	}
+	if cfg.BindIP != "" {
+		if err := validateBindIP(cfg.BindIP); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.BindIP6 != "" {
+		if cfg.Role != "server" || !cfg.MultiConnEnabled {
+			return nil, fmt.Errorf("bind_ip6 requires role=server and multi_conn_enabled=true")
+		}
+		if err := validateBindIP(cfg.BindIP6); err != nil {
+			return nil, fmt.Errorf("bind_ip6: %w", err)
+		}
+		if ip := net.ParseIP(cfg.BindIP6); ip != nil && ip.To4() != nil {
+			return nil, fmt.Errorf("bind_ip6 must be an IPv6 address: %q", cfg.BindIP6)
+		}
+		if _, family := splitBindInterface(cfg.BindIP); strings.HasPrefix(cfg.BindIP, "if:") && family == "ipv6" {
+			return nil, fmt.Errorf("bind_ip6 requires an IPv4 bind_ip, got %q", cfg.BindIP)
+		}
+		// A wildcard bind_ip already listens on both families (dual-stack
+		// socket) and would conflict with a second listener on the port.
+		if ip := net.ParseIP(cfg.BindIP); ip != nil && (ip.IsUnspecified() || ip.To4() == nil) {
+			return nil, fmt.Errorf("bind_ip6 requires a specific IPv4 bind_ip, got %q", cfg.BindIP)
+		}
+	}
 	if !(cfg.Role == "client" && cfg.MultipathEnabled) {
 		if cfg.RemotePort <= 0 || cfg.RemotePort > 65535 {
 			return nil, fmt.Errorf("remote_port invalid")
@@ -120,8 +219,8 @@ func loadConfig(path string) (*Config, error) {
 		if cfg.MultipathPolicy == "" {
 			cfg.MultipathPolicy = "priority"
 		}
-		if cfg.MultipathPolicy != "priority" && cfg.MultipathPolicy != "failover" && cfg.MultipathPolicy != "balanced" {
-			return nil, fmt.Errorf("multipath_policy must be one of: priority, failover, balanced")
+		if !isValidSchedulerPolicy(cfg.MultipathPolicy) {
+			return nil, fmt.Errorf("multipath_policy must be one of: %s", schedulerPolicyList)
 		}
 		if len(cfg.MultipathPaths) == 0 {
 			return nil, fmt.Errorf("multipath_paths required when multipath_enabled=true")
@@ -131,23 +230,36 @@ func loadConfig(path string) (*Config, error) {
 			if p.Name == "" {
 				p.Name = fmt.Sprintf("path%d", i+1)
 			}
-			if p.BindIP == "" {
-				return nil, fmt.Errorf("multipath_paths[%d].bind_ip required", i)
-			}
-			if p.RemoteAddr == "" {
-				return nil, fmt.Errorf("multipath_paths[%d].remote_addr required", i)
-			}
-			if p.RemotePort <= 0 || p.RemotePort > 65535 {
-				return nil, fmt.Errorf("multipath_paths[%d].remote_port invalid", i)
-			}
-			if p.Weight <= 0 {
-				p.Weight = 1
+			if err := normalizeMultipathPath(p); err != nil {
+				return nil, fmt.Errorf("multipath_paths[%d].%w", i, err)
 			}
 		}
+		if err := validateQUICMPPaths(cfg); err != nil {
+			return nil, err
+		}
+		if err := loadAndValidateDataplaneConfig(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	// The server only uses the dataplane for class rate limits on the
+	// return direction; load it only when configured.
+	if cfg.Role == "server" && (len(cfg.Dataplane.Classes) > 0 || cfg.DataplaneConfigFile != "") {
 		if err := loadAndValidateDataplaneConfig(path, cfg); err != nil {
 			return nil, err
 		}
 	}
+	if cfg.Role == "server" {
+		for peer, dp := range cfg.PeerDataplanes {
+			if _, err := netip.ParseAddr(peer); err != nil {
+				return nil, fmt.Errorf("peer_dataplanes: key %q must be a peer TUN IP", peer)
+			}
+			normalizeDataplaneConfig(&dp, "priority")
+			if err := validateDataplaneConfig(dp, peerDataplanePaths(dp)); err != nil {
+				return nil, fmt.Errorf("peer_dataplanes[%s]: %w", peer, err)
+			}
+			cfg.PeerDataplanes[peer] = dp
+		}
+	}
 	if cfg.TunName == "" {
 		return nil, fmt.Errorf("tun_name required")
 	}
@@ -157,6 +269,70 @@ func loadConfig(path string) (*Config, error) {
 	if cfg.TunMTU <= 0 {
 		cfg.TunMTU = 1300
 	}
+	if cfg.TunCIDR6 != "" {
+		prefix, err := netip.ParsePrefix(cfg.TunCIDR6)
+		if err != nil || !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+			return nil, fmt.Errorf("tun_cidr6 must be an IPv6 prefix: %q", cfg.TunCIDR6)
+		}
+	}
+	if addrs := cfg.tunAddrs(); (len(addrs) > 1 || (len(addrs) == 1 && addrs[0].Is6())) && cfg.TunMTU < 1280 {
+		return nil, fmt.Errorf("tun_mtu must be >= 1280 for an IPv6 tunnel")
+	}
+	if cfg.BondingReorderHoldMs <= 0 {
+		cfg.BondingReorderHoldMs = int(defaultBondReorderHold / time.Millisecond)
+	}
+	if cfg.BondingReorderMax <= 0 {
+		cfg.BondingReorderMax = defaultBondReorderMax
+	}
+	if cfg.ProbeIntervalMs < 0 || (cfg.ProbeIntervalMs > 0 && time.Duration(cfg.ProbeIntervalMs)*time.Millisecond < minProbeInterval) {
+		return nil, fmt.Errorf("probe_interval_ms must be 0 or >= %d", minProbeInterval/time.Millisecond)
+	}
+	if cfg.ProbeDetectMultiplier < 0 {
+		return nil, fmt.Errorf("probe_detect_multiplier must be >= 0")
+	}
+	if cfg.ProbeDetectMultiplier == 0 {
+		cfg.ProbeDetectMultiplier = defaultProbeDetectMultiplier
+	}
+	if cfg.StripeRekeyIntervalSec < -1 || cfg.StripeRekeyMB < -1 {
+		return nil, fmt.Errorf("stripe_rekey_interval_sec and stripe_rekey_mb must be >= -1")
+	}
+	if cfg.StripeRekeyIntervalSec > 0 && time.Duration(cfg.StripeRekeyIntervalSec)*time.Second < stripeRekeyOverlap {
+		return nil, fmt.Errorf("stripe_rekey_interval_sec must be -1, 0 or >= %d", int(stripeRekeyOverlap/time.Second))
+	}
+	if cfg.StripeRekeyIntervalSec == 0 {
+		cfg.StripeRekeyIntervalSec = defaultStripeRekeyIntervalSec
+	}
+	if cfg.StripeRekeyMB == 0 {
+		cfg.StripeRekeyMB = defaultStripeRekeyMB
+	}
+	cfg.StripePipeScheduler = strings.ToLower(strings.TrimSpace(cfg.StripePipeScheduler))
+	if cfg.StripePipeScheduler == "" {
+		cfg.StripePipeScheduler = stripeSchedulerRR
+	}
+	if cfg.StripePipeScheduler != stripeSchedulerRR && cfg.StripePipeScheduler != stripeSchedulerWeighted {
+		return nil, fmt.Errorf("stripe_pipe_scheduler must be %s or %s", stripeSchedulerRR, stripeSchedulerWeighted)
+	}
+	cfg.QlogDir = strings.TrimSpace(cfg.QlogDir)
+	if cfg.QlogMaxFileMB < 0 || cfg.QlogMaxDirMB < 0 {
+		return nil, fmt.Errorf("qlog_max_file_mb and qlog_max_dir_mb must be >= 0")
+	}
+	if cfg.QlogMaxFileMB == 0 {
+		cfg.QlogMaxFileMB = defaultQlogMaxFileMB
+	}
+	if cfg.QlogMaxDirMB == 0 {
+		cfg.QlogMaxDirMB = defaultQlogMaxDirMB
+	}
+	if cfg.QlogMaxDirMB < cfg.QlogMaxFileMB {
+		return nil, fmt.Errorf("qlog_max_dir_mb must be >= qlog_max_file_mb")
+	}
+	if cfg.QlogEnabled && cfg.QlogDir == "" {
+		return nil, fmt.Errorf("qlog_enabled requires qlog_dir")
+	}
+	for i, p := range cfg.MultipathPaths {
+		if p.Qlog && cfg.QlogDir == "" {
+			return nil, fmt.Errorf("multipath_paths[%d].qlog requires qlog_dir", i)
+		}
+	}
 
 	// Resolve metrics_listen: "auto" → derive from tun_cidr IP + port 9090
 	cfg.MetricsListen = strings.TrimSpace(cfg.MetricsListen)
@@ -190,6 +366,31 @@ func loadConfig(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// tunCIDRs returns the configured TUN prefixes (tun_cidr, then tun_cidr6).
+func (cfg *Config) tunCIDRs() []string {
+	var out []string
+	for _, cidr := range []string{cfg.TunCIDR, cfg.TunCIDR6} {
+		if cidr != "" {
+			out = append(out, cidr)
+		}
+	}
+	return out
+}
+
+// tunAddrs returns the local TUN addresses of tunCIDRs; the first one is
+// the peer's identity on the server.
+func (cfg *Config) tunAddrs() []netip.Addr {
+	var out []netip.Addr
+	for _, cidr := range cfg.tunCIDRs() {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			out = append(out, prefix.Addr())
+		} else if addr, err := netip.ParseAddr(cidr); err == nil {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
 func loadAndValidateDataplaneConfig(configPath string, cfg *Config) error {
 	dp := cfg.Dataplane
 
@@ -234,6 +435,9 @@ func mergeDataplaneConfig(base DataplaneConfig, override DataplaneConfig) Datapl
 	if len(override.Classifiers) > 0 {
 		out.Classifiers = override.Classifiers
 	}
+	if override.TotalRateMbps > 0 {
+		out.TotalRateMbps = override.TotalRateMbps
+	}
 	return out
 }
 
@@ -263,6 +467,18 @@ func normalizeDataplaneConfig(dp *DataplaneConfig, fallbackPolicy string) {
 		if policy.SchedulerPolicy == "" {
 			policy.SchedulerPolicy = fallbackPolicy
 		}
+		policy.DuplicateMode = strings.ToLower(strings.TrimSpace(policy.DuplicateMode))
+		if policy.DuplicateMode == "adaptive" {
+			policy.Duplicate = true
+			if policy.DuplicateLossPct == 0 && policy.DuplicateRTTMs == 0 {
+				policy.DuplicateLossPct = defaultDuplicateLossPct
+			}
+			if policy.DuplicateMinOnMs == 0 {
+				policy.DuplicateMinOnMs = defaultDuplicateMinOnMs
+			}
+		} else if policy.Duplicate && policy.DuplicateMode == "" {
+			policy.DuplicateMode = "always"
+		}
 		if policy.Duplicate && policy.DuplicateCopies < 2 {
 			policy.DuplicateCopies = 2
 		}
@@ -278,9 +494,106 @@ func normalizeDataplaneConfig(dp *DataplaneConfig, fallbackPolicy string) {
 		r.Name = strings.TrimSpace(r.Name)
 		r.ClassName = strings.ToLower(strings.TrimSpace(r.ClassName))
 		r.Protocol = strings.ToLower(strings.TrimSpace(r.Protocol))
+		r.Domains = normalizeDomainPatterns(r.Domains)
+		r.SNI = normalizeDomainPatterns(r.SNI)
 	}
 }
 
+// schedulerPolicyList is the human-readable set of accepted scheduler
+// policies, used in validation errors.
+const schedulerPolicyList = "priority, failover, balanced, lowest_rtt, min_loss, latency_budget, bonding"
+
+// isValidSchedulerPolicy reports whether policy is a known scheduler policy.
+// lowest_rtt, min_loss and latency_budget rank paths on live RTT/loss
+// (see path_quality.go); bonding spreads packets over all QUIC paths
+// (see bonding.go); the others use static priority/weight.
+func isValidSchedulerPolicy(policy string) bool {
+	switch policy {
+	case "priority", "failover", "balanced", "bonding":
+		return true
+	}
+	return isQualityPolicy(policy)
+}
+
+// normalizeMultipathPath validates one multipath_paths entry (name already
+// set) and fills in defaults. Shared by loadConfig and the runtime
+// POST /paths control API.
+func normalizeMultipathPath(p *MultipathPathConfig) error {
+	if p.BindIP == "" {
+		return fmt.Errorf("bind_ip required")
+	}
+	if err := validateBindIP(p.BindIP); err != nil {
+		return err
+	}
+	if p.RemoteAddr == "" {
+		return fmt.Errorf("remote_addr required")
+	}
+	if p.RemotePort <= 0 || p.RemotePort > 65535 {
+		return fmt.Errorf("remote_port invalid")
+	}
+	if p.Weight <= 0 {
+		p.Weight = 1
+	}
+	p.Transport = strings.ToLower(strings.TrimSpace(p.Transport))
+	switch p.Transport {
+	case "", "quic", "quic-mp", "stripe", "auto":
+	default:
+		return fmt.Errorf("transport must be one of: quic, quic-mp, stripe, auto")
+	}
+	if p.ProbeIntervalMs < -1 || (p.ProbeIntervalMs > 0 && time.Duration(p.ProbeIntervalMs)*time.Millisecond < minProbeInterval) {
+		return fmt.Errorf("probe_interval_ms must be -1, 0 or >= %d", minProbeInterval/time.Millisecond)
+	}
+	if p.ProbeDetectMultiplier < 0 {
+		return fmt.Errorf("probe_detect_multiplier must be >= 0")
+	}
+	p.CongestionAlgorithm = strings.ToLower(strings.TrimSpace(p.CongestionAlgorithm))
+	if p.CongestionAlgorithm != "" && !isValidCongestionAlgorithm(p.CongestionAlgorithm) {
+		return fmt.Errorf("congestion_algorithm must be one of: %s", congestionAlgorithmList)
+	}
+	return nil
+}
+
+// congestionAlgorithmList lists the congestion controllers: CUBIC, BBRv1 and
+// BBRv3 of local-quic-go, and the coupled LIA of coupled_cc.go.
+const congestionAlgorithmList = "cubic, bbr, bbr3, lia"
+
+func isValidCongestionAlgorithm(algo string) bool {
+	switch algo {
+	case "cubic", "bbr", "bbr3", congestionLIA:
+		return true
+	}
+	return false
+}
+
+// pathCongestionAlgorithm returns the congestion algorithm of the QUIC
+// connection of a path.
+func pathCongestionAlgorithm(p MultipathPathConfig, cfg *Config) string {
+	if p.CongestionAlgorithm != "" {
+		return p.CongestionAlgorithm
+	}
+	return cfg.CongestionAlgorithm
+}
+
+// validateBindIP checks the syntax of a bind_ip value: a literal IP or
+// "if:<name>" with an optional "/ipv4" or "/ipv6" family suffix. The
+// interface itself is resolved when the socket is opened.
+func validateBindIP(value string) error {
+	if !strings.HasPrefix(value, "if:") {
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("bind_ip invalid: %q", value)
+		}
+		return nil
+	}
+	ifName, family := splitBindInterface(value)
+	if ifName == "" {
+		return fmt.Errorf("bind_ip invalid: %q", value)
+	}
+	if family != "" && family != "ipv4" && family != "ipv6" {
+		return fmt.Errorf("bind_ip family must be ipv4 or ipv6: %q", value)
+	}
+	return nil
+}
+
 func validateDataplaneConfig(dp DataplaneConfig, paths []MultipathPathConfig) error {
 	if len(dp.Classes) == 0 {
 		return fmt.Errorf("dataplane.classes must not be empty")
@@ -298,10 +611,37 @@ func validateDataplaneConfig(dp DataplaneConfig, paths []MultipathPathConfig) er
 		}
 	}
 
+	if dp.TotalRateMbps < 0 {
+		return fmt.Errorf("dataplane.total_rate_mbps must be >= 0")
+	}
+
+	var minRateSum float64
 	for className, policy := range dp.Classes {
-		if policy.SchedulerPolicy != "priority" && policy.SchedulerPolicy != "failover" && policy.SchedulerPolicy != "balanced" {
-			return fmt.Errorf("dataplane.classes[%s].scheduler_policy must be one of: priority, failover, balanced", className)
+		if !isValidSchedulerPolicy(policy.SchedulerPolicy) {
+			return fmt.Errorf("dataplane.classes[%s].scheduler_policy must be one of: %s", className, schedulerPolicyList)
+		}
+		if policy.LatencyBudgetMs < 0 {
+			return fmt.Errorf("dataplane.classes[%s].latency_budget_ms must be >= 0", className)
+		}
+		if policy.DuplicateMode != "" && policy.DuplicateMode != "always" && policy.DuplicateMode != "adaptive" {
+			return fmt.Errorf("dataplane.classes[%s].duplicate_mode must be one of: always, adaptive", className)
 		}
+		if policy.DuplicateLossPct < 0 || policy.DuplicateLossPct > 100 {
+			return fmt.Errorf("dataplane.classes[%s].duplicate_loss_pct must be in 0..100", className)
+		}
+		if policy.DuplicateRTTMs < 0 {
+			return fmt.Errorf("dataplane.classes[%s].duplicate_rtt_ms must be >= 0", className)
+		}
+		if policy.DuplicateMinOnMs < -1 {
+			return fmt.Errorf("dataplane.classes[%s].duplicate_min_on_ms must be >= 0 or -1", className)
+		}
+		if policy.MaxRateMbps < 0 || policy.MinRateMbps < 0 || policy.BurstBytes < 0 {
+			return fmt.Errorf("dataplane.classes[%s] max_rate_mbps, min_rate_mbps and burst_bytes must be >= 0", className)
+		}
+		if policy.MaxRateMbps > 0 && policy.MinRateMbps > policy.MaxRateMbps {
+			return fmt.Errorf("dataplane.classes[%s].min_rate_mbps (%g) exceeds max_rate_mbps (%g)", className, policy.MinRateMbps, policy.MaxRateMbps)
+		}
+		minRateSum += policy.MinRateMbps
 		for _, name := range policy.PreferredPaths {
 			if _, ok := pathSet[name]; !ok {
 				return fmt.Errorf("dataplane.classes[%s].preferred_paths references unknown path: %s", className, name)
@@ -314,6 +654,15 @@ func validateDataplaneConfig(dp DataplaneConfig, paths []MultipathPathConfig) er
 		}
 	}
 
+	if minRateSum > 0 {
+		if dp.TotalRateMbps <= 0 {
+			return fmt.Errorf("dataplane.total_rate_mbps required when a class sets min_rate_mbps")
+		}
+		if minRateSum > dp.TotalRateMbps {
+			return fmt.Errorf("dataplane: sum of min_rate_mbps (%g) exceeds total_rate_mbps (%g)", minRateSum, dp.TotalRateMbps)
+		}
+	}
+
 	for i, rule := range dp.Classifiers {
 		if rule.ClassName == "" {
 			return fmt.Errorf("dataplane.classifiers[%d].class required", i)
@@ -341,6 +690,16 @@ func validateDataplaneConfig(dp DataplaneConfig, paths []MultipathPathConfig) er
 				return fmt.Errorf("dataplane.classifiers[%d].dscp value out of range: %d", i, dscp)
 			}
 		}
+		for _, d := range rule.Domains {
+			if !isValidDomainPattern(d) {
+				return fmt.Errorf("dataplane.classifiers[%d].domains invalid: %q (use example.com or *.example.com)", i, d)
+			}
+		}
+		for _, d := range rule.SNI {
+			if !isValidDomainPattern(d) {
+				return fmt.Errorf("dataplane.classifiers[%d].sni invalid: %q (use example.com or *.example.com)", i, d)
+			}
+		}
 	}
 
 	return nil
@@ -348,7 +707,8 @@ func validateDataplaneConfig(dp DataplaneConfig, paths []MultipathPathConfig) er
 
 func cloneDataplaneConfig(in DataplaneConfig) DataplaneConfig {
 	out := DataplaneConfig{
-		DefaultClass: in.DefaultClass,
+		DefaultClass:  in.DefaultClass,
+		TotalRateMbps: in.TotalRateMbps,
 	}
 	if in.Classes != nil {
 		out.Classes = make(map[string]DataplaneClassPolicy, len(in.Classes))
@@ -367,6 +727,8 @@ func cloneDataplaneConfig(in DataplaneConfig) DataplaneConfig {
 			copyRule.DstCIDRs = append([]string(nil), rule.DstCIDRs...)
 			copyRule.SrcPorts = append([]string(nil), rule.SrcPorts...)
 			copyRule.DstPorts = append([]string(nil), rule.DstPorts...)
+			copyRule.Domains = append([]string(nil), rule.Domains...)
+			copyRule.SNI = append([]string(nil), rule.SNI...)
 			copyRule.DSCP = append([]int(nil), rule.DSCP...)
 			out.Classifiers = append(out.Classifiers, copyRule)
 		}
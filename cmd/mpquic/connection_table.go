@@ -28,6 +28,29 @@ type connectionTable struct {
 	byIP    map[netip.Addr]*connGroup  // primary: peerIP → group of paths
 	routed  map[netip.Addr]netip.Addr  // learned: srcIP → peerIP (reverse map)
 	dedup   *packetDedup               // optional: de-duplicate packets from multi-path clients
+
+	// Bonding (see bonding.go): QUIC stats feed the capacity-weighted
+	// return-path scheduler; bondHold/bondMax size each group's reorder buffer.
+	quicStats *quicStatsRegistry
+	bondHold  time.Duration
+	bondMax   int
+
+	// Coupled congestion control (see coupled_cc.go): registering a
+	// connection couples it with the other pipes of its client path.
+	lia liaGroups
+	// Per-path congestion control (see path_cc.go): nil unless the server
+	// creates its controllers through cc.byConn.
+	cc *pathCCs
+
+	// Per-class rate limits for the return direction (see class_shaper.go).
+	// dataplane is the server's compiled policy; each peer group gets its
+	// own classShaper from it, so one peer's bulk traffic never consumes
+	// another peer's capacity.
+	dataplane *compiledDataplane
+
+	// peer_dataplanes entries by peer TUN IP (see peer_dataplane.go). They
+	// take precedence over a policy pushed by the client.
+	peerDataplanes map[netip.Addr]*compiledDataplane
 }
 
 // pathConn represents a single QUIC connection (path) within a connGroup.
@@ -46,6 +69,10 @@ type pathConn struct {
 	dispatchHit  uint64        // atomic: packets successfully queued via dispatch
 	dispatchDrop uint64        // atomic: packets dropped (sendCh full)
 	fecCapable   bool          // true for stripe paths (FEC handles reordering)
+	quicStats    *quicPathStats // tracer-fed cwnd/RTT (nil for stripe paths)
+	bondCurrent  int64         // smooth-WRR counter for bonded dispatch
+	name         string        // client path name, from the peer hello
+	basePath     string        // client base path name (before pipe expansion)
 }
 
 // connGroup holds all QUIC connections from the same peer (same TUN IP).
@@ -70,16 +97,37 @@ type connGroup struct {
 	flowPaths  map[uint32]int
 	flowPrev   map[uint32]int // previous generation for promotion
 	flowRR     int  // round-robin for new flow assignment
+	// Bonding: set once the peer sends a bonded packet. From then on the
+	// return direction is spread across all active QUIC paths with a
+	// sequence header, and bondRx restores order for the forward direction.
+	bonding    bool
+	bondTxSeq  uint32
+	bondRx     *bondReorderBuffer
+	shaper     *classShaper // per-peer class rate limits (nil = unshaped)
+	// Dataplane pushed by the client (see peer_dataplane.go), identified
+	// by the hash of its body; policyAsm collects its chunks.
+	pushed     *compiledDataplane
+	pushedHash uint32
+	policyAsm  peerPolicyAsm
 }
 
 // flowHash extracts a lightweight hash from an IP packet's 5-tuple
 // (src IP, dst IP, protocol, src port, dst port) so that packets belonging
 // to the same TCP/UDP flow consistently map to the same path index.
-// Returns (hash, true) for parseable IPv4 TCP/UDP packets, (0, false) otherwise.
+// Returns (hash, true) for parseable IPv4/IPv6 TCP/UDP packets, (0, false)
+// otherwise.
+//
+// IPv6 packets whose ports are not directly after the fixed header
+// (extension headers, ESP, ...) are hashed on addresses and flow label
+// (RFC 6437) when the sender set one. The label is not used when ports are
+// available: Linux re-rolls it after an RTO, which would move the flow.
 func flowHash(pkt []byte) (uint32, bool) {
 	if len(pkt) < 20 {
 		return 0, false
 	}
+	if pkt[0]>>4 == 6 {
+		return flowHash6(pkt)
+	}
 	ihl := int(pkt[0]&0x0f) * 4
 	proto := pkt[9]
 	if ihl < 20 || len(pkt) < ihl+4 {
@@ -104,11 +152,63 @@ func flowHash(pkt []byte) (uint32, bool) {
 	return h, true
 }
 
+func flowHash6(pkt []byte) (uint32, bool) {
+	if len(pkt) < 40 {
+		return 0, false
+	}
+	next := pkt[6]
+	hasPorts := (next == 6 || next == 17) && len(pkt) >= 44
+	label := pkt[1:4:4]
+	if !hasPorts && label[0]&0x0f == 0 && label[1] == 0 && label[2] == 0 {
+		return 0, false
+	}
+	h := uint32(2166136261)
+	for _, b := range pkt[8:40] { // src IP + dst IP
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	h ^= uint32(next)
+	h *= 16777619
+	tail := pkt[40:44] // src port + dst port
+	if !hasPorts {
+		tail = []byte{label[0] & 0x0f, label[1], label[2]}
+	}
+	for _, b := range tail {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	return h, true
+}
+
+// packetSrcIP returns the source address of an IPv4 or IPv6 packet.
+func packetSrcIP(pkt []byte) (netip.Addr, bool) {
+	switch {
+	case len(pkt) >= 20 && pkt[0]>>4 == 4:
+		return netip.AddrFrom4([4]byte(pkt[12:16])), true
+	case len(pkt) >= 40 && pkt[0]>>4 == 6:
+		return netip.AddrFrom16([16]byte(pkt[8:24])), true
+	}
+	return netip.Addr{}, false
+}
+
+// packetDstIP returns the destination address of an IPv4 or IPv6 packet.
+func packetDstIP(pkt []byte) (netip.Addr, bool) {
+	switch {
+	case len(pkt) >= 20 && pkt[0]>>4 == 4:
+		return netip.AddrFrom4([4]byte(pkt[16:20])), true
+	case len(pkt) >= 40 && pkt[0]>>4 == 6:
+		return netip.AddrFrom16([16]byte(pkt[24:40])), true
+	}
+	return netip.Addr{}, false
+}
+
 func newConnectionTable() *connectionTable {
 	return &connectionTable{
 		byIP:   make(map[netip.Addr]*connGroup),
 		routed: make(map[netip.Addr]netip.Addr),
 		dedup:  newPacketDedup(4096),
+		quicStats: newQUICStatsRegistry(),
+		peerDataplanes: make(map[netip.Addr]*compiledDataplane),
 	}
 }
 
@@ -120,6 +220,7 @@ func (ct *connectionTable) register(peerIP netip.Addr, quicConn quic.Connection,
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
+	ct.lia.join(quicConn.RemoteAddr(), peerIP)
 	remote := quicConn.RemoteAddr().String()
 	pc := &pathConn{
 		quicConn:   quicConn,
@@ -128,6 +229,7 @@ func (ct *connectionTable) register(peerIP netip.Addr, quicConn quic.Connection,
 		remoteAddr: remote,
 		sendCh:     make(chan []byte, 256),
 		sendDone:   make(chan struct{}),
+		quicStats:  ct.quicStats.lookup(quicConn),
 	}
 	go pc.drainSendCh()
 
@@ -196,6 +298,47 @@ func (ct *connectionTable) registerStripe(peerIP netip.Addr, remoteID string, dc
 	ct.refreshAllFEC(grp)
 }
 
+// registerQUICPath adds an additional QUIC path of a quic-mp connection
+// (see quic_mp.go) to the group for peerIP. The path shares the
+// connection's tracer, so quicStats stays nil and bonding weights it by
+// configuration only; cancel abandons the path.
+func (ct *connectionTable) registerQUICPath(peerIP netip.Addr, path *quic.Path, cancel context.CancelFunc) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.lia.join(path.RemoteAddr(), peerIP)
+	remote := path.RemoteAddr().String()
+	pc := &pathConn{
+		dc:         path,
+		cancel:     cancel,
+		remoteAddr: remote,
+		sendCh:     make(chan []byte, 256),
+		sendDone:   make(chan struct{}),
+	}
+	go pc.drainSendCh()
+
+	grp, exists := ct.byIP[peerIP]
+	if !exists {
+		ct.byIP[peerIP] = &connGroup{peerIP: peerIP, paths: []*pathConn{pc}, allFEC: false}
+		return
+	}
+
+	for i, old := range grp.paths {
+		if old.remoteAddr == remote {
+			old.stopSendCh()
+			old.cancel()
+			if old.quicConn != nil {
+				_ = old.quicConn.CloseWithError(0, "superseded")
+			}
+			grp.paths[i] = pc
+			ct.refreshAllFEC(grp)
+			return
+		}
+	}
+	grp.paths = append(grp.paths, pc)
+	ct.refreshAllFEC(grp)
+}
+
 // refreshAllFEC recomputes grp.allFEC. Must be called with ct.mu held.
 func (ct *connectionTable) refreshAllFEC(grp *connGroup) {
 	grp.allFEC = true
@@ -269,6 +412,9 @@ func (ct *connectionTable) unregisterConn(peerIP netip.Addr, remoteAddr string)
 
 	if len(grp.paths) == 0 {
 		// No more paths — remove peer and all learned routes
+		if grp.bondRx != nil {
+			grp.bondRx.close()
+		}
 		for src, peer := range ct.routed {
 			if peer == peerIP {
 				delete(ct.routed, src)
@@ -290,6 +436,9 @@ func (ct *connectionTable) unregister(peerIP netip.Addr) {
 				_ = pc.quicConn.CloseWithError(0, "unregistered")
 			}
 		}
+		if grp.bondRx != nil {
+			grp.bondRx.close()
+		}
 	}
 	for src, peer := range ct.routed {
 		if peer == peerIP {
@@ -409,6 +558,9 @@ func (ct *connectionTable) lookup(dstIP netip.Addr) (datagramConn, bool) {
 //   - QUIC groups: flow-hash on the IP 5-tuple so that packets from the
 //     same TCP/UDP connection always traverse the same path, preventing
 //     reordering that would cripple TCP throughput.
+//
+// With a per-peer dataplane the packet's class first narrows the active
+// paths (excluded/preferred) and duplicated classes are sent on several.
 func (ct *connectionTable) dispatch(dstIP netip.Addr, pkt []byte) bool {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
@@ -418,8 +570,27 @@ func (ct *connectionTable) dispatch(dstIP netip.Addr, pkt []byte) bool {
 		return false
 	}
 
+	// Class rate limits: a policed packet is dropped on purpose, so it is
+	// reported as handled rather than as a dispatch failure.
+	if ct.dataplane != nil && ct.dataplane.shaper != nil {
+		if grp.shaper == nil {
+			grp.shaper = newClassShaper(*ct.dataplane)
+		}
+		className, _ := ct.dataplane.resolve(pkt)
+		if !grp.shaper.allow(className, len(pkt)) {
+			return true
+		}
+	}
+
+	var classPolicy DataplaneClassPolicy
+	steered := false
+	if dp := ct.peerDataplaneLocked(grp); dp != nil {
+		_, classPolicy = dp.resolveReturn(pkt)
+		steered = len(classPolicy.PreferredPaths) > 0 || len(classPolicy.ExcludedPaths) > 0 || classPolicy.Duplicate
+	}
+
 	// Single path — fast path
-	if len(grp.paths) == 1 {
+	if len(grp.paths) == 1 && !steered {
 		select {
 		case grp.paths[0].sendCh <- pkt:
 			return true
@@ -449,6 +620,32 @@ func (ct *connectionTable) dispatch(dstIP netip.Addr, pkt []byte) bool {
 		}
 	}
 
+	if steered {
+		active = filterReturnPaths(grp, active, classPolicy)
+		if len(active) == 0 {
+			return false // every path excluded, as on the client
+		}
+		if classPolicy.Duplicate && len(active) > 1 {
+			return ct.dispatchDuplicate(grp, active, classPolicy, pkt)
+		}
+	}
+
+	if grp.bonding && !grp.allFEC {
+		if idx, ok := ct.pickBondPath(grp, active); ok {
+			frame := encodeBondPacket(grp.bondTxSeq, pkt)
+			pc := grp.paths[idx]
+			select {
+			case pc.sendCh <- frame:
+				grp.bondTxSeq++
+				atomic.AddUint64(&pc.dispatchHit, 1)
+				return true
+			default:
+				atomic.AddUint64(&pc.dispatchDrop, 1)
+				return false
+			}
+		}
+	}
+
 	var idx int
 	if grp.allFEC {
 		// FEC-capable paths: per-flow affinity with round-robin assignment.
@@ -516,6 +713,56 @@ func (ct *connectionTable) dispatch(dstIP netip.Addr, pkt []byte) bool {
 	return dispatched
 }
 
+// pickBondPath chooses the QUIC path for a bonded return packet among the
+// active paths, weighted by estimated capacity (cwnd / sRTT). Paths whose
+// send queue is full are skipped so a stalled WAN does not collect the
+// packets the others could carry. Returns false when fewer than two QUIC
+// paths are usable: bonding a single path only adds header overhead.
+// Caller must hold ct.mu.
+func (ct *connectionTable) pickBondPath(grp *connGroup, active []int) (int, bool) {
+	var candArr [8]int
+	var weightArr, curArr [8]int64
+	cand := candArr[:0]
+	weights := weightArr[:0]
+	current := curArr[:0]
+	for _, i := range active {
+		pc := grp.paths[i]
+		if pc.fecCapable || len(pc.sendCh) == cap(pc.sendCh) {
+			continue
+		}
+		cand = append(cand, i)
+		weights = append(weights, bondCapacity(pc.quicStats, 1))
+		current = append(current, pc.bondCurrent)
+	}
+	if len(cand) < 2 {
+		return 0, false
+	}
+	pick := bondPick(weights, current)
+	for k, i := range cand {
+		grp.paths[i].bondCurrent = current[k]
+	}
+	return cand[pick], true
+}
+
+// bondReceive hands a bonded packet from peerIP to the group's reorder
+// buffer, creating it (and switching the group to bonded return traffic)
+// on the first bonded packet. deliver writes an in-order packet to the TUN.
+func (ct *connectionTable) bondReceive(peerIP netip.Addr, seq uint32, pkt []byte, deliver func([]byte)) {
+	ct.mu.Lock()
+	grp, ok := ct.byIP[peerIP]
+	if !ok {
+		ct.mu.Unlock()
+		return
+	}
+	if grp.bondRx == nil {
+		grp.bondRx = newBondReorderBuffer(ct.bondHold, ct.bondMax, deliver)
+		grp.bonding = true
+	}
+	rx := grp.bondRx
+	ct.mu.Unlock()
+	rx.push(seq, pkt)
+}
+
 // pathCount returns the number of active path connections for a peer.
 func (ct *connectionTable) pathCount(peerIP netip.Addr) int {
 	ct.mu.RLock()
@@ -587,6 +834,9 @@ func (ct *connectionTable) closeAll() {
 				_ = pc.quicConn.CloseWithError(0, "shutdown")
 			}
 		}
+		if grp.bondRx != nil {
+			grp.bondRx.close()
+		}
 		delete(ct.byIP, ip)
 	}
 	for src := range ct.routed {
@@ -290,3 +290,34 @@ func TestConnectionTable_CloseAll(t *testing.T) {
 		t.Errorf("routedCount = %d, want 0 after closeAll", ct.routedCount())
 	}
 }
+
+func TestPacketAddrs_DualStack(t *testing.T) {
+	v4 := ipv4Packet(17, udpDatagram(nil))
+	if src, ok := packetSrcIP(v4); !ok || src != netip.MustParseAddr("10.200.1.1") {
+		t.Errorf("IPv4 src = %s ok=%t", src, ok)
+	}
+	v6 := ipv6Packet(17, 0, udpDatagram(nil))
+	src, ok1 := packetSrcIP(v6)
+	dst, ok2 := packetDstIP(v6)
+	if !ok1 || !ok2 || src != netip.MustParseAddr("fd00::1") || dst != netip.MustParseAddr("2001:db8::7") {
+		t.Errorf("IPv6 src=%s dst=%s", src, dst)
+	}
+	if _, ok := packetSrcIP(v6[:39]); ok {
+		t.Error("truncated IPv6 header parsed")
+	}
+}
+
+func TestConnectionTable_HelloRoutesSecondTunAddress(t *testing.T) {
+	ct := newConnectionTable()
+	peer, peer6 := netip.MustParseAddr("10.200.1.1"), netip.MustParseAddr("fd00:200::1")
+	dc := &mockDC{}
+	helperRegisterStripe(ct, peer, "stripe:1", dc)
+
+	hello := encodePeerHello([]netip.Addr{peer, peer6}, "wan1", "", "")
+	if _, err := ct.peerControl(peer, "stripe:1", hello); err != nil {
+		t.Fatal(err)
+	}
+	if !ct.dispatch(peer6, ipv6Packet(17, 0, udpDatagram(nil))) {
+		t.Fatal("IPv6 return traffic to the peer's tun_cidr6 address not dispatched")
+	}
+}
@@ -46,6 +46,17 @@ func startControlAPI(ctx context.Context, cfg *Config, mp *multipathConn, logger
 		}
 	})
 
+	mux.HandleFunc("/dataplane/domains", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		if !authorizeControlAPI(w, r, cfg) {
+			return
+		}
+		writeJSON(w, http.StatusOK, mp.domains.snapshot(r.URL.Query().Get("domain")))
+	})
+
 	mux.HandleFunc("/dataplane/validate", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
@@ -61,7 +72,7 @@ func startControlAPI(ctx context.Context, cfg *Config, mp *multipathConn, logger
 			return
 		}
 		normalizeDataplaneConfig(&dp, cfg.MultipathPolicy)
-		if err := validateDataplaneConfig(dp, cfg.MultipathPaths); err != nil {
+		if err := validateDataplaneConfig(dp, mp.knownPaths()); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
@@ -104,6 +115,91 @@ func startControlAPI(ctx context.Context, cfg *Config, mp *multipathConn, logger
 		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 	})
 
+	mux.HandleFunc("/paths", func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeControlAPI(w, r, cfg) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]any{"paths": mp.snapshotPaths()})
+		case http.MethodPost:
+			p, err := decodePathFromRequest(r)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			names, err := mp.addPath(p)
+			if err != nil {
+				writeJSON(w, pathErrorStatus(err), map[string]any{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]any{"ok": true, "paths": names})
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		}
+	})
+
+	// /paths/{name} (DELETE), /paths/{name}/drain and /paths/{name}/qlog (POST)
+	mux.HandleFunc("/paths/", func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeControlAPI(w, r, cfg) {
+			return
+		}
+		name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/paths/"), "/")
+		if name == "" {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "path name required"})
+			return
+		}
+		switch {
+		case action == "" && r.Method == http.MethodDelete:
+			if err := mp.removePath(name); err != nil {
+				writeJSON(w, pathErrorStatus(err), map[string]any{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		case action == "drain" && r.Method == http.MethodPost:
+			var req struct {
+				TimeoutSec int `json:"timeout_sec"`
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+			if err == nil && len(strings.TrimSpace(string(body))) > 0 {
+				err = json.Unmarshal(body, &req)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			if err := mp.drainPath(name, time.Duration(req.TimeoutSec)*time.Second); err != nil {
+				writeJSON(w, pathErrorStatus(err), map[string]any{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusAccepted, map[string]any{"ok": true})
+		case action == "qlog" && r.Method == http.MethodPost:
+			var req struct {
+				Enabled *bool `json:"enabled"`
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+			if err == nil {
+				err = json.Unmarshal(body, &req)
+			}
+			if err == nil && req.Enabled == nil {
+				err = errors.New("enabled required")
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			if err := mp.setPathQlog(name, *req.Enabled); err != nil {
+				writeJSON(w, pathErrorStatus(err), map[string]any{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"ok": true, "qlog": *req.Enabled})
+		case action == "" || action == "drain" || action == "qlog":
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+		}
+	})
+
 	server := &http.Server{
 		Addr:    cfg.ControlAPIListen,
 		Handler: mux,
@@ -176,6 +272,35 @@ func decodeDataplaneFromRequest(r *http.Request) (DataplaneConfig, error) {
 	return dp, nil
 }
 
+// decodePathFromRequest parses a multipath_paths entry; YAML decoding also
+// accepts JSON bodies with the same snake_case keys.
+func decodePathFromRequest(r *http.Request) (MultipathPathConfig, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64*1024))
+	if err != nil {
+		return MultipathPathConfig{}, err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return MultipathPathConfig{}, fmt.Errorf("empty request body")
+	}
+	p := MultipathPathConfig{}
+	if err := yaml.Unmarshal(body, &p); err != nil {
+		return MultipathPathConfig{}, fmt.Errorf("payload must be JSON or YAML")
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	return p, nil
+}
+
+func pathErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errPathNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errPathExists), errors.Is(err, errPathLast), errors.Is(err, errQlogDisabled):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
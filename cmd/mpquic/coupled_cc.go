@@ -0,0 +1,420 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/congestion"
+)
+
+// ─── Coupled congestion control (LIA, RFC 6356) ──────────────────────────
+//
+// With pipes > 1 every pipe of a path is its own QUIC connection, and all of
+// them cross the same bottleneck (one Starlink dish, one LTE cell). With an
+// independent Cubic/BBR each, N pipes take N times the share of a single
+// flow: they push each other into loss and overshoot the queue.
+//
+// congestion_algorithm: lia couples the controllers of the connections that
+// cross one bottleneck in a liaGroup: the pipes of a base path on the client,
+// the connections of one client path on the server. Each member runs
+// Reno slow start and halves its window on loss or ECN-CE, but in congestion
+// avoidance the window grows by
+//
+//	min(α · acked · MSS / cwnd_total, acked · MSS / cwnd_i)
+//	α = cwnd_total · max_i(cwnd_i / rtt_i²) / (Σ_i cwnd_i / rtt_i)²
+//
+// so the group together is no more aggressive than one Reno flow on its best
+// member, and shifts traffic away from the more congested members. With
+// quic-mp the client couples the paths of the multipath connection.
+//
+// Members that haven't sent for a while (an idle or reconnected pipe, the
+// controller left behind by a migration) drop out of the coupling.
+
+const (
+	congestionLIA = "lia"
+
+	liaInitialWindowPackets = 32
+	liaMinWindowPackets     = 2
+	liaMaxWindowPackets     = 10000
+	// available window below which the sender counts as cwnd-limited
+	liaMaxBurstPackets = 3
+	// a member that didn't send for max(liaIdleTimeout, 4·srtt) is not coupled
+	liaIdleTimeout = time.Second
+	// members idle for longer are dropped from the group
+	liaMemberExpiry = time.Minute
+)
+
+// withCongestionControl sets the congestion controller algo on conf.
+// local-quic-go selects its own controllers by name; lia controllers are
+// created by newLIA, which picks the group they are coupled in. A nil newLIA
+// gives every connection a group of its own, i.e. plain Reno.
+func withCongestionControl(conf *quic.Config, algo string, newLIA func(congestion.PathInfo) congestion.SendAlgorithm) *quic.Config {
+	if algo != congestionLIA {
+		conf.CongestionAlgorithm = algo
+		return conf
+	}
+	if newLIA == nil {
+		newLIA = func(info congestion.PathInfo) congestion.SendAlgorithm {
+			return newLIAGroup().newSender(info)
+		}
+	}
+	conf.CongestionControl = newLIA
+	return conf
+}
+
+// liaGroup couples the congestion controllers of the connections sharing a
+// bottleneck.
+type liaGroup struct {
+	mu      sync.Mutex
+	members []*liaSender
+}
+
+func newLIAGroup() *liaGroup {
+	return &liaGroup{}
+}
+
+// liaGroups hands out one group per bottleneck: per base path on the client,
+// per client path on the server.
+type liaGroups struct {
+	mu     sync.Mutex
+	groups map[string]*liaGroup
+	// server: controllers by the remote address of their connection, see
+	// byConn.
+	conns map[string]*liaConn
+}
+
+// liaConn is the controller of a server connection, and the key of the
+// group join put it in ("" before).
+type liaConn struct {
+	sender *liaSender
+	key    string
+}
+
+func (g *liaGroups) get(key string) *liaGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.getLocked(key)
+}
+
+func (g *liaGroups) getLocked(key string) *liaGroup {
+	if g.groups == nil {
+		g.groups = make(map[string]*liaGroup)
+	}
+	now := time.Now()
+	for k, grp := range g.groups {
+		if k != key && grp.expired(now) {
+			delete(g.groups, k)
+		}
+	}
+	for addr, c := range g.conns {
+		if now.Sub(time.Unix(0, c.sender.lastSent.Load())) >= liaMemberExpiry {
+			delete(g.conns, addr)
+		}
+	}
+	grp, ok := g.groups[key]
+	if !ok {
+		grp = newLIAGroup()
+		g.groups[key] = grp
+	}
+	return grp
+}
+
+// forPath returns the group of the pipes of path p.
+func (g *liaGroups) forPath(p MultipathPathConfig) *liaGroup {
+	if p.BasePath != "" {
+		return g.get(p.BasePath)
+	}
+	return g.get(p.Name)
+}
+
+// byConn creates the controller of a server connection. The server creates
+// it on the first packet, before it knows whether the connection carries a
+// tunnel or a stripe key exchange, and which client it belongs to: the
+// controller starts uncoupled, join couples it once the connection registers.
+// A controller recreated for the same connection (after a migration) goes
+// back to the group join put the connection in.
+func (g *liaGroups) byConn(info congestion.PathInfo) congestion.SendAlgorithm {
+	s := newLIASender(info)
+	if info.RemoteAddr == nil {
+		newLIAGroup().add(s)
+		return s
+	}
+	addr := info.RemoteAddr.String()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.conns[addr]; ok && c.key != "" && c.sender.rttStats == info.RTTStats {
+		g.getLocked(c.key).add(s)
+		c.sender = s
+		return s
+	}
+	newLIAGroup().add(s)
+	if g.conns == nil {
+		g.conns = make(map[string]*liaConn)
+	}
+	g.conns[addr] = &liaConn{sender: s}
+	return s
+}
+
+// join couples the controller of the server connection from remote with the
+// other connections of the same client path: same client (peer TUN IP), same
+// WAN address. Connections that never register, such as the stripe key
+// exchange, stay uncoupled.
+func (g *liaGroups) join(remote net.Addr, peerIP netip.Addr) {
+	udp, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	key := peerIP.String() + "/" + udp.IP.String()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.conns[remote.String()]
+	if !ok || c.key == key {
+		return
+	}
+	c.key = key
+	c.sender.group.Load().remove(c.sender)
+	g.getLocked(key).add(c.sender)
+}
+
+func (g *liaGroup) newSender(info congestion.PathInfo) congestion.SendAlgorithm {
+	s := newLIASender(info)
+	g.add(s)
+	return s
+}
+
+// newLIASender creates a controller. It is not usable before a group adds it.
+func newLIASender(info congestion.PathInfo) *liaSender {
+	s := &liaSender{
+		rttStats:                 info.RTTStats,
+		maxDatagramSize:          info.InitialMaxDatagramSize,
+		cwnd:                     liaInitialWindowPackets * info.InitialMaxDatagramSize,
+		ssthresh:                 liaMaxWindowPackets * info.InitialMaxDatagramSize,
+		largestSent:              -1,
+		largestAcked:             -1,
+		largestSentAtLastCutback: -1,
+	}
+	s.pacer = congestion.NewPacer(s.bandwidth)
+	s.pacer.SetMaxDatagramSize(s.maxDatagramSize)
+	s.lastSent.Store(time.Now().UnixNano())
+	s.publish()
+	return s
+}
+
+// add makes s a member of g.
+func (g *liaGroup) add(s *liaSender) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	members := g.members[:0]
+	for _, m := range g.members {
+		if now.Sub(time.Unix(0, m.lastSent.Load())) < liaMemberExpiry {
+			members = append(members, m)
+		}
+	}
+	g.members = append(members, s)
+	s.group.Store(g)
+}
+
+// remove drops s from the members of g.
+func (g *liaGroup) remove(s *liaSender) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, m := range g.members {
+		if m == s {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// expired reports whether no member of the group sent for liaMemberExpiry.
+func (g *liaGroup) expired(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.members {
+		if now.Sub(time.Unix(0, m.lastSent.Load())) < liaMemberExpiry {
+			return false
+		}
+	}
+	return true
+}
+
+// alpha returns the LIA increase factor α and the total window of the active
+// members. s is always active.
+func (g *liaGroup) alpha(s *liaSender, now time.Time) (float64, congestion.ByteCount) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var total congestion.ByteCount
+	var maxRatio, sum float64
+	for _, m := range g.members {
+		srtt := time.Duration(m.srtt.Load())
+		if m != s && (srtt <= 0 || now.Sub(time.Unix(0, m.lastSent.Load())) > max(liaIdleTimeout, 4*srtt)) {
+			continue
+		}
+		cwnd := congestion.ByteCount(m.cwndShared.Load())
+		total += cwnd
+		if srtt <= 0 {
+			continue
+		}
+		rtt := srtt.Seconds()
+		maxRatio = max(maxRatio, float64(cwnd)/(rtt*rtt))
+		sum += float64(cwnd) / rtt
+	}
+	if sum == 0 {
+		return 1, total
+	}
+	return float64(total) * maxRatio / (sum * sum), total
+}
+
+// liaSender is the congestion controller of one member of a liaGroup.
+// Apart from the values it publishes to the group and the group itself
+// (changed by liaGroups.join), it is only used from the run loop of its
+// connection.
+type liaSender struct {
+	group    atomic.Pointer[liaGroup]
+	rttStats *congestion.RTTStats
+	pacer    *congestion.Pacer
+
+	maxDatagramSize congestion.ByteCount
+	cwnd            congestion.ByteCount
+	ssthresh        congestion.ByteCount
+	// congestion avoidance increase not yet applied, in bytes
+	caCredit float64
+
+	largestSent              congestion.PacketNumber
+	largestAcked             congestion.PacketNumber
+	largestSentAtLastCutback congestion.PacketNumber
+
+	// published for the other members of the group
+	cwndShared atomic.Uint64
+	srtt       atomic.Int64
+	lastSent   atomic.Int64 // unix nanoseconds
+}
+
+var _ congestion.SendAlgorithm = &liaSender{}
+
+func (s *liaSender) publish() {
+	s.cwndShared.Store(uint64(s.cwnd))
+	s.srtt.Store(int64(s.rttStats.SmoothedRTT()))
+}
+
+// bandwidth is the pacing bandwidth, one window per smoothed RTT.
+func (s *liaSender) bandwidth() congestion.Bandwidth {
+	srtt := s.rttStats.SmoothedRTT()
+	if srtt <= 0 {
+		return congestion.Bandwidth(math.MaxUint64) // no RTT sample yet, don't pace
+	}
+	return congestion.Bandwidth(s.cwnd) * congestion.Bandwidth(time.Second) / congestion.Bandwidth(srtt) * congestion.BytesPerSecond
+}
+
+func (s *liaSender) TimeUntilSend(congestion.ByteCount) time.Time {
+	return s.pacer.TimeUntilSend()
+}
+
+func (s *liaSender) HasPacingBudget(now time.Time) bool {
+	return s.pacer.Budget(now) >= s.maxDatagramSize
+}
+
+func (s *liaSender) OnPacketSent(sentTime time.Time, _ congestion.ByteCount, pn congestion.PacketNumber, bytes congestion.ByteCount, isRetransmittable bool) {
+	s.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	s.largestSent = pn
+	s.lastSent.Store(sentTime.UnixNano())
+}
+
+func (s *liaSender) CanSend(bytesInFlight congestion.ByteCount) bool {
+	return bytesInFlight < s.cwnd
+}
+
+func (s *liaSender) MaybeExitSlowStart() {}
+
+func (s *liaSender) OnPacketAcked(pn congestion.PacketNumber, ackedBytes, priorInFlight congestion.ByteCount, eventTime time.Time) {
+	s.largestAcked = max(s.largestAcked, pn)
+	defer s.publish()
+	if s.InRecovery() || !s.isCwndLimited(priorInFlight) || s.cwnd >= s.maxWindow() {
+		return
+	}
+	if s.InSlowStart() {
+		s.cwnd = min(s.cwnd+ackedBytes, s.maxWindow())
+		return
+	}
+	alpha, total := s.group.Load().alpha(s, eventTime)
+	mss := float64(s.maxDatagramSize)
+	coupled := alpha * float64(ackedBytes) * mss / float64(max(total, s.cwnd))
+	uncoupled := float64(ackedBytes) * mss / float64(s.cwnd)
+	s.caCredit += min(coupled, uncoupled)
+	if s.caCredit >= 1 {
+		inc := congestion.ByteCount(s.caCredit)
+		s.caCredit -= float64(inc)
+		s.cwnd = min(s.cwnd+inc, s.maxWindow())
+	}
+}
+
+// OnCongestionEvent halves the window, once per window of data: losses of
+// packets sent before the last reduction belong to the same congestion event.
+func (s *liaSender) OnCongestionEvent(pn congestion.PacketNumber, _, _ congestion.ByteCount) {
+	if pn <= s.largestSentAtLastCutback {
+		return
+	}
+	s.cwnd = max(s.cwnd/2, s.minWindow())
+	s.ssthresh = s.cwnd
+	s.caCredit = 0
+	s.largestSentAtLastCutback = s.largestSent
+	s.publish()
+}
+
+func (s *liaSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if !packetsRetransmitted {
+		return
+	}
+	s.ssthresh = max(s.cwnd/2, s.minWindow())
+	s.cwnd = s.minWindow()
+	s.largestSentAtLastCutback = -1
+	s.publish()
+}
+
+func (s *liaSender) SetMaxDatagramSize(size congestion.ByteCount) {
+	if size < s.maxDatagramSize {
+		return
+	}
+	s.maxDatagramSize = size
+	s.cwnd = max(s.cwnd, s.minWindow())
+	s.pacer.SetMaxDatagramSize(size)
+}
+
+func (s *liaSender) InSlowStart() bool {
+	return s.cwnd < s.ssthresh
+}
+
+func (s *liaSender) InRecovery() bool {
+	return s.largestSentAtLastCutback >= 0 && s.largestAcked <= s.largestSentAtLastCutback
+}
+
+func (s *liaSender) GetCongestionWindow() congestion.ByteCount {
+	return s.cwnd
+}
+
+// isCwndLimited reports whether the window, not the application, limited
+// the sender.
+func (s *liaSender) isCwndLimited(bytesInFlight congestion.ByteCount) bool {
+	if bytesInFlight >= s.cwnd {
+		return true
+	}
+	available := s.cwnd - bytesInFlight
+	return (s.InSlowStart() && bytesInFlight > s.cwnd/2) || available <= liaMaxBurstPackets*s.maxDatagramSize
+}
+
+func (s *liaSender) minWindow() congestion.ByteCount {
+	return liaMinWindowPackets * s.maxDatagramSize
+}
+
+func (s *liaSender) maxWindow() congestion.ByteCount {
+	return liaMaxWindowPackets * s.maxDatagramSize
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/congestion"
+)
+
+const testLIAMSS = 1200
+
+// newTestLIASender adds a member with smoothed RTT rtt to g, in congestion
+// avoidance.
+func newTestLIASender(t *testing.T, g *liaGroup, rtt time.Duration) *liaSender {
+	t.Helper()
+	rttStats := &congestion.RTTStats{}
+	rttStats.UpdateRTT(rtt, 0, time.Now())
+	s := g.newSender(congestion.PathInfo{RTTStats: rttStats, InitialMaxDatagramSize: testLIAMSS}).(*liaSender)
+	s.ssthresh = s.cwnd
+	s.publish()
+	return s
+}
+
+// ackWindow sends and acknowledges one congestion window, always
+// cwnd-limited, and returns the window increase.
+func ackWindow(s *liaSender) congestion.ByteCount {
+	before := s.cwnd
+	now := time.Now()
+	first := s.largestSent + 1
+	n := congestion.PacketNumber(before / testLIAMSS)
+	for pn := first; pn < first+n; pn++ {
+		s.OnPacketSent(now, s.cwnd, pn, testLIAMSS, true)
+	}
+	for pn := first; pn < first+n; pn++ {
+		s.OnPacketAcked(pn, testLIAMSS, s.cwnd, now)
+	}
+	return s.cwnd - before
+}
+
+func TestLIA_SingleMemberIsReno(t *testing.T) {
+	s := newTestLIASender(t, newLIAGroup(), 50*time.Millisecond)
+	// Reno: acked · MSS / cwnd per ACK, slightly less than one MSS per window
+	// as the window grows along the way
+	if inc := ackWindow(s); inc < testLIAMSS*97/100 || inc > testLIAMSS {
+		t.Fatalf("increase per window = %d, want about one MSS (%d)", inc, testLIAMSS)
+	}
+}
+
+func TestLIA_CoupledNoMoreAggressiveThanOneFlow(t *testing.T) {
+	g := newLIAGroup()
+	a := newTestLIASender(t, g, 50*time.Millisecond)
+	b := newTestLIASender(t, g, 50*time.Millisecond)
+	total := ackWindow(a) + ackWindow(b)
+	if total > testLIAMSS {
+		t.Fatalf("total increase per window = %d, want <= one MSS (%d)", total, testLIAMSS)
+	}
+	if total == 0 {
+		t.Fatal("coupled members don't grow")
+	}
+}
+
+func TestLIA_Alpha(t *testing.T) {
+	g := newLIAGroup()
+	a := newTestLIASender(t, g, 50*time.Millisecond)
+	b := newTestLIASender(t, g, 100*time.Millisecond)
+	a.cwnd, b.cwnd = 40*testLIAMSS, 40*testLIAMSS
+	a.publish()
+	b.publish()
+
+	// α = (w_a + w_b) · (w_a / r_a²) / (w_a/r_a + w_b/r_b)², with w_a = w_b:
+	// 2 · (1/r_a²) / (1/r_a + 1/r_b)² = 2 · 400 / 30² = 8/9.
+	alpha, total := g.alpha(a, time.Now())
+	if total != 80*testLIAMSS {
+		t.Fatalf("total = %d, want %d", total, 80*testLIAMSS)
+	}
+	if alpha < 0.888 || alpha > 0.889 {
+		t.Fatalf("alpha = %f, want 8/9", alpha)
+	}
+}
+
+func TestLIA_IdleMemberNotCoupled(t *testing.T) {
+	g := newLIAGroup()
+	a := newTestLIASender(t, g, 50*time.Millisecond)
+	b := newTestLIASender(t, g, 50*time.Millisecond)
+	b.lastSent.Store(time.Now().Add(-2 * liaIdleTimeout).UnixNano())
+
+	alpha, total := g.alpha(a, time.Now())
+	if total != a.cwnd || alpha < 0.999 || alpha > 1.001 {
+		t.Fatalf("alpha = %f total = %d, want 1 and %d", alpha, total, a.cwnd)
+	}
+}
+
+func TestLIA_CongestionEventHalvesOncePerWindow(t *testing.T) {
+	s := newTestLIASender(t, newLIAGroup(), 50*time.Millisecond)
+	now := time.Now()
+	for pn := congestion.PacketNumber(0); pn < 10; pn++ {
+		s.OnPacketSent(now, s.cwnd, pn, testLIAMSS, true)
+	}
+	cwnd := s.cwnd
+	s.OnCongestionEvent(3, testLIAMSS, s.cwnd)
+	if s.cwnd != cwnd/2 || !s.InRecovery() {
+		t.Fatalf("cwnd = %d recovery = %v, want %d in recovery", s.cwnd, s.InRecovery(), cwnd/2)
+	}
+	s.OnCongestionEvent(5, testLIAMSS, s.cwnd)
+	s.OnCongestionEvent(0, 0, s.cwnd) // ECN-CE
+	if s.cwnd != cwnd/2 {
+		t.Fatalf("cwnd = %d after losses in the same window, want %d", s.cwnd, cwnd/2)
+	}
+
+	s.OnPacketSent(now, s.cwnd, 10, testLIAMSS, true)
+	s.OnPacketSent(now, s.cwnd, 11, testLIAMSS, true)
+	s.OnPacketAcked(9, testLIAMSS, s.cwnd, now)
+	if !s.InRecovery() {
+		t.Fatal("recovery ended before a packet sent after the reduction was acknowledged")
+	}
+	s.OnPacketAcked(10, testLIAMSS, s.cwnd, now)
+	if s.InRecovery() {
+		t.Fatal("still in recovery after a packet sent after the reduction was acknowledged")
+	}
+	cwnd = s.cwnd
+	s.OnCongestionEvent(11, testLIAMSS, s.cwnd)
+	if s.cwnd != cwnd/2 {
+		t.Fatalf("cwnd = %d after a loss in the next window, want %d", s.cwnd, cwnd/2)
+	}
+}
+
+func TestLIAGroups_ByConn(t *testing.T) {
+	var groups liaGroups
+	info := func(addr string) congestion.PathInfo {
+		return congestion.PathInfo{
+			RTTStats:               &congestion.RTTStats{},
+			InitialMaxDatagramSize: testLIAMSS,
+			RemoteAddr:             net.UDPAddrFromAddrPort(netip.MustParseAddrPort(addr)),
+		}
+	}
+	newSender := func(addr string) *liaSender {
+		return groups.byConn(info(addr)).(*liaSender)
+	}
+	join := func(addr, peerIP string) {
+		groups.join(net.UDPAddrFromAddrPort(netip.MustParseAddrPort(addr)), netip.MustParseAddr(peerIP))
+	}
+	// two pipes of a client path, another client behind the same CGNAT
+	// address, and a stripe key exchange that never registers
+	a := newSender("192.0.2.1:40000")
+	b := newSender("192.0.2.1:40001")
+	c := newSender("192.0.2.1:40002")
+	kx := newSender("192.0.2.1:40003")
+	if a.group.Load() == b.group.Load() {
+		t.Fatal("connections coupled before they registered")
+	}
+	join("192.0.2.1:40000", "10.200.0.2")
+	join("192.0.2.1:40001", "10.200.0.2")
+	join("192.0.2.1:40002", "10.200.0.3")
+	if a.group.Load() != b.group.Load() {
+		t.Fatal("pipes of the same client path not coupled")
+	}
+	if a.group.Load() == c.group.Load() {
+		t.Fatal("different clients behind one address coupled")
+	}
+	if a.group.Load() == kx.group.Load() {
+		t.Fatal("key exchange connection coupled")
+	}
+	if n := len(a.group.Load().members); n != 2 {
+		t.Fatalf("group has %d members, want 2", n)
+	}
+
+	// after a migration the connection gets a new controller, for the same
+	// RTT stats and the address it was created for
+	ai := info("192.0.2.1:40000")
+	ai.RTTStats = a.rttStats
+	a2 := groups.byConn(ai).(*liaSender)
+	if a2.group.Load() != b.group.Load() {
+		t.Fatal("controller recreated after a migration left the group")
+	}
+	// a new connection from the same address starts uncoupled
+	if d := newSender("192.0.2.1:40001"); d.group.Load() == b.group.Load() {
+		t.Fatal("new connection from a registered address coupled before it registered")
+	}
+}
+
+func TestWithCongestionControl(t *testing.T) {
+	conf := withCongestionControl(&quic.Config{}, "bbr3", nil)
+	if conf.CongestionAlgorithm != "bbr3" || conf.CongestionControl != nil {
+		t.Fatalf("bbr3: algorithm=%q custom=%v", conf.CongestionAlgorithm, conf.CongestionControl != nil)
+	}
+	// local-quic-go doesn't know lia: it must not be passed by name
+	conf = withCongestionControl(&quic.Config{}, congestionLIA, nil)
+	if conf.CongestionAlgorithm != "" || conf.CongestionControl == nil {
+		t.Fatalf("lia: algorithm=%q custom=%v", conf.CongestionAlgorithm, conf.CongestionControl != nil)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ─── Domain classification via DNS snooping ───────────────────────────────
+//
+// Classifier rules may list domains ("teams.microsoft.com",
+// "*.zoom.us") instead of IP ranges. The client learns which IPs belong to
+// which names by parsing the DNS responses it delivers to the TUN: every
+// A/AAAA answer binds its address to the question name and to every name in
+// the CNAME chain, for the record TTL (clamped to domainCacheMinTTL ..
+// domainCacheMaxTTL). A packet then matches a domain rule when its
+// destination address (or source, for return traffic) is bound to a
+// matching name.
+//
+// Only plain DNS over UDP port 53 is visible. Hosts using DoH/DoT resolve
+// outside the tunnel's view and fall through to the IP/port rules.
+
+const (
+	// Bindings outlive short DNS TTLs: a connection opened right before
+	// expiry keeps its class for a while.
+	domainCacheMinTTL = 60 * time.Second
+	domainCacheMaxTTL = 24 * time.Hour
+	// Upper bound on cached addresses; new bindings are dropped when full
+	// after a sweep of expired ones.
+	defaultDomainCacheMax = 16384
+	domainCacheSweepEvery = 30 * time.Second
+)
+
+// domainCache maps addresses to the DNS names they were resolved from.
+type domainCache struct {
+	mu        sync.RWMutex
+	byAddr    map[netip.Addr]map[string]time.Time // addr → name → expiry
+	max       int
+	lastSweep time.Time
+
+	responses uint64 // DNS responses parsed
+	dropped   uint64 // bindings dropped because the cache was full
+}
+
+func newDomainCache(max int) *domainCache {
+	if max <= 0 {
+		max = defaultDomainCacheMax
+	}
+	return &domainCache{
+		byAddr: make(map[netip.Addr]map[string]time.Time),
+		max:    max,
+	}
+}
+
+// observe learns bindings from pkt if it is a DNS response (UDP source
+// port 53). Any other packet returns after a few header checks.
+func (c *domainCache) observe(pkt []byte) {
+	if c == nil {
+		return
+	}
+	payload, ok := dnsResponsePayload(pkt)
+	if !ok {
+		return
+	}
+	names, addrs := parseDNSAnswers(payload)
+	if len(addrs) == 0 || len(names) == 0 {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses++
+	if now.Sub(c.lastSweep) >= domainCacheSweepEvery {
+		c.sweepLocked(now)
+	}
+	for addr, ttl := range addrs {
+		if ttl < domainCacheMinTTL {
+			ttl = domainCacheMinTTL
+		}
+		if ttl > domainCacheMaxTTL {
+			ttl = domainCacheMaxTTL
+		}
+		bound := c.byAddr[addr]
+		if bound == nil {
+			if len(c.byAddr) >= c.max {
+				c.sweepLocked(now)
+			}
+			if len(c.byAddr) >= c.max {
+				c.dropped++
+				continue
+			}
+			bound = make(map[string]time.Time, len(names))
+			c.byAddr[addr] = bound
+		}
+		expires := now.Add(ttl)
+		for _, name := range names {
+			if expires.After(bound[name]) {
+				bound[name] = expires
+			}
+		}
+	}
+}
+
+// sweepLocked drops expired bindings. Caller holds c.mu for writing.
+func (c *domainCache) sweepLocked(now time.Time) {
+	c.lastSweep = now
+	for addr, bound := range c.byAddr {
+		for name, expires := range bound {
+			if now.After(expires) {
+				delete(bound, name)
+			}
+		}
+		if len(bound) == 0 {
+			delete(c.byAddr, addr)
+		}
+	}
+}
+
+// lookup returns the live names bound to addr.
+func (c *domainCache) lookup(addr netip.Addr) []string {
+	if c == nil || !addr.IsValid() {
+		return nil
+	}
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	bound := c.byAddr[addr]
+	if len(bound) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(bound))
+	for name, expires := range bound {
+		if now.Before(expires) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// domainCacheEntry is one address of the control API view.
+type domainCacheEntry struct {
+	Addr         string   `json:"addr"`
+	Domains      []string `json:"domains"`
+	ExpiresInSec int      `json:"expires_in_sec"` // latest expiry among the names
+}
+
+// domainCacheSnapshot is the control API view of the cache.
+type domainCacheSnapshot struct {
+	Count     int                `json:"count"`
+	Responses uint64             `json:"dns_responses"`
+	Dropped   uint64             `json:"dropped"`
+	Entries   []domainCacheEntry `json:"entries"`
+}
+
+// snapshot returns the live bindings, optionally restricted to addresses
+// bound to a name matching pattern (exact or "*.suffix").
+func (c *domainCache) snapshot(pattern string) domainCacheSnapshot {
+	out := domainCacheSnapshot{Entries: []domainCacheEntry{}}
+	if c == nil {
+		return out
+	}
+	pattern = normalizeDomainName(pattern)
+	now := time.Now()
+
+	c.mu.RLock()
+	out.Responses = c.responses
+	out.Dropped = c.dropped
+	for addr, bound := range c.byAddr {
+		var latest time.Time
+		names := make([]string, 0, len(bound))
+		for name, expires := range bound {
+			if !now.Before(expires) {
+				continue
+			}
+			names = append(names, name)
+			if expires.After(latest) {
+				latest = expires
+			}
+		}
+		if len(names) == 0 || (pattern != "" && !matchDomainPatterns(names, []string{pattern})) {
+			continue
+		}
+		sort.Strings(names)
+		out.Entries = append(out.Entries, domainCacheEntry{
+			Addr:         addr.String(),
+			Domains:      names,
+			ExpiresInSec: int(latest.Sub(now) / time.Second),
+		})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(out.Entries, func(i, j int) bool { return out.Entries[i].Addr < out.Entries[j].Addr })
+	out.Count = len(out.Entries)
+	return out
+}
+
+// dnsResponsePayload returns the UDP payload of pkt if it is an IPv4/IPv6
+// UDP packet from port 53.
+func dnsResponsePayload(pkt []byte) ([]byte, bool) {
+	if len(pkt) < 1 {
+		return nil, false
+	}
+	var l4 []byte
+	switch pkt[0] >> 4 {
+	case 4:
+		if len(pkt) < 20 || pkt[9] != 17 {
+			return nil, false
+		}
+		ihl := int(pkt[0]&0x0f) * 4
+		if ihl < 20 || len(pkt) < ihl {
+			return nil, false
+		}
+		l4 = pkt[ihl:]
+	case 6:
+		// Extension headers are not followed: DNS responses do not use them.
+		if len(pkt) < 40 || pkt[6] != 17 {
+			return nil, false
+		}
+		l4 = pkt[40:]
+	default:
+		return nil, false
+	}
+	if len(l4) < 8 || binary.BigEndian.Uint16(l4[0:2]) != 53 {
+		return nil, false
+	}
+	return l4[8:], true
+}
+
+// parseDNSAnswers returns the names of a DNS response (questions and CNAME
+// chain) and the A/AAAA addresses it resolves to, with their TTLs.
+// Malformed or truncated messages yield whatever was parsed before the error.
+func parseDNSAnswers(msg []byte) ([]string, map[netip.Addr]time.Duration) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(msg)
+	if err != nil || !hdr.Response || hdr.RCode != dnsmessage.RCodeSuccess {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{}, 4)
+	var names []string
+	addName := func(n dnsmessage.Name) {
+		name := normalizeDomainName(n.String())
+		if name == "" {
+			return
+		}
+		if _, dup := seen[name]; dup {
+			return
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	for {
+		q, err := p.Question()
+		if err != nil {
+			break
+		}
+		addName(q.Name)
+	}
+
+	var addrs map[netip.Addr]time.Duration
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			break
+		}
+		ttl := time.Duration(h.TTL) * time.Second
+		switch h.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return names, addrs
+			}
+			addName(h.Name)
+			if addrs == nil {
+				addrs = make(map[netip.Addr]time.Duration, 4)
+			}
+			addrs[netip.AddrFrom4(r.A)] = ttl
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return names, addrs
+			}
+			addName(h.Name)
+			if addrs == nil {
+				addrs = make(map[netip.Addr]time.Duration, 4)
+			}
+			addrs[netip.AddrFrom16(r.AAAA)] = ttl
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				return names, addrs
+			}
+			addName(h.Name)
+			addName(r.CNAME)
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return names, addrs
+			}
+		}
+	}
+	return names, addrs
+}
+
+// normalizeDomainName lowercases name and strips the trailing root dot.
+func normalizeDomainName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// normalizeDomainPatterns normalizes a domains/sni rule list, dropping
+// empty entries.
+func normalizeDomainPatterns(patterns []string) []string {
+	if len(patterns) == 0 {
+		return patterns
+	}
+	out := make([]string, 0, len(patterns))
+	for _, d := range patterns {
+		if d = normalizeDomainName(d); d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// isValidDomainPattern accepts "example.com" and "*.example.com"; a
+// wildcard is only allowed as the whole first label.
+func isValidDomainPattern(d string) bool {
+	return !strings.Contains(strings.TrimPrefix(d, "*."), "*") &&
+		!strings.HasPrefix(d, ".") && !strings.Contains(d, "..")
+}
+
+// matchDomainPatterns reports whether any name matches any pattern.
+// "*.example.com" matches subdomains of example.com (not the apex);
+// any other pattern matches exactly.
+func matchDomainPatterns(names []string, patterns []string) bool {
+	for _, name := range names {
+		for _, pattern := range patterns {
+			if strings.HasPrefix(pattern, "*.") {
+				if strings.HasSuffix(name, pattern[1:]) {
+					return true
+				}
+			} else if name == pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// buildDNSResponse returns an IPv4/UDP packet from 192.0.2.53:53 carrying
+// a response for qname: qname CNAME cname, cname A addrs.
+func buildDNSResponse(t *testing.T, qname, cname string, ttl uint32, addrs ...[4]byte) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeSuccess})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	q := dnsmessage.MustNewName(qname)
+	if err := b.Question(dnsmessage.Question{Name: q, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	owner := q
+	if cname != "" {
+		target := dnsmessage.MustNewName(cname)
+		hdr := dnsmessage.ResourceHeader{Name: q, Class: dnsmessage.ClassINET, TTL: ttl}
+		if err := b.CNAMEResource(hdr, dnsmessage.CNAMEResource{CNAME: target}); err != nil {
+			t.Fatal(err)
+		}
+		owner = target
+	}
+	for _, a := range addrs {
+		hdr := dnsmessage.ResourceHeader{Name: owner, Class: dnsmessage.ClassINET, TTL: ttl}
+		if err := b.AResource(hdr, dnsmessage.AResource{A: a}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := make([]byte, 28+len(msg))
+	pkt[0] = 0x45
+	pkt[9] = 17
+	copy(pkt[12:16], []byte{192, 0, 2, 53})
+	copy(pkt[16:20], []byte{10, 200, 1, 1})
+	binary.BigEndian.PutUint16(pkt[20:22], 53)
+	binary.BigEndian.PutUint16(pkt[22:24], 40000)
+	binary.BigEndian.PutUint16(pkt[24:26], uint16(8+len(msg)))
+	copy(pkt[28:], msg)
+	return pkt
+}
+
+func TestDomainCache_LearnsCNAMEChain(t *testing.T) {
+	c := newDomainCache(0)
+	c.observe(buildDNSResponse(t, "teams.microsoft.com.", "teams.cdn.example.net.", 30, [4]byte{52, 1, 2, 3}))
+
+	names := c.lookup(netip.MustParseAddr("52.1.2.3"))
+	if len(names) != 2 {
+		t.Fatalf("names = %v, want question name and CNAME target", names)
+	}
+	if !matchDomainPatterns(names, []string{"teams.microsoft.com"}) {
+		t.Errorf("names %v do not include the question name", names)
+	}
+
+	snap := c.snapshot("*.microsoft.com")
+	if snap.Count != 1 || snap.Entries[0].Addr != "52.1.2.3" {
+		t.Errorf("snapshot = %+v, want 52.1.2.3", snap)
+	}
+	// TTL 30 s is clamped up to domainCacheMinTTL.
+	if got := snap.Entries[0].ExpiresInSec; got < 55 {
+		t.Errorf("expires_in_sec = %d, want clamped to ~%d", got, int(domainCacheMinTTL.Seconds()))
+	}
+}
+
+func TestDomainCache_IgnoresNonDNS(t *testing.T) {
+	c := newDomainCache(0)
+	pkt := buildDNSResponse(t, "example.com.", "", 300, [4]byte{93, 184, 216, 34})
+	binary.BigEndian.PutUint16(pkt[20:22], 5353) // not port 53
+	c.observe(pkt)
+	if snap := c.snapshot(""); snap.Count != 0 || snap.Responses != 0 {
+		t.Errorf("snapshot = %+v, want empty", snap)
+	}
+}
+
+func TestDomainCache_FullDropsNewBindings(t *testing.T) {
+	c := newDomainCache(1)
+	c.observe(buildDNSResponse(t, "a.example.com.", "", 300, [4]byte{10, 0, 0, 1}))
+	c.observe(buildDNSResponse(t, "b.example.com.", "", 300, [4]byte{10, 0, 0, 2}))
+	if snap := c.snapshot(""); snap.Count != 1 || snap.Dropped != 1 {
+		t.Errorf("snapshot = %+v, want 1 entry and 1 dropped", snap)
+	}
+}
+
+func TestMatchDomainPatterns(t *testing.T) {
+	cases := []struct {
+		name, pattern string
+		want          bool
+	}{
+		{"teams.microsoft.com", "teams.microsoft.com", true},
+		{"teams.microsoft.com", "*.microsoft.com", true},
+		{"microsoft.com", "*.microsoft.com", false},
+		{"evilmicrosoft.com", "*.microsoft.com", false},
+		{"teams.microsoft.com", "microsoft.com", false},
+	}
+	for _, tc := range cases {
+		if got := matchDomainPatterns([]string{tc.name}, []string{tc.pattern}); got != tc.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tc.name, tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestResolve_DomainRule(t *testing.T) {
+	dp := DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"default":  {},
+			"critical": {},
+		},
+		Classifiers: []DataplaneClassifierRule{
+			{Name: "teams", ClassName: "critical", Domains: []string{"*.Microsoft.com."}},
+		},
+	}
+	normalizeDataplaneConfig(&dp, "priority")
+	if err := validateDataplaneConfig(dp, nil); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	compiled, err := compileDataplaneConfig(dp)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	compiled.domains = newDomainCache(0)
+	compiled.domains.observe(buildDNSResponse(t, "teams.microsoft.com.", "", 300, [4]byte{52, 1, 2, 3}))
+
+	pkt := make([]byte, 28)
+	pkt[0] = 0x45
+	pkt[9] = 6
+	copy(pkt[12:16], []byte{10, 200, 1, 1})
+	copy(pkt[16:20], []byte{52, 1, 2, 3})
+	if class, _ := compiled.resolve(pkt); class != "critical" {
+		t.Errorf("class = %q, want critical for a learned teams.microsoft.com address", class)
+	}
+	copy(pkt[16:20], []byte{52, 9, 9, 9})
+	if class, _ := compiled.resolve(pkt); class != "default" {
+		t.Errorf("class = %q, want default for an unknown address", class)
+	}
+}
+
+func TestValidateDataplaneConfig_InvalidDomain(t *testing.T) {
+	dp := DataplaneConfig{
+		DefaultClass: "default",
+		Classes:      map[string]DataplaneClassPolicy{"default": {SchedulerPolicy: "priority"}},
+		Classifiers:  []DataplaneClassifierRule{{ClassName: "default", Domains: []string{"teams.*.com"}}},
+	}
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for a wildcard that is not a leading label")
+	}
+}
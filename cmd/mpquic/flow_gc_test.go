@@ -141,3 +141,42 @@ func TestFlowHashRejectsShortPackets(t *testing.T) {
 		})
 	}
 }
+
+// ipv6Packet builds an IPv6 packet fd00::1 → 2001:db8::7 with the given
+// next header, flow label and payload.
+func ipv6Packet(next byte, label uint32, payload []byte) []byte {
+	pkt := make([]byte, 40+len(payload))
+	pkt[0] = 0x60 | byte(label>>16)&0x0f
+	pkt[2], pkt[3] = byte(label>>8), byte(label)
+	pkt[6] = next
+	copy(pkt[8:24], netip.MustParseAddr("fd00::1").AsSlice())
+	copy(pkt[24:40], netip.MustParseAddr("2001:db8::7").AsSlice())
+	copy(pkt[40:], payload)
+	return pkt
+}
+
+func TestFlowHash_IPv6(t *testing.T) {
+	a := ipv6Packet(6, 0, tcpSegment(nil))
+	b := ipv6Packet(6, 0x12345, tcpSegment(nil)) // same flow, relabelled
+	ha, okA := flowHash(a)
+	hb, okB := flowHash(b)
+	if !okA || !okB || ha != hb {
+		t.Fatalf("TCP over IPv6: %08x/%t vs %08x/%t, want equal hashes", ha, okA, hb, okB)
+	}
+	other := ipv6Packet(6, 0, tcpSegment(nil))
+	other[41] ^= 1 // different source port
+	if ho, _ := flowHash(other); ho == ha {
+		t.Error("different ports hash equal")
+	}
+
+	// Behind an extension header (hop-by-hop, 0) only the flow label
+	// identifies the flow.
+	if _, ok := flowHash(ipv6Packet(0, 0, make([]byte, 16))); ok {
+		t.Error("unlabelled packet without ports hashed")
+	}
+	l1, ok1 := flowHash(ipv6Packet(0, 0xabcde, make([]byte, 16)))
+	l2, _ := flowHash(ipv6Packet(0, 0xabcdf, make([]byte, 16)))
+	if !ok1 || l1 == l2 {
+		t.Error("flow label not used for packets without ports")
+	}
+}
@@ -69,6 +69,15 @@ func (s *streamConn) ReceiveDatagram(_ context.Context) ([]byte, error) {
 }
 
 func openStreamConn(ctx context.Context, conn quic.Connection) (*streamConn, error) {
+	// A stream opened in 0-RTT is reset if the server rejects 0-RTT: open
+	// the tunnel stream once the handshake is done (session_resumption.go).
+	if ec, ok := conn.(quic.EarlyConnection); ok {
+		next, err := ec.NextConnection(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("handshake: %w", err)
+		}
+		conn = next
+	}
 	stream, err := conn.OpenStreamSync(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("open stream: %w", err)
@@ -163,15 +172,13 @@ func main() {
 		}()
 	}
 
-	// Default congestion algorithm to "cubic" and normalize
-	cfg.CongestionAlgorithm = strings.ToLower(strings.TrimSpace(cfg.CongestionAlgorithm))
-	if cfg.CongestionAlgorithm == "" {
-		cfg.CongestionAlgorithm = "cubic"
-	}
 	cfg.TransportMode = strings.ToLower(strings.TrimSpace(cfg.TransportMode))
 	if cfg.TransportMode == "" {
 		cfg.TransportMode = "datagram"
 	}
+	if cfg.unknownCongestionAlgorithm != "" {
+		logger.Errorf("congestion_algorithm %q unknown (valid: %s), using cubic", cfg.unknownCongestionAlgorithm, congestionAlgorithmList)
+	}
 	logger.Infof("congestion_algorithm=%s transport_mode=%s", cfg.CongestionAlgorithm, cfg.TransportMode)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
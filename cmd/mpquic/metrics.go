@@ -18,9 +18,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/quic-go/quic-go"
 )
 
 // ─── Global metrics registry ──────────────────────────────────────────────
@@ -35,6 +39,7 @@ type metricsRegistry struct {
 	client       *multipathConn
 	clientPaths  func() []*multipathPathState // snapshot under lock
 	singlePath   *countingConn // non-nil for single-path client/server tunnels
+	connTable    *connectionTable // multi-conn server, per-peer QUIC statistics
 }
 
 // countingConn wraps a datagramConn and counts TX/RX bytes and packets
@@ -92,6 +97,12 @@ func registerMetricsServer(ss *stripeServer) {
 	globalMetrics.mu.Unlock()
 }
 
+func registerMetricsConnTable(ct *connectionTable) {
+	globalMetrics.mu.Lock()
+	globalMetrics.connTable = ct
+	globalMetrics.mu.Unlock()
+}
+
 func registerMetricsClient(mc *multipathConn) {
 	globalMetrics.mu.Lock()
 	globalMetrics.client = mc
@@ -197,6 +208,19 @@ type SessionStats struct {
 	UptimeSec float64 `json:"uptime_sec"`
 
 	DecryptFail uint64 `json:"decrypt_fail"`
+	ReplayDrop  uint64 `json:"replay_drop"` // authentic packets rejected by the anti-replay window
+
+	// Key epochs (stripe_crypto.go): current epoch and switches per direction.
+	TxKeyEpoch uint8  `json:"tx_key_epoch"`
+	RxKeyEpoch uint8  `json:"rx_key_epoch"`
+	TxRekeys   uint64 `json:"tx_rekeys"`
+	RxRekeys   uint64 `json:"rx_rekeys"`
+
+	// Pipe liveness (stripe_pipehealth.go) and delay from keepalive
+	// timestamps (stripe_timing.go): session average and per pipe.
+	PipesQuarantined int               `json:"pipes_quarantined"`
+	Timing           *StripeTiming     `json:"timing,omitempty"`
+	PipeStats        []StripePipeStats `json:"pipe_stats,omitempty"`
 }
 
 // PathStats holds a point-in-time snapshot of one multipath path (client).
@@ -210,6 +234,17 @@ type PathStats struct {
 	RxBytes uint64 `json:"rx_bytes"`
 	RxPkts  uint64 `json:"rx_pkts"`
 
+	// Live quality used by the RTT/loss-aware scheduler policies.
+	SRTTMs   float64 `json:"srtt_ms"`
+	RTTVarMs float64 `json:"rttvar_ms"`
+	LossPct  float64 `json:"loss_pct"`
+
+	FlowCount int  `json:"flow_count"` // flows pinned to this path (balanced policy)
+	Draining  bool `json:"draining"`   // POST /paths/{name}/drain in progress
+
+	Probe *probeSnapshot `json:"probe,omitempty"` // active probing, when enabled
+	QUIC  *QUICStats     `json:"quic,omitempty"`  // transport statistics, QUIC paths only
+
 	StripeTxBytes uint64 `json:"stripe_tx_bytes,omitempty"`
 	StripeTxPkts  uint64 `json:"stripe_tx_pkts,omitempty"`
 	StripeRxBytes uint64 `json:"stripe_rx_bytes,omitempty"`
@@ -249,6 +284,13 @@ type PathStats struct {
 	StripeARQNackThresh  uint32 `json:"stripe_arq_nack_thresh,omitempty"`
 	StripeARQMaxOOO      uint32 `json:"stripe_arq_max_ooo,omitempty"`
 	StripeARQPendingSpan uint32 `json:"stripe_arq_pending_span,omitempty"`
+	StripeDecryptFail    uint64 `json:"stripe_decrypt_fail,omitempty"`
+	StripeReplayDrop     uint64 `json:"stripe_replay_drop,omitempty"`
+	StripeTxRekeys       uint64 `json:"stripe_tx_rekeys,omitempty"`
+	StripeRxRekeys       uint64 `json:"stripe_rx_rekeys,omitempty"`
+	StripePipesQuarantined int             `json:"stripe_pipes_quarantined,omitempty"`
+	StripeTiming         *StripeTiming     `json:"stripe_timing,omitempty"`
+	StripePipes          []StripePipeStats `json:"stripe_pipes,omitempty"`
 }
 
 // GlobalStats is the top-level JSON response.
@@ -259,10 +301,67 @@ type GlobalStats struct {
 	Sessions   []SessionStats `json:"sessions,omitempty"`
 	Paths      []PathStats    `json:"paths,omitempty"`
 	Dispatch   []DispatchPathStats `json:"dispatch,omitempty"`
+	Peers      []PeerStats    `json:"peers,omitempty"` // multi-conn server: QUIC connections per peer
 	TotalTxBytes uint64       `json:"total_tx_bytes"`
 	TotalRxBytes uint64       `json:"total_rx_bytes"`
 	TotalTxPkts  uint64       `json:"total_tx_pkts"`
 	TotalRxPkts  uint64       `json:"total_rx_pkts"`
+	Bonding      *bondReorderStats `json:"bonding,omitempty"` // receiver reorder buffer (bonded traffic only)
+	ClassShaping []ClassShapingStats `json:"class_shaping,omitempty"` // per-class rate limits (when configured)
+	Classes      []ClassStats        `json:"classes,omitempty"`       // per-class TX counters (client)
+}
+
+// QUICStats holds the transport statistics of a QUIC connection, or of one
+// path of a quic-mp connection, as reported by local-quic-go.
+type QUICStats struct {
+	SRTTMs            float64 `json:"srtt_ms"`
+	RTTVarMs          float64 `json:"rttvar_ms"`
+	MinRTTMs          float64 `json:"min_rtt_ms"`
+	CwndBytes         uint64  `json:"cwnd_bytes"`
+	BytesInFlight     uint64  `json:"bytes_in_flight"`
+	PktsSent          uint64  `json:"pkts_sent"`
+	PktsLost          uint64  `json:"pkts_lost"`
+	PktsRetransmitted uint64  `json:"pkts_retransmitted"`
+}
+
+func newQUICStats(st quic.ConnectionStats) *QUICStats {
+	return &QUICStats{
+		SRTTMs:            float64(st.SmoothedRTT) / float64(time.Millisecond),
+		RTTVarMs:          float64(st.MeanDeviation) / float64(time.Millisecond),
+		MinRTTMs:          float64(st.MinRTT) / float64(time.Millisecond),
+		CwndBytes:         st.CongestionWindow,
+		BytesInFlight:     st.BytesInFlight,
+		PktsSent:          st.PacketsSent,
+		PktsLost:          st.PacketsLost,
+		PktsRetransmitted: st.PacketsRetransmitted,
+	}
+}
+
+// PeerStats holds the QUIC connections of one multi-conn peer (server).
+type PeerStats struct {
+	PeerIP string          `json:"peer_ip"`
+	Paths  []PeerPathStats `json:"paths"`
+}
+
+// PeerPathStats is one QUIC connection (or quic-mp path) of a peer.
+type PeerPathStats struct {
+	Name       string     `json:"name,omitempty"` // client path name, from the peer hello
+	BasePath   string     `json:"base_path,omitempty"`
+	RemoteAddr string     `json:"remote_addr"`
+	QUIC       *QUICStats `json:"quic"`
+}
+
+// ClassStats holds per-traffic-class TX counters of the multipath client.
+type ClassStats struct {
+	Class          string  `json:"class"`
+	TxPkts         uint64  `json:"tx_pkts"`
+	TxErrors       uint64  `json:"tx_errors"`
+	TxDuplicates   uint64  `json:"tx_duplicates"`
+	DupBytes       uint64  `json:"dup_bytes"`
+	DupActive      bool    `json:"dup_active"`
+	DupActiveSec   float64 `json:"dup_active_sec"`
+	DupActivations uint64  `json:"dup_activations"`
+	TxPoliced      uint64  `json:"tx_policed"`
 }
 
 // DispatchPathStats holds aggregated dispatch metrics for a path index.
@@ -310,7 +409,21 @@ func snapshotServerSessions(ss *stripeServer) []SessionStats {
 			LossRate:   atomic.LoadUint32(&sess.peerLossRate),
 			UptimeSec:  now.Sub(sess.createdAt).Seconds(),
 			DecryptFail: atomic.LoadUint64(&sess.securityDecryptFail),
+			ReplayDrop:  atomic.LoadUint64(&sess.securityReplayDrop),
+		}
+		s.TxKeyEpoch, s.TxRekeys = sess.currentTxCipher().epochStats()
+		s.RxKeyEpoch, s.RxRekeys = sess.rxCipher.Load().epochStats()
+		pipeAddrs := make([]string, len(sess.pipes))
+		for i, p := range sess.pipes {
+			if p != nil {
+				pipeAddrs[i] = p.String()
+			}
 		}
+		s.Timing, s.PipeStats = stripeTimingStats(sess.pipeTiming, pipeAddrs)
+		sess.txMu.Lock()
+		schedule := sess.txActiveHealth
+		sess.txMu.Unlock()
+		s.PipesQuarantined = stripePipeHealthStats(s.PipeStats, sess.pipeHealth, schedule, now)
 		if sess.xorTx != nil {
 			s.XorEmitted = atomic.LoadUint64(&sess.xorTx.emitted)
 			s.XorWindow, s.XorStride, _ = sess.xorTx.stats()
@@ -356,18 +469,61 @@ func snapshotServerSessions(ss *stripeServer) []SessionStats {
 	return stats
 }
 
+// snapshotClientClasses builds per-class stats sorted by class name.
+func snapshotClientClasses(mc *multipathConn) []ClassStats {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	now := time.Now()
+	stats := make([]ClassStats, 0, len(mc.classTx))
+	for name, c := range mc.classTx {
+		stats = append(stats, ClassStats{
+			Class:          name,
+			TxPkts:         c.txPackets,
+			TxErrors:       c.txErrors,
+			TxDuplicates:   c.txDuplicates,
+			DupBytes:       c.txDupBytes,
+			DupActive:      c.adaptive.active,
+			DupActiveSec:   c.adaptive.activeFor(now).Seconds(),
+			DupActivations: c.adaptive.activations,
+			TxPoliced:      c.txPoliced,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Class < stats[j].Class })
+	return stats
+}
+
 func snapshotClientPaths(mc *multipathConn) []PathStats {
 	mc.mu.RLock()
 	defer mc.mu.RUnlock()
 
 	stats := make([]PathStats, 0, len(mc.paths))
-	for _, p := range mc.paths {
+	flowCounts := mc.flowCountsLocked()
+	for i, p := range mc.paths {
+		if p.removed {
+			continue
+		}
 		ps := PathStats{
 			Name:      p.cfg.Name,
 			BindIP:    p.cfg.BindIP,
 			Alive:     p.alive,
 			TxPkts:    atomic.LoadUint64(&p.txPackets),
 			RxPkts:    atomic.LoadUint64(&p.rxPackets),
+			SRTTMs:    float64(p.quality.srtt) / float64(time.Millisecond),
+			RTTVarMs:  float64(p.quality.rttVar) / float64(time.Millisecond),
+			LossPct:   p.quality.lossRate * 100,
+			FlowCount: flowCounts[i],
+			Draining:  p.draining,
+		}
+		if p.probe != nil {
+			snap := p.probe.snapshot()
+			ps.Probe = &snap
+		}
+		switch {
+		case p.mpPath != nil:
+			ps.QUIC = newQUICStats(p.mpPath.Stats())
+		case p.conn != nil && p.stripeConn == nil:
+			ps.QUIC = newQUICStats(p.conn.Stats())
 		}
 		if p.stripeConn != nil {
 			ps.StripeTxBytes = atomic.LoadUint64(&p.stripeConn.txBytes)
@@ -420,12 +576,59 @@ func snapshotClientPaths(mc *multipathConn) []PathStats {
 				ps.StripeARQNackSent, ps.StripeARQRetxRecv, ps.StripeARQDupFiltered = p.stripeConn.arqRx.stats()
 				ps.StripeARQNackThresh, ps.StripeARQMaxOOO, ps.StripeARQPendingSpan = p.stripeConn.arqRx.dynamicStats()
 			}
+			ps.StripeDecryptFail, ps.StripeReplayDrop = p.stripeConn.SecurityStats()
+			_, ps.StripeTxRekeys = p.stripeConn.txCipher.epochStats()
+			_, ps.StripeRxRekeys = p.stripeConn.rxCipher.epochStats()
+			ps.StripeTiming, ps.StripePipes, ps.StripePipesQuarantined = p.stripeConn.PipeStats()
 		}
 		stats = append(stats, ps)
 	}
 	return stats
 }
 
+// snapshotBondingStats sums the reorder buffer counters of all bonded peer
+// groups. Returns nil when no peer uses bonding.
+func snapshotBondingStats(ct *connectionTable) *bondReorderStats {
+	ct.mu.RLock()
+	buffers := make([]*bondReorderBuffer, 0, len(ct.byIP))
+	for _, grp := range ct.byIP {
+		if grp.bondRx != nil {
+			buffers = append(buffers, grp.bondRx)
+		}
+	}
+	ct.mu.RUnlock()
+	if len(buffers) == 0 {
+		return nil
+	}
+
+	var total bondReorderStats
+	for _, b := range buffers {
+		s := b.snapshot()
+		total.InOrder += s.InOrder
+		total.Reordered += s.Reordered
+		total.Late += s.Late
+		total.GapSkips += s.GapSkips
+		total.Overflows += s.Overflows
+		total.Resyncs += s.Resyncs
+		total.Pending += s.Pending
+	}
+	return &total
+}
+
+// snapshotClassShapingStats sums the per-peer class shaper counters.
+// Returns nil when class rate limits are not configured.
+func snapshotClassShapingStats(ct *connectionTable) []ClassShapingStats {
+	ct.mu.RLock()
+	snaps := make([][]ClassShapingStats, 0, len(ct.byIP))
+	for _, grp := range ct.byIP {
+		if grp.shaper != nil {
+			snaps = append(snaps, grp.shaper.snapshot())
+		}
+	}
+	ct.mu.RUnlock()
+	return mergeClassShapingStats(snaps...)
+}
+
 // snapshotDispatchStats aggregates per-path dispatch metrics from the
 // connectionTable. Walks all connGroups under ct.mu.RLock and builds
 // per-pathIdx stats including dispatched packets, drops, bytes, queue
@@ -486,11 +689,56 @@ func snapshotDispatchStats(ss *stripeServer) []DispatchPathStats {
 	return stats
 }
 
+// transportStats returns the QUIC statistics of pc, nil for stripe paths.
+func (pc *pathConn) transportStats() *QUICStats {
+	if pc.quicConn != nil {
+		return newQUICStats(pc.quicConn.Stats())
+	}
+	if path, ok := pc.dc.(*quic.Path); ok {
+		return newQUICStats(path.Stats())
+	}
+	return nil
+}
+
+// snapshotPeerStats lists the QUIC connections of every peer in the
+// connectionTable, sorted by peer IP.
+func snapshotPeerStats(ct *connectionTable) []PeerStats {
+	if ct == nil {
+		return nil
+	}
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	stats := make([]PeerStats, 0, len(ct.byIP))
+	for peerIP, grp := range ct.byIP {
+		ps := PeerStats{PeerIP: peerIP.String()}
+		for _, pc := range grp.paths {
+			if pc == nil {
+				continue
+			}
+			if qs := pc.transportStats(); qs != nil {
+				ps.Paths = append(ps.Paths, PeerPathStats{
+					Name:       pc.name,
+					BasePath:   pc.basePath,
+					RemoteAddr: pc.remoteAddr,
+					QUIC:       qs,
+				})
+			}
+		}
+		if len(ps.Paths) > 0 {
+			stats = append(stats, ps)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].PeerIP < stats[j].PeerIP })
+	return stats
+}
+
 func buildGlobalStats() GlobalStats {
 	globalMetrics.mu.RLock()
 	role := globalMetrics.role
 	ss := globalMetrics.server
 	mc := globalMetrics.client
+	ct := globalMetrics.connTable
 	start := globalMetrics.startTime
 	globalMetrics.mu.RUnlock()
 
@@ -511,8 +759,25 @@ func buildGlobalStats() GlobalStats {
 		}
 	}
 
+	gs.Peers = snapshotPeerStats(ct)
+
+	if ss != nil && ss.ct != nil {
+		gs.Bonding = snapshotBondingStats(ss.ct)
+		gs.ClassShaping = snapshotClassShapingStats(ss.ct)
+	}
+
 	if mc != nil {
+		if mc.bondRx != nil {
+			if b := mc.bondRx.snapshot(); b.InOrder+b.Late+b.Reordered > 0 {
+				gs.Bonding = &b
+			}
+		}
+		mc.mu.RLock()
+		shaper := mc.dataplane.shaper
+		mc.mu.RUnlock()
+		gs.ClassShaping = shaper.snapshot()
 		gs.Paths = snapshotClientPaths(mc)
+		gs.Classes = snapshotClientClasses(mc)
 		for _, p := range gs.Paths {
 			gs.TotalTxPkts += p.TxPkts
 			gs.TotalRxPkts += p.RxPkts
@@ -544,6 +809,65 @@ func handleStatsJSON(w http.ResponseWriter, r *http.Request) {
 	_ = enc.Encode(gs)
 }
 
+// promLabelEscaper escapes a Prometheus label value (text exposition format).
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabel escapes a label value that may come from a peer, such as the path
+// names announced in its hello.
+func promLabel(v string) string {
+	return promLabelEscaper.Replace(v)
+}
+
+// quicStatsMetrics are the QUICStats fields exported per client path and
+// per peer connection.
+var quicStatsMetrics = []struct {
+	name, help, kind string
+	value            func(*QUICStats) string
+}{
+	{"srtt_ms", "QUIC smoothed RTT in milliseconds", "gauge", func(q *QUICStats) string { return fmt.Sprintf("%.3f", q.SRTTMs) }},
+	{"rttvar_ms", "QUIC RTT mean deviation in milliseconds", "gauge", func(q *QUICStats) string { return fmt.Sprintf("%.3f", q.RTTVarMs) }},
+	{"min_rtt_ms", "QUIC minimum RTT in milliseconds", "gauge", func(q *QUICStats) string { return fmt.Sprintf("%.3f", q.MinRTTMs) }},
+	{"cwnd_bytes", "QUIC congestion window in bytes", "gauge", func(q *QUICStats) string { return fmt.Sprint(q.CwndBytes) }},
+	{"bytes_in_flight", "QUIC bytes in flight", "gauge", func(q *QUICStats) string { return fmt.Sprint(q.BytesInFlight) }},
+	{"packets_sent_total", "QUIC packets sent", "counter", func(q *QUICStats) string { return fmt.Sprint(q.PktsSent) }},
+	{"packets_lost_total", "QUIC packets declared lost", "counter", func(q *QUICStats) string { return fmt.Sprint(q.PktsLost) }},
+	{"packets_retransmitted_total", "QUIC packets whose frames were retransmitted", "counter", func(q *QUICStats) string { return fmt.Sprint(q.PktsRetransmitted) }},
+}
+
+// stripeTimingMetrics are the StripeTiming fields exported per stripe
+// session and client stripe path, and per pipe of each.
+var stripeTimingMetrics = []struct {
+	name, help string
+	value      func(*StripeTiming) float64
+}{
+	{"rtt_ms", "Stripe keepalive smoothed RTT in milliseconds", func(t *StripeTiming) float64 { return t.RTTMs }},
+	{"rttvar_ms", "Stripe keepalive RTT variation in milliseconds", func(t *StripeTiming) float64 { return t.RTTVarMs }},
+	{"jitter_ms", "Stripe RX interarrival jitter in milliseconds", func(t *StripeTiming) float64 { return t.JitterMs }},
+	{"rx_owd_ms", "Stripe RX one-way queueing delay above the 2-minute minimum in milliseconds", func(t *StripeTiming) float64 { return t.RxOWDMs }},
+	{"tx_owd_ms", "Stripe TX one-way queueing delay reported by the peer in milliseconds", func(t *StripeTiming) float64 { return t.TxOWDMs }},
+}
+
+// stripePipeHealthMetrics are the StripePipeStats liveness, delivery and
+// TX share fields exported per pipe of each stripe session and client stripe
+// path.
+var stripePipeHealthMetrics = []struct {
+	name, help, kind string
+	value            func(*StripePipeStats) string
+}{
+	{"up", "Stripe pipe in TX service (1) or quarantined (0)", "gauge", func(p *StripePipeStats) string {
+		if p.State == stripePipeStateUp {
+			return "1"
+		}
+		return "0"
+	}},
+	{"rx_packets_total", "Stripe packets received", "counter", func(p *StripePipeStats) string { return fmt.Sprint(p.RxPkts) }},
+	{"quarantines_total", "Stripe pipe quarantines", "counter", func(p *StripePipeStats) string { return fmt.Sprint(p.Quarantines) }},
+	{"tx_shards_total", "Stripe data-plane shards sent", "counter", func(p *StripePipeStats) string { return fmt.Sprint(p.TxShards) }},
+	{"rx_shards_total", "Stripe data-plane shards received", "counter", func(p *StripePipeStats) string { return fmt.Sprint(p.RxShards) }},
+	{"delivery_ratio", "Stripe smoothed share of sent shards received by the peer", "gauge", func(p *StripePipeStats) string { return fmt.Sprintf("%.3f", p.DeliveryPct/100) }},
+	{"tx_share", "Stripe share of the TX schedule", "gauge", func(p *StripePipeStats) string { return fmt.Sprintf("%.3f", p.SharePct/100) }},
+}
+
 func handlePrometheus(w http.ResponseWriter, r *http.Request) {
 	gs := buildGlobalStats()
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
@@ -792,6 +1116,55 @@ func handlePrometheus(w http.ResponseWriter, r *http.Request) {
 		for _, s := range gs.Sessions {
 			fmt.Fprintf(w, "mpquic_session_decrypt_fail_total{session=\"%s\",peer=\"%s\"} %d\n", s.SessionID, s.PeerIP, s.DecryptFail)
 		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_session_replay_drop_total Authentic packets dropped by the anti-replay window per session.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_session_replay_drop_total counter\n")
+		for _, s := range gs.Sessions {
+			fmt.Fprintf(w, "mpquic_session_replay_drop_total{session=\"%s\",peer=\"%s\"} %d\n", s.SessionID, s.PeerIP, s.ReplayDrop)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_session_rekeys_total Stripe key epoch switches per session and direction.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_session_rekeys_total counter\n")
+		for _, s := range gs.Sessions {
+			fmt.Fprintf(w, "mpquic_session_rekeys_total{session=\"%s\",peer=\"%s\",dir=\"tx\"} %d\n", s.SessionID, s.PeerIP, s.TxRekeys)
+			fmt.Fprintf(w, "mpquic_session_rekeys_total{session=\"%s\",peer=\"%s\",dir=\"rx\"} %d\n", s.SessionID, s.PeerIP, s.RxRekeys)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_session_pipes_quarantined Pipes excluded from TX because silent.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_session_pipes_quarantined gauge\n")
+		for _, s := range gs.Sessions {
+			fmt.Fprintf(w, "mpquic_session_pipes_quarantined{session=\"%s\",peer=\"%s\"} %d\n", s.SessionID, s.PeerIP, s.PipesQuarantined)
+		}
+		for _, m := range stripePipeHealthMetrics {
+			fmt.Fprintf(w, "\n# HELP mpquic_session_pipe_%s %s per session pipe.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_session_pipe_%s %s\n", m.name, m.kind)
+			for _, s := range gs.Sessions {
+				for _, p := range s.PipeStats {
+					if p.State != "" {
+						fmt.Fprintf(w, "mpquic_session_pipe_%s{session=\"%s\",peer=\"%s\",pipe=\"%d\"} %s\n", m.name, s.SessionID, s.PeerIP, p.Pipe, m.value(&p))
+					}
+				}
+			}
+		}
+
+		for _, m := range stripeTimingMetrics {
+			fmt.Fprintf(w, "\n# HELP mpquic_session_%s %s per session.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_session_%s gauge\n", m.name)
+			for _, s := range gs.Sessions {
+				if s.Timing != nil {
+					fmt.Fprintf(w, "mpquic_session_%s{session=\"%s\",peer=\"%s\"} %.3f\n", m.name, s.SessionID, s.PeerIP, m.value(s.Timing))
+				}
+			}
+			fmt.Fprintf(w, "\n# HELP mpquic_session_pipe_%s %s per session pipe.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_session_pipe_%s gauge\n", m.name)
+			for _, s := range gs.Sessions {
+				for _, p := range s.PipeStats {
+					if p.Samples > 0 {
+						fmt.Fprintf(w, "mpquic_session_pipe_%s{session=\"%s\",peer=\"%s\",pipe=\"%d\"} %.3f\n", m.name, s.SessionID, s.PeerIP, p.Pipe, m.value(&p.StripeTiming))
+					}
+				}
+			}
+		}
 		fmt.Fprintln(w)
 	}
 
@@ -823,6 +1196,86 @@ func handlePrometheus(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w)
 	}
 
+	// Bonding reorder buffer (client or server)
+	if b := gs.Bonding; b != nil {
+		fmt.Fprintf(w, "# HELP mpquic_bond_rx_packets Bonded packets delivered by the reorder buffer.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_bond_rx_packets counter\n")
+		fmt.Fprintf(w, "mpquic_bond_rx_packets{order=\"in_order\"} %d\n", b.InOrder)
+		fmt.Fprintf(w, "mpquic_bond_rx_packets{order=\"reordered\"} %d\n", b.Reordered)
+		fmt.Fprintf(w, "mpquic_bond_rx_packets{order=\"late\"} %d\n", b.Late)
+
+		fmt.Fprintf(w, "\n# HELP mpquic_bond_gap_skips Sequence gaps given up after the hold time or buffer overflow.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_bond_gap_skips counter\n")
+		fmt.Fprintf(w, "mpquic_bond_gap_skips %d\n", b.GapSkips)
+
+		fmt.Fprintf(w, "\n# HELP mpquic_bond_overflows Reorder buffer overflows.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_bond_overflows counter\n")
+		fmt.Fprintf(w, "mpquic_bond_overflows %d\n", b.Overflows)
+
+		fmt.Fprintf(w, "\n# HELP mpquic_bond_pending Bonded packets currently held for reordering.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_bond_pending gauge\n")
+		fmt.Fprintf(w, "mpquic_bond_pending %d\n", b.Pending)
+		fmt.Fprintln(w)
+	}
+
+	// Per-class rate limits (client or server)
+	if len(gs.ClassShaping) > 0 {
+		fmt.Fprintf(w, "# HELP mpquic_class_shaper_packets Packets seen by the class shaper, by outcome.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_class_shaper_packets counter\n")
+		for _, c := range gs.ClassShaping {
+			fmt.Fprintf(w, "mpquic_class_shaper_packets{class=\"%s\",result=\"passed\"} %d\n", c.Class, c.Passed)
+			fmt.Fprintf(w, "mpquic_class_shaper_packets{class=\"%s\",result=\"borrowed\"} %d\n", c.Class, c.Borrowed)
+			fmt.Fprintf(w, "mpquic_class_shaper_packets{class=\"%s\",result=\"policed\"} %d\n", c.Class, c.Policed)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Per-class (client)
+	if len(gs.Classes) > 0 {
+		fmt.Fprintf(w, "# HELP mpquic_class_tx_packets Packets sent per traffic class.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_class_tx_packets counter\n")
+		for _, c := range gs.Classes {
+			fmt.Fprintf(w, "mpquic_class_tx_packets{class=\"%s\"} %d\n", c.Class, c.TxPkts)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_class_dup_bytes Bytes sent as duplicate copies.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_class_dup_bytes counter\n")
+		for _, c := range gs.Classes {
+			fmt.Fprintf(w, "mpquic_class_dup_bytes{class=\"%s\"} %d\n", c.Class, c.DupBytes)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_class_dup_active Whether duplication is currently on for the class (1) or not (0).\n")
+		fmt.Fprintf(w, "# TYPE mpquic_class_dup_active gauge\n")
+		for _, c := range gs.Classes {
+			v := 0
+			if c.DupActive {
+				v = 1
+			}
+			fmt.Fprintf(w, "mpquic_class_dup_active{class=\"%s\"} %d\n", c.Class, v)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_class_dup_active_seconds Total time adaptive duplication has been on.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_class_dup_active_seconds counter\n")
+		for _, c := range gs.Classes {
+			fmt.Fprintf(w, "mpquic_class_dup_active_seconds{class=\"%s\"} %.1f\n", c.Class, c.DupActiveSec)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Per-peer QUIC connections (multi-conn server)
+	if len(gs.Peers) > 0 {
+		for _, m := range quicStatsMetrics {
+			fmt.Fprintf(w, "# HELP mpquic_peer_quic_%s %s per peer QUIC connection.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_peer_quic_%s %s\n", m.name, m.kind)
+			for _, peer := range gs.Peers {
+				for _, p := range peer.Paths {
+					fmt.Fprintf(w, "mpquic_peer_quic_%s{peer=\"%s\",path=\"%s\",remote=\"%s\"} %s\n", m.name, promLabel(peer.PeerIP), promLabel(p.Name), promLabel(p.RemoteAddr), m.value(p.QUIC))
+				}
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
 	// Per-path (client)
 	if len(gs.Paths) > 0 {
 		fmt.Fprintf(w, "# HELP mpquic_path_alive Whether the path is alive (1) or down (0).\n")
@@ -847,6 +1300,77 @@ func handlePrometheus(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprintf(w, "mpquic_path_rx_packets{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.RxPkts)
 		}
 
+		fmt.Fprintf(w, "\n# HELP mpquic_path_srtt_ms Smoothed RTT per path in milliseconds (0 = no sample).\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_srtt_ms gauge\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_srtt_ms{path=\"%s\",bind=\"%s\"} %.3f\n", p.Name, p.BindIP, p.SRTTMs)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_rttvar_ms RTT mean deviation per path in milliseconds.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_rttvar_ms gauge\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_rttvar_ms{path=\"%s\",bind=\"%s\"} %.3f\n", p.Name, p.BindIP, p.RTTVarMs)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_loss_pct Smoothed TX loss per path (percent).\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_loss_pct gauge\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_loss_pct{path=\"%s\",bind=\"%s\"} %.2f\n", p.Name, p.BindIP, p.LossPct)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_probe_up Probe session state (1=up, 0=down or no reply yet).\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_probe_up gauge\n")
+		for _, p := range gs.Paths {
+			if p.Probe == nil {
+				continue
+			}
+			up := 0
+			if p.Probe.State == "up" {
+				up = 1
+			}
+			fmt.Fprintf(w, "mpquic_path_probe_up{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, up)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_probe_rtt_ms Smoothed probe round-trip time.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_probe_rtt_ms gauge\n")
+		for _, p := range gs.Paths {
+			if p.Probe != nil {
+				fmt.Fprintf(w, "mpquic_path_probe_rtt_ms{path=\"%s\",bind=\"%s\"} %.3f\n", p.Name, p.BindIP, p.Probe.RTTMs)
+			}
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_probe_jitter_ms Probe RTT jitter (RFC 3550 estimator).\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_probe_jitter_ms gauge\n")
+		for _, p := range gs.Paths {
+			if p.Probe != nil {
+				fmt.Fprintf(w, "mpquic_path_probe_jitter_ms{path=\"%s\",bind=\"%s\"} %.3f\n", p.Name, p.BindIP, p.Probe.JitterMs)
+			}
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_probe_loss_pct Probe loss over the last %d probes.\n", probeLossWindow)
+		fmt.Fprintf(w, "# TYPE mpquic_path_probe_loss_pct gauge\n")
+		for _, p := range gs.Paths {
+			if p.Probe != nil {
+				fmt.Fprintf(w, "mpquic_path_probe_loss_pct{path=\"%s\",bind=\"%s\"} %.2f\n", p.Name, p.BindIP, p.Probe.LossPct)
+			}
+		}
+
+		for _, m := range quicStatsMetrics {
+			fmt.Fprintf(w, "\n# HELP mpquic_path_quic_%s %s per QUIC path.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_path_quic_%s %s\n", m.name, m.kind)
+			for _, p := range gs.Paths {
+				if p.QUIC != nil {
+					fmt.Fprintf(w, "mpquic_path_quic_%s{path=\"%s\",bind=\"%s\"} %s\n", m.name, p.Name, p.BindIP, m.value(p.QUIC))
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_flow_count Flows pinned to the path by client flow affinity.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_flow_count gauge\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_flow_count{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.FlowCount)
+		}
+
 		fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_tx_bytes Stripe bytes transmitted per path.\n")
 		fmt.Fprintf(w, "# TYPE mpquic_path_stripe_tx_bytes counter\n")
 		for _, p := range gs.Paths {
@@ -1050,6 +1574,63 @@ func handlePrometheus(w http.ResponseWriter, r *http.Request) {
 		for _, p := range gs.Paths {
 			fmt.Fprintf(w, "mpquic_path_stripe_arq_pending_span{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.StripeARQPendingSpan)
 		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_decrypt_fail_total Decryption failures per client stripe path.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_stripe_decrypt_fail_total counter\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_stripe_decrypt_fail_total{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.StripeDecryptFail)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_replay_drop_total Authentic packets dropped by the anti-replay window per client stripe path.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_stripe_replay_drop_total counter\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_stripe_replay_drop_total{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.StripeReplayDrop)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_rekeys_total Stripe key epoch switches per client stripe path and direction.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_stripe_rekeys_total counter\n")
+		for _, p := range gs.Paths {
+			fmt.Fprintf(w, "mpquic_path_stripe_rekeys_total{path=\"%s\",bind=\"%s\",dir=\"tx\"} %d\n", p.Name, p.BindIP, p.StripeTxRekeys)
+			fmt.Fprintf(w, "mpquic_path_stripe_rekeys_total{path=\"%s\",bind=\"%s\",dir=\"rx\"} %d\n", p.Name, p.BindIP, p.StripeRxRekeys)
+		}
+
+		fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_pipes_quarantined Stripe pipes excluded from TX because silent.\n")
+		fmt.Fprintf(w, "# TYPE mpquic_path_stripe_pipes_quarantined gauge\n")
+		for _, p := range gs.Paths {
+			if len(p.StripePipes) > 0 {
+				fmt.Fprintf(w, "mpquic_path_stripe_pipes_quarantined{path=\"%s\",bind=\"%s\"} %d\n", p.Name, p.BindIP, p.StripePipesQuarantined)
+			}
+		}
+		for _, m := range stripePipeHealthMetrics {
+			fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_pipe_%s %s per client stripe pipe.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_path_stripe_pipe_%s %s\n", m.name, m.kind)
+			for _, p := range gs.Paths {
+				for _, pp := range p.StripePipes {
+					if pp.State != "" {
+						fmt.Fprintf(w, "mpquic_path_stripe_pipe_%s{path=\"%s\",bind=\"%s\",pipe=\"%d\"} %s\n", m.name, p.Name, p.BindIP, pp.Pipe, m.value(&pp))
+					}
+				}
+			}
+		}
+
+		for _, m := range stripeTimingMetrics {
+			fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_%s %s per client stripe path.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_path_stripe_%s gauge\n", m.name)
+			for _, p := range gs.Paths {
+				if p.StripeTiming != nil {
+					fmt.Fprintf(w, "mpquic_path_stripe_%s{path=\"%s\",bind=\"%s\"} %.3f\n", m.name, p.Name, p.BindIP, m.value(p.StripeTiming))
+				}
+			}
+			fmt.Fprintf(w, "\n# HELP mpquic_path_stripe_pipe_%s %s per client stripe pipe.\n", m.name, m.help)
+			fmt.Fprintf(w, "# TYPE mpquic_path_stripe_pipe_%s gauge\n", m.name)
+			for _, p := range gs.Paths {
+				for _, pp := range p.StripePipes {
+					if pp.Samples > 0 {
+						fmt.Fprintf(w, "mpquic_path_stripe_pipe_%s{path=\"%s\",bind=\"%s\",pipe=\"%d\"} %.3f\n", m.name, p.Name, p.BindIP, pp.Pipe, m.value(&pp.StripeTiming))
+					}
+				}
+			}
+		}
 		fmt.Fprintln(w)
 	}
 }
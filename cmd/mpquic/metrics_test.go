@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestNewQUICStats(t *testing.T) {
+	qs := newQUICStats(quic.ConnectionStats{
+		SmoothedRTT:          25 * time.Millisecond,
+		MeanDeviation:        1500 * time.Microsecond,
+		MinRTT:               20 * time.Millisecond,
+		CongestionWindow:     64000,
+		BytesInFlight:        1200,
+		PacketsSent:          100,
+		PacketsLost:          3,
+		PacketsRetransmitted: 2,
+	})
+	want := QUICStats{SRTTMs: 25, RTTVarMs: 1.5, MinRTTMs: 20, CwndBytes: 64000, BytesInFlight: 1200, PktsSent: 100, PktsLost: 3, PktsRetransmitted: 2}
+	if *qs != want {
+		t.Fatalf("got %+v, want %+v", *qs, want)
+	}
+}
+
+// TestSnapshotPeerStats registers a real QUIC connection and a stripe path for
+// one peer: only the QUIC connection is listed, with live statistics.
+func TestSnapshotPeerStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := quic.ListenAddr("127.0.0.1:0", testServerTLSConfig(t), &quic.Config{EnableDatagrams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept(ctx)
+		if err != nil {
+			return
+		}
+		pkt, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			return
+		}
+		_ = conn.SendDatagram(pkt)
+	}()
+
+	conn, err := quic.DialAddr(ctx, ln.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"mpquic-ip"}}, &quic.Config{EnableDatagrams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+	if err := conn.SendDatagram([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ReceiveDatagram(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ct := newConnectionTable()
+	defer ct.closeAll()
+	peer := netip.MustParseAddr("10.200.1.1")
+	ct.register(peer, conn, conn, func() {})
+	helperRegisterStripe(ct, peer, "stripe-a", &mockDC{})
+
+	stats := snapshotPeerStats(ct)
+	if len(stats) != 1 || stats[0].PeerIP != peer.String() || len(stats[0].Paths) != 1 {
+		t.Fatalf("peers = %+v, want one peer with one QUIC path", stats)
+	}
+	p := stats[0].Paths[0]
+	if p.RemoteAddr != conn.RemoteAddr().String() {
+		t.Fatalf("remote = %s, want %s", p.RemoteAddr, conn.RemoteAddr())
+	}
+	if p.QUIC.SRTTMs <= 0 || p.QUIC.CwndBytes == 0 || p.QUIC.PktsSent == 0 {
+		t.Fatalf("quic stats = %+v, want RTT, window and packets sent", *p.QUIC)
+	}
+
+	if snapshotPeerStats(nil) != nil {
+		t.Fatal("nil connection table should give no peers")
+	}
+}
+
+func TestPromLabel(t *testing.T) {
+	got := promLabel("wan1\",evil=\"x\"} 1\nmpquic_fake 1\\")
+	want := `wan1\",evil=\"x\"} 1\nmpquic_fake 1\\`
+	if got != want {
+		t.Fatalf("promLabel = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/congestion"
+)
+
+// ─── Per-path congestion control on the server ───────────────────────────
+//
+// multipath_paths[].congestion_algorithm selects the controller of a client
+// path in both directions. The client uses it for the connections it opens,
+// and names it in the peer hello it sends on the path (peer_dataplane.go).
+// The server creates a controller on the first packet of a connection, long
+// before that hello: every server connection starts with the server's own
+// congestion_algorithm, and switches to the one the hello names. The new
+// controller starts from scratch, as after a migration.
+//
+// Paths without an override keep the server's algorithm, and so does a
+// path asking for lia: coupling is decided for all the connections of a
+// client path, by the server's congestion_algorithm.
+
+// pathCCs creates the controllers of the server connections and switches
+// them on request of the client paths.
+type pathCCs struct {
+	algo   string                                             // server congestion_algorithm
+	newLIA func(congestion.PathInfo) congestion.SendAlgorithm // lia controllers, see liaGroups.byConn
+
+	mu sync.Mutex
+	// controllers by the remote address of their connection
+	conns map[string]*pathCC
+}
+
+func newPathCCs(algo string, newLIA func(congestion.PathInfo) congestion.SendAlgorithm) *pathCCs {
+	return &pathCCs{algo: algo, newLIA: newLIA}
+}
+
+// byConn creates the controller of a server connection. A controller
+// recreated for the same connection (after a migration) keeps the algorithm
+// the client path asked for.
+func (p *pathCCs) byConn(info congestion.PathInfo) congestion.SendAlgorithm {
+	c := &pathCC{owner: p, info: info}
+	c.lastSent.Store(time.Now().UnixNano())
+	if info.RemoteAddr == nil {
+		c.switchTo(p.algo)
+		return c
+	}
+	addr := info.RemoteAddr.String()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for a, old := range p.conns {
+		if now.Sub(time.Unix(0, old.lastSent.Load())) >= liaMemberExpiry {
+			delete(p.conns, a)
+		}
+	}
+	algo := p.algo
+	if old, ok := p.conns[addr]; ok && old.info.RTTStats == info.RTTStats {
+		if want := old.want.Load(); want != nil {
+			algo = *want
+			c.want.Store(want)
+		}
+	}
+	c.switchTo(algo)
+	if p.conns == nil {
+		p.conns = make(map[string]*pathCC)
+	}
+	p.conns[addr] = c
+	return c
+}
+
+// set switches the controller of the connection from remote to algo ("" or
+// lia: the server's algorithm). It takes effect on the next packet sent.
+func (p *pathCCs) set(remote, algo string) {
+	if p == nil {
+		return
+	}
+	if algo == "" || algo == congestionLIA || !isValidCongestionAlgorithm(algo) {
+		algo = p.algo
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.conns[remote]; ok {
+		c.want.Store(&algo)
+	}
+}
+
+// newSender creates a controller running algo. cubic is the stack's
+// default, i.e. in Reno mode.
+func (p *pathCCs) newSender(algo string, info congestion.PathInfo) congestion.SendAlgorithm {
+	switch algo {
+	case "bbr":
+		return congestion.NewBBR(info)
+	case "bbr3":
+		return congestion.NewBBR3(info)
+	case congestionLIA:
+		if p.newLIA != nil {
+			return p.newLIA(info)
+		}
+	}
+	return congestion.NewCubic(info, true)
+}
+
+// pathCC is the controller of one server connection. Like every controller
+// it is only used from the run loop of its connection; set only stores the
+// wanted algorithm, and the run loop switches before the next packet.
+type pathCC struct {
+	congestion.SendAlgorithm
+	owner *pathCCs
+	info  congestion.PathInfo
+	algo  string // algorithm of SendAlgorithm
+
+	want     atomic.Pointer[string]
+	lastSent atomic.Int64 // unix nanoseconds
+}
+
+func (c *pathCC) switchTo(algo string) {
+	c.algo = algo
+	c.SendAlgorithm = c.owner.newSender(algo, c.info)
+}
+
+func (c *pathCC) OnPacketSent(sentTime time.Time, bytesInFlight congestion.ByteCount, pn congestion.PacketNumber, bytes congestion.ByteCount, isRetransmittable bool) {
+	if want := c.want.Load(); want != nil && *want != c.algo {
+		c.switchTo(*want)
+	}
+	c.lastSent.Store(sentTime.UnixNano())
+	c.SendAlgorithm.OnPacketSent(sentTime, bytesInFlight, pn, bytes, isRetransmittable)
+}
+
+// SetMaxDatagramSize also applies to the controllers switched to later.
+func (c *pathCC) SetMaxDatagramSize(size congestion.ByteCount) {
+	c.info.InitialMaxDatagramSize = size
+	c.SendAlgorithm.SetMaxDatagramSize(size)
+}
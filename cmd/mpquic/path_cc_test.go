@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/congestion"
+)
+
+func TestPathCCs_HelloSwitchesController(t *testing.T) {
+	peer := netip.MustParseAddr("10.200.1.1")
+	ct, _ := newPeerTestTable(peer)
+	ct.cc = newPathCCs("cubic", ct.lia.byConn)
+
+	remote := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 7), Port: 4242}
+	info := congestion.PathInfo{RTTStats: &congestion.RTTStats{}, InitialMaxDatagramSize: 1200, RemoteAddr: remote}
+	c := ct.cc.byConn(info).(*pathCC)
+	if c.algo != "cubic" {
+		t.Fatalf("initial algorithm = %q, want the server's cubic", c.algo)
+	}
+
+	c.SetMaxDatagramSize(1400)
+	hello := encodePeerHello([]netip.Addr{peer}, "wan1", "", "bbr3")
+	if _, err := ct.peerControl(peer, remote.String(), hello); err != nil {
+		t.Fatal(err)
+	}
+	if c.algo != "cubic" {
+		t.Fatal("switched outside the run loop")
+	}
+	c.OnPacketSent(time.Now(), 0, 1, 1200, true)
+	if c.algo != "bbr3" || c.info.InitialMaxDatagramSize != 1400 {
+		t.Fatalf("after the hello: %q with datagram size %d, want bbr3 with 1400", c.algo, c.info.InitialMaxDatagramSize)
+	}
+
+	// The controller recreated by a migration keeps the path's algorithm;
+	// another connection from the same address starts with the server's.
+	if again := ct.cc.byConn(info).(*pathCC); again.algo != "bbr3" {
+		t.Fatalf("after migration: %q, want bbr3", again.algo)
+	}
+	info.RTTStats = &congestion.RTTStats{}
+	if fresh := ct.cc.byConn(info).(*pathCC); fresh.algo != "cubic" {
+		t.Fatalf("new connection: %q, want cubic", fresh.algo)
+	}
+
+	// lia is the server's choice: the path goes back to cubic.
+	c = ct.cc.byConn(info).(*pathCC)
+	ct.cc.set(remote.String(), "bbr")
+	c.OnPacketSent(time.Now(), 0, 1, 1200, true)
+	ct.cc.set(remote.String(), congestionLIA)
+	c.OnPacketSent(time.Now(), 0, 2, 1200, true)
+	if c.algo != "cubic" {
+		t.Fatalf("lia requested: %q, want the server's cubic", c.algo)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// ─── Live path quality (RTT / jitter / loss) ──────────────────────────────
+//
+// The quality-aware scheduler policies (lowest_rtt, min_loss, latency_budget)
+// rank paths on live measurements instead of static priority/weight only.
+// Inputs per transport:
+//   - QUIC paths: smoothed RTT, RTT mean deviation and lost/sent packet
+//     counters from the quic-go ConnectionTracer (UpdatedMetrics, LostPacket).
+//   - Stripe paths: the peer-reported TX loss carried in server keepalives.
+//     Stripe does not measure RTT yet, so those paths are scored with
+//     pathQualityUnknownRTT until a sample is available.
+//   - Additional quic-mp paths: the smoothed RTT of their QUIC path; loss
+//     comes from probes only.
+//   - Any path with probe_interval_ms set: probe RTT/jitter/loss (probe.go).
+//
+// The tracer callbacks run on the quic-go connection goroutine and only
+// touch atomics; qualityLoop folds them into the per-path EWMA fields
+// under multipathConn.mu once per pathQualityInterval.
+
+const (
+	pathQualityInterval = 1 * time.Second
+	// EWMA weight applied to each new loss sample.
+	pathQualityLossAlpha = 0.3
+	// Minimum packets sent in a window before its loss ratio is trusted.
+	pathQualityMinSamples = 20
+	// RTT assumed for paths without a measurement (matches quic-go's
+	// initial RTT before the first sample).
+	pathQualityUnknownRTT = 100 * time.Millisecond
+	// Budget used by latency_budget when the class sets none.
+	defaultLatencyBudget = 150 * time.Millisecond
+	// Penalty added to paths whose effective RTT exceeds the class budget,
+	// large enough to rank them behind every in-budget path.
+	latencyBudgetOverPenalty = 1_000_000
+)
+
+// quicPathStats is fed by the ConnectionTracer of a single QUIC connection.
+type quicPathStats struct {
+	smoothedRTT int64 // ns
+	rttVar      int64 // ns
+	minRTT      int64 // ns
+	cwnd        int64 // bytes
+	sentPkts    uint64
+	lostPkts    uint64
+}
+
+// newQUICPathTracer returns a quic.Config.Tracer that records RTT and loss
+// into st. A fresh quicPathStats is used for every dial so samples from a
+// previous connection never leak into a new one.
+func newQUICPathTracer(st *quicPathStats) func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+	return func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+		return &logging.ConnectionTracer{
+			SentShortHeaderPacket: func(*logging.ShortHeader, logging.ByteCount, logging.ECN, *logging.AckFrame, []logging.Frame) {
+				atomic.AddUint64(&st.sentPkts, 1)
+			},
+			LostPacket: func(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+				atomic.AddUint64(&st.lostPkts, 1)
+			},
+			UpdatedMetrics: func(rtt *logging.RTTStats, cwnd, _ logging.ByteCount, _ int) {
+				atomic.StoreInt64(&st.cwnd, int64(cwnd))
+				atomic.StoreInt64(&st.smoothedRTT, int64(rtt.SmoothedRTT()))
+				atomic.StoreInt64(&st.rttVar, int64(rtt.MeanDeviation()))
+				atomic.StoreInt64(&st.minRTT, int64(rtt.MinRTT()))
+			},
+		}
+	}
+}
+
+// quicStatsRegistry hands out quicPathStats for connections accepted by a
+// quic.Listener. The server cannot create the stats before the connection
+// exists, so the Tracer registers them under the connection's tracing ID
+// and the tunnel goroutine picks them up from conn.Context(). Entries are
+// dropped when the connection context ends.
+type quicStatsRegistry struct {
+	mu    sync.Mutex
+	byID  map[quic.ConnectionTracingID]*quicPathStats
+}
+
+func newQUICStatsRegistry() *quicStatsRegistry {
+	return &quicStatsRegistry{byID: make(map[quic.ConnectionTracingID]*quicPathStats)}
+}
+
+// tracer is used as quic.Config.Tracer on the server listener.
+func (r *quicStatsRegistry) tracer(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+	id, ok := ctx.Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return nil
+	}
+	st := &quicPathStats{}
+	r.mu.Lock()
+	r.byID[id] = st
+	r.mu.Unlock()
+	context.AfterFunc(ctx, func() {
+		r.mu.Lock()
+		delete(r.byID, id)
+		r.mu.Unlock()
+	})
+	return newQUICPathTracer(st)(ctx, p, connID)
+}
+
+// lookup returns the stats of conn, or nil if it was not traced.
+func (r *quicStatsRegistry) lookup(conn quic.Connection) *quicPathStats {
+	if r == nil || conn == nil {
+		return nil
+	}
+	id, ok := conn.Context().Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.byID[id]
+}
+
+// pathQuality is the scheduler's view of a path, refreshed by qualityLoop.
+type pathQuality struct {
+	srtt      time.Duration
+	rttVar    time.Duration
+	lossRate  float64 // 0.0 – 1.0, EWMA
+	updatedAt time.Time
+
+	prevSent uint64
+	prevLost uint64
+}
+
+// effectiveRTT is the RTT the scheduler compares: sRTT plus two mean
+// deviations, so a path that is jittering through a handover ranks behind
+// a steady one with a similar average.
+func (q *pathQuality) effectiveRTT() time.Duration {
+	if q.srtt <= 0 {
+		return pathQualityUnknownRTT
+	}
+	return q.srtt + 2*q.rttVar
+}
+
+// observeLoss folds a windowed loss ratio into the EWMA.
+func (q *pathQuality) observeLoss(sample float64) {
+	if sample < 0 {
+		sample = 0
+	}
+	if sample > 1 {
+		sample = 1
+	}
+	q.lossRate = q.lossRate*(1-pathQualityLossAlpha) + sample*pathQualityLossAlpha
+}
+
+// reset drops all samples; called when a path reconnects.
+func (q *pathQuality) reset() {
+	*q = pathQuality{}
+}
+
+// refreshQuality updates p.quality from its transport. Caller holds m.mu.
+func refreshQuality(p *multipathPathState, now time.Time) {
+	q := &p.quality
+	switch {
+	case p.stripeConn != nil:
+		loss := atomic.LoadUint32(&p.stripeConn.peerLossRate)
+		// 255 is the XOR anti-waste sentinel, not a loss percentage.
+		if loss <= 100 {
+			q.observeLoss(float64(loss) / 100)
+		}
+		q.updatedAt = now
+	case p.quicStats != nil:
+		st := p.quicStats
+		q.srtt = time.Duration(atomic.LoadInt64(&st.smoothedRTT))
+		q.rttVar = time.Duration(atomic.LoadInt64(&st.rttVar))
+		sent := atomic.LoadUint64(&st.sentPkts)
+		lost := atomic.LoadUint64(&st.lostPkts)
+		dSent := sent - q.prevSent
+		dLost := lost - q.prevLost
+		if dSent >= pathQualityMinSamples {
+			q.observeLoss(float64(dLost) / float64(dSent))
+			q.prevSent = sent
+			q.prevLost = lost
+		} else if dSent == 0 && dLost == 0 {
+			// Idle path: decay towards zero so an old burst does not pin it.
+			q.observeLoss(0)
+		}
+		q.updatedAt = now
+	case p.mpPath != nil:
+		// Additional quic-mp paths: the tracer only sees the connection,
+		// the per-path RTT comes from the path itself.
+		q.srtt = p.mpPath.SmoothedRTT()
+		q.updatedAt = now
+	}
+
+	// Probe results: RTT where the transport has none (stripe, or QUIC
+	// before its first sample), and loss even when no data flows.
+	if rtt, jitter, loss, ok := p.probe.measurements(); ok {
+		if p.stripeConn != nil || q.srtt <= 0 {
+			q.srtt = rtt
+			q.rttVar = jitter
+		}
+		if loss > q.lossRate {
+			q.lossRate = loss
+		}
+		q.updatedAt = now
+	}
+}
+
+// qualityLoop periodically refreshes path quality for the scheduler.
+func (m *multipathConn) qualityLoop(ctx context.Context) {
+	ticker := time.NewTicker(pathQualityInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for _, p := range m.paths {
+				if p.alive {
+					refreshQuality(p, now)
+				}
+			}
+			m.evaluateAdaptiveDuplication(now)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// isQualityPolicy reports whether policy ranks paths on live measurements.
+func isQualityPolicy(policy string) bool {
+	switch policy {
+	case "lowest_rtt", "min_loss", "latency_budget":
+		return true
+	}
+	return false
+}
+
+// pathQualityScore scores a path for the quality-aware policies.
+// Lower is better, consistent with pathPolicyScore.
+func pathQualityScore(policy string, p *multipathPathState, budget time.Duration) int {
+	failPenalty := p.consecutiveFails * 100
+	rttMs := int(p.quality.effectiveRTT() / time.Millisecond)
+	lossPermille := int(p.quality.lossRate * 1000)
+
+	switch policy {
+	case "lowest_rtt":
+		// 0.1 ms resolution; priority only breaks exact ties.
+		return int(p.quality.effectiveRTT()/(100*time.Microsecond)) + failPenalty + p.cfg.Priority
+	case "min_loss":
+		// Loss dominates (per-mille × 100), RTT breaks ties.
+		return lossPermille*100 + rttMs + failPenalty
+	case "latency_budget":
+		if budget <= 0 {
+			budget = defaultLatencyBudget
+		}
+		// Within budget: behave like "priority", with loss as a secondary
+		// penalty. Over budget: rank behind every in-budget path, ordered
+		// by how far over they are.
+		score := p.cfg.Priority*1000 + failPenalty + lossPermille*10
+		if eff := p.quality.effectiveRTT(); eff > budget {
+			score += latencyBudgetOverPenalty + int((eff-budget)/time.Millisecond)*10
+		}
+		return score
+	}
+	return pathPolicyScore(policy, p)
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sync/atomic"
+	"time"
+)
+
+// ─── Per-peer dataplane for the return direction ──────────────────────────
+//
+// The client steers its upstream traffic by class; without help the server
+// sends the replies by flow hash, so downstream VoIP could land on the lossy
+// Starlink path and is never duplicated. A server peer group can therefore
+// carry a dataplane policy of its own, from:
+//   - peer_dataplanes in the server config, keyed by the peer's TUN IP;
+//   - the client, with dataplane_push: true (a local entry wins).
+//
+// The policy is written from the client's side, so the client's file can be
+// reused as is: return packets are classified with source and destination
+// swapped (a dst_ports 5060 rule matches replies from port 5060), and path
+// names are the client's multipath_paths names, learned from a hello the
+// client sends on every path. dispatch then applies excluded_paths (strict),
+// preferred_paths (with fallback to the others) and duplication (always;
+// adaptive needs the client's path measurements) to that peer's traffic.
+// Rate limits stay with the server's own dataplane (class_shaper.go).
+//
+// Control datagrams share one header:
+//
+//	[0]    magic 0xB7   (same 0xB nibble as bondMagic, distinct low nibble)
+//	[1]    version 1
+//	[2]    type
+//	[3]    reserved (0)
+//
+//	hello (client → server, on every path, refreshed every peerCtlRefresh):
+//	  address count(1) + TUN addresses (16 each, IPv4-mapped for IPv4) +
+//	  name len(1) + name + base path len(1) + base path +
+//	  congestion algorithm len(1) + the path's congestion_algorithm (path_cc.go)
+//	policy (client → server, on every path with the hello):
+//	  policy hash(4) + chunk index(1) + chunk count(1) + JSON chunk
+//	dup (server → client): the inner IP packet of a duplicated copy,
+//	  de-duplicated by the client before delivery.
+//
+// A hello also registers a QUIC path that has not sent traffic yet, under
+// the first TUN address; the others (tun_cidr6 next to tun_cidr) become
+// learned routes of the peer.
+
+const (
+	peerCtlMagic   = 0xB7
+	peerCtlVersion = 1
+	peerCtlHdrLen  = 4
+
+	peerCtlHello  = 1
+	peerCtlPolicy = 2
+	peerCtlDup    = 3
+
+	peerPolicyHdrLen = peerCtlHdrLen + 6
+	// JSON bytes per policy datagram, below the QUIC datagram limit.
+	peerPolicyChunk = 1000
+
+	peerCtlRefresh = 30 * time.Second
+	peerCtlPoll    = time.Second
+)
+
+func isPeerCtlPacket(pkt []byte) bool {
+	return len(pkt) >= peerCtlHdrLen && pkt[0] == peerCtlMagic && pkt[1] == peerCtlVersion
+}
+
+func peerCtlHeader(typ byte, size int) []byte {
+	out := make([]byte, peerCtlHdrLen, peerCtlHdrLen+size)
+	out[0] = peerCtlMagic
+	out[1] = peerCtlVersion
+	out[2] = typ
+	return out
+}
+
+func encodePeerHello(tunAddrs []netip.Addr, name, base, algo string) []byte {
+	if len(tunAddrs) > 4 {
+		tunAddrs = tunAddrs[:4]
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	if len(base) > 255 {
+		base = base[:255]
+	}
+	out := peerCtlHeader(peerCtlHello, 4+16*len(tunAddrs)+len(name)+len(base)+len(algo))
+	out = append(out, byte(len(tunAddrs)))
+	for _, a := range tunAddrs {
+		ip := a.As16()
+		out = append(out, ip[:]...)
+	}
+	out = append(out, byte(len(name)))
+	out = append(out, name...)
+	out = append(out, byte(len(base)))
+	out = append(out, base...)
+	out = append(out, byte(len(algo)))
+	out = append(out, algo...)
+	return out
+}
+
+// decodePeerHello returns the sender's TUN addresses (possibly none), path
+// names and congestion algorithm (empty if the path has none of its own, or
+// the sender predates it).
+func decodePeerHello(pkt []byte) (tunAddrs []netip.Addr, name, base, algo string, ok bool) {
+	if !isPeerCtlPacket(pkt) || pkt[2] != peerCtlHello || len(pkt) < peerCtlHdrLen+3 {
+		return nil, "", "", "", false
+	}
+	b := pkt[peerCtlHdrLen:]
+	n := int(b[0])
+	b = b[1:]
+	if len(b) < 16*n+2 {
+		return nil, "", "", "", false
+	}
+	for i := 0; i < n; i++ {
+		a := netip.AddrFrom16([16]byte(b[16*i : 16*i+16])).Unmap()
+		if !a.IsUnspecified() {
+			tunAddrs = append(tunAddrs, a)
+		}
+	}
+	b = b[16*n:]
+	l := int(b[0])
+	if len(b) < 1+l+1 {
+		return nil, "", "", "", false
+	}
+	name = string(b[1 : 1+l])
+	b = b[1+l:]
+	l = int(b[0])
+	if len(b) < 1+l {
+		return nil, "", "", "", false
+	}
+	base = string(b[1 : 1+l])
+	b = b[1+l:]
+	if len(b) > 0 {
+		l = int(b[0])
+		if len(b) < 1+l {
+			return nil, "", "", "", false
+		}
+		algo = string(b[1 : 1+l])
+	}
+	return tunAddrs, name, base, algo, true
+}
+
+// encodePeerPolicy splits the JSON form of dp into policy datagrams. The
+// hash of the body identifies the policy, so the server skips a policy it
+// already applied, also across client restarts.
+func encodePeerPolicy(dp DataplaneConfig) ([][]byte, error) {
+	body, err := json.Marshal(dp)
+	if err != nil {
+		return nil, err
+	}
+	total := (len(body) + peerPolicyChunk - 1) / peerPolicyChunk
+	if total > 255 {
+		return nil, fmt.Errorf("dataplane too large to push: %d bytes", len(body))
+	}
+	hash := fnv1aHash(body)
+	out := make([][]byte, 0, total)
+	for i := 0; i < total; i++ {
+		chunk := body[i*peerPolicyChunk : min((i+1)*peerPolicyChunk, len(body))]
+		pkt := peerCtlHeader(peerCtlPolicy, 6+len(chunk))
+		pkt = binary.BigEndian.AppendUint32(pkt, hash)
+		pkt = append(pkt, byte(i), byte(total))
+		pkt = append(pkt, chunk...)
+		out = append(out, pkt)
+	}
+	return out, nil
+}
+
+func decodePeerPolicyChunk(pkt []byte) (hash uint32, idx, total int, chunk []byte, ok bool) {
+	if !isPeerCtlPacket(pkt) || pkt[2] != peerCtlPolicy || len(pkt) < peerPolicyHdrLen {
+		return 0, 0, 0, nil, false
+	}
+	hash = binary.BigEndian.Uint32(pkt[peerCtlHdrLen:])
+	idx = int(pkt[peerCtlHdrLen+4])
+	total = int(pkt[peerCtlHdrLen+5])
+	if total == 0 || idx >= total {
+		return 0, 0, 0, nil, false
+	}
+	return hash, idx, total, pkt[peerPolicyHdrLen:], true
+}
+
+func encodePeerDup(pkt []byte) []byte {
+	return append(peerCtlHeader(peerCtlDup, len(pkt)), pkt...)
+}
+
+func decodePeerDup(pkt []byte) ([]byte, bool) {
+	if !isPeerCtlPacket(pkt) || pkt[2] != peerCtlDup || len(pkt) == peerCtlHdrLen {
+		return nil, false
+	}
+	return pkt[peerCtlHdrLen:], true
+}
+
+// peerPolicyAsm reassembles the chunks of one pushed policy.
+type peerPolicyAsm struct {
+	hash  uint32
+	parts [][]byte
+	got   int
+}
+
+// add stores a chunk and returns the full body once every chunk of the
+// policy arrived. A chunk of another policy restarts the assembly.
+func (a *peerPolicyAsm) add(hash uint32, idx, total int, chunk []byte) ([]byte, bool) {
+	if a.hash != hash || len(a.parts) != total {
+		a.hash = hash
+		a.parts = make([][]byte, total)
+		a.got = 0
+	}
+	if a.parts[idx] != nil {
+		return nil, false
+	}
+	a.parts[idx] = append([]byte(nil), chunk...)
+	a.got++
+	if a.got < total {
+		return nil, false
+	}
+	body := bytes.Join(a.parts, nil)
+	a.parts = nil
+	a.got = 0
+	return body, true
+}
+
+// peerDataplanePaths lists every path name dp refers to. The server cannot
+// know a peer's paths in advance, so name checks are left to the client.
+func peerDataplanePaths(dp DataplaneConfig) []MultipathPathConfig {
+	var out []MultipathPathConfig
+	for _, policy := range dp.Classes {
+		for _, name := range policy.PreferredPaths {
+			out = append(out, MultipathPathConfig{Name: name})
+		}
+		for _, name := range policy.ExcludedPaths {
+			out = append(out, MultipathPathConfig{Name: name})
+		}
+	}
+	return out
+}
+
+// compilePeerDataplane normalizes, validates and compiles a per-peer policy.
+func compilePeerDataplane(dp DataplaneConfig) (*compiledDataplane, error) {
+	normalizeDataplaneConfig(&dp, "priority")
+	if err := validateDataplaneConfig(dp, peerDataplanePaths(dp)); err != nil {
+		return nil, err
+	}
+	compiled, err := compileDataplaneConfig(dp)
+	if err != nil {
+		return nil, err
+	}
+	return &compiled, nil
+}
+
+// ─── Server side ──────────────────────────────────────────────────────────
+
+// peerDataplaneLocked returns the policy steering grp's return traffic: the
+// local peer_dataplanes entry, else the one pushed by the client. Caller
+// must hold ct.mu.
+func (ct *connectionTable) peerDataplaneLocked(grp *connGroup) *compiledDataplane {
+	if dp := ct.peerDataplanes[grp.peerIP]; dp != nil {
+		return dp
+	}
+	return grp.pushed
+}
+
+// peerControl handles a control datagram received from peerIP on the path
+// remoteAddr. It returns the pushed policy when one was just applied.
+func (ct *connectionTable) peerControl(peerIP netip.Addr, remoteAddr string, pkt []byte) (*compiledDataplane, error) {
+	switch pkt[2] {
+	case peerCtlHello:
+		addrs, name, base, algo, ok := decodePeerHello(pkt)
+		if !ok {
+			return nil, fmt.Errorf("malformed peer hello")
+		}
+		ct.cc.set(remoteAddr, algo)
+		ct.mu.Lock()
+		grp, ok := ct.byIP[peerIP]
+		if ok {
+			for _, pc := range grp.paths {
+				if pc.remoteAddr == remoteAddr {
+					pc.name, pc.basePath = name, base
+				}
+			}
+		}
+		ct.mu.Unlock()
+		if !ok {
+			return nil, nil
+		}
+		for _, a := range addrs {
+			if a != peerIP {
+				ct.learnRoute(a, peerIP)
+			}
+		}
+		return nil, nil
+
+	case peerCtlPolicy:
+		hash, idx, total, chunk, ok := decodePeerPolicyChunk(pkt)
+		if !ok {
+			return nil, fmt.Errorf("malformed peer policy chunk")
+		}
+		ct.mu.Lock()
+		grp, ok := ct.byIP[peerIP]
+		if !ok || (grp.pushed != nil && grp.pushedHash == hash) {
+			ct.mu.Unlock()
+			return nil, nil
+		}
+		body, done := grp.policyAsm.add(hash, idx, total, chunk)
+		ct.mu.Unlock()
+		if !done {
+			return nil, nil
+		}
+
+		var dp DataplaneConfig
+		if err := json.Unmarshal(body, &dp); err != nil {
+			return nil, fmt.Errorf("pushed dataplane: %w", err)
+		}
+		compiled, err := compilePeerDataplane(dp)
+		if err != nil {
+			return nil, fmt.Errorf("pushed dataplane: %w", err)
+		}
+		ct.mu.Lock()
+		defer ct.mu.Unlock()
+		if grp, ok := ct.byIP[peerIP]; ok {
+			grp.pushed = compiled
+			grp.pushedHash = hash
+		}
+		return compiled, nil
+	}
+	return nil, nil
+}
+
+// matchesPath reports whether the client path name (pipe or base name)
+// refers to pc.
+func (pc *pathConn) matchesPath(name string) bool {
+	return name != "" && (name == pc.name || name == pc.basePath)
+}
+
+// filterReturnPaths narrows active to the paths policy allows, in place:
+// excluded paths are dropped, and when a preferred path is active only the
+// preferred ones are kept. Caller must hold ct.mu.
+func filterReturnPaths(grp *connGroup, active []int, policy DataplaneClassPolicy) []int {
+	out := active[:0]
+	for _, i := range active {
+		excluded := false
+		for _, name := range policy.ExcludedPaths {
+			if grp.paths[i].matchesPath(name) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			out = append(out, i)
+		}
+	}
+	if len(policy.PreferredPaths) == 0 {
+		return out
+	}
+
+	preferred := 0
+	for _, i := range out {
+		if pathPreferred(grp.paths[i], policy) {
+			preferred++
+		}
+	}
+	if preferred == 0 {
+		return out
+	}
+	kept := out[:0]
+	for _, i := range out {
+		if pathPreferred(grp.paths[i], policy) {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+func pathPreferred(pc *pathConn, policy DataplaneClassPolicy) bool {
+	for _, name := range policy.PreferredPaths {
+		if pc.matchesPath(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatchDuplicate queues a dup-framed copy of pkt on up to
+// DuplicateCopies active paths, skipping paths whose queue is full.
+// Caller must hold ct.mu.
+func (ct *connectionTable) dispatchDuplicate(grp *connGroup, active []int, policy DataplaneClassPolicy, pkt []byte) bool {
+	copies := policy.DuplicateCopies
+	if copies < 2 {
+		copies = 2
+	}
+	frame := encodePeerDup(pkt)
+	sent := 0
+	for _, i := range active {
+		if sent == copies {
+			break
+		}
+		pc := grp.paths[i]
+		out := frame
+		if sent > 0 {
+			out = append([]byte(nil), frame...) // each drain goroutine owns its buffer
+		}
+		select {
+		case pc.sendCh <- out:
+			atomic.AddUint64(&pc.dispatchHit, 1)
+			sent++
+		default:
+			atomic.AddUint64(&pc.dispatchDrop, 1)
+		}
+	}
+	return sent > 0
+}
+
+// ─── Client side ──────────────────────────────────────────────────────────
+
+// setPeerPolicyLocked prepares the policy datagrams sent with the next
+// hellos (none unless dataplane_push is set) and makes every path resend.
+// Caller must hold m.mu.
+func (m *multipathConn) setPeerPolicyLocked(dp DataplaneConfig) {
+	m.peerPolicy = nil
+	if m.cfg.DataplanePush {
+		pkts, err := encodePeerPolicy(dp)
+		if err != nil {
+			m.logger.Errorf("dataplane push disabled: %v", err)
+		} else {
+			m.peerPolicy = pkts
+		}
+	}
+	for _, p := range m.paths {
+		p.ctlDC = nil
+	}
+}
+
+// peerCtlLoop sends the hello (and pushed policy) on every path that came
+// up on a new connection, and refreshes it every peerCtlRefresh so a
+// restarted server learns the names again.
+func (m *multipathConn) peerCtlLoop(ctx context.Context) {
+	ticker := time.NewTicker(peerCtlPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.peerCtlTick(now)
+		}
+	}
+}
+
+func (m *multipathConn) peerCtlTick(now time.Time) {
+	type pending struct {
+		dc   datagramConn
+		pkts [][]byte
+	}
+	var sends []pending
+
+	m.mu.Lock()
+	for _, p := range m.paths {
+		if p.removed || !p.alive || p.dc == nil {
+			continue
+		}
+		if p.ctlDC == p.dc && now.Sub(p.ctlSentAt) < peerCtlRefresh {
+			continue
+		}
+		p.ctlDC = p.dc
+		p.ctlSentAt = now
+		pkts := append([][]byte{encodePeerHello(m.tunAddrs, p.cfg.Name, p.cfg.BasePath, p.cfg.CongestionAlgorithm)}, m.peerPolicy...)
+		sends = append(sends, pending{dc: p.dc, pkts: pkts})
+	}
+	m.mu.Unlock()
+
+	for _, s := range sends {
+		for _, pkt := range s.pkts {
+			_ = s.dc.SendDatagram(pkt)
+		}
+	}
+}
+
+// handlePeerControl applies a control datagram received on a server path
+// and logs the outcome.
+func handlePeerControl(ct *connectionTable, peerIP netip.Addr, remoteAddr string, pkt []byte, logger *Logger) {
+	pushed, err := ct.peerControl(peerIP, remoteAddr, pkt)
+	switch {
+	case err != nil:
+		logger.Errorf("peer control rejected peer=%s remote=%s err=%v", peerIP, remoteAddr, err)
+	case pushed != nil:
+		ct.mu.RLock()
+		_, local := ct.peerDataplanes[peerIP]
+		ct.mu.RUnlock()
+		logger.Infof("peer dataplane pushed peer=%s classes=%d classifiers=%d overridden_by_local=%t",
+			peerIP, len(pushed.classes), len(pushed.classifiers), local)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// peerTestDataplane is a client-side policy: VoIP to port 5060 stays off
+// starlink and is duplicated, bulk prefers starlink.
+func peerTestDataplane() DataplaneConfig {
+	return DataplaneConfig{
+		DefaultClass: "bulk",
+		Classes: map[string]DataplaneClassPolicy{
+			"voip": {ExcludedPaths: []string{"starlink"}, Duplicate: true},
+			"bulk": {PreferredPaths: []string{"starlink"}},
+		},
+		Classifiers: []DataplaneClassifierRule{
+			{Name: "sip", ClassName: "voip", Protocol: "udp", DstPorts: []string{"5060"}},
+		},
+	}
+}
+
+// newPeerTestTable returns a table with one peer whose paths wan1, wan2
+// and starlink (a pipe of base path starlink) announced their names.
+func newPeerTestTable(peer netip.Addr) (*connectionTable, *connGroup) {
+	ct := newConnectionTable()
+	grp := &connGroup{peerIP: peer}
+	for _, name := range []string{"wan1", "wan2", "starlink.0"} {
+		pc := &pathConn{remoteAddr: name, name: name, sendCh: make(chan []byte, 16), lastRecv: time.Now()}
+		if name == "starlink.0" {
+			pc.basePath = "starlink"
+		}
+		grp.paths = append(grp.paths, pc)
+	}
+	ct.byIP[peer] = grp
+	return ct, grp
+}
+
+// sipReply is a packet from the SIP server (port 5060) back to the client.
+func sipReply() []byte {
+	pkt := ipv4Packet(17, udpDatagram([]byte("rtp")))
+	binary.BigEndian.PutUint16(pkt[20:22], 5060)
+	return pkt
+}
+
+func TestPeerHello_RoundTrip(t *testing.T) {
+	v4, v6 := netip.MustParseAddr("10.200.1.1"), netip.MustParseAddr("fd00:200::1")
+	addrs, name, base, algo, ok := decodePeerHello(encodePeerHello([]netip.Addr{v4, v6}, "starlink.0", "starlink", "bbr3"))
+	if !ok || len(addrs) != 2 || addrs[0] != v4 || addrs[1] != v6 || name != "starlink.0" || base != "starlink" || algo != "bbr3" {
+		t.Fatalf("hello = %v %q %q %q ok=%t", addrs, name, base, algo, ok)
+	}
+	if addrs, _, _, _, ok := decodePeerHello(encodePeerHello(nil, "wan1", "", "")); !ok || len(addrs) != 0 {
+		t.Errorf("hello without TUN address: addrs=%v ok=%t", addrs, ok)
+	}
+	if _, _, _, _, ok := decodePeerHello(encodePeerHello([]netip.Addr{v4}, "wan1", "", "")[:12]); ok {
+		t.Error("truncated hello decoded")
+	}
+	// A hello from a client without the congestion algorithm field.
+	old := encodePeerHello([]netip.Addr{v4}, "wan1", "", "")
+	old = old[:len(old)-1]
+	if _, name, _, algo, ok := decodePeerHello(old); !ok || name != "wan1" || algo != "" {
+		t.Errorf("hello without algorithm: name=%q algo=%q ok=%t", name, algo, ok)
+	}
+}
+
+func TestPeerControl_PushedPolicyReassembled(t *testing.T) {
+	peer := netip.MustParseAddr("10.200.1.1")
+	ct, grp := newPeerTestTable(peer)
+
+	dp := peerTestDataplane()
+	for i := 0; i < 30; i++ { // large enough to need several chunks
+		dp.Classifiers = append(dp.Classifiers, DataplaneClassifierRule{
+			Name: fmt.Sprintf("r%d", i), ClassName: "bulk", DstCIDRs: []string{"192.0.2.0/24"},
+		})
+	}
+	pkts, err := encodePeerPolicy(dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkts) < 2 {
+		t.Fatalf("policy fits %d datagram(s), want several", len(pkts))
+	}
+
+	// Chunks arrive out of order, the last one twice (every path sends
+	// them): the policy is applied once the final missing chunk arrives.
+	order := append([][]byte{pkts[len(pkts)-1]}, pkts...)
+	for i, pkt := range order {
+		pushed, err := ct.peerControl(peer, "wan1", pkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (pushed != nil) != (i == len(order)-2) {
+			t.Fatalf("chunk %d: applied=%t", i, pushed != nil)
+		}
+	}
+	if grp.pushed == nil || len(grp.pushed.classifiers) != 31 {
+		t.Fatal("pushed policy not applied")
+	}
+
+	// The same policy again is not recompiled.
+	for _, pkt := range pkts {
+		if pushed, _ := ct.peerControl(peer, "wan2", pkt); pushed != nil {
+			t.Fatal("identical policy applied twice")
+		}
+	}
+
+	// A local entry wins over the pushed policy.
+	local, err := compilePeerDataplane(DataplaneConfig{DefaultClass: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct.peerDataplanes[peer] = local
+	if got := ct.peerDataplaneLocked(grp); got != local {
+		t.Error("local peer dataplane does not take precedence")
+	}
+}
+
+func TestConnectionTable_DispatchAppliesPeerPolicy(t *testing.T) {
+	peer := netip.MustParseAddr("10.200.1.1")
+	ct, grp := newPeerTestTable(peer)
+	dp, err := compilePeerDataplane(peerTestDataplane())
+	if err != nil {
+		t.Fatal(err)
+	}
+	grp.pushed = dp
+
+	// VoIP replies: duplicated on wan1 and wan2, never on starlink.
+	if !ct.dispatch(peer, sipReply()) {
+		t.Fatal("dispatch failed")
+	}
+	for i, want := range []int{1, 1, 0} {
+		if got := len(grp.paths[i].sendCh); got != want {
+			t.Errorf("%s queued %d, want %d", grp.paths[i].name, got, want)
+		}
+	}
+	frame := <-grp.paths[0].sendCh
+	if inner, ok := decodePeerDup(frame); !ok || len(inner) != len(sipReply()) {
+		t.Error("duplicated copy not dup-framed")
+	}
+	<-grp.paths[1].sendCh
+
+	// Bulk prefers starlink, matched by its base path name.
+	for port := uint16(40000); port < 40010; port++ {
+		if !ct.dispatch(peer, tcpFlowPacket(port)) {
+			t.Fatal("dispatch failed")
+		}
+	}
+	if got := len(grp.paths[2].sendCh); got != 10 {
+		t.Errorf("starlink queued %d bulk packets, want 10", got)
+	}
+
+	// Preferred falls back to the other paths once starlink goes stale.
+	grp.paths[2].lastRecv = time.Now().Add(-10 * time.Second)
+	if !ct.dispatch(peer, tcpFlowPacket(41000)) {
+		t.Fatal("dispatch failed")
+	}
+	if len(grp.paths[0].sendCh)+len(grp.paths[1].sendCh) != 1 {
+		t.Error("bulk not moved off the stale preferred path")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"net"
 	"testing"
 )
 
@@ -108,3 +109,55 @@ func TestExpandMultipathPipes_Mixed(t *testing.T) {
 		}
 	}
 }
+
+func TestPickBindIP_Family(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("::1"),
+		net.ParseIP("fe80::1"),
+		net.ParseIP("fd00::5"),
+		net.ParseIP("2001:db8::5"),
+		net.ParseIP("100.64.1.2"),
+	}
+	cases := []struct{ family, want string }{
+		{"", "100.64.1.2"},
+		{"ipv4", "100.64.1.2"},
+		{"ipv6", "2001:db8::5"}, // global wins over ULA, never link-local
+	}
+	for _, c := range cases {
+		if got := pickBindIP(ips, c.family); got.String() != c.want {
+			t.Errorf("family %q: got %s, want %s", c.family, got, c.want)
+		}
+	}
+	// IPv6-only interface: the default falls back to the global address.
+	if got := pickBindIP(ips[:4], ""); got.String() != "2001:db8::5" {
+		t.Errorf("IPv6-only interface: got %s", got)
+	}
+	if got := pickBindIP(ips[:3], "ipv6"); got.String() != "fd00::5" {
+		t.Errorf("ULA-only interface: got %s", got)
+	}
+	if got := pickBindIP(ips[:4], "ipv4"); got != nil {
+		t.Errorf("ipv4 on an IPv6-only interface: got %s", got)
+	}
+}
+
+func TestValidateBindIP(t *testing.T) {
+	for _, v := range []string{"0.0.0.0", "2001:db8::1", "if:wwan0", "if:wwan0/ipv6", "if:enp7s7/ipv4"} {
+		if err := validateBindIP(v); err != nil {
+			t.Errorf("%q: %v", v, err)
+		}
+	}
+	for _, v := range []string{"wwan0", "if:", "if:wwan0/v6", "if:/ipv6"} {
+		if err := validateBindIP(v); err == nil {
+			t.Errorf("%q accepted", v)
+		}
+	}
+	if name := bindInterfaceName("if:wwan0/ipv6"); name != "wwan0" {
+		t.Errorf("interface name = %q", name)
+	}
+	if got := withBindFamily("if:wwan0", "ipv6"); got != "if:wwan0/ipv6" {
+		t.Errorf("withBindFamily = %q", got)
+	}
+	if udpNetworkFor("2001:db8::1") != "udp6" || udpNetworkFor("10.0.0.1") != "udp4" {
+		t.Error("udpNetworkFor does not follow the bind family")
+	}
+}
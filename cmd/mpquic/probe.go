@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// ─── Active path probing (BFD-like) ───────────────────────────────────────
+//
+// Send errors and 15 s QUIC keepalives notice a black-holed WAN only after
+// seconds of lost traffic. With probe_interval_ms set, the client sends an
+// echo request on every path each interval, independent of data traffic:
+//   - QUIC paths: a datagram starting with probeMagic (never a valid IP
+//     version nibble), echoed by runServerMultiConnTunnel before
+//     registration and bonding handling.
+//   - Stripe paths: a stripePROBE control packet carrying the same
+//     payload, echoed by the stripe server on the pipe it arrived on.
+//
+// Replies give RTT, jitter (RFC 3550 interarrival estimator) and loss over
+// the last probeLossWindow probes. A path that answered at least once is
+// marked down after detect_multiplier intervals without a reply and up again
+// on the next reply, so the scheduler reacts within interval × multiplier.
+// A path that never answered (server without probe support) is left to the
+// send-error and keepalive checks.
+
+const (
+	probeMagic   = 0xB6
+	probeVersion = 1
+	probeHdrLen  = 16
+
+	probeRequest = 1
+	probeReply   = 2
+
+	defaultProbeDetectMultiplier = 3
+	minProbeInterval             = 20 * time.Millisecond
+	// Probes remembered for the loss ratio.
+	probeLossWindow = 64
+	// Loss ratio below this many completed probes is not reported.
+	probeMinSamples = 5
+)
+
+// Wire format: magic(1) + ver(1) + type(1) + reserved(1) + id(4) + sent ns(8).
+func encodeProbePacket(typ byte, id uint32, sent int64) []byte {
+	pkt := make([]byte, probeHdrLen)
+	pkt[0] = probeMagic
+	pkt[1] = probeVersion
+	pkt[2] = typ
+	binary.BigEndian.PutUint32(pkt[4:8], id)
+	binary.BigEndian.PutUint64(pkt[8:16], uint64(sent))
+	return pkt
+}
+
+// isProbePacket reports whether pkt is a probe request or reply.
+func isProbePacket(pkt []byte) bool {
+	return len(pkt) >= probeHdrLen && pkt[0] == probeMagic && pkt[1] == probeVersion
+}
+
+func decodeProbePacket(pkt []byte) (typ byte, id uint32, sent int64, ok bool) {
+	if !isProbePacket(pkt) {
+		return 0, 0, 0, false
+	}
+	return pkt[2], binary.BigEndian.Uint32(pkt[4:8]), int64(binary.BigEndian.Uint64(pkt[8:16])), true
+}
+
+// probeEchoReply turns a probe request into its reply, or returns nil if
+// pkt is not a request.
+func probeEchoReply(pkt []byte) []byte {
+	typ, _, _, ok := decodeProbePacket(pkt)
+	if !ok || typ != probeRequest {
+		return nil
+	}
+	reply := append([]byte(nil), pkt[:probeHdrLen]...)
+	reply[2] = probeReply
+	return reply
+}
+
+// probeSettings returns the effective probe interval and detect multiplier
+// of a path; a zero interval disables probing.
+func probeSettings(p MultipathPathConfig, cfg *Config) (time.Duration, int) {
+	intervalMs := p.ProbeIntervalMs
+	if intervalMs == 0 {
+		intervalMs = cfg.ProbeIntervalMs
+	}
+	if intervalMs <= 0 {
+		return 0, 0
+	}
+	mult := p.ProbeDetectMultiplier
+	if mult <= 0 {
+		mult = cfg.ProbeDetectMultiplier
+	}
+	if mult <= 0 {
+		mult = defaultProbeDetectMultiplier
+	}
+	return time.Duration(intervalMs) * time.Millisecond, mult
+}
+
+// probeState is the probe session of one path.
+type probeState struct {
+	mu       sync.Mutex
+	interval time.Duration
+	detect   time.Duration // interval × multiplier
+	nextID   uint32
+	pending  map[uint32]time.Time // id → sent
+
+	// Outcome of the last probeLossWindow completed probes (true = lost).
+	outcomes [probeLossWindow]bool
+	outIdx   int
+	outCount int
+
+	srtt      time.Duration
+	jitter    time.Duration
+	lastRTT   time.Duration
+	lastReply time.Time
+	everUp    bool
+	up        bool
+
+	sent, received, lost uint64
+	transitions          uint64
+}
+
+func newProbeState(interval time.Duration, multiplier int) *probeState {
+	return &probeState{
+		interval: interval,
+		detect:   interval * time.Duration(multiplier),
+		pending:  make(map[uint32]time.Time),
+	}
+}
+
+// next registers a new probe sent at now and returns its packet.
+func (s *probeState) next(now time.Time) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.pending[s.nextID] = now
+	s.sent++
+	return encodeProbePacket(probeRequest, s.nextID, now.UnixNano())
+}
+
+func (s *probeState) recordLocked(lost bool) {
+	s.outcomes[s.outIdx] = lost
+	s.outIdx = (s.outIdx + 1) % probeLossWindow
+	if s.outCount < probeLossWindow {
+		s.outCount++
+	}
+}
+
+// onReply folds a reply into the measurements. Returns true if the path
+// went from down (or never up) to up.
+func (s *probeState) onReply(pkt []byte, now time.Time) bool {
+	typ, id, _, ok := decodeProbePacket(pkt)
+	if !ok || typ != probeReply {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sentAt, ok := s.pending[id]
+	if !ok {
+		return false // late reply, already counted as lost
+	}
+	delete(s.pending, id)
+	s.received++
+	s.recordLocked(false)
+
+	rtt := now.Sub(sentAt)
+	if s.srtt == 0 {
+		s.srtt = rtt
+	} else {
+		s.srtt += (rtt - s.srtt) / 8
+		d := rtt - s.lastRTT
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.lastRTT = rtt
+	s.lastReply = now
+
+	wasUp := s.up
+	s.up = true
+	s.everUp = true
+	if !wasUp {
+		s.transitions++
+	}
+	return !wasUp
+}
+
+// expire counts probes unanswered for longer than the detect time as lost
+// and reports whether the path just went down.
+func (s *probeState) expire(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sentAt := range s.pending {
+		if now.Sub(sentAt) > s.detect {
+			delete(s.pending, id)
+			s.lost++
+			s.recordLocked(true)
+		}
+	}
+	if s.up && now.Sub(s.lastReply) > s.detect {
+		s.up = false
+		s.transitions++
+		return true
+	}
+	return false
+}
+
+// down reports whether probing currently holds the path down.
+func (s *probeState) down() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.everUp && !s.up
+}
+
+// probeSnapshot is the metrics view of a probe session.
+type probeSnapshot struct {
+	State    string  `json:"state"` // init | up | down
+	RTTMs    float64 `json:"rtt_ms"`
+	JitterMs float64 `json:"jitter_ms"`
+	LossPct  float64 `json:"loss_pct"`
+	Sent     uint64  `json:"sent"`
+	Received uint64  `json:"received"`
+	Lost     uint64  `json:"lost"`
+}
+
+func (s *probeState) snapshot() probeSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := probeSnapshot{
+		State:    "init",
+		RTTMs:    float64(s.srtt) / float64(time.Millisecond),
+		JitterMs: float64(s.jitter) / float64(time.Millisecond),
+		LossPct:  s.lossLocked() * 100,
+		Sent:     s.sent,
+		Received: s.received,
+		Lost:     s.lost,
+	}
+	switch {
+	case s.up:
+		out.State = "up"
+	case s.everUp:
+		out.State = "down"
+	}
+	return out
+}
+
+// lossLocked returns the loss ratio over the window, 0 until enough probes
+// completed.
+func (s *probeState) lossLocked() float64 {
+	if s.outCount < probeMinSamples {
+		return 0
+	}
+	lost := 0
+	for i := 0; i < s.outCount; i++ {
+		if s.outcomes[i] {
+			lost++
+		}
+	}
+	return float64(lost) / float64(s.outCount)
+}
+
+// measurements returns RTT, jitter and windowed loss for the scheduler;
+// ok is false until the path answered a probe.
+func (s *probeState) measurements() (rtt, jitter time.Duration, loss float64, ok bool) {
+	if s == nil {
+		return 0, 0, 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.everUp {
+		return 0, 0, 0, false
+	}
+	return s.srtt, s.jitter, s.lossLocked(), true
+}
+
+// probeLoop sends a probe on path idx every interval and applies up/down
+// transitions. It exits when the path's context ends.
+func (m *multipathConn) probeLoop(ctx context.Context, idx int, st *probeState) {
+	ticker := time.NewTicker(st.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if st.expire(now) {
+				m.onProbeDown(idx, st)
+			}
+
+			m.mu.RLock()
+			p := m.paths[idx]
+			dc, stripe := p.dc, p.stripeConn
+			m.mu.RUnlock()
+			if dc == nil {
+				continue
+			}
+			pkt := st.next(now)
+			if stripe != nil {
+				_ = stripe.sendProbe(pkt)
+			} else {
+				_ = dc.SendDatagram(pkt)
+			}
+		}
+	}
+}
+
+// onProbeReply handles a probe reply received on path idx.
+func (m *multipathConn) onProbeReply(idx int, pkt []byte) {
+	m.mu.RLock()
+	st := m.paths[idx].probe
+	name := m.paths[idx].cfg.Name
+	m.mu.RUnlock()
+	if st == nil || !st.onReply(pkt, time.Now()) {
+		return
+	}
+	snap := st.snapshot()
+	m.logger.Infof("probe path up name=%s rtt=%.1fms", name, snap.RTTMs)
+}
+
+func (m *multipathConn) onProbeDown(idx int, st *probeState) {
+	m.mu.RLock()
+	name := m.paths[idx].cfg.Name
+	m.mu.RUnlock()
+	m.logger.Errorf("probe path down name=%s no reply for %s", name, st.detect)
+}
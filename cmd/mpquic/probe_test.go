@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbePacket_EchoRoundTrip(t *testing.T) {
+	req := encodeProbePacket(probeRequest, 42, 123456789)
+	if req[0]>>4 == 4 || req[0]>>4 == 6 {
+		t.Fatal("probe packet looks like an IP packet")
+	}
+	reply := probeEchoReply(req)
+	typ, id, sent, ok := decodeProbePacket(reply)
+	if !ok || typ != probeReply || id != 42 || sent != 123456789 {
+		t.Fatalf("reply = typ %d id %d sent %d ok %t", typ, id, sent, ok)
+	}
+	if probeEchoReply(reply) != nil {
+		t.Error("a reply must not be echoed again")
+	}
+	if isProbePacket(ipv4Packet(17, udpDatagram([]byte("x")))) {
+		t.Error("IPv4 packet classified as probe")
+	}
+}
+
+// echo feeds s the reply to probe pkt, received at at.
+func echo(t *testing.T, s *probeState, pkt []byte, at time.Time) bool {
+	t.Helper()
+	return s.onReply(probeEchoReply(pkt), at)
+}
+
+func TestProbeState_UpDownWithinDetectTime(t *testing.T) {
+	s := newProbeState(100*time.Millisecond, 3)
+	start := time.Now()
+
+	// No reply ever: the path is never held down.
+	s.next(start)
+	if s.expire(start.Add(time.Second)) || s.down() {
+		t.Fatal("path without any probe reply marked down")
+	}
+
+	pkt := s.next(start.Add(time.Second))
+	if !echo(t, s, pkt, start.Add(time.Second+20*time.Millisecond)) {
+		t.Fatal("first reply did not bring the path up")
+	}
+	if s.down() {
+		t.Fatal("path down after a reply")
+	}
+
+	// Silent for less than interval × multiplier: still up.
+	last := start.Add(time.Second + 20*time.Millisecond)
+	if s.expire(last.Add(250 * time.Millisecond)) {
+		t.Fatal("path down before the detect time")
+	}
+	if !s.expire(last.Add(301*time.Millisecond)) || !s.down() {
+		t.Fatal("path not down after the detect time")
+	}
+
+	// The next reply brings it back.
+	pkt = s.next(last.Add(400 * time.Millisecond))
+	if !echo(t, s, pkt, last.Add(420*time.Millisecond)) || s.down() {
+		t.Fatal("path not up again after a reply")
+	}
+}
+
+func TestProbeState_RTTJitterLoss(t *testing.T) {
+	s := newProbeState(50*time.Millisecond, 3)
+	now := time.Now()
+	rtts := []time.Duration{20, 40, 20, 40, 20, 40, 20, 40}
+	for _, rtt := range rtts {
+		pkt := s.next(now)
+		echo(t, s, pkt, now.Add(rtt*time.Millisecond))
+		now = now.Add(50 * time.Millisecond)
+	}
+	// Two probes lost.
+	s.next(now)
+	s.next(now)
+	s.expire(now.Add(time.Second))
+
+	snap := s.snapshot()
+	if snap.RTTMs < 20 || snap.RTTMs > 40 {
+		t.Errorf("rtt = %.1f ms, want between 20 and 40", snap.RTTMs)
+	}
+	if snap.JitterMs <= 0 {
+		t.Errorf("jitter = %.1f ms, want > 0 for alternating RTTs", snap.JitterMs)
+	}
+	if snap.LossPct != 20 {
+		t.Errorf("loss = %.1f%%, want 20%% (2 of 10)", snap.LossPct)
+	}
+	if snap.Sent != 10 || snap.Received != 8 || snap.Lost != 2 {
+		t.Errorf("counters = %+v", snap)
+	}
+
+	// A reply arriving after its probe expired is ignored.
+	late := s.next(now)
+	s.expire(now.Add(time.Second))
+	if echo(t, s, late, now.Add(2*time.Second)) || s.snapshot().Received != 8 {
+		t.Error("late reply counted")
+	}
+}
+
+func TestProbeSettings_PathOverrides(t *testing.T) {
+	cfg := &Config{ProbeIntervalMs: 200, ProbeDetectMultiplier: 3}
+	if iv, mult := probeSettings(MultipathPathConfig{}, cfg); iv != 200*time.Millisecond || mult != 3 {
+		t.Errorf("inherited = %s ×%d", iv, mult)
+	}
+	if iv, mult := probeSettings(MultipathPathConfig{ProbeIntervalMs: 50, ProbeDetectMultiplier: 5}, cfg); iv != 50*time.Millisecond || mult != 5 {
+		t.Errorf("override = %s ×%d", iv, mult)
+	}
+	if iv, _ := probeSettings(MultipathPathConfig{ProbeIntervalMs: -1}, cfg); iv != 0 {
+		t.Errorf("probe_interval_ms -1 = %s, want disabled", iv)
+	}
+	if iv, _ := probeSettings(MultipathPathConfig{}, &Config{}); iv != 0 {
+		t.Errorf("default = %s, want disabled", iv)
+	}
+}
+
+func TestSelectBestPath_SkipsProbeDownPath(t *testing.T) {
+	m, _ := newFlowTestConn("priority", 2)
+	m.paths[0].cfg.Priority = 0 // preferred
+	now := time.Now()
+	st := newProbeState(100*time.Millisecond, 3)
+	st.onReply(probeEchoReply(st.next(now)), now)
+	m.paths[0].probe = st
+
+	if idx, _ := m.selectBestPath(DataplaneClassPolicy{}, nil); idx != 0 {
+		t.Fatalf("best path = %d, want 0 while probes answer", idx)
+	}
+	st.expire(now.Add(time.Second))
+	if idx, _ := m.selectBestPath(DataplaneClassPolicy{}, nil); idx != 1 {
+		t.Fatalf("best path = %d, want 1 while path 0 is probe-down", idx)
+	}
+}
+
+func TestRefreshQuality_StripeUsesProbeRTT(t *testing.T) {
+	now := time.Now()
+	st := newProbeState(100*time.Millisecond, 3)
+	st.onReply(probeEchoReply(st.next(now)), now.Add(35*time.Millisecond))
+	p := &multipathPathState{stripeConn: &stripeClientConn{}, probe: st}
+
+	refreshQuality(p, now)
+	if p.quality.srtt != 35*time.Millisecond {
+		t.Errorf("srtt = %s, want the probe RTT", p.quality.srtt)
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// ─── qlog traces ──────────────────────────────────────────────────────────
+//
+// With qlog_dir set, every QUIC connection of the tunnel (path connections,
+// the shared quic-mp connection, stripe key exchanges, the server's
+// accepted connections) gets a switchable tracer. While a connection is
+// traced its qlog events go to <qlog_dir>/<name>_<label>_<odcid>_<start>_<n>.sqlog,
+// ready to be loaded into qvis:
+//
+//   - name is the path name ("tunnel" for a single-path client, "quic-mp"
+//     for the shared quic-mp connection, "server" on the server), label the
+//     role of the connection ("client", "server" or "kx").
+//   - a file is rotated after qlog_max_file_mb; each part starts with the
+//     trace header so it loads on its own.
+//   - when the directory grows beyond qlog_max_dir_mb the oldest closed
+//     files are deleted.
+//
+// qlog_enabled traces everything from the start, a path's qlog: true only
+// that path. On the client POST /paths/{name}/qlog switches the tracing of
+// a path at runtime, including its live connection: a trace switched on
+// mid-connection starts with the next event, without the handshake.
+
+const (
+	defaultQlogMaxFileMB = 16
+	defaultQlogMaxDirMB  = 256
+
+	qlogFileExt = ".sqlog"
+	// qlogQUICMPName traces the connection shared by the quic-mp paths:
+	// switching any quic-mp path switches it.
+	qlogQUICMPName = "quic-mp"
+	// records of JSON text sequences (RFC 7464) start with this byte
+	qlogRecordSeparator = 0x1e
+)
+
+type qlogTracerFunc = func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer
+
+// qlogTraces owns the qlog directory and the tracing state of the paths.
+// A nil *qlogTraces (qlog_dir unset) traces nothing.
+type qlogTraces struct {
+	dir     string
+	maxFile int64
+	maxDir  int64
+	all     bool // qlog_enabled
+	logger  *Logger
+
+	mu        sync.Mutex
+	overrides map[string]bool // set at runtime, by name
+	conns     map[*qlogConn]struct{}
+	open      map[string]struct{} // files being written
+}
+
+func newQlogTraces(cfg *Config, logger *Logger) (*qlogTraces, error) {
+	if cfg.QlogDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.QlogDir, 0o755); err != nil {
+		return nil, fmt.Errorf("qlog_dir: %w", err)
+	}
+	return &qlogTraces{
+		dir:       cfg.QlogDir,
+		maxFile:   int64(cfg.QlogMaxFileMB) << 20,
+		maxDir:    int64(cfg.QlogMaxDirMB) << 20,
+		all:       cfg.QlogEnabled,
+		logger:    logger,
+		overrides: make(map[string]bool),
+		conns:     make(map[*qlogConn]struct{}),
+		open:      make(map[string]struct{}),
+	}, nil
+}
+
+// qlogPathName is the name a path's connection is traced under.
+func qlogPathName(p MultipathPathConfig) string {
+	if p.Transport == "quic-mp" {
+		return qlogQUICMPName
+	}
+	return p.Name
+}
+
+// tracer returns a quic.Config.Tracer that records the connection as name,
+// together with next (may be nil). traced is the configured state, used
+// until tracing is switched at runtime.
+func (q *qlogTraces) tracer(name, label string, traced bool, next qlogTracerFunc) qlogTracerFunc {
+	if q == nil {
+		return next
+	}
+	return func(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+		c := &qlogConn{q: q, name: name, label: label, perspective: p, odcid: connID}
+		q.mu.Lock()
+		on, ok := q.overrides[name]
+		if !ok {
+			on = traced || q.all
+		}
+		q.conns[c] = struct{}{}
+		q.mu.Unlock()
+		if on {
+			c.start()
+		}
+		t := c.connectionTracer()
+		if next != nil {
+			if n := next(ctx, p, connID); n != nil {
+				return logging.NewMultiplexedConnectionTracer(t, n)
+			}
+		}
+		return t
+	}
+}
+
+// set switches tracing of the connections named name, current and future.
+func (q *qlogTraces) set(name string, on bool) {
+	q.mu.Lock()
+	q.overrides[name] = on
+	var conns []*qlogConn
+	for c := range q.conns {
+		if c.name == name {
+			conns = append(conns, c)
+		}
+	}
+	q.mu.Unlock()
+
+	// Stopping waits for the qlog writer, which may prune the directory
+	// under q.mu.
+	for _, c := range conns {
+		if on {
+			c.start()
+		} else {
+			c.stop()
+		}
+	}
+	q.logger.Infof("qlog switched name=%s enabled=%v connections=%d", name, on, len(conns))
+}
+
+// tracing reports whether the connections named name are traced.
+func (q *qlogTraces) tracing(name string, traced bool) bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if on, ok := q.overrides[name]; ok {
+		return on
+	}
+	return traced || q.all
+}
+
+func (q *qlogTraces) remove(c *qlogConn) {
+	q.mu.Lock()
+	delete(q.conns, c)
+	q.mu.Unlock()
+}
+
+// create opens a new trace file and makes room for it in the directory.
+func (q *qlogTraces) create(name string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(q.dir, name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	q.open[name] = struct{}{}
+	q.mu.Unlock()
+	q.prune()
+	return f, nil
+}
+
+func (q *qlogTraces) release(name string) {
+	q.mu.Lock()
+	delete(q.open, name)
+	q.mu.Unlock()
+}
+
+// prune deletes the oldest trace files not being written while the
+// directory holds more than maxDir bytes.
+func (q *qlogTraces) prune() {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return
+	}
+	type traceFile struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var files []traceFile
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), qlogFileExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, traceFile{e.Name(), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	if total <= q.maxDir {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range files {
+		if total <= q.maxDir {
+			break
+		}
+		if _, busy := q.open[f.name]; busy {
+			continue
+		}
+		if err := os.Remove(filepath.Join(q.dir, f.name)); err == nil {
+			total -= f.size
+		}
+	}
+}
+
+// qlogFileName sanitizes s for use in a file name.
+func qlogFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// qlogFile is the io.WriteCloser of one trace, rotated by size. It is
+// written by the goroutine of the qlog writer only.
+type qlogFile struct {
+	q      *qlogTraces
+	prefix string
+	part   int
+	name   string
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	header []byte // first record: the trace header, repeated in every part
+}
+
+func newQlogFile(q *qlogTraces, prefix string) (*qlogFile, error) {
+	qf := &qlogFile{q: q, prefix: prefix}
+	if err := qf.openPart(); err != nil {
+		return nil, err
+	}
+	return qf, nil
+}
+
+func (qf *qlogFile) openPart() error {
+	qf.name = fmt.Sprintf("%s_%03d%s", qf.prefix, qf.part, qlogFileExt)
+	f, err := qf.q.create(qf.name)
+	if err != nil {
+		return err
+	}
+	qf.f = f
+	qf.w = bufio.NewWriter(f)
+	qf.size = 0
+	return nil
+}
+
+func (qf *qlogFile) closePart() error {
+	err := qf.w.Flush()
+	if cerr := qf.f.Close(); err == nil {
+		err = cerr
+	}
+	qf.q.release(qf.name)
+	return err
+}
+
+// Write rotates at a record boundary once the part is full. The qlog
+// writer starts every record with its own Write of the separator.
+func (qf *qlogFile) Write(p []byte) (int, error) {
+	switch {
+	case qf.header == nil:
+		qf.header = append([]byte(nil), p...)
+	case len(p) > 0 && p[0] == qlogRecordSeparator && qf.size >= qf.q.maxFile:
+		if err := qf.closePart(); err != nil {
+			return 0, err
+		}
+		qf.part++
+		if err := qf.openPart(); err != nil {
+			return 0, err
+		}
+		n, err := qf.w.Write(qf.header)
+		qf.size += int64(n)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := qf.w.Write(p)
+	qf.size += int64(n)
+	return n, err
+}
+
+func (qf *qlogFile) Close() error {
+	return qf.closePart()
+}
+
+// qlogConn is the switchable tracer of one connection: events go to the
+// qlog tracer cur while tracing is on.
+type qlogConn struct {
+	q           *qlogTraces
+	name, label string
+	perspective logging.Perspective
+	odcid       quic.ConnectionID
+
+	on     atomic.Bool // fast path for events while not traced
+	mu     sync.RWMutex
+	cur    *logging.ConnectionTracer
+	closed bool
+}
+
+func (c *qlogConn) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.cur != nil {
+		return
+	}
+	prefix := fmt.Sprintf("%s_%s_%s_%s", qlogFileName(c.name), c.label, c.odcid, time.Now().Format("20060102T150405"))
+	f, err := newQlogFile(c.q, prefix)
+	if err != nil {
+		c.q.logger.Errorf("qlog create failed name=%s err=%v", c.name, err)
+		return
+	}
+	c.cur = qlog.NewConnectionTracer(f, c.perspective, c.odcid)
+	c.on.Store(true)
+}
+
+func (c *qlogConn) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.on.Store(false)
+	if c.cur != nil {
+		c.cur.Close()
+		c.cur = nil
+	}
+}
+
+func (c *qlogConn) close() {
+	c.stop()
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.q.remove(c)
+}
+
+// with calls f with the qlog tracer while the connection is traced.
+func (c *qlogConn) with(f func(t *logging.ConnectionTracer)) {
+	if !c.on.Load() {
+		return
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cur != nil {
+		f(c.cur)
+	}
+}
+
+// connectionTracer forwards every event to the qlog tracer, which sets all
+// of them.
+func (c *qlogConn) connectionTracer() *logging.ConnectionTracer {
+	return &logging.ConnectionTracer{
+		StartedConnection: func(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+			c.with(func(t *logging.ConnectionTracer) { t.StartedConnection(local, remote, srcConnID, destConnID) })
+		},
+		NegotiatedVersion: func(chosen logging.Version, clientVersions, serverVersions []logging.Version) {
+			c.with(func(t *logging.ConnectionTracer) { t.NegotiatedVersion(chosen, clientVersions, serverVersions) })
+		},
+		ClosedConnection: func(e error) {
+			c.with(func(t *logging.ConnectionTracer) { t.ClosedConnection(e) })
+		},
+		SentTransportParameters: func(tp *logging.TransportParameters) {
+			c.with(func(t *logging.ConnectionTracer) { t.SentTransportParameters(tp) })
+		},
+		ReceivedTransportParameters: func(tp *logging.TransportParameters) {
+			c.with(func(t *logging.ConnectionTracer) { t.ReceivedTransportParameters(tp) })
+		},
+		RestoredTransportParameters: func(tp *logging.TransportParameters) {
+			c.with(func(t *logging.ConnectionTracer) { t.RestoredTransportParameters(tp) })
+		},
+		SentLongHeaderPacket: func(hdr *logging.ExtendedHeader, size logging.ByteCount, ecn logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			c.with(func(t *logging.ConnectionTracer) { t.SentLongHeaderPacket(hdr, size, ecn, ack, frames) })
+		},
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			c.with(func(t *logging.ConnectionTracer) { t.SentShortHeaderPacket(hdr, size, ecn, ack, frames) })
+		},
+		ReceivedVersionNegotiationPacket: func(dest, src logging.ArbitraryLenConnectionID, versions []logging.Version) {
+			c.with(func(t *logging.ConnectionTracer) { t.ReceivedVersionNegotiationPacket(dest, src, versions) })
+		},
+		ReceivedRetry: func(hdr *logging.Header) {
+			c.with(func(t *logging.ConnectionTracer) { t.ReceivedRetry(hdr) })
+		},
+		ReceivedLongHeaderPacket: func(hdr *logging.ExtendedHeader, size logging.ByteCount, ecn logging.ECN, frames []logging.Frame) {
+			c.with(func(t *logging.ConnectionTracer) { t.ReceivedLongHeaderPacket(hdr, size, ecn, frames) })
+		},
+		ReceivedShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, frames []logging.Frame) {
+			c.with(func(t *logging.ConnectionTracer) { t.ReceivedShortHeaderPacket(hdr, size, ecn, frames) })
+		},
+		BufferedPacket: func(pt logging.PacketType, size logging.ByteCount) {
+			c.with(func(t *logging.ConnectionTracer) { t.BufferedPacket(pt, size) })
+		},
+		DroppedPacket: func(pt logging.PacketType, pn logging.PacketNumber, size logging.ByteCount, reason logging.PacketDropReason) {
+			c.with(func(t *logging.ConnectionTracer) { t.DroppedPacket(pt, pn, size, reason) })
+		},
+		UpdatedMetrics: func(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedMetrics(rttStats, cwnd, bytesInFlight, packetsInFlight) })
+		},
+		AcknowledgedPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber) {
+			c.with(func(t *logging.ConnectionTracer) {
+				if t.AcknowledgedPacket != nil {
+					t.AcknowledgedPacket(encLevel, pn)
+				}
+			})
+		},
+		LostPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, reason logging.PacketLossReason) {
+			c.with(func(t *logging.ConnectionTracer) { t.LostPacket(encLevel, pn, reason) })
+		},
+		UpdatedMTU: func(mtu logging.ByteCount, done bool) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedMTU(mtu, done) })
+		},
+		UpdatedCongestionState: func(state logging.CongestionState) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedCongestionState(state) })
+		},
+		UpdatedPTOCount: func(value uint32) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedPTOCount(value) })
+		},
+		UpdatedKeyFromTLS: func(encLevel logging.EncryptionLevel, p logging.Perspective) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedKeyFromTLS(encLevel, p) })
+		},
+		UpdatedKey: func(keyPhase logging.KeyPhase, remote bool) {
+			c.with(func(t *logging.ConnectionTracer) { t.UpdatedKey(keyPhase, remote) })
+		},
+		DroppedEncryptionLevel: func(encLevel logging.EncryptionLevel) {
+			c.with(func(t *logging.ConnectionTracer) { t.DroppedEncryptionLevel(encLevel) })
+		},
+		DroppedKey: func(keyPhase logging.KeyPhase) {
+			c.with(func(t *logging.ConnectionTracer) { t.DroppedKey(keyPhase) })
+		},
+		SetLossTimer: func(tt logging.TimerType, encLevel logging.EncryptionLevel, timeout time.Time) {
+			c.with(func(t *logging.ConnectionTracer) { t.SetLossTimer(tt, encLevel, timeout) })
+		},
+		LossTimerExpired: func(tt logging.TimerType, encLevel logging.EncryptionLevel) {
+			c.with(func(t *logging.ConnectionTracer) { t.LossTimerExpired(tt, encLevel) })
+		},
+		LossTimerCanceled: func() {
+			c.with(func(t *logging.ConnectionTracer) { t.LossTimerCanceled() })
+		},
+		ECNStateUpdated: func(state logging.ECNState, trigger logging.ECNStateTrigger) {
+			c.with(func(t *logging.ConnectionTracer) { t.ECNStateUpdated(state, trigger) })
+		},
+		ChoseALPN: func(protocol string) {
+			c.with(func(t *logging.ConnectionTracer) { t.ChoseALPN(protocol) })
+		},
+		Debug: func(name, msg string) {
+			c.with(func(t *logging.ConnectionTracer) { t.Debug(name, msg) })
+		},
+		Close: c.close,
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func newTestQlogTraces(t *testing.T, maxFile, maxDir int64) *qlogTraces {
+	t.Helper()
+	q, err := newQlogTraces(&Config{QlogDir: t.TempDir(), QlogMaxFileMB: 1, QlogMaxDirMB: 1}, newLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.maxFile, q.maxDir = maxFile, maxDir
+	return q
+}
+
+func qlogFiles(t *testing.T, q *qlogTraces) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(q.dir, "*"+qlogFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// writeRecord writes one record the way the qlog writer does.
+func writeRecord(t *testing.T, qf *qlogFile, body string) {
+	t.Helper()
+	for _, p := range [][]byte{{qlogRecordSeparator}, []byte(body), []byte("\n")} {
+		if _, err := qf.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQlogFile_RotatesWithHeader(t *testing.T) {
+	q := newTestQlogTraces(t, 100, 1<<20)
+	qf, err := newQlogFile(q, "wan1_client_0102_x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := append([]byte{qlogRecordSeparator}, `{"qlog_version":"0.3"}`+"\n"...)
+	if _, err := qf.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		writeRecord(t, qf, `{"name":"transport:packet_sent","data":{}}`)
+	}
+	if err := qf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := qlogFiles(t, q)
+	if len(files) < 3 {
+		t.Fatalf("files = %v, want the trace rotated", files)
+	}
+	events := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, header) {
+			t.Fatalf("%s does not start with the trace header", f)
+		}
+		events += bytes.Count(data, []byte("packet_sent"))
+	}
+	if events != 10 {
+		t.Fatalf("events = %d over all parts, want 10", events)
+	}
+	if len(q.open) != 0 {
+		t.Fatalf("open files after close: %v", q.open)
+	}
+}
+
+func TestQlogTraces_PrunesOldestClosedFiles(t *testing.T) {
+	q := newTestQlogTraces(t, 1<<20, 250)
+	old := time.Now().Add(-time.Hour)
+	for i, name := range []string{"a_000.sqlog", "b_000.sqlog", "c_000.sqlog"} {
+		path := filepath.Join(q.dir, name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 100), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.open["a_000.sqlog"] = struct{}{} // oldest, but still being written
+
+	q.prune()
+	var left []string
+	for _, f := range qlogFiles(t, q) {
+		left = append(left, filepath.Base(f))
+	}
+	if strings.Join(left, ",") != "a_000.sqlog,c_000.sqlog" {
+		t.Fatalf("left = %v, want the oldest closed file deleted", left)
+	}
+}
+
+// TestQlogTraces_SwitchLiveConnection switches the trace of a connection on
+// and off while it runs.
+func TestQlogTraces_SwitchLiveConnection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := quic.ListenAddr("127.0.0.1:0", testServerTLSConfig(t), &quic.Config{EnableDatagrams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept(ctx)
+		if err != nil {
+			return
+		}
+		for {
+			pkt, err := conn.ReceiveDatagram(ctx)
+			if err != nil {
+				return
+			}
+			_ = conn.SendDatagram(pkt)
+		}
+	}()
+
+	q := newTestQlogTraces(t, 1<<20, 1<<20)
+	conn, err := quic.DialAddr(ctx, ln.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"mpquic-ip"}}, &quic.Config{
+		EnableDatagrams: true,
+		Tracer:          q.tracer("wan1", "client", false, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+	ping := func() {
+		t.Helper()
+		if err := conn.SendDatagram([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.ReceiveDatagram(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ping()
+	if files := qlogFiles(t, q); len(files) != 0 || q.tracing("wan1", false) {
+		t.Fatalf("traced before switching on: %v", files)
+	}
+
+	q.set("wan1", true)
+	ping()
+	q.set("wan1", false)
+	files := qlogFiles(t, q)
+	if len(files) != 1 || !strings.HasPrefix(filepath.Base(files[0]), "wan1_client_") {
+		t.Fatalf("files = %v, want one wan1 trace", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("qlog_version")) || !bytes.Contains(data, []byte("packet_sent")) {
+		t.Fatalf("trace has no header or no packets: %q", data)
+	}
+
+	// Switched off: nothing more is written.
+	ping()
+	after, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(data) {
+		t.Fatal("trace grew after switching off")
+	}
+}
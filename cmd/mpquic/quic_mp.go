@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ─── Native QUIC multipath (transport: quic-mp) ───────────────────────────
+//
+// Paths with transport quic-mp share a single QUIC connection using the
+// multipath extension of local-quic-go (draft-ietf-quic-multipath). The
+// first quic-mp path to come up dials the connection and carries QUIC path
+// 0; every other quic-mp path binds its own UDP socket and opens an
+// additional QUIC path on it. There is one handshake for all of them, and
+// each path has its own packet number space, ACKs, RTT estimate and
+// congestion controller.
+//
+// Additional paths only carry datagrams: transport_mode reliable is not
+// supported. Connection-level frames (stream data, CONNECTION_CLOSE, ...)
+// travel on path 0, so losing the path that dialled closes the connection
+// and all quic-mp paths reconnect; the first one to do so dials again.
+//
+// The server accepts quic-mp paths on the multi-conn listener and adds each
+// one to the peer's connGroup like a separate connection.
+
+// quicMPMaxPaths is the number of QUIC paths a quic-mp connection may use,
+// including path 0. Both client and server offer it.
+const quicMPMaxPaths = 8
+
+// quicMPOpenTimeout bounds the handshake or path validation of one path.
+const quicMPOpenTimeout = 8 * time.Second
+
+// validateQUICMPPaths checks the quic-mp paths of a client: they must reach
+// the same server endpoint, fit in quicMPMaxPaths once pipes are expanded,
+// and can only be used with transport_mode datagram.
+func validateQUICMPPaths(cfg *Config) error {
+	var first *MultipathPathConfig
+	count := 0
+	for i := range cfg.MultipathPaths {
+		p := &cfg.MultipathPaths[i]
+		if p.Transport != "quic-mp" {
+			continue
+		}
+		if p.CongestionAlgorithm != "" && p.CongestionAlgorithm != cfg.CongestionAlgorithm {
+			// all quic-mp paths belong to one connection, configured once
+			return fmt.Errorf("multipath_paths[%d]: quic-mp paths use the global congestion_algorithm", i)
+		}
+		if first == nil {
+			first = p
+		} else if p.RemoteAddr != first.RemoteAddr || p.RemotePort != first.RemotePort {
+			return fmt.Errorf("multipath_paths[%d]: quic-mp paths must share remote_addr/remote_port with %s", i, first.Name)
+		}
+		count += max(p.Pipes, 1)
+	}
+	if first == nil {
+		return nil
+	}
+	if count > quicMPMaxPaths {
+		return fmt.Errorf("quic-mp supports at most %d paths (pipes included), got %d", quicMPMaxPaths, count)
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.TransportMode), "reliable") {
+		return fmt.Errorf("transport quic-mp requires transport_mode datagram")
+	}
+	return nil
+}
+
+// quicMPSession is the connection shared by the quic-mp paths of a client.
+type quicMPSession struct {
+	mu   sync.Mutex // serialises dials and path opens
+	conn quic.Connection
+	qlog *qlogTraces
+}
+
+// quicMPLink is one quic-mp path, either the dialled connection (path 0)
+// or an additional QUIC path.
+type quicMPLink struct {
+	udpConn   *net.UDPConn
+	transport *quic.Transport
+	conn      quic.Connection // set for the path that dialled the connection
+	path      *quic.Path      // set for additional paths
+	qstats    *quicPathStats  // connection stats, only for path 0
+}
+
+func (l *quicMPLink) dc() datagramConn {
+	if l.path != nil {
+		return l.path
+	}
+	return l.conn
+}
+
+func (l *quicMPLink) pathID() quic.PathID {
+	if l.path != nil {
+		return l.path.ID()
+	}
+	return 0
+}
+
+// connect brings up quic-mp path p: it dials the shared connection if
+// there is none (or it is gone), and opens an additional path otherwise.
+func (s *quicMPSession) connect(ctx context.Context, cfg *Config, p MultipathPathConfig) (*quicMPLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bindIP, err := resolveBindIP(p.BindIP)
+	if err != nil {
+		return nil, fmt.Errorf("bind-resolve: %w", err)
+	}
+	remoteUDP, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(p.RemoteAddr, fmt.Sprintf("%d", p.RemotePort)))
+	if err != nil {
+		return nil, fmt.Errorf("remote-resolve: %w", err)
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(bindIP), Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	link := &quicMPLink{udpConn: udpConn, transport: &quic.Transport{Conn: udpConn}}
+
+	openCtx, cancel := context.WithTimeout(ctx, quicMPOpenTimeout)
+	defer cancel()
+
+	if s.conn != nil && s.conn.Context().Err() == nil {
+		if s.conn.RemoteAddr().String() != remoteUDP.String() {
+			_ = udpConn.Close()
+			return nil, fmt.Errorf("remote %s differs from the quic-mp connection's %s", remoteUDP, s.conn.RemoteAddr())
+		}
+		path, err := s.conn.(quic.MultipathConnection).OpenPath(openCtx, link.transport)
+		if err != nil {
+			_ = udpConn.Close()
+			return nil, fmt.Errorf("open-path: %w", err)
+		}
+		link.path = path
+		return link, nil
+	}
+
+	tlsConf, err := loadClientTLSConfig(cfg)
+	if err != nil {
+		_ = udpConn.Close()
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	link.qstats = &quicPathStats{}
+	// No 0-RTT: the other paths are opened on this connection right away,
+	// which needs a completed handshake.
+	// With lia the paths of the connection are coupled in one group.
+	conn, err := link.transport.Dial(openCtx, remoteUDP, resumableTLSConfig(tlsConf, remoteUDP), withCongestionControl(&quic.Config{
+		EnableDatagrams: true,
+		KeepAlivePeriod: 15 * time.Second,
+		MaxIdleTimeout:  60 * time.Second,
+		Tracer:          s.qlog.tracer(qlogQUICMPName, "client", p.Qlog, newQUICPathTracer(link.qstats)),
+		MaxPaths:        quicMPMaxPaths,
+	}, cfg.CongestionAlgorithm, newLIAGroup().newSender))
+	if err != nil {
+		_ = udpConn.Close()
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	link.conn = conn
+	s.conn = conn
+	return link, nil
+}
+
+// connectQUICMP brings up quic-mp path idx and installs it in its slot.
+func (m *multipathConn) connectQUICMP(ctx context.Context, idx int, pcfg MultipathPathConfig) error {
+	link, err := m.quicMP.connect(ctx, m.cfg, pcfg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	p := m.paths[idx]
+	if p.removed {
+		m.mu.Unlock()
+		link.close("path-removed")
+		return nil
+	}
+	p.udpConn = link.udpConn
+	p.transport = link.transport
+	p.conn = link.conn
+	p.mpPath = link.path
+	p.dc = link.dc()
+	p.quicStats = link.qstats
+	p.quality.reset()
+	p.alive = true
+	p.reconnecting = false
+	p.lastUp = time.Now()
+	if p.consecutiveFails > 0 {
+		p.consecutiveFails--
+	}
+	m.mu.Unlock()
+
+	m.logger.Infof("quic-mp path up name=%s path_id=%d local=%s remote=%s", pcfg.Name, link.pathID(), link.udpConn.LocalAddr(), link.remoteAddr())
+	return nil
+}
+
+func (l *quicMPLink) remoteAddr() net.Addr {
+	if l.path != nil {
+		return l.path.RemoteAddr()
+	}
+	return l.conn.RemoteAddr()
+}
+
+func (l *quicMPLink) close(reason string) {
+	if l.path != nil {
+		_ = l.path.Close()
+	}
+	if l.conn != nil {
+		_ = l.conn.CloseWithError(0, reason)
+	}
+	_ = l.udpConn.Close()
+}
+
+// ─── Server side ──────────────────────────────────────────────────────────
+
+// acceptQUICMPPaths serves the additional paths of a quic-mp connection
+// until it closes. Connections from clients without quic-mp paths return
+// at once.
+func acceptQUICMPPaths(ctx context.Context, conn quic.Connection, serve func(*quic.Path)) {
+	mc, ok := conn.(quic.MultipathConnection)
+	if !ok {
+		return
+	}
+	for {
+		path, err := mc.AcceptPath(ctx)
+		if err != nil {
+			return
+		}
+		go serve(path)
+	}
+}
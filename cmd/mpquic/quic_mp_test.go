@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestValidateQUICMPPaths(t *testing.T) {
+	mpPath := func(name, remote string, pipes int) MultipathPathConfig {
+		return MultipathPathConfig{Name: name, RemoteAddr: remote, RemotePort: 45000, Pipes: pipes, Transport: "quic-mp"}
+	}
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "no quic-mp paths", cfg: Config{MultipathPaths: []MultipathPathConfig{{Name: "a", RemoteAddr: "x"}}}},
+		{name: "shared endpoint", cfg: Config{MultipathPaths: []MultipathPathConfig{
+			mpPath("a", "10.0.0.1", 0), mpPath("b", "10.0.0.1", 2), {Name: "c", RemoteAddr: "10.0.0.2", Transport: "stripe"},
+		}}},
+		{name: "different endpoint", cfg: Config{MultipathPaths: []MultipathPathConfig{
+			mpPath("a", "10.0.0.1", 0), mpPath("b", "10.0.0.2", 0),
+		}}, wantErr: "share remote_addr"},
+		{name: "too many pipes", cfg: Config{MultipathPaths: []MultipathPathConfig{
+			mpPath("a", "10.0.0.1", 4), mpPath("b", "10.0.0.1", 5),
+		}}, wantErr: "at most 8"},
+		{name: "reliable", cfg: Config{TransportMode: "Reliable", MultipathPaths: []MultipathPathConfig{
+			mpPath("a", "10.0.0.1", 0),
+		}}, wantErr: "transport_mode datagram"},
+		{name: "per-path congestion algorithm", cfg: Config{CongestionAlgorithm: "cubic", MultipathPaths: []MultipathPathConfig{
+			mpPath("a", "10.0.0.1", 0), {Name: "b", RemoteAddr: "10.0.0.1", RemotePort: 45000, Transport: "quic-mp", CongestionAlgorithm: "bbr3"},
+		}}, wantErr: "global congestion_algorithm"},
+	}
+	for _, tc := range tests {
+		err := validateQUICMPPaths(&tc.cfg)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: error = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNormalizeMultipathPath_Transport(t *testing.T) {
+	p := MultipathPathConfig{BindIP: "127.0.0.1", RemoteAddr: "x", RemotePort: 1, Transport: " QUIC-MP "}
+	if err := normalizeMultipathPath(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Transport != "quic-mp" {
+		t.Fatalf("transport = %q, want quic-mp", p.Transport)
+	}
+	p.Transport = "tcp"
+	if err := normalizeMultipathPath(&p); err == nil {
+		t.Fatal("expected an error for transport tcp")
+	}
+}
+
+func TestNormalizeMultipathPath_CongestionAlgorithm(t *testing.T) {
+	cfg := &Config{CongestionAlgorithm: "cubic"}
+	p := MultipathPathConfig{BindIP: "127.0.0.1", RemoteAddr: "x", RemotePort: 1}
+	if err := normalizeMultipathPath(&p); err != nil {
+		t.Fatal(err)
+	}
+	if algo := pathCongestionAlgorithm(p, cfg); algo != "cubic" {
+		t.Fatalf("inherited = %q, want cubic", algo)
+	}
+	p.CongestionAlgorithm = " BBR3 "
+	if err := normalizeMultipathPath(&p); err != nil {
+		t.Fatal(err)
+	}
+	if algo := pathCongestionAlgorithm(p, cfg); algo != "bbr3" {
+		t.Fatalf("override = %q, want bbr3", algo)
+	}
+	p.CongestionAlgorithm = "reno"
+	if err := normalizeMultipathPath(&p); err == nil {
+		t.Fatal("expected an error for congestion_algorithm reno")
+	}
+}
+
+func testServerTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"mpquic-ip"},
+	}
+}
+
+// TestQUICMPSession_SharesConnection brings up two quic-mp paths against a
+// local listener: the first dials, the second opens a new QUIC path on its own
+// socket, and datagrams sent on it come back on the same path.
+func TestQUICMPSession_SharesConnection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := quic.ListenAddr("127.0.0.1:0", testServerTLSConfig(t), &quic.Config{EnableDatagrams: true, MaxPaths: quicMPMaxPaths})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept(ctx)
+		if err != nil {
+			return
+		}
+		acceptQUICMPPaths(ctx, conn, func(path *quic.Path) {
+			for {
+				pkt, err := path.ReceiveDatagram(ctx)
+				if err != nil {
+					return
+				}
+				_ = path.SendDatagram(pkt)
+			}
+		})
+	}()
+
+	cfg := &Config{TLSInsecureSkipVerify: true}
+	port := ln.Addr().(*net.UDPAddr).Port
+	pathCfg := func(name string) MultipathPathConfig {
+		return MultipathPathConfig{Name: name, BindIP: "127.0.0.1", RemoteAddr: "127.0.0.1", RemotePort: port, Transport: "quic-mp"}
+	}
+
+	var s quicMPSession
+	first, err := s.connect(ctx, cfg, pathCfg("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.close("done")
+	if first.conn == nil || first.path != nil || first.pathID() != 0 {
+		t.Fatalf("first link should dial the connection: %+v", first)
+	}
+
+	second, err := s.connect(ctx, cfg, pathCfg("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.close("done")
+	if second.path == nil || second.pathID() == 0 {
+		t.Fatalf("second link should open an additional QUIC path, got path %d", second.pathID())
+	}
+	if second.udpConn.LocalAddr().String() == first.udpConn.LocalAddr().String() {
+		t.Fatal("paths share a socket")
+	}
+
+	if err := second.dc().SendDatagram([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := second.dc().ReceiveDatagram(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pkt) != "ping" {
+		t.Fatalf("echo = %q", pkt)
+	}
+
+	// A path to another endpoint cannot join the connection.
+	other := pathCfg("c")
+	other.RemotePort = port + 1
+	if _, err := s.connect(ctx, cfg, other); err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("err = %v, want remote mismatch", err)
+	}
+
+	// Once the connection is gone the next path dials again.
+	_ = first.conn.CloseWithError(0, "test")
+	<-second.path.Context().Done()
+	third, err := s.connect(ctx, cfg, pathCfg("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.close("done")
+	if third.conn == nil {
+		t.Fatal("expected a new connection after the old one closed")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 // ─── pathPolicyScore tests ────────────────────────────────────────────────
@@ -94,6 +95,98 @@ func TestPathPolicyScore_BalancedFavorsWeight(t *testing.T) {
 	}
 }
 
+// ─── quality-aware policy tests ────────────────────────────────────────────
+
+func TestPathPolicyScore_LowestRTTPrefersFasterPath(t *testing.T) {
+	fibre := &multipathPathState{
+		cfg:     MultipathPathConfig{Priority: 100, Weight: 1},
+		quality: pathQuality{srtt: 20 * time.Millisecond, rttVar: 2 * time.Millisecond},
+	}
+	starlink := &multipathPathState{
+		cfg:     MultipathPathConfig{Priority: 1, Weight: 1},
+		quality: pathQuality{srtt: 600 * time.Millisecond, rttVar: 80 * time.Millisecond},
+	}
+	if pathPolicyScore("lowest_rtt", fibre) >= pathPolicyScore("lowest_rtt", starlink) {
+		t.Error("lowest_rtt should prefer the lower-RTT path regardless of priority")
+	}
+}
+
+func TestPathPolicyScore_LowestRTTPenalisesJitter(t *testing.T) {
+	steady := &multipathPathState{
+		quality: pathQuality{srtt: 40 * time.Millisecond, rttVar: 1 * time.Millisecond},
+	}
+	jittery := &multipathPathState{
+		quality: pathQuality{srtt: 35 * time.Millisecond, rttVar: 20 * time.Millisecond},
+	}
+	if pathPolicyScore("lowest_rtt", steady) >= pathPolicyScore("lowest_rtt", jittery) {
+		t.Error("lowest_rtt should rank a steady path ahead of a jittery one with similar sRTT")
+	}
+}
+
+func TestPathPolicyScore_MinLoss(t *testing.T) {
+	lossy := &multipathPathState{
+		quality: pathQuality{srtt: 20 * time.Millisecond, lossRate: 0.05},
+	}
+	clean := &multipathPathState{
+		quality: pathQuality{srtt: 80 * time.Millisecond, lossRate: 0},
+	}
+	if pathPolicyScore("min_loss", clean) >= pathPolicyScore("min_loss", lossy) {
+		t.Error("min_loss should prefer the path with lower loss")
+	}
+}
+
+func TestPathQualityScore_LatencyBudget(t *testing.T) {
+	primary := &multipathPathState{
+		cfg:     MultipathPathConfig{Priority: 1},
+		quality: pathQuality{srtt: 600 * time.Millisecond},
+	}
+	backup := &multipathPathState{
+		cfg:     MultipathPathConfig{Priority: 10},
+		quality: pathQuality{srtt: 30 * time.Millisecond},
+	}
+	budget := 150 * time.Millisecond
+	if pathQualityScore("latency_budget", backup, budget) >= pathQualityScore("latency_budget", primary, budget) {
+		t.Error("latency_budget should skip a higher-priority path that is over budget")
+	}
+
+	primary.quality.srtt = 40 * time.Millisecond
+	if pathQualityScore("latency_budget", primary, budget) >= pathQualityScore("latency_budget", backup, budget) {
+		t.Error("latency_budget should fall back to priority when all paths are within budget")
+	}
+}
+
+func TestPathQuality_UnknownRTT(t *testing.T) {
+	q := pathQuality{}
+	if q.effectiveRTT() != pathQualityUnknownRTT {
+		t.Errorf("effectiveRTT = %v, want %v for a path without samples", q.effectiveRTT(), pathQualityUnknownRTT)
+	}
+}
+
+func TestRefreshQuality_QUICLossWindow(t *testing.T) {
+	p := &multipathPathState{quicStats: &quicPathStats{}}
+	p.quicStats.smoothedRTT = int64(25 * time.Millisecond)
+	p.quicStats.sentPkts = 100
+	p.quicStats.lostPkts = 10
+
+	refreshQuality(p, time.Now())
+	if p.quality.srtt != 25*time.Millisecond {
+		t.Errorf("srtt = %v, want 25ms", p.quality.srtt)
+	}
+	// first window: 10% loss × alpha
+	want := 0.1 * pathQualityLossAlpha
+	if diff := p.quality.lossRate - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("lossRate = %f, want %f", p.quality.lossRate, want)
+	}
+
+	// Too few packets in the next window: sample is not trusted.
+	p.quicStats.sentPkts = 105
+	p.quicStats.lostPkts = 15
+	refreshQuality(p, time.Now())
+	if diff := p.quality.lossRate - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("lossRate = %f, want unchanged %f", p.quality.lossRate, want)
+	}
+}
+
 // ─── normalizeDataplaneConfig tests ────────────────────────────────────────
 
 func TestNormalizeDataplaneConfig_Defaults(t *testing.T) {
@@ -246,6 +339,72 @@ func TestValidateDataplaneConfig_InvalidPolicy(t *testing.T) {
 	}
 }
 
+func TestValidateDataplaneConfig_QualityPolicies(t *testing.T) {
+	for _, policy := range []string{"lowest_rtt", "min_loss", "latency_budget"} {
+		dp := DataplaneConfig{
+			DefaultClass: "default",
+			Classes: map[string]DataplaneClassPolicy{
+				"default": {SchedulerPolicy: policy, LatencyBudgetMs: 120},
+			},
+		}
+		if err := validateDataplaneConfig(dp, nil); err != nil {
+			t.Errorf("policy %s: unexpected error: %v", policy, err)
+		}
+	}
+}
+
+func TestValidateDataplaneConfig_NegativeLatencyBudget(t *testing.T) {
+	dp := DataplaneConfig{
+		DefaultClass: "default",
+		Classes: map[string]DataplaneClassPolicy{
+			"default": {SchedulerPolicy: "latency_budget", LatencyBudgetMs: -1},
+		},
+	}
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for negative latency_budget_ms")
+	}
+}
+
+func TestValidateDataplaneConfig_ClassRates(t *testing.T) {
+	base := func() DataplaneConfig {
+		return DataplaneConfig{
+			DefaultClass:  "default",
+			TotalRateMbps: 20,
+			Classes: map[string]DataplaneClassPolicy{
+				"default":  {SchedulerPolicy: "priority", MaxRateMbps: 15},
+				"critical": {SchedulerPolicy: "priority", MinRateMbps: 2, BorrowPriority: 1},
+			},
+		}
+	}
+	if err := validateDataplaneConfig(base(), nil); err != nil {
+		t.Fatalf("valid rates rejected: %v", err)
+	}
+
+	dp := base()
+	dp.Classes["critical"] = DataplaneClassPolicy{SchedulerPolicy: "priority", MinRateMbps: 5, MaxRateMbps: 4}
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for min_rate_mbps > max_rate_mbps")
+	}
+
+	dp = base()
+	dp.TotalRateMbps = 0
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for min_rate_mbps without total_rate_mbps")
+	}
+
+	dp = base()
+	dp.Classes["default"] = DataplaneClassPolicy{SchedulerPolicy: "priority", MinRateMbps: 19}
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for guarantees exceeding total_rate_mbps")
+	}
+
+	dp = base()
+	dp.Classes["default"] = DataplaneClassPolicy{SchedulerPolicy: "priority", BurstBytes: -1}
+	if err := validateDataplaneConfig(dp, nil); err == nil {
+		t.Error("expected error for negative burst_bytes")
+	}
+}
+
 func TestValidateDataplaneConfig_UnknownPreferredPath(t *testing.T) {
 	dp := DataplaneConfig{
 		DefaultClass: "default",
@@ -15,28 +15,32 @@ import (
 	"github.com/songgao/water"
 )
 
-// configureTUN applies IP address, MTU and UP state to a TUN device.
+// configureTUN applies IP addresses, MTU and UP state to a TUN device.
+// cidrs may mix IPv4 and IPv6 prefixes (tun_cidr, tun_cidr6).
 // This is called AFTER water.New() to ensure the config is applied even
 // if the library recreated the device (which wipes ensure_tun.sh settings).
-func configureTUN(name, cidr string, mtu int, logger *Logger) error {
-	if cidr == "" {
+func configureTUN(name string, cidrs []string, mtu int, logger *Logger) error {
+	if len(cidrs) == 0 {
 		return nil // nothing to configure (server-side may not have cidr)
 	}
 	if mtu <= 0 {
 		mtu = 1300
 	}
-	cmds := [][]string{
-		{"ip", "addr", "replace", cidr, "dev", name},
-		{"ip", "link", "set", name, "mtu", fmt.Sprintf("%d", mtu)},
-		{"ip", "link", "set", name, "up"},
+	var cmds [][]string
+	for _, cidr := range cidrs {
+		cmds = append(cmds, []string{"ip", "addr", "replace", cidr, "dev", name})
 	}
+	cmds = append(cmds,
+		[]string{"ip", "link", "set", name, "mtu", fmt.Sprintf("%d", mtu)},
+		[]string{"ip", "link", "set", name, "up"},
+	)
 	for _, args := range cmds {
 		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("%s: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
 		}
 	}
-	logger.Infof("TUN %s configured: cidr=%s mtu=%d up=true", name, cidr, mtu)
+	logger.Infof("TUN %s configured: cidr=%s mtu=%d up=true", name, strings.Join(cidrs, ","), mtu)
 	return nil
 }
 
@@ -79,7 +83,7 @@ func openTUN(name string, logger *Logger) (tun *water.Interface, multiQueue bool
 // sending it as the first datagram. The TUN reader dispatches return packets
 // to the correct connection by inspecting the destination IP.
 func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error {
-	bindIP, err := resolveBindIP(cfg.BindIP)
+	bindIPs, err := serverBindIPs(cfg)
 	if err != nil {
 		return err
 	}
@@ -89,12 +93,33 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 		return err
 	}
 	defer tun.Close()
-	if err := configureTUN(cfg.TunName, cfg.TunCIDR, cfg.TunMTU, logger); err != nil {
+	if err := configureTUN(cfg.TunName, cfg.tunCIDRs(), cfg.TunMTU, logger); err != nil {
 		return fmt.Errorf("configure TUN: %w", err)
 	}
 
 	ct := newConnectionTable()
+	ct.bondHold = time.Duration(cfg.BondingReorderHoldMs) * time.Millisecond
+	ct.bondMax = cfg.BondingReorderMax
+	if cfg.Dataplane.Classes != nil {
+		dp, err := compileDataplaneConfig(cfg.Dataplane)
+		if err != nil {
+			return fmt.Errorf("dataplane compile failed: %w", err)
+		}
+		if dp.shaper != nil {
+			ct.dataplane = &dp
+			logger.Infof("class shaping enabled classes=%d total_rate_mbps=%g", len(dp.classes), dp.totalRateMbps)
+		}
+	}
+	for peer, dp := range cfg.PeerDataplanes {
+		compiled, err := compilePeerDataplane(dp)
+		if err != nil {
+			return fmt.Errorf("peer_dataplanes[%s] compile failed: %w", peer, err)
+		}
+		ct.peerDataplanes[netip.MustParseAddr(peer)] = compiled
+		logger.Infof("peer dataplane configured peer=%s classes=%d classifiers=%d", peer, len(dp.Classes), len(dp.Classifiers))
+	}
 	defer ct.closeAll()
+	registerMetricsConnTable(ct)
 
 	// Periodic GC for stale flow entries in dispatch flowPaths maps.
 	// Every 30s: current → prev, fresh map allocated. Active flows are
@@ -127,16 +152,8 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 				continue
 			}
 
-			// Extract dst IP from IPv4 header (bytes 16-19)
-			version := pkt[0] >> 4
-			var dstIP netip.Addr
-			if version == 4 && len(pkt) >= 20 {
-				dstIP = netip.AddrFrom4([4]byte{pkt[16], pkt[17], pkt[18], pkt[19]})
-			} else if version == 6 && len(pkt) >= 40 {
-				var b [16]byte
-				copy(b[:], pkt[24:40])
-				dstIP = netip.AddrFrom16(b)
-			} else {
+			dstIP, ok := packetDstIP(pkt)
+			if !ok {
 				continue
 			}
 
@@ -155,6 +172,10 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 	if err != nil {
 		return err
 	}
+	qlogs, err := newQlogTraces(cfg, logger)
+	if err != nil {
+		return err
+	}
 
 	// Shared pending-keys store for stripe QUIC key exchange
 	pendingKeys := newStripePendingKeys()
@@ -171,19 +192,55 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 		}
 	}
 
-	listenAddr := net.JoinHostPort(bindIP, fmt.Sprintf("%d", cfg.RemotePort))
-	logger.Infof("server multi-conn listen=%s tun=%s", listenAddr, cfg.TunName)
-	listener, err := quic.ListenAddr(listenAddr, tlsConf, &quic.Config{
-		EnableDatagrams:     true,
-		KeepAlivePeriod:     15 * time.Second,
-		MaxIdleTimeout:      60 * time.Second,
-		CongestionAlgorithm: cfg.CongestionAlgorithm,
-	})
-	if err != nil {
-		return err
+	// With lia the connections of one client path (its pipes) are coupled
+	// once they register in ct; a client path with a congestion_algorithm of
+	// its own switches its connections to it with the peer hello.
+	ct.cc = newPathCCs(cfg.CongestionAlgorithm, ct.lia.byConn)
+	quicConf := &quic.Config{
+		EnableDatagrams: true,
+		KeepAlivePeriod: 15 * time.Second,
+		MaxIdleTimeout:  60 * time.Second,
+		Tracer:          qlogs.tracer("server", "server", false, ct.quicStats.tracer),
+		MaxPaths:        quicMPMaxPaths, // only used if the client has quic-mp paths
+		Allow0RTT:       cfg.TLS0RTT,
+		// Clients move their connections on WAN address changes (wan_migration.go).
+		AllowActiveMigration: true,
+		CongestionControl:    ct.cc.byConn,
 	}
-	defer listener.Close()
+	var listeners []*quic.EarlyListener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	for _, ip := range bindIPs {
+		listenAddr := net.JoinHostPort(ip, fmt.Sprintf("%d", cfg.RemotePort))
+		logger.Infof("server multi-conn listen=%s tun=%s", listenAddr, cfg.TunName)
+		listener, err := quic.ListenAddrEarly(listenAddr, tlsConf, quicConf)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	// One accept loop per listener (bind_ip6 adds an IPv6 one); the first
+	// to fail ends the server.
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(listener *quic.EarlyListener) {
+			errCh <- acceptMultiConn(ctx, listener, tun, ct, pendingKeys, cfg, logger)
+		}(l)
+	}
+	return <-errCh
+}
 
+// acceptMultiConn accepts connections on listener until it fails or ctx ends.
+//
+// The listener hands out connections before their handshake completes, so
+// that resuming clients can send 0-RTT (tls_0rtt). 0-RTT data can be
+// replayed: a connection is only served once its handshake completes,
+// which a replayed ClientHello never does (session_resumption.go).
+func acceptMultiConn(ctx context.Context, listener *quic.EarlyListener, tun *water.Interface, ct *connectionTable, pendingKeys *stripePendingKeys, cfg *Config, logger *Logger) error {
 	for {
 		conn, err := listener.Accept(ctx)
 		if err != nil {
@@ -193,17 +250,21 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 			return err
 		}
 
-		// Route by ALPN: stripe key exchange vs regular tunnel
-		alpn := conn.ConnectionState().TLS.NegotiatedProtocol
-		if alpn == stripeKXALPN {
-			logger.Infof("stripe KX accepted remote=%s", conn.RemoteAddr())
-			go handleStripeKeyExchange(conn, pendingKeys, logger)
-			continue
-		}
+		go func(c quic.EarlyConnection) {
+			if err := waitHandshake(ctx, c); err != nil {
+				logger.Debugf("handshake failed remote=%s err=%v", c.RemoteAddr(), err)
+				return
+			}
+			tlsState := c.ConnectionState()
 
-		logger.Infof("multi-conn accepted remote=%s", conn.RemoteAddr())
+			// Route by ALPN: stripe key exchange vs regular tunnel
+			if tlsState.TLS.NegotiatedProtocol == stripeKXALPN {
+				logger.Infof("stripe KX accepted remote=%s resumed=%t 0rtt=%t", c.RemoteAddr(), tlsState.TLS.DidResume, tlsState.Used0RTT)
+				handleStripeKeyExchange(c, pendingKeys, logger)
+				return
+			}
 
-		go func(c quic.Connection) {
+			logger.Infof("multi-conn accepted remote=%s resumed=%t 0rtt=%t", c.RemoteAddr(), tlsState.TLS.DidResume, tlsState.Used0RTT)
 			if err := runServerMultiConnTunnel(ctx, c, tun, ct, cfg, logger); err != nil && !errors.Is(err, context.Canceled) {
 				logger.Errorf("multi-conn tunnel closed: %v", err)
 			}
@@ -211,6 +272,28 @@ func runServerMultiConn(ctx context.Context, cfg *Config, logger *Logger) error
 	}
 }
 
+// serverBindIPs returns the listen addresses of the server: bind_ip and,
+// when set, bind_ip6. With bind_ip6 an "if:" bind_ip is resolved to IPv4
+// so each listener serves one family.
+func serverBindIPs(cfg *Config) ([]string, error) {
+	if cfg.BindIP6 == "" {
+		bindIP, err := resolveBindIP(cfg.BindIP)
+		if err != nil {
+			return nil, err
+		}
+		return []string{bindIP}, nil
+	}
+	bindIP, err := resolveBindIP(withBindFamily(cfg.BindIP, "ipv4"))
+	if err != nil {
+		return nil, err
+	}
+	bindIP6, err := resolveBindIP(withBindFamily(cfg.BindIP6, "ipv6"))
+	if err != nil {
+		return nil, fmt.Errorf("bind_ip6: %w", err)
+	}
+	return []string{bindIP, bindIP6}, nil
+}
+
 // runServerMultiConnTunnel handles a single QUIC connection in multi-conn mode.
 // First datagram received is expected to be a 4-byte registration containing the
 // client's TUN IP. After registration, all received datagrams are written to TUN.
@@ -237,6 +320,40 @@ func runServerMultiConnTunnel(parentCtx context.Context, conn quic.Connection, t
 		dc = conn
 	}
 
+	// quic-mp clients open more QUIC paths on this connection; each is
+	// served like a connection of its own.
+	go acceptQUICMPPaths(connCtx, conn, func(path *quic.Path) {
+		if err := runServerQUICMPPath(connCtx, path, tun, ct, logger); err != nil && connCtx.Err() == nil {
+			logger.Infof("quic-mp path closed remote=%s path_id=%d err=%v", path.RemoteAddr(), path.ID(), err)
+		}
+	})
+
+	return serveMultiConnPath(connCtx, remoteAddr, dc, tun, ct, logger, func(peerIP netip.Addr) {
+		ct.register(peerIP, conn, dc, cancel)
+	})
+}
+
+// runServerQUICMPPath serves an additional QUIC path of a quic-mp
+// connection. Cancelling its group entry abandons the path, not the
+// connection.
+func runServerQUICMPPath(parentCtx context.Context, path *quic.Path, tun *water.Interface, ct *connectionTable, logger *Logger) error {
+	pathCtx, cancelCtx := context.WithCancel(parentCtx)
+	cancel := func() {
+		cancelCtx()
+		_ = path.Close()
+	}
+	defer cancel()
+
+	logger.Infof("quic-mp path accepted remote=%s path_id=%d", path.RemoteAddr(), path.ID())
+	return serveMultiConnPath(pathCtx, path.RemoteAddr().String(), path, tun, ct, logger, func(peerIP netip.Addr) {
+		ct.registerQUICPath(peerIP, path, cancel)
+	})
+}
+
+// serveMultiConnPath runs the receive loop of one path of a multi-conn
+// client: it registers the path with register on the first datagram that
+// identifies the peer, then writes all datagrams to TUN.
+func serveMultiConnPath(connCtx context.Context, remoteAddr string, dc datagramConn, tun *water.Interface, ct *connectionTable, logger *Logger, register func(netip.Addr)) error {
 	// Wait for registration: first message = 4-byte IPv4 peer IP
 	var peerIP netip.Addr
 	registered := false
@@ -250,33 +367,69 @@ func runServerMultiConnTunnel(parentCtx context.Context, conn quic.Connection, t
 		}
 	}()
 
+	// Bonded packets are written to TUN by the group's reorder buffer,
+	// possibly from another path's goroutine or the hold timer.
+	deliverBonded := func(p []byte) {
+		if _, err := tun.Write(p); err != nil {
+			logger.Debugf("bonded tun write failed peer=%s err=%v", peerIP, err)
+		}
+	}
+
 	for {
 		pkt, err := dc.ReceiveDatagram(connCtx)
 		if err != nil {
 			return err
 		}
 
+		// Path probes are answered on the connection they arrived on,
+		// before registration, and never reach the TUN.
+		if isProbePacket(pkt) {
+			if reply := probeEchoReply(pkt); reply != nil {
+				_ = dc.SendDatagram(reply)
+			}
+			continue
+		}
+
+		// Peer control (hello, pushed dataplane): a hello also registers
+		// the path, so names are known before the first data packet.
+		if isPeerCtlPacket(pkt) {
+			if addrs, _, _, _, ok := decodePeerHello(pkt); ok && !registered && len(addrs) > 0 {
+				peerIP = addrs[0]
+				register(peerIP)
+				registered = true
+				logger.Infof("multi-conn registered peer=%s remote=%s paths=%d (hello)",
+					peerIP, remoteAddr, ct.pathCount(peerIP))
+			}
+			if registered {
+				handlePeerControl(ct, peerIP, remoteAddr, pkt, logger)
+			}
+			continue
+		}
+
+		bondSeq, inner, bonded := decodeBondPacket(pkt)
+		if bonded {
+			pkt = inner
+		}
+
 		if !registered {
-			// Registration: first datagram is a 4-byte IPv4 address
-			if len(pkt) == 4 {
-				peerIP = netip.AddrFrom4([4]byte{pkt[0], pkt[1], pkt[2], pkt[3]})
-				ct.register(peerIP, conn, dc, cancel)
+			// Registration: first datagram is a 4-byte IPv4 or 16-byte
+			// IPv6 address
+			if len(pkt) == 4 || len(pkt) == 16 {
+				peerIP, _ = netip.AddrFromSlice(pkt)
+				register(peerIP)
 				registered = true
 				logger.Infof("multi-conn registered peer=%s remote=%s paths=%d",
 					peerIP, remoteAddr, ct.pathCount(peerIP))
 				continue
 			}
 			// Not a registration packet, try to auto-detect from IP header
-			if len(pkt) >= 20 {
-				version := pkt[0] >> 4
-				if version == 4 {
-					peerIP = netip.AddrFrom4([4]byte{pkt[12], pkt[13], pkt[14], pkt[15]})
-					ct.register(peerIP, conn, dc, cancel)
-					registered = true
-					logger.Infof("multi-conn auto-registered peer=%s remote=%s paths=%d (from packet src)",
-						peerIP, remoteAddr, ct.pathCount(peerIP))
-					// Fall through to write this packet to TUN
-				}
+			if srcIP, ok := packetSrcIP(pkt); ok {
+				peerIP = srcIP
+				register(peerIP)
+				registered = true
+				logger.Infof("multi-conn auto-registered peer=%s remote=%s paths=%d (from packet src)",
+					peerIP, remoteAddr, ct.pathCount(peerIP))
+				// Fall through to write this packet to TUN
 			}
 		}
 
@@ -290,7 +443,9 @@ func runServerMultiConnTunnel(parentCtx context.Context, conn quic.Connection, t
 
 		// De-duplicate: if a multi-path client sends the same packet via
 		// multiple paths (duplication mode), skip writing it to TUN twice.
-		if ct.pathCount(peerIP) > 1 && ct.dedup.isDuplicate(pkt) {
+		// Bonded packets are never duplicated; their sequence number
+		// already identifies them.
+		if !bonded && ct.pathCount(peerIP) > 1 && ct.dedup.isDuplicate(pkt) {
 			logger.Debugf("dedup: skipping duplicate packet from peer=%s remote=%s len=%d", peerIP, remoteAddr, len(pkt))
 			continue
 		}
@@ -298,14 +453,13 @@ func runServerMultiConnTunnel(parentCtx context.Context, conn quic.Connection, t
 		// Learn source IP for return-path routing: if the client
 		// forwards traffic from LAN hosts (src != peerIP), we record
 		// src→peerIP so the TUN reader can dispatch replies.
-		if len(pkt) >= 20 {
-			version := pkt[0] >> 4
-			if version == 4 {
-				srcIP := netip.AddrFrom4([4]byte{pkt[12], pkt[13], pkt[14], pkt[15]})
-				if srcIP != peerIP {
-					ct.learnRoute(srcIP, peerIP)
-				}
-			}
+		if srcIP, ok := packetSrcIP(pkt); ok && srcIP != peerIP {
+			ct.learnRoute(srcIP, peerIP)
+		}
+
+		if bonded {
+			ct.bondReceive(peerIP, bondSeq, pkt, deliverBonded)
+			continue
 		}
 
 		if _, err := tun.Write(pkt); err != nil {
@@ -327,7 +481,7 @@ func runServerSingleConn(ctx context.Context, cfg *Config, logger *Logger) error
 		return err
 	}
 	defer tun.Close()
-	if err := configureTUN(cfg.TunName, cfg.TunCIDR, cfg.TunMTU, logger); err != nil {
+	if err := configureTUN(cfg.TunName, cfg.tunCIDRs(), cfg.TunMTU, logger); err != nil {
 		return fmt.Errorf("configure TUN: %w", err)
 	}
 
@@ -361,12 +515,18 @@ func runServerSingleConn(ctx context.Context, cfg *Config, logger *Logger) error
 	}
 	listenAddr := net.JoinHostPort(bindIP, fmt.Sprintf("%d", cfg.RemotePort))
 	logger.Infof("server listen=%s tun=%s", listenAddr, cfg.TunName)
-	listener, err := quic.ListenAddr(listenAddr, tlsConf, &quic.Config{
-		EnableDatagrams:     true,
-		KeepAlivePeriod:     15 * time.Second,
-		MaxIdleTimeout:      60 * time.Second,
-		CongestionAlgorithm: cfg.CongestionAlgorithm,
-	})
+	qlogs, err := newQlogTraces(cfg, logger)
+	if err != nil {
+		return err
+	}
+	listener, err := quic.ListenAddr(listenAddr, tlsConf, withCongestionControl(&quic.Config{
+		EnableDatagrams: true,
+		KeepAlivePeriod: 15 * time.Second,
+		MaxIdleTimeout:  60 * time.Second,
+		Tracer:          qlogs.tracer("server", "server", false, nil),
+		// Clients move their connections on WAN address changes (wan_migration.go).
+		AllowActiveMigration: true,
+	}, cfg.CongestionAlgorithm, nil))
 	if err != nil {
 		return err
 	}
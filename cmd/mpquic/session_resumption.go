@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ─── TLS session resumption and 0-RTT ─────────────────────────────────────
+//
+// The client keeps the session tickets of each server for the lifetime of
+// the process, so reconnects (reconnectLoop, runClientLoop, a new stripe key
+// exchange) resume the TLS session instead of running a full handshake: no
+// certificate on the wire, which on a satellite path often saves a round
+// trip to the amplification limit. When the server allows it (tls_0rtt) the
+// client sends its first datagrams in 0-RTT packets together with the
+// ClientHello.
+//
+// 0-RTT data can be replayed by anyone who captured it. The server
+// therefore does not act on a connection before its handshake completes
+// (acceptMultiConn): data received in 0-RTT stays queued until then, and a
+// replayed ClientHello never completes the handshake. A replayed
+// registration or stripe key exchange cannot register a peer or overwrite a
+// pending stripe key.
+
+// sessionCacheSize is the number of tickets kept per server and ALPN.
+const sessionCacheSize = 8
+
+var clientSessionCaches = struct {
+	sync.Mutex
+	m map[string]tls.ClientSessionCache
+}{m: make(map[string]tls.ClientSessionCache)}
+
+// clientSessionCache returns the session cache for connections to server
+// with the given ALPN. Tickets are kept apart per ALPN: a ticket only
+// allows 0-RTT for the protocol it was issued for. tls_server_name is the
+// same for all servers, so crypto/tls's own cache key can't tell them apart.
+func clientSessionCache(server net.Addr, alpn []string) tls.ClientSessionCache {
+	key := server.String() + "|" + strings.Join(alpn, ",")
+	clientSessionCaches.Lock()
+	defer clientSessionCaches.Unlock()
+	c, ok := clientSessionCaches.m[key]
+	if !ok {
+		c = tls.NewLRUClientSessionCache(sessionCacheSize)
+		clientSessionCaches.m[key] = c
+	}
+	return c
+}
+
+// resumableTLSConfig returns a copy of tlsConf that resumes sessions with
+// server.
+func resumableTLSConfig(tlsConf *tls.Config, server net.Addr) *tls.Config {
+	conf := tlsConf.Clone()
+	conf.ClientSessionCache = clientSessionCache(server, conf.NextProtos)
+	return conf
+}
+
+// dialEarly dials remote resuming the last session with it. With a 0-RTT
+// ticket the connection is returned right after the ClientHello is sent
+// and datagrams sent before the handshake completes travel as 0-RTT. If the
+// server rejects 0-RTT those datagrams are lost like any other; streams
+// must not be opened before the handshake completes (see openStreamConn).
+func dialEarly(ctx context.Context, tr *quic.Transport, remote net.Addr, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error) {
+	return tr.DialEarly(ctx, remote, resumableTLSConfig(tlsConf, remote), conf)
+}
+
+// waitHandshake blocks until the handshake of conn completes. It returns
+// an error if the handshake fails or ctx is done first.
+func waitHandshake(ctx context.Context, conn quic.EarlyConnection) error {
+	select {
+	case <-conn.HandshakeComplete():
+		return nil
+	case <-conn.Context().Done():
+		return context.Cause(conn.Context())
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// TestDialEarly_Resumes0RTT reconnects to a server that allows 0-RTT: the
+// second connection resumes the session and its first datagram goes out
+// before the handshake completes.
+func TestDialEarly_Resumes0RTT(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := quic.ListenAddrEarly("127.0.0.1:0", testServerTLSConfig(t), &quic.Config{EnableDatagrams: true, Allow0RTT: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept(ctx)
+			if err != nil {
+				return
+			}
+			go func() {
+				if waitHandshake(ctx, conn) != nil {
+					return
+				}
+				for {
+					pkt, err := conn.ReceiveDatagram(ctx)
+					if err != nil {
+						return
+					}
+					_ = conn.SendDatagram(pkt)
+				}
+			}()
+		}
+	}()
+
+	tlsConf, err := loadClientTLSConfig(&Config{TLSInsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := func(msg string) quic.ConnectionState {
+		t.Helper()
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := &quic.Transport{Conn: udpConn}
+		defer tr.Close()
+		conn, err := dialEarly(ctx, tr, ln.Addr(), tlsConf, &quic.Config{EnableDatagrams: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.CloseWithError(0, "done")
+		if err := conn.SendDatagram([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		pkt, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(pkt) != msg {
+			t.Fatalf("echo = %q, want %q", pkt, msg)
+		}
+		return conn.ConnectionState()
+	}
+
+	if state := dial("first"); state.TLS.DidResume || state.Used0RTT {
+		t.Fatalf("first connection resumed=%t 0rtt=%t, want a full handshake", state.TLS.DidResume, state.Used0RTT)
+	}
+	if state := dial("second"); !state.TLS.DidResume || !state.Used0RTT {
+		t.Fatalf("second connection resumed=%t 0rtt=%t, want both", state.TLS.DidResume, state.Used0RTT)
+	}
+}
+
+// TestStripeNegotiateKey_Resumes runs two stripe key exchanges against
+// acceptMultiConn: both store the key the client derived, and the second
+// resumes the TLS session.
+func TestStripeNegotiateKey_Resumes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var resumed atomic.Int32
+	tlsConf := testServerTLSConfig(t)
+	tlsConf.NextProtos = []string{"mpquic-ip", stripeKXALPN}
+	tlsConf.VerifyConnection = func(cs tls.ConnectionState) error {
+		if cs.DidResume {
+			resumed.Add(1)
+		}
+		return nil
+	}
+	ln, err := quic.ListenAddrEarly("127.0.0.1:0", tlsConf, &quic.Config{Allow0RTT: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	pendingKeys := newStripePendingKeys()
+	logger := newLogger("error")
+	go acceptMultiConn(ctx, ln, nil, newConnectionTable(), pendingKeys, &Config{}, logger)
+
+	cfg := &Config{RemoteAddr: "127.0.0.1", RemotePort: ln.Addr().(*net.UDPAddr).Port, TLSInsecureSkipVerify: true}
+	pathCfg := MultipathPathConfig{BindIP: "127.0.0.1"}
+	for _, sessionID := range []uint32{0x1001, 0x1002} {
+		km, err := stripeNegotiateKey(ctx, cfg, pathCfg, sessionID, nil, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored := pendingKeys.Get(sessionID)
+		if stored == nil || stored.c2sKey != km.c2sKey || stored.s2cKey != km.s2cKey {
+			t.Fatalf("session=%08x: server key does not match the client's", sessionID)
+		}
+	}
+	if n := resumed.Load(); n != 1 {
+		t.Fatalf("resumed handshakes = %d, want 1", n)
+	}
+}
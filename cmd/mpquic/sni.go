@@ -0,0 +1,469 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ─── SNI classification (TLS ClientHello / QUIC Initial) ──────────────────
+//
+// Classifier rules may list server names under `sni`. The name is read
+// from the first packet of a flow that carries it:
+//   - TCP: a TLS ClientHello at the start of the segment payload.
+//   - UDP: a QUIC Initial packet (v1 or v2). Initial packets are encrypted
+//     with keys derived from the Destination Connection ID and the
+//     version's public salt (RFC 9001 §5.2, RFC 9369 §3.3.1), so any
+//     on-path observer can decrypt them.
+//
+// Only the ClientHello prefix present in that single packet is parsed: when
+// a large ClientHello (e.g. post-quantum key shares) places the SNI
+// extension in a later segment or Initial, the flow is not classified by
+// SNI. The name found is remembered per 5-tuple in an sniFlowTable, so the
+// remaining packets of the flow (which carry no SNI) land in the same class.
+// This covers hosts using DoH or hard-coded IPs, which DNS snooping misses.
+
+const (
+	// Flows idle for between one and two periods are forgotten.
+	sniFlowIdle = 2 * time.Minute
+	// Upper bound on remembered flows; reaching it forces an early rotation.
+	defaultSNIFlowMax = 65536
+)
+
+var (
+	quicV1InitialSalt = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+	quicV2InitialSalt = []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9}
+)
+
+const (
+	quicVersion1 = 0x00000001
+	quicVersion2 = 0x6b3343cf
+)
+
+// extractSNI returns the server name carried by pkt, or "" when pkt is not
+// a TLS ClientHello / QUIC Initial or the name is not in this packet.
+func extractSNI(pkt []byte, meta packetMeta) string {
+	if !meta.hasPorts || meta.l4Offset <= 0 || meta.l4Offset >= len(pkt) {
+		return ""
+	}
+	l4 := pkt[meta.l4Offset:]
+	switch meta.protocol {
+	case "tcp":
+		if len(l4) < 20 {
+			return ""
+		}
+		dataOff := int(l4[12]>>4) * 4
+		if dataOff < 20 || len(l4) <= dataOff {
+			return ""
+		}
+		return tlsRecordSNI(l4[dataOff:])
+	case "udp":
+		if len(l4) <= 8 {
+			return ""
+		}
+		return quicInitialSNI(l4[8:])
+	}
+	return ""
+}
+
+// tlsRecordSNI parses a TLS handshake record holding a ClientHello.
+func tlsRecordSNI(rec []byte) string {
+	// ContentType handshake(22), legacy version 3.x, length.
+	if len(rec) < 5 || rec[0] != 0x16 || rec[1] != 0x03 {
+		return ""
+	}
+	return clientHelloSNI(rec[5:])
+}
+
+// clientHelloSNI parses a TLS handshake message and returns the host name
+// of the server_name extension if the message is a ClientHello. hs may be
+// truncated; parsing stops at the first missing byte.
+func clientHelloSNI(hs []byte) string {
+	if len(hs) < 4 || hs[0] != 0x01 { // HandshakeType client_hello
+		return ""
+	}
+	b := hs[4:]
+	// legacy_version(2) random(32)
+	if len(b) < 34 {
+		return ""
+	}
+	b = b[34:]
+	var ok bool
+	// legacy_session_id<0..32>
+	if b, ok = skipVec8(b); !ok {
+		return ""
+	}
+	// cipher_suites<2..2^16-2>
+	if b, ok = skipVec16(b); !ok {
+		return ""
+	}
+	// legacy_compression_methods<1..2^8-1>
+	if b, ok = skipVec8(b); !ok {
+		return ""
+	}
+	if len(b) < 2 {
+		return ""
+	}
+	extLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if extLen < len(b) {
+		b = b[:extLen]
+	}
+	for len(b) >= 4 {
+		extType := binary.BigEndian.Uint16(b)
+		n := int(binary.BigEndian.Uint16(b[2:]))
+		b = b[4:]
+		if n > len(b) {
+			return ""
+		}
+		if extType == 0 { // server_name
+			return serverNameExt(b[:n])
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+// serverNameExt returns the first host_name entry of a server_name
+// extension body.
+func serverNameExt(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	b = b[2:] // server_name_list length
+	for len(b) >= 3 {
+		nameType := b[0]
+		n := int(binary.BigEndian.Uint16(b[1:]))
+		b = b[3:]
+		if n > len(b) {
+			return ""
+		}
+		if nameType == 0 { // host_name
+			return normalizeDomainName(string(b[:n]))
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+func skipVec8(b []byte) ([]byte, bool) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, false
+	}
+	return b[1+int(b[0]):], true
+}
+
+func skipVec16(b []byte) ([]byte, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return nil, false
+	}
+	return b[2+n:], true
+}
+
+// quicVarint decodes a QUIC variable-length integer (RFC 9000 §16).
+func quicVarint(b []byte) (uint64, int, bool) {
+	if len(b) == 0 {
+		return 0, 0, false
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0, false
+	}
+	v := uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n, true
+}
+
+// quicInitialSNI decrypts a client QUIC Initial packet and returns the SNI
+// of the ClientHello in its CRYPTO frames.
+func quicInitialSNI(p []byte) string {
+	// Long header: form(1)=1, fixed(1)=1, type(2), ...
+	if len(p) < 7 || p[0]&0xc0 != 0xc0 {
+		return ""
+	}
+	version := binary.BigEndian.Uint32(p[1:5])
+	pktType := (p[0] >> 4) & 0x03
+	var salt []byte
+	var labelPrefix string
+	switch {
+	case version == quicVersion1 && pktType == 0:
+		salt, labelPrefix = quicV1InitialSalt, "quic "
+	case version == quicVersion2 && pktType == 1:
+		salt, labelPrefix = quicV2InitialSalt, "quicv2 "
+	default:
+		return ""
+	}
+
+	off := 5
+	dcidLen := int(p[off])
+	off++
+	if dcidLen > 20 || len(p) < off+dcidLen+1 {
+		return ""
+	}
+	dcid := p[off : off+dcidLen]
+	off += dcidLen
+	scidLen := int(p[off])
+	off += 1 + scidLen
+	tokenLen, n, ok := quicVarint(p[min(off, len(p)):])
+	if !ok {
+		return ""
+	}
+	off += n + int(tokenLen)
+	length, n, ok := quicVarint(p[min(off, len(p)):])
+	if !ok {
+		return ""
+	}
+	off += n
+	pnOffset := off
+	if length < 20 || len(p) < pnOffset+int(length) {
+		return ""
+	}
+
+	initial := hkdf.Extract(sha256.New, dcid, salt)
+	clientSecret := hkdfExpandLabel(initial, "client in", 32)
+	key := hkdfExpandLabel(clientSecret, labelPrefix+"key", 16)
+	iv := hkdfExpandLabel(clientSecret, labelPrefix+"iv", 12)
+	hp := hkdfExpandLabel(clientSecret, labelPrefix+"hp", 16)
+
+	// Remove header protection on a copy: pkt is still to be forwarded.
+	hpBlock, err := aes.NewCipher(hp)
+	if err != nil {
+		return ""
+	}
+	var mask [16]byte
+	hpBlock.Encrypt(mask[:], p[pnOffset+4:pnOffset+20])
+	first := p[0] ^ (mask[0] & 0x0f)
+	pnLen := int(first&0x03) + 1
+	header := make([]byte, pnOffset+pnLen)
+	copy(header, p[:pnOffset+pnLen])
+	header[0] = first
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(header[pnOffset+i])
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return ""
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return ""
+	}
+	nonce := make([]byte, len(iv))
+	copy(nonce, iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+	plain, err := aead.Open(nil, nonce, p[pnOffset+pnLen:pnOffset+int(length)], header)
+	if err != nil {
+		return ""
+	}
+	return clientHelloSNI(quicCryptoPrefix(plain))
+}
+
+// quicCryptoPrefix reassembles the CRYPTO frames of a decrypted Initial
+// payload and returns the contiguous stream prefix starting at offset 0.
+// Clients may split and shuffle the ClientHello across frames.
+func quicCryptoPrefix(b []byte) []byte {
+	type fragment struct {
+		offset uint64
+		data   []byte
+	}
+	var frags []fragment
+	for len(b) > 0 {
+		frameType, n, ok := quicVarint(b)
+		if !ok {
+			break
+		}
+		b = b[n:]
+		switch frameType {
+		case 0x00, 0x01: // PADDING, PING
+			continue
+		case 0x02, 0x03: // ACK, ACK_ECN
+			fields := 4 // largest, delay, range count, first range
+			var rangeCount uint64
+			for i := 0; i < fields; i++ {
+				v, n, ok := quicVarint(b)
+				if !ok {
+					return nil
+				}
+				if i == 2 {
+					rangeCount = v
+				}
+				b = b[n:]
+			}
+			skip := int(rangeCount) * 2
+			if frameType == 0x03 {
+				skip += 3
+			}
+			for i := 0; i < skip; i++ {
+				_, n, ok := quicVarint(b)
+				if !ok {
+					return nil
+				}
+				b = b[n:]
+			}
+			continue
+		case 0x06: // CRYPTO
+			offset, n, ok := quicVarint(b)
+			if !ok {
+				return nil
+			}
+			b = b[n:]
+			length, n, ok := quicVarint(b)
+			if !ok || uint64(len(b)-n) < length {
+				return nil
+			}
+			b = b[n:]
+			frags = append(frags, fragment{offset: offset, data: b[:length]})
+			b = b[length:]
+			continue
+		}
+		break // unexpected frame: stop parsing
+	}
+
+	sort.Slice(frags, func(i, j int) bool { return frags[i].offset < frags[j].offset })
+	var out []byte
+	for _, f := range frags {
+		end := f.offset + uint64(len(f.data))
+		if f.offset > uint64(len(out)) {
+			break // gap: the rest of the ClientHello is in another packet
+		}
+		if end > uint64(len(out)) {
+			out = append(out, f.data[uint64(len(out))-f.offset:]...)
+		}
+	}
+	return out
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from TLS 1.3 (RFC 8446 §7.1)
+// with an empty context.
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	full := "tls13 " + label
+	info := make([]byte, 0, 4+len(full))
+	info = binary.BigEndian.AppendUint16(info, uint16(length))
+	info = append(info, byte(len(full)))
+	info = append(info, full...)
+	info = append(info, 0)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(sha256.New, secret, info).Read(out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// ─── Per-flow SNI memory ──────────────────────────────────────────────────
+
+type sniFlowKey struct {
+	src, dst         netip.Addr
+	srcPort, dstPort uint16
+	protocol         string
+}
+
+func sniFlowKeyOf(meta packetMeta) sniFlowKey {
+	return sniFlowKey{
+		src:      meta.srcAddr,
+		dst:      meta.dstAddr,
+		srcPort:  meta.srcPort,
+		dstPort:  meta.dstPort,
+		protocol: meta.protocol,
+	}
+}
+
+// sniFlowTable remembers the server name of each flow. Like the server's
+// flowPaths it uses two generations: entries not touched during a full
+// sniFlowIdle period are dropped on the next rotation.
+type sniFlowTable struct {
+	mu        sync.Mutex
+	cur       map[sniFlowKey]string
+	prev      map[sniFlowKey]string
+	rotatedAt time.Time
+	max       int
+}
+
+func newSNIFlowTable(max int) *sniFlowTable {
+	if max <= 0 {
+		max = defaultSNIFlowMax
+	}
+	return &sniFlowTable{
+		cur:       make(map[sniFlowKey]string),
+		rotatedAt: time.Now(),
+		max:       max,
+	}
+}
+
+func (t *sniFlowTable) rotateLocked(now time.Time) {
+	if now.Sub(t.rotatedAt) < sniFlowIdle && len(t.cur) < t.max {
+		return
+	}
+	t.prev = t.cur
+	t.cur = make(map[sniFlowKey]string, len(t.prev))
+	t.rotatedAt = now
+}
+
+// get returns the remembered name of a flow, or "".
+func (t *sniFlowTable) get(key sniFlowKey) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rotateLocked(time.Now())
+	if name, ok := t.cur[key]; ok {
+		return name
+	}
+	if name, ok := t.prev[key]; ok {
+		t.cur[key] = name
+		return name
+	}
+	return ""
+}
+
+func (t *sniFlowTable) set(key sniFlowKey, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rotateLocked(time.Now())
+	t.cur[key] = name
+}
+
+// size returns the number of remembered flows (both generations).
+func (t *sniFlowTable) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.cur)
+	for key := range t.prev {
+		if _, ok := t.cur[key]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// lookupOrLearn returns the server name for the flow of pkt, extracting it
+// from pkt on first sight.
+func (t *sniFlowTable) lookupOrLearn(pkt []byte, meta packetMeta) string {
+	if t == nil || !meta.hasPorts {
+		return ""
+	}
+	key := sniFlowKeyOf(meta)
+	if name := t.get(key); name != "" {
+		return name
+	}
+	name := extractSNI(pkt, meta)
+	if name != "" {
+		t.set(key, name)
+	}
+	return name
+}
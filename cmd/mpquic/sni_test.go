@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// buildClientHello returns a minimal TLS 1.3 ClientHello handshake message
+// with an ALPN extension ahead of server_name, as real clients send.
+func buildClientHello(sni string) []byte {
+	var ext []byte
+	// application_layer_protocol_negotiation: "h3"
+	ext = append(ext, 0x00, 0x10, 0x00, 0x05, 0x00, 0x03, 0x02, 'h', '3')
+	// server_name
+	name := []byte(sni)
+	ext = binary.BigEndian.AppendUint16(ext, 0)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(name)+5))
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(name)+3))
+	ext = append(ext, 0)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(name)))
+	ext = append(ext, name...)
+
+	var body []byte
+	body = append(body, 0x03, 0x03)
+	body = append(body, make([]byte, 32)...)    // random
+	body = append(body, 0x00)                   // session id
+	body = append(body, 0x00, 0x02, 0x13, 0x01) // cipher suites
+	body = append(body, 0x01, 0x00)             // compression
+	body = binary.BigEndian.AppendUint16(body, uint16(len(ext)))
+	body = append(body, ext...)
+
+	hs := []byte{0x01, 0, 0, 0}
+	hs[1] = byte(len(body) >> 16)
+	hs[2] = byte(len(body) >> 8)
+	hs[3] = byte(len(body))
+	return append(hs, body...)
+}
+
+// ipv4Packet wraps an L4 header+payload in a minimal IPv4 header.
+func ipv4Packet(proto byte, l4 []byte) []byte {
+	pkt := make([]byte, 20+len(l4))
+	pkt[0] = 0x45
+	pkt[9] = proto
+	copy(pkt[12:16], []byte{10, 200, 1, 1})
+	copy(pkt[16:20], []byte{203, 0, 113, 7})
+	copy(pkt[20:], l4)
+	return pkt
+}
+
+func tcpSegment(payload []byte) []byte {
+	seg := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(seg[0:2], 50000)
+	binary.BigEndian.PutUint16(seg[2:4], 443)
+	seg[12] = 5 << 4
+	copy(seg[20:], payload)
+	return seg
+}
+
+func udpDatagram(payload []byte) []byte {
+	d := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(d[0:2], 50001)
+	binary.BigEndian.PutUint16(d[2:4], 443)
+	binary.BigEndian.PutUint16(d[4:6], uint16(len(d)))
+	copy(d[8:], payload)
+	return d
+}
+
+// sealQUICInitial builds a protected QUIC v1 client Initial carrying the
+// given CRYPTO frames, padded to 1200 bytes.
+func sealQUICInitial(t *testing.T, dcid []byte, frames []byte) []byte {
+	t.Helper()
+	initial := hkdf.Extract(sha256.New, dcid, quicV1InitialSalt)
+	secret := hkdfExpandLabel(initial, "client in", 32)
+	key := hkdfExpandLabel(secret, "quic key", 16)
+	iv := hkdfExpandLabel(secret, "quic iv", 12)
+	hp := hkdfExpandLabel(secret, "quic hp", 16)
+
+	const pn = 2
+	hdr := []byte{0xc3, 0, 0, 0, 1, byte(len(dcid))}
+	hdr = append(hdr, dcid...)
+	hdr = append(hdr, 0, 0) // scid len, token len
+	lengthOff := len(hdr)
+	hdr = append(hdr, 0x40, 0x00) // 2-byte length, patched below
+	pnOffset := len(hdr)
+	hdr = binary.BigEndian.AppendUint32(hdr, pn)
+
+	plain := append([]byte(nil), frames...)
+	plain = append(plain, make([]byte, 1200-len(hdr)-16-len(frames))...)
+	binary.BigEndian.PutUint16(hdr[lengthOff:], 0x4000|uint16(4+len(plain)+16))
+
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := append([]byte(nil), iv...)
+	nonce[len(nonce)-1] ^= pn
+	pkt := aead.Seal(append([]byte(nil), hdr...), nonce, plain, hdr)
+
+	hpBlock, _ := aes.NewCipher(hp)
+	var mask [16]byte
+	hpBlock.Encrypt(mask[:], pkt[pnOffset+4:pnOffset+20])
+	pkt[0] ^= mask[0] & 0x0f
+	for i := 0; i < 4; i++ {
+		pkt[pnOffset+i] ^= mask[1+i]
+	}
+	return pkt
+}
+
+func cryptoFrame(offset int, data []byte) []byte {
+	f := []byte{0x06}
+	f = binary.BigEndian.AppendUint16(f, 0x4000|uint16(offset))
+	f = binary.BigEndian.AppendUint16(f, 0x4000|uint16(len(data)))
+	return append(f, data...)
+}
+
+func TestHKDFExpandLabel_RFC9001Keys(t *testing.T) {
+	// RFC 9001 Appendix A.1
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	initial := hkdf.Extract(sha256.New, dcid, quicV1InitialSalt)
+	secret := hkdfExpandLabel(initial, "client in", 32)
+	for _, tc := range []struct {
+		label, want string
+		n           int
+	}{
+		{"quic key", "1f369613dd76d5467730efcbe3b1a22d", 16},
+		{"quic iv", "fa044b2f42a3fd3b46fb255c", 12},
+		{"quic hp", "9f50449e04a0e810283a1e9933adedd2", 16},
+	} {
+		if got := hex.EncodeToString(hkdfExpandLabel(secret, tc.label, tc.n)); got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.label, got, tc.want)
+		}
+	}
+}
+
+func TestExtractSNI_TLSClientHello(t *testing.T) {
+	hello := buildClientHello("Teams.Microsoft.com")
+	rec := []byte{0x16, 0x03, 0x01, byte(len(hello) >> 8), byte(len(hello))}
+	pkt := ipv4Packet(6, tcpSegment(append(rec, hello...)))
+	meta, ok := parsePacketMeta(pkt)
+	if !ok {
+		t.Fatal("parsePacketMeta failed")
+	}
+	if got := extractSNI(pkt, meta); got != "teams.microsoft.com" {
+		t.Errorf("sni = %q, want teams.microsoft.com", got)
+	}
+
+	// A segment holding only the first part of the ClientHello yields nothing.
+	short := ipv4Packet(6, tcpSegment(append(rec, hello[:40]...)))
+	meta, _ = parsePacketMeta(short)
+	if got := extractSNI(short, meta); got != "" {
+		t.Errorf("truncated ClientHello sni = %q, want empty", got)
+	}
+}
+
+func TestExtractSNI_QUICInitialShuffledCrypto(t *testing.T) {
+	hello := buildClientHello("meet.google.com")
+	half := len(hello) / 2
+	// Second half first, with PING and PADDING in between.
+	frames := cryptoFrame(half, hello[half:])
+	frames = append(frames, 0x01, 0x00)
+	frames = append(frames, cryptoFrame(0, hello[:half])...)
+
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	initial := sealQUICInitial(t, dcid, frames)
+	pkt := ipv4Packet(17, udpDatagram(initial))
+	orig := append([]byte(nil), pkt...)
+
+	meta, ok := parsePacketMeta(pkt)
+	if !ok {
+		t.Fatal("parsePacketMeta failed")
+	}
+	if got := extractSNI(pkt, meta); got != "meet.google.com" {
+		t.Errorf("sni = %q, want meet.google.com", got)
+	}
+	if !bytes.Equal(pkt, orig) {
+		t.Error("extractSNI modified the packet")
+	}
+}
+
+func TestResolve_SNIRememberedPerFlow(t *testing.T) {
+	dp := DataplaneConfig{
+		Classes: map[string]DataplaneClassPolicy{
+			"default":  {},
+			"critical": {},
+		},
+		Classifiers: []DataplaneClassifierRule{
+			{Name: "teams", ClassName: "critical", SNI: []string{"*.microsoft.com"}},
+		},
+	}
+	normalizeDataplaneConfig(&dp, "priority")
+	if err := validateDataplaneConfig(dp, nil); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	compiled, err := compileDataplaneConfig(dp)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	compiled.sniFlows = newSNIFlowTable(0)
+
+	// Data packet before the handshake: unknown flow.
+	data := ipv4Packet(6, tcpSegment([]byte{0x17, 0x03, 0x03, 0x00, 0x10}))
+	if class, _ := compiled.resolve(data); class != "default" {
+		t.Fatalf("class before ClientHello = %q, want default", class)
+	}
+
+	hello := buildClientHello("teams.microsoft.com")
+	rec := []byte{0x16, 0x03, 0x01, byte(len(hello) >> 8), byte(len(hello))}
+	if class, _ := compiled.resolve(ipv4Packet(6, tcpSegment(append(rec, hello...)))); class != "critical" {
+		t.Fatalf("ClientHello class = %q, want critical", class)
+	}
+	if class, _ := compiled.resolve(data); class != "critical" {
+		t.Errorf("later packet of the flow class = %q, want critical", class)
+	}
+	if n := compiled.sniFlows.size(); n != 1 {
+		t.Errorf("remembered flows = %d, want 1", n)
+	}
+}
@@ -20,6 +20,7 @@ package main
 
 import (
 	"encoding/binary"
+	"net/netip"
 	"sync"
 	"time"
 )
@@ -64,6 +65,7 @@ const (
 	stripeXOR_REPAIR    uint8 = 0x06
 	stripeRLC_REPAIR    uint8 = 0x07
 	stripeRS_IL_PARITY  uint8 = 0x08 // RS interleaved parity shard
+	stripePROBE         uint8 = 0x09 // path probe echo (payload: probe.go packet)
 
 	// Header: magic(2) + ver(1) + type(1) + session(4) + groupSeq(4) + shardIdx(1) + groupDataN(1) + dataLen(2) = 16
 	stripeHdrLen = 16
@@ -199,6 +201,40 @@ func decodeStripeHdr(buf []byte) (stripeHdr, bool) {
 	return h, true
 }
 
+// REGISTER payload. An IPv4 tunnel keeps the original layout
+// TUN IP(4) + pipeIdx(1) + totalPipes(1), which older servers understand.
+// Any other address uses the tagged layout 0x00 + addr len(1) + TUN IP +
+// pipeIdx(1) + totalPipes(1): a leading zero is never a TUN IPv4 address
+// (0.0.0.0/8), so the two layouts are told apart by the first byte and not
+// by the payload length.
+const stripeRegisterTagged = 0x00
+
+func encodeStripeRegister(tunIP netip.Addr, pipeIdx, totalPipes int) []byte {
+	ip := tunIP.Unmap().AsSlice()
+	var out []byte
+	if len(ip) != 4 {
+		out = append(out, stripeRegisterTagged, uint8(len(ip)))
+	}
+	out = append(out, ip...)
+	return append(out, uint8(pipeIdx), uint8(totalPipes))
+}
+
+func decodeStripeRegister(payload []byte) (tunIP netip.Addr, pipeIdx, totalPipes int, ok bool) {
+	n := 4
+	if len(payload) > 0 && payload[0] == stripeRegisterTagged {
+		if len(payload) < 2 || (payload[1] != 4 && payload[1] != 16) {
+			return netip.Addr{}, 0, 0, false
+		}
+		n = int(payload[1])
+		payload = payload[2:]
+	}
+	if len(payload) < n+2 {
+		return netip.Addr{}, 0, 0, false
+	}
+	tunIP, _ = netip.AddrFromSlice(payload[:n])
+	return tunIP, int(payload[n]), int(payload[n+1]), true
+}
+
 // ─── FEC Group ────────────────────────────────────────────────────────────
 
 type fecGroup struct {
@@ -5,7 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
-	"strings"
+	"net/netip"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/klauspost/reedsolomon"
 	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 // ─── Stripe Client Connection ────────────────────────────────────────────
@@ -22,7 +24,7 @@ type stripeClientConn struct {
 	pipes      []*net.UDPConn
 	serverAddr *net.UDPAddr
 	sessionID  uint32
-	tunIPU32   uint32 // TUN IP as uint32 for periodic re-register
+	tunIP      netip.Addr // TUN IP for periodic re-register
 
 	dataK   int
 	parityM int
@@ -54,7 +56,9 @@ type stripeClientConn struct {
 
 	// TX state
 	txSeq      uint32 // atomic: next data sequence number
+	probePipe  uint32 // atomic: pipe rotation for sendProbe
 	txPipe     uint32 // atomic: round-robin pipe selector
+	txLive     atomic.Pointer[[]int] // TX schedule of pipe indexes (nil = all), see stripe_scheduler.go
 	txGroup    [][]byte
 	txGrpSeq   uint32
 	txMu       sync.Mutex
@@ -77,6 +81,11 @@ type stripeClientConn struct {
 	peerLossRate uint32 // atomic: 0-100
 	lastPeerLoss int64  // atomic: unix-nano of last nonzero peer loss report
 
+	// Per-pipe liveness, delivery, RTT, jitter and OWD (parallel to pipes)
+	pipeHealth    []*stripePipeHealth
+	pipeTiming    []*stripePipeTiming
+	pipeScheduler string // stripe_pipe_scheduler: "rr" or "weighted"
+
 	// Loss computation: previous window values (updated each keepalive cycle)
 	rxLossPrevSeqHigh    uint64
 	rxLossPrevDirectCnt  uint64
@@ -117,6 +126,7 @@ type stripeClientConn struct {
 	rxCipher *stripeCipher // server→client decryption
 
 	securityDecryptFail uint64
+	securityReplayDrop  uint64 // authentic packets rejected by the replay window
 }
 
 // gsoTxPipeBuf accumulates encrypted wire packets for a single pipe.
@@ -128,9 +138,29 @@ type gsoTxPipeBuf struct {
 	segSize int
 }
 
-// SecurityStats returns the decrypt failure counter.
-func (scc *stripeClientConn) SecurityStats() uint64 {
-	return atomic.LoadUint64(&scc.securityDecryptFail)
+// SecurityStats returns the decrypt failure and replay drop counters.
+func (scc *stripeClientConn) SecurityStats() (decryptFail, replayDrop uint64) {
+	return atomic.LoadUint64(&scc.securityDecryptFail), atomic.LoadUint64(&scc.securityReplayDrop)
+}
+
+// PipeStats returns the session delay measurements (averaged over the pipes,
+// nil before the first timed keepalive reply), the per-pipe liveness and
+// delay, and the number of pipes in quarantine.
+func (scc *stripeClientConn) PipeStats() (*StripeTiming, []StripePipeStats, int) {
+	addrs := make([]string, len(scc.pipes))
+	for i, pipe := range scc.pipes {
+		addrs[i] = pipe.LocalAddr().String()
+	}
+	var schedule []*stripePipeHealth
+	if live := scc.txLive.Load(); live != nil {
+		schedule = make([]*stripePipeHealth, len(*live))
+		for i, p := range *live {
+			schedule[i] = scc.pipeHealth[p]
+		}
+	}
+	timing, pipes := stripeTimingStats(scc.pipeTiming, addrs)
+	quarantined := stripePipeHealthStats(pipes, scc.pipeHealth, schedule, time.Now())
+	return timing, pipes, quarantined
 }
 
 
@@ -169,6 +199,24 @@ func bindPipeToDevice(conn *net.UDPConn, ifname string) error {
 	return sysErr
 }
 
+// stripeBatchConn is the recvmmsg/sendmmsg view of a stripe socket.
+// ipv4.Message and ipv6.Message are the same type, so either PacketConn
+// fits; the one matching the socket family is used so cmsgs and addresses
+// are parsed for that family.
+type stripeBatchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// newStripeBatchConn wraps conn for batch I/O. A socket bound to an IPv6
+// address (including the dual-stack wildcard "::") is an AF_INET6 socket.
+func newStripeBatchConn(conn *net.UDPConn) stripeBatchConn {
+	if la, ok := conn.LocalAddr().(*net.UDPAddr); ok && la.IP.To4() == nil {
+		return ipv6.NewPacketConn(conn)
+	}
+	return ipv4.NewPacketConn(conn)
+}
+
 func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPathConfig, keys *stripeKeyMaterial, logger *Logger) (*stripeClientConn, error) {
 	pipes := pathCfg.Pipes
 	if pipes <= 1 {
@@ -181,10 +229,7 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 	}
 
 	// Extract interface name for SO_BINDTODEVICE (e.g. "if:enp7s7" → "enp7s7")
-	var ifName string
-	if strings.HasPrefix(pathCfg.BindIP, "if:") {
-		ifName = strings.TrimPrefix(pathCfg.BindIP, "if:")
-	}
+	ifName := bindInterfaceName(pathCfg.BindIP)
 
 	remoteHost := pathCfg.RemoteAddr
 	if remoteHost == "" {
@@ -200,7 +245,7 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 		stripePort = remotePort + 1000
 	}
 
-	serverAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(remoteHost, fmt.Sprintf("%d", stripePort)))
+	serverAddr, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(remoteHost, fmt.Sprintf("%d", stripePort)))
 	if err != nil {
 		return nil, fmt.Errorf("stripe: resolve remote: %w", err)
 	}
@@ -210,6 +255,8 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 		return nil, fmt.Errorf("stripe: parse tun cidr: %w", err)
 	}
 	sessionID := pathSessionID(tunIP, pathCfg.Name)
+	tunAddr, _ := netip.AddrFromSlice(tunIP)
+	tunAddr = tunAddr.Unmap()
 
 	dataK := cfg.StripeDataShards
 	if dataK <= 0 {
@@ -270,6 +317,7 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 	if err != nil {
 		return nil, fmt.Errorf("stripe: TX cipher: %w", err)
 	}
+	txCipher.setRekeyPolicy(stripeRekeyPolicy(cfg))
 	rxCipher, err := newStripeCipher(keys.s2cKey)
 	if err != nil {
 		return nil, fmt.Errorf("stripe: RX cipher: %w", err)
@@ -278,12 +326,13 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 	scc := &stripeClientConn{
 		serverAddr: serverAddr,
 		sessionID:  sessionID,
-		tunIPU32:   ipToUint32(tunIP),
+		tunIP:      tunAddr,
 		dataK:      dataK,
 		parityM:    parityM,
 		enc:        enc,
 		fecMode:    fecMode,
 		fecType:    fecType,
+		pipeScheduler: cfg.StripePipeScheduler,
 		txGroup:    make([][]byte, 0, dataK),
 		rxCh:       make(chan []byte, 512),
 		rxGroups:   make(map[uint32]*fecGroup),
@@ -349,7 +398,7 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 	// Open N UDP sockets bound to the same interface
 	for i := 0; i < pipes; i++ {
 		laddr := &net.UDPAddr{IP: net.ParseIP(bindIP), Port: 0}
-		conn, err := net.ListenUDP("udp4", laddr)
+		conn, err := net.ListenUDP(udpNetworkFor(bindIP), laddr)
 		if err != nil {
 			scc.Close()
 			return nil, fmt.Errorf("stripe: listen pipe %d: %w", i, err)
@@ -364,6 +413,8 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 		scc.pipes = append(scc.pipes, conn)
 		logger.Infof("stripe pipe %d: local=%s → remote=%s dev=%s", i, conn.LocalAddr(), serverAddr, ifName)
 	}
+	scc.pipeHealth = newStripePipeHealths(len(scc.pipes), time.Now())
+	scc.pipeTiming = newStripePipeTimings(len(scc.pipes))
 
 	// Probe GSO (UDP_SEGMENT) support on the first pipe.
 	// If supported, allocate per-pipe accumulation buffers for batch TX.
@@ -409,10 +460,7 @@ func newStripeClientConn(ctx context.Context, cfg *Config, pathCfg MultipathPath
 			return nil, ctx.Err()
 		}
 		for i, pipe := range scc.pipes {
-			regPayload := make([]byte, 6)
-			binary.BigEndian.PutUint32(regPayload[0:4], ipToUint32(tunIP))
-			regPayload[4] = uint8(i)
-			regPayload[5] = uint8(len(scc.pipes))
+			regPayload := encodeStripeRegister(scc.tunIP, i, len(scc.pipes))
 
 			pkt := make([]byte, stripeHdrLen+len(regPayload))
 			encodeStripeHdr(pkt, &stripeHdr{
@@ -541,8 +589,7 @@ func (scc *stripeClientConn) SendDatagram(pkt []byte) error {
 		if scc.pacer != nil {
 			scc.pacer.pace(len(wirePkt))
 		}
-		idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-		pipeIdx := int(idx) % len(scc.pipes)
+		pipeIdx := scc.nextTxPipe()
 		if scc.gsoEnabled && atomic.LoadUint32(&scc.gsoDisabled) == 0 {
 			scc.gsoAccumLocked(pipeIdx, wirePkt)
 		} else {
@@ -706,8 +753,7 @@ func (scc *stripeClientConn) sendFECGroupLocked() {
 		if scc.pacer != nil {
 			scc.pacer.pace(len(wirePkt))
 		}
-		idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-		pipeIdx := int(idx) % len(scc.pipes)
+		pipeIdx := scc.nextTxPipe()
 		if gsoActive {
 			scc.gsoAccumLocked(pipeIdx, wirePkt)
 		} else {
@@ -730,8 +776,7 @@ func (scc *stripeClientConn) sendFECGroupLocked() {
 		if scc.pacer != nil {
 			scc.pacer.pace(len(wirePkt))
 		}
-		idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-		pipeIdx := int(idx) % len(scc.pipes)
+		pipeIdx := scc.nextTxPipe()
 		if gsoActive {
 			scc.gsoAccumLocked(pipeIdx, wirePkt)
 		} else {
@@ -758,8 +803,7 @@ func (scc *stripeClientConn) sendXorRepairLocked(firstSeq uint32, window uint8,
 	if scc.pacer != nil {
 		scc.pacer.pace(len(wirePkt))
 	}
-	idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-	pipeIdx := int(idx) % len(scc.pipes)
+	pipeIdx := scc.nextTxPipe()
 	if scc.gsoEnabled && atomic.LoadUint32(&scc.gsoDisabled) == 0 {
 		scc.gsoAccumLocked(pipeIdx, wirePkt)
 	} else {
@@ -783,8 +827,7 @@ func (scc *stripeClientConn) sendRLCRepairLocked(firstSeq uint32, window uint8,
 	if scc.pacer != nil {
 		scc.pacer.pace(len(wirePkt))
 	}
-	idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-	pipeIdx := int(idx) % len(scc.pipes)
+	pipeIdx := scc.nextTxPipe()
 	if scc.gsoEnabled && atomic.LoadUint32(&scc.gsoDisabled) == 0 {
 		scc.gsoAccumLocked(pipeIdx, wirePkt)
 	} else {
@@ -807,8 +850,7 @@ func (scc *stripeClientConn) sendRSILParityLocked(p rsilParity) {
 	if scc.pacer != nil {
 		scc.pacer.pace(len(wirePkt))
 	}
-	idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-	pipeIdx := int(idx) % len(scc.pipes)
+	pipeIdx := scc.nextTxPipe()
 	if scc.gsoEnabled && atomic.LoadUint32(&scc.gsoDisabled) == 0 {
 		scc.gsoAccumLocked(pipeIdx, wirePkt)
 	} else {
@@ -869,6 +911,23 @@ func (scc *stripeClientConn) getEffectiveM() int {
 	}
 }
 
+// fecSpread returns the shards of a FEC group (or repair window) as sent now
+// and how many of them it rebuilds; 0, 0 while no parity is sent.
+func (scc *stripeClientConn) fecSpread() (groupLen, tolerance int) {
+	switch {
+	case scc.rsilTx != nil:
+		return scc.rsilTx.K + scc.rsilTx.M, scc.rsilTx.M
+	case scc.xorTx != nil && atomic.LoadInt32(&scc.xorActive) == 1:
+		return scc.xorTx.window + 1, 1
+	case scc.rlcTx != nil && atomic.LoadInt32(&scc.rlcActive) == 1:
+		return scc.rlcTx.window + 1, 1
+	}
+	if m := scc.getEffectiveM(); m > 0 {
+		return scc.dataK + m, m
+	}
+	return 0, 0
+}
+
 // ─── Client GSO TX (UDP Generic Segmentation Offload) ─────────────────────
 //
 // Instead of N individual WriteToUDP syscalls per pipe, GSO concatenates
@@ -989,21 +1048,106 @@ func (scc *stripeClientConn) FlushTxBatch() {
 	scc.txMu.Unlock()
 }
 
+// nextTxPipe returns the pipe for the next shard, round-robin over the TX
+// schedule, and counts the shard on it.
+func (scc *stripeClientConn) nextTxPipe() int {
+	idx := int(atomic.AddUint32(&scc.txPipe, 1) - 1)
+	if live := scc.txLive.Load(); live != nil {
+		idx = (*live)[idx%len(*live)]
+	} else {
+		idx %= len(scc.pipes)
+	}
+	scc.pipeHealth[idx].sentShard()
+	return idx
+}
+
+// checkPipes quarantines the pipes that went silent, brings back those that
+// recovered and recomputes the TX schedule. Called every keepalive interval.
+func (scc *stripeClientConn) checkPipes(now time.Time) {
+	changed := false
+	for i, h := range scc.pipeHealth {
+		if !h.check(now) {
+			continue
+		}
+		changed = true
+		if h.up() {
+			scc.logger.Infof("stripe: session %08x pipe %d/%d back in service", scc.sessionID, i, len(scc.pipes))
+		} else {
+			scc.logger.Infof("stripe: session %08x pipe %d/%d quarantined (silent for %v)",
+				scc.sessionID, i, len(scc.pipes), h.silentFor(now).Round(time.Second))
+		}
+	}
+	if scc.pipeScheduler == stripeSchedulerWeighted {
+		live := stripeLivePipes(scc.pipeHealth)
+		if live == nil {
+			live = make([]int, len(scc.pipes))
+			for i := range live {
+				live[i] = i
+			}
+		}
+		groupLen, tolerance := scc.fecSpread()
+		scc.setTxSchedule(stripeWeightedSchedule(live, scc.pipeHealth, scc.pipeTiming, groupLen, tolerance))
+		return
+	}
+	if !changed {
+		return
+	}
+	scc.setTxSchedule(stripeLivePipes(scc.pipeHealth))
+}
+
+// setTxSchedule swaps in the TX schedule (nil = all pipes) if it differs from
+// the current one and restarts the round-robin at its first slot, under txMu
+// so that no FEC group is split across the two schedules: the spread cap of
+// stripeWeightedSchedule only holds for groups sent within one of them.
+func (scc *stripeClientConn) setTxSchedule(schedule []int) {
+	if cur := scc.txLive.Load(); (cur == nil) == (schedule == nil) && (cur == nil || slices.Equal(*cur, schedule)) {
+		return
+	}
+	scc.txMu.Lock()
+	defer scc.txMu.Unlock()
+	if schedule == nil {
+		scc.txLive.Store(nil)
+	} else {
+		scc.txLive.Store(&schedule)
+	}
+	atomic.StoreUint32(&scc.txPipe, 0)
+}
+
 // sendToPipe encrypts and sends a pre-built stripe packet to a pipe (round-robin).
 // Used only by low-frequency paths (keepalive, register); the FEC TX hot path
 // calls stripeEncryptShard directly to avoid the intermediate cleartext buffer.
 func (scc *stripeClientConn) sendToPipe(pkt []byte) {
-	idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-	pipe := scc.pipes[int(idx)%len(scc.pipes)]
+	pipe := scc.pipes[scc.nextTxPipe()]
 	pkt = stripeEncrypt(scc.txCipher, pkt)
 	_, _ = pipe.WriteToUDP(pkt, scc.serverAddr)
 }
 
+// sendProbe sends a path probe (see probe.go) as a stripe control packet,
+// rotating over the pipes so every pipe's NAT binding is exercised.
+func (scc *stripeClientConn) sendProbe(probe []byte) error {
+	if len(scc.pipes) == 0 {
+		return fmt.Errorf("stripe: no pipes")
+	}
+	pipe := scc.pipes[int(atomic.AddUint32(&scc.probePipe, 1))%len(scc.pipes)]
+	pkt := make([]byte, stripeHdrLen+len(probe))
+	encodeStripeHdr(pkt, &stripeHdr{
+		Magic:   stripeMagic,
+		Version: stripeVersion,
+		Type:    stripePROBE,
+		Session: scc.sessionID,
+		DataLen: uint16(len(probe)),
+	})
+	copy(pkt[stripeHdrLen:], probe)
+	pkt = stripeEncrypt(scc.txCipher, pkt)
+	_, err := pipe.WriteToUDP(pkt, scc.serverAddr)
+	return err
+}
+
 // ─── Client RX internals ──────────────────────────────────────────────────
 
 func (scc *stripeClientConn) recvPipeLoop(ctx context.Context, pipeIdx int, conn *net.UDPConn) {
 	// ── Batch RX: use recvmmsg to read up to stripeBatchSize packets per syscall ──
-	pc := ipv4.NewPacketConn(conn)
+	pc := newStripeBatchConn(conn)
 	msgs := make([]ipv4.Message, stripeBatchSize)
 	for i := range msgs {
 		msgs[i].Buffers = make([][]byte, 1)
@@ -1042,7 +1186,14 @@ func (scc *stripeClientConn) recvPipeLoop(ctx context.Context, pipeIdx int, conn
 
 			raw := msgs[mi].Buffers[0][:n]
 			if scc.rxCipher != nil {
-				decrypted, decOK := stripeDecryptPkt(scc.rxCipher.aead, raw)
+				decrypted, decOK, replayed := stripeDecryptRx(scc.rxCipher, raw)
+				if replayed {
+					count := atomic.AddUint64(&scc.securityReplayDrop, 1)
+					if count <= 3 || count%1000 == 0 {
+						scc.logger.Errorf("stripe: pipe %d replayed packet dropped (total=%d)", pipeIdx, count)
+					}
+					continue
+				}
 				if !decOK {
 					count := atomic.AddUint64(&scc.securityDecryptFail, 1)
 					if count <= 3 || count%1000 == 0 {
@@ -1060,7 +1211,9 @@ func (scc *stripeClientConn) recvPipeLoop(ctx context.Context, pipeIdx int, conn
 
 			payload := raw[stripeHdrLen:]
 
-			atomic.StoreInt64(&scc.lastRx, time.Now().UnixNano())
+			rxNow := time.Now().UnixNano()
+			atomic.StoreInt64(&scc.lastRx, rxNow)
+			scc.pipeHealth[pipeIdx].recordRx(rxNow, stripeIsShard(hdr.Type))
 
 			switch hdr.Type {
 			case stripeDATA:
@@ -1075,6 +1228,17 @@ func (scc *stripeClientConn) recvPipeLoop(ctx context.Context, pipeIdx int, conn
 					if peerLoss > 0 {
 						atomic.StoreInt64(&scc.lastPeerLoss, time.Now().UnixNano())
 					}
+					// Timing block: the reply echoes our keepalive on this pipe.
+					if scc.pipeTiming[pipeIdx].observe(payload[1:], monoNowNs()) {
+						scc.pipeHealth[pipeIdx].observeDelivery(payload[1+stripeTimingLen:])
+					}
+				}
+			case stripePROBE:
+				// Echoed path probe: hand it to multipathConn.recvLoop,
+				// which recognises it by its probe header.
+				select {
+				case scc.rxCh <- append([]byte(nil), payload...):
+				default:
 				}
 			case stripeNACK:
 				scc.handleNack(hdr, payload)
@@ -1379,6 +1543,14 @@ func (scc *stripeClientConn) keepaliveLoop(ctx context.Context) {
 				return
 			}
 
+			// ── Key epochs: switch TX key when due, drop old RX key ──
+			now := time.Now()
+			scc.txCipher.rekeyTick(now)
+			scc.rxCipher.rekeyTick(now)
+
+			// ── Pipe liveness: quarantine silent pipes, keepalives below re-probe them ──
+			scc.checkPipes(now)
+
 			// ── Compute RX loss for this window ──
 			rxLoss := scc.computeRxLoss()
 
@@ -1392,10 +1564,7 @@ func (scc *stripeClientConn) keepaliveLoop(ctx context.Context) {
 			// this ensures pipe mappings are refreshed without a full restart.
 			if tickCount%6 == 0 {
 				for i, pipe := range scc.pipes {
-					regPayload := make([]byte, 6)
-					binary.BigEndian.PutUint32(regPayload[0:4], scc.tunIPU32)
-					regPayload[4] = uint8(i)
-					regPayload[5] = uint8(len(scc.pipes))
+					regPayload := encodeStripeRegister(scc.tunIP, i, len(scc.pipes))
 					pkt := make([]byte, stripeHdrLen+len(regPayload))
 					encodeStripeHdr(pkt, &stripeHdr{
 						Magic:   stripeMagic,
@@ -1411,8 +1580,8 @@ func (scc *stripeClientConn) keepaliveLoop(ctx context.Context) {
 			}
 
 			for i, pipe := range scc.pipes {
-				// Keepalive payload: [pipe_index: 1B][rx_loss_pct: 1B]
-				pkt := make([]byte, stripeHdrLen+2)
+				// Keepalive payload: [pipe_index: 1B][rx_loss_pct: 1B][timing: 24B][rx_shards: 4B]
+				pkt := make([]byte, stripeHdrLen+2+stripeTimingLen+stripeDeliveryLen)
 				encodeStripeHdr(pkt, &stripeHdr{
 					Magic:   stripeMagic,
 					Version: stripeVersion,
@@ -1421,6 +1590,8 @@ func (scc *stripeClientConn) keepaliveLoop(ctx context.Context) {
 				})
 				pkt[stripeHdrLen] = byte(i)
 				pkt[stripeHdrLen+1] = rxLoss
+				scc.pipeTiming[i].encode(pkt[stripeHdrLen+2:], monoNowNs())
+				scc.pipeHealth[i].encodeDelivery(pkt[stripeHdrLen+2+stripeTimingLen:])
 				pkt = stripeEncrypt(scc.txCipher, pkt)
 				_, _ = pipe.WriteToUDP(pkt, scc.serverAddr)
 			}
@@ -1508,6 +1679,8 @@ func (scc *stripeClientConn) updateAdaptiveM() {
 
 	peerLoss := atomic.LoadUint32(&scc.peerLossRate)
 	lastLoss := time.Unix(0, atomic.LoadInt64(&scc.lastPeerLoss))
+	timing, _ := stripeTimingStats(scc.pipeTiming, nil)
+	lossThreshold := stripeFECLossThreshold(timing)
 
 	// Step 4.28 Anti-waste sentinel: Server says "your XOR is useless"
 	if peerLoss == 255 {
@@ -1523,7 +1696,7 @@ func (scc *stripeClientConn) updateAdaptiveM() {
 	// ── RS adaptive (parityM > 0) ──
 	if scc.parityM > 0 {
 		currentM := atomic.LoadInt32(&scc.adaptiveM)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentM == 0 {
 				atomic.StoreInt32(&scc.adaptiveM, int32(scc.parityM))
 				scc.logger.Infof("adaptive FEC: TX M=0→%d (peer reports %d%% loss)", scc.parityM, peerLoss)
@@ -1539,7 +1712,7 @@ func (scc *stripeClientConn) updateAdaptiveM() {
 	// ── XOR adaptive (xorTx != nil) ──
 	if scc.xorTx != nil {
 		currentXor := atomic.LoadInt32(&scc.xorActive)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentXor == 0 {
 				atomic.StoreInt32(&scc.xorActive, 1)
 				scc.logger.Infof("adaptive XOR FEC: ON (peer reports %d%% loss)", peerLoss)
@@ -1554,7 +1727,7 @@ func (scc *stripeClientConn) updateAdaptiveM() {
 
 	if scc.rlcTx != nil {
 		currentRLC := atomic.LoadInt32(&scc.rlcActive)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentRLC == 0 {
 				atomic.StoreInt32(&scc.rlcActive, 1)
 				scc.logger.Infof("adaptive RLC FEC: ON (peer reports %d%% loss)", peerLoss)
@@ -1704,8 +1877,7 @@ func (scc *stripeClientConn) handleNack(hdr stripeHdr, payload []byte) {
 			GroupDataN: 1,
 			DataLen:    dataLen,
 		}, shardData)
-		idx := atomic.AddUint32(&scc.txPipe, 1) - 1
-		pipe := scc.pipes[int(idx)%len(scc.pipes)]
+		pipe := scc.pipes[scc.nextTxPipe()]
 		_, _ = pipe.WriteToUDP(wirePkt, scc.serverAddr)
 		retxCount++
 	}
@@ -1760,32 +1932,26 @@ func (scc *stripeClientConn) arqNackLoop(ctx context.Context) {
 }
 
 // dynamicPacingLoop dynamically adjusts the pacing rate (txtimeGapNs) based on
-// current throughput and RTT to avoid queue buildup when bandwidth drops.
+// the loss and the queueing delay the server reports for our TX direction, to
+// avoid queue buildup when bandwidth drops.
 func (scc *stripeClientConn) dynamicPacingLoop(ctx context.Context, baseRate int) {
-ticker := time.NewTicker(200 * time.Millisecond)
-defer ticker.Stop()
-
-// Target base pacing converted to nanoseconds
-baseNs := int64(1000000000 / baseRate)
-
-for {
-select {
-case <-ctx.Done():
-return
-case <-ticker.C:
-// Minimal dynamic pacing: 
-// In Phase 4d we scale pacing off RTT jitter & loss.
-// Currently this is a base implementation avoiding panic.
-// TODO: Add real scaling based on srtt and ewma bandwidth
-targetNs := baseNs
-
-// Increase pacing gap slightly if loss is high
-loss := atomic.LoadUint32(&scc.peerLossRate)
-if loss > 0 && loss < 255 {
-targetNs += targetNs * int64(loss) / 100 // up to 2x slower
-}
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
 
-atomic.StoreInt64(&scc.txtimeGapNs, targetNs)
-}
-}
+	// Target base pacing converted to nanoseconds
+	baseNs := int64(1000000000 / baseRate)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var txOWDMs float64
+			if timing, _ := stripeTimingStats(scc.pipeTiming, nil); timing != nil {
+				txOWDMs = timing.TxOWDMs
+			}
+			loss := atomic.LoadUint32(&scc.peerLossRate)
+			atomic.StoreInt64(&scc.txtimeGapNs, stripePacingGapNs(baseNs, loss, txOWDMs))
+		}
+	}
 }
@@ -16,14 +16,37 @@ package main
 // The 16-byte header remains in cleartext so the server can identify the session
 // and look up the decryption key. It is authenticated (AAD) but not encrypted.
 // Per-packet overhead: 24 bytes (8 seq + 16 tag) — vs 20 bytes for the old MAC.
+//
+// Key epochs: the top byte of the sequence counter is the key epoch, the low
+// 56 bits count packets within it. Each direction rekeys on its own, on a
+// timer (stripe_rekey_interval_sec) or a volume limit (stripe_rekey_mb): the
+// sender switches to the next key of a one-way HKDF ratchet, and the receiver
+// — which keeps that next key ready — follows as soon as a packet of the new
+// epoch authenticates. The previous RX key is kept stripeRekeyOverlap more for
+// packets still in flight on slower pipes (FEC groups straddling the switch
+// decode normally: every shard is opened with the key of its own epoch), then
+// dropped. Raw keys are wiped once their successor is derived, so a key
+// leaked from memory does not decrypt earlier epochs. Epoch 0 packets are
+// identical to those of peers without rekeying.
+//
+// Anti-replay: every RX cipher keeps a sliding window of the sequence numbers
+// it has accepted (RFC 6479 bitmap, as in IPsec/WireGuard). A packet whose tag
+// verifies but whose sequence number was already seen, or lies more than
+// stripeReplayWindow behind the highest one, is dropped before dispatch, so a
+// captured REGISTER/KEEPALIVE cannot be re-injected to move a pipe's address.
 
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
 )
 
 const (
@@ -37,6 +60,25 @@ const (
 	stripeCryptoSeqLen   = 8  // explicit 8-byte sequence number
 	stripeCryptoTagLen   = 16 // AES-GCM authentication tag
 	stripeCryptoOverhead = stripeCryptoSeqLen + stripeCryptoTagLen // 24 bytes total
+
+	// Replay window: 128 words of 64 bits. One word is the ring slack, so the
+	// window accepts sequence numbers up to 8128 behind the highest seen —
+	// far more than the reordering across pipes (a few FEC groups per pipe).
+	stripeReplayRingWords = 128
+	stripeReplayWindow    = (stripeReplayRingWords - 1) * 64
+
+	// HKDF info of the key ratchet (followed by the new epoch byte).
+	stripeRekeyLabel = "mpquic-stripe-rekey"
+	// Position of the key epoch in the sequence counter.
+	stripeEpochShift = 56
+	// How long the previous RX key stays usable after a switch, and the
+	// minimum time between two TX switches (so the peer never needs an
+	// epoch older than the previous one).
+	stripeRekeyOverlap = 10 * time.Second
+
+	// Defaults of stripe_rekey_interval_sec / stripe_rekey_mb.
+	defaultStripeRekeyIntervalSec = 3600
+	defaultStripeRekeyMB          = 64 * 1024
 )
 
 // ── Key material ──────────────────────────────────────────────────────────
@@ -93,15 +135,19 @@ func (pk *stripePendingKeys) Delete(sessionID uint32) {
 
 // ── Cipher ────────────────────────────────────────────────────────────────
 
-// stripeCipher wraps an AES-256-GCM AEAD with a monotonic TX nonce counter.
-// Safe for concurrent use (txNonce is atomic, AEAD is goroutine-safe).
-type stripeCipher struct {
+// stripeEpochKey is the AES-256-GCM key of one key epoch.
+type stripeEpochKey struct {
+	epoch   uint8
 	aead    cipher.AEAD
-	txNonce uint64 // atomic: next TX sequence number
+	secret  [32]byte // ratchet input for the next epoch, wiped once used
+	txNonce uint64   // atomic: next TX counter within the epoch
+	txBytes uint64   // atomic: payload bytes sealed under this key
+	created time.Time
+
+	replay stripeReplayFilter // RX sequence numbers seen
 }
 
-// newStripeCipher creates an AES-256-GCM cipher from a 32-byte key.
-func newStripeCipher(key [32]byte) (*stripeCipher, error) {
+func newStripeEpochKey(epoch uint8, key [32]byte) (*stripeEpochKey, error) {
 	block, err := aes.NewCipher(key[:])
 	if err != nil {
 		return nil, fmt.Errorf("stripe: AES init: %w", err)
@@ -110,7 +156,212 @@ func newStripeCipher(key [32]byte) (*stripeCipher, error) {
 	if err != nil {
 		return nil, fmt.Errorf("stripe: GCM init: %w", err)
 	}
-	return &stripeCipher{aead: aead}, nil
+	return &stripeEpochKey{epoch: epoch, aead: aead, secret: key, created: time.Now()}, nil
+}
+
+// successor derives the key of the next epoch and wipes k's secret.
+func (k *stripeEpochKey) successor() (*stripeEpochKey, error) {
+	var next [32]byte
+	epoch := k.epoch + 1
+	r := hkdf.Expand(sha256.New, k.secret[:], append([]byte(stripeRekeyLabel), epoch))
+	if _, err := io.ReadFull(r, next[:]); err != nil {
+		return nil, fmt.Errorf("stripe: rekey derive: %w", err)
+	}
+	k.secret = [32]byte{}
+	return newStripeEpochKey(epoch, next)
+}
+
+// nextSeq reserves the sequence number of the next packet sealed with k.
+func (k *stripeEpochKey) nextSeq(payloadLen int) uint64 {
+	atomic.AddUint64(&k.txBytes, uint64(payloadLen))
+	return uint64(k.epoch)<<stripeEpochShift | (atomic.AddUint64(&k.txNonce, 1) - 1)
+}
+
+// stripeCipher holds the keys of one direction of a stripe session: the
+// current epoch, the next one (derived ahead so the receiver can follow a
+// switch) and, on the RX side, the previous one during the overlap.
+// Safe for concurrent use: the current key is an atomic pointer, epoch
+// switches happen under mu, the AEADs are goroutine-safe.
+type stripeCipher struct {
+	cur atomic.Pointer[stripeEpochKey]
+
+	mu        sync.Mutex
+	next      *stripeEpochKey
+	prev      *stripeEpochKey // RX only
+	prevUntil time.Time
+
+	// TX rekey policy (zero = never); set with setRekeyPolicy.
+	rekeyInterval time.Duration
+	rekeyBytes    uint64
+
+	rekeys uint64 // atomic: epoch switches so far
+}
+
+// newStripeCipher creates an AES-256-GCM cipher from a 32-byte key, which
+// becomes the key of epoch 0.
+func newStripeCipher(key [32]byte) (*stripeCipher, error) {
+	k, err := newStripeEpochKey(0, key)
+	if err != nil {
+		return nil, err
+	}
+	next, err := k.successor()
+	if err != nil {
+		return nil, err
+	}
+	sc := &stripeCipher{next: next}
+	sc.cur.Store(k)
+	return sc, nil
+}
+
+// setRekeyPolicy makes a TX cipher switch epoch after interval or after
+// maxBytes of payload, whichever comes first (zero disables either limit).
+// Must be called before the cipher is used.
+func (sc *stripeCipher) setRekeyPolicy(interval time.Duration, maxBytes uint64) {
+	sc.rekeyInterval = interval
+	sc.rekeyBytes = maxBytes
+}
+
+// stripeRekeyPolicy returns the TX rekey limits configured by
+// stripe_rekey_interval_sec and stripe_rekey_mb (-1 = no limit).
+func stripeRekeyPolicy(cfg *Config) (interval time.Duration, maxBytes uint64) {
+	if cfg.StripeRekeyIntervalSec > 0 {
+		interval = time.Duration(cfg.StripeRekeyIntervalSec) * time.Second
+	}
+	if cfg.StripeRekeyMB > 0 {
+		maxBytes = uint64(cfg.StripeRekeyMB) << 20
+	}
+	return interval, maxBytes
+}
+
+// txKey returns the key to seal the next packet with, switching epoch first
+// when the volume limit has been reached.
+func (sc *stripeCipher) txKey() *stripeEpochKey {
+	k := sc.cur.Load()
+	if sc.rekeyBytes > 0 && atomic.LoadUint64(&k.txBytes) >= sc.rekeyBytes {
+		sc.rekeyTick(time.Now())
+		k = sc.cur.Load()
+	}
+	return k
+}
+
+// rekeyTick runs the time-based part of the key schedule: a TX cipher whose
+// key is due switches to the next epoch, an RX cipher drops the previous key
+// once the overlap is over. Called from the keepalive paths.
+func (sc *stripeCipher) rekeyTick(now time.Time) {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.prev != nil && now.After(sc.prevUntil) {
+		sc.prev = nil
+	}
+	if sc.rekeyInterval <= 0 && sc.rekeyBytes == 0 {
+		return
+	}
+	k := sc.cur.Load()
+	age := now.Sub(k.created)
+	if age < stripeRekeyOverlap {
+		return
+	}
+	if (sc.rekeyInterval > 0 && age >= sc.rekeyInterval) ||
+		(sc.rekeyBytes > 0 && atomic.LoadUint64(&k.txBytes) >= sc.rekeyBytes) {
+		sc.advanceLocked(now, false)
+	}
+}
+
+// advanceLocked makes sc.next the current key. keepPrev retains the old
+// current key for the overlap (RX side). Caller holds sc.mu.
+func (sc *stripeCipher) advanceLocked(now time.Time, keepPrev bool) {
+	next := sc.next
+	succ, err := next.successor()
+	if err != nil {
+		return // keep the current key; retried on the next switch
+	}
+	next.created = now
+	sc.next = succ
+	if keepPrev {
+		sc.prev, sc.prevUntil = sc.cur.Load(), now.Add(stripeRekeyOverlap)
+	}
+	sc.cur.Store(next)
+	atomic.AddUint64(&sc.rekeys, 1)
+}
+
+// rxKey returns the key for a received packet of the given epoch, or nil.
+func (sc *stripeCipher) rxKey(epoch uint8) *stripeEpochKey {
+	k := sc.cur.Load()
+	if epoch == k.epoch {
+		return k
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	switch {
+	case epoch == sc.next.epoch:
+		return sc.next
+	case sc.prev != nil && epoch == sc.prev.epoch && time.Now().Before(sc.prevUntil):
+		return sc.prev
+	}
+	return nil
+}
+
+// rxAdvance follows the peer to the epoch of k once a packet sealed with k
+// has authenticated.
+func (sc *stripeCipher) rxAdvance(k *stripeEpochKey) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.next == k {
+		sc.advanceLocked(time.Now(), true)
+	}
+}
+
+// epochStats returns the current key epoch and the number of switches.
+func (sc *stripeCipher) epochStats() (epoch uint8, rekeys uint64) {
+	if sc == nil {
+		return 0, 0
+	}
+	return sc.cur.Load().epoch, atomic.LoadUint64(&sc.rekeys)
+}
+
+// ── Replay window ─────────────────────────────────────────────────────────
+
+// stripeReplayFilter is a sliding window over received sequence numbers.
+// Each key epoch has its own filter, so a re-keyed session starts from an
+// empty one.
+type stripeReplayFilter struct {
+	mu   sync.Mutex
+	last uint64 // highest sequence number accepted
+	ring [stripeReplayRingWords]uint64
+}
+
+// accept reports whether seq is new and marks it as seen. It must only be
+// called for packets whose tag has been verified: otherwise forged sequence
+// numbers could slide the window forward.
+func (f *stripeReplayFilter) accept(seq uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	word := seq >> 6
+	if seq > f.last {
+		// Slide forward, clearing the words the window leaves behind.
+		cur := f.last >> 6
+		diff := word - cur
+		if diff > stripeReplayRingWords {
+			diff = stripeReplayRingWords
+		}
+		for i := cur + 1; i <= cur+diff; i++ {
+			f.ring[i%stripeReplayRingWords] = 0
+		}
+		f.last = seq
+	} else if f.last-seq > stripeReplayWindow {
+		return false // too old
+	}
+	idx := word % stripeReplayRingWords
+	bit := uint64(1) << (seq & 63)
+	if f.ring[idx]&bit != 0 {
+		return false // duplicate
+	}
+	f.ring[idx] |= bit
+	return true
 }
 
 // ── Encrypt / Decrypt ─────────────────────────────────────────────────────
@@ -130,7 +381,8 @@ func stripeEncrypt(sc *stripeCipher, pkt []byte) []byte {
 	payload := pkt[stripeHdrLen:]
 
 	// Monotonic sequence number — unique per (key, direction).
-	seq := atomic.AddUint64(&sc.txNonce, 1) - 1
+	k := sc.txKey()
+	seq := k.nextSeq(len(payload))
 
 	// 12-byte GCM nonce: [4 zero bytes][8-byte seq].
 	var nonce [12]byte
@@ -142,11 +394,11 @@ func stripeEncrypt(sc *stripeCipher, pkt []byte) []byte {
 	binary.BigEndian.PutUint64(aad[stripeHdrLen:], seq)
 
 	// Single allocation: [hdr 16][seq 8][ciphertext+tag].
-	outLen := stripeHdrLen + stripeCryptoSeqLen + len(payload) + sc.aead.Overhead()
+	outLen := stripeHdrLen + stripeCryptoSeqLen + len(payload) + k.aead.Overhead()
 	out := make([]byte, stripeHdrLen+stripeCryptoSeqLen, outLen)
 	copy(out, aad[:])
 	// Seal appends ciphertext+tag after header+seq.
-	out = sc.aead.Seal(out, nonce[:], payload, aad[:])
+	out = k.aead.Seal(out, nonce[:], payload, aad[:])
 	return out
 }
 
@@ -164,7 +416,8 @@ func stripeEncryptShard(sc *stripeCipher, hdr *stripeHdr, shard []byte) []byte {
 		return pkt
 	}
 
-	seq := atomic.AddUint64(&sc.txNonce, 1) - 1
+	k := sc.txKey()
+	seq := k.nextSeq(len(shard))
 
 	var nonce [12]byte
 	binary.BigEndian.PutUint64(nonce[4:], seq)
@@ -175,10 +428,10 @@ func stripeEncryptShard(sc *stripeCipher, hdr *stripeHdr, shard []byte) []byte {
 	binary.BigEndian.PutUint64(aad[stripeHdrLen:], seq)
 
 	// Single allocation for the entire wire packet.
-	outLen := stripeHdrLen + stripeCryptoSeqLen + len(shard) + sc.aead.Overhead()
+	outLen := stripeHdrLen + stripeCryptoSeqLen + len(shard) + k.aead.Overhead()
 	out := make([]byte, stripeHdrLen+stripeCryptoSeqLen, outLen)
 	copy(out, aad[:])
-	out = sc.aead.Seal(out, nonce[:], shard, aad[:])
+	out = k.aead.Seal(out, nonce[:], shard, aad[:])
 	return out
 }
 
@@ -196,7 +449,8 @@ func stripeEncryptShardReuse(sc *stripeCipher, hdr *stripeHdr, shard []byte, buf
 		return pkt
 	}
 
-	seq := atomic.AddUint64(&sc.txNonce, 1) - 1
+	k := sc.txKey()
+	seq := k.nextSeq(len(shard))
 
 	var nonce [12]byte
 	binary.BigEndian.PutUint64(nonce[4:], seq)
@@ -205,13 +459,13 @@ func stripeEncryptShardReuse(sc *stripeCipher, hdr *stripeHdr, shard []byte, buf
 	encodeStripeHdr(aad[:stripeHdrLen], hdr)
 	binary.BigEndian.PutUint64(aad[stripeHdrLen:], seq)
 
-	outLen := stripeHdrLen + stripeCryptoSeqLen + len(shard) + sc.aead.Overhead()
+	outLen := stripeHdrLen + stripeCryptoSeqLen + len(shard) + k.aead.Overhead()
 	if cap(*buf) < outLen {
 		*buf = make([]byte, outLen)
 	}
 	out := (*buf)[:stripeHdrLen+stripeCryptoSeqLen]
 	copy(out, aad[:])
-	out = sc.aead.Seal(out, nonce[:], shard, aad[:])
+	out = k.aead.Seal(out, nonce[:], shard, aad[:])
 	return out
 }
 
@@ -220,9 +474,41 @@ func stripeEncryptShardReuse(sc *stripeCipher, hdr *stripeHdr, shard []byte, buf
 //
 // Hot path — optimised for 1 heap allocation per call.
 func stripeDecryptPkt(aead cipher.AEAD, pkt []byte) ([]byte, bool) {
+	out, _, ok := stripeOpenPkt(aead, pkt)
+	return out, ok
+}
+
+// stripeDecryptRx decrypts a received packet with the key of its epoch and
+// checks its sequence number against that key's replay window. replayed is
+// true when the packet is authentic but was already received (or is too
+// old): callers must drop it without acting on its content. The first
+// authentic packet of the next epoch switches sc to it.
+func stripeDecryptRx(sc *stripeCipher, pkt []byte) (out []byte, ok, replayed bool) {
+	if len(pkt) < stripeHdrLen+stripeCryptoSeqLen {
+		return nil, false, false
+	}
+	k := sc.rxKey(pkt[stripeHdrLen])
+	if k == nil {
+		return nil, false, false
+	}
+	out, seq, ok := stripeOpenPkt(k.aead, pkt)
+	if !ok {
+		return nil, false, false
+	}
+	if !k.replay.accept(seq) {
+		return nil, false, true
+	}
+	if k != sc.cur.Load() {
+		sc.rxAdvance(k)
+	}
+	return out, true, false
+}
+
+// stripeOpenPkt is stripeDecryptPkt returning the packet sequence number too.
+func stripeOpenPkt(aead cipher.AEAD, pkt []byte) ([]byte, uint64, bool) {
 	minLen := stripeHdrLen + stripeCryptoSeqLen + aead.Overhead()
 	if len(pkt) < minLen {
-		return nil, false
+		return nil, 0, false
 	}
 
 	aadEnd := stripeHdrLen + stripeCryptoSeqLen
@@ -239,9 +525,9 @@ func stripeDecryptPkt(aead cipher.AEAD, pkt []byte) ([]byte, bool) {
 	var err error
 	out, err = aead.Open(out, nonce[:], ciphertext, pkt[:aadEnd])
 	if err != nil {
-		return nil, false
+		return nil, 0, false
 	}
-	return out, true
+	return out, seq, true
 }
 
 // ── Helpers ───────────────────────────────────────────────────────────────
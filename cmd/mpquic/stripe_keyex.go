@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
-	"strings"
 	"time"
 
 	quic "github.com/quic-go/quic-go"
@@ -17,7 +17,7 @@ import (
 // stripeNegotiateKey establishes a temporary QUIC connection to the server's
 // QUIC port using ALPN "mpquic-stripe-kx", exports keying material from the
 // TLS 1.3 session, and derives AES-256-GCM keys for stripe encryption.
-func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathConfig, sessionID uint32, logger *Logger) (*stripeKeyMaterial, error) {
+func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathConfig, sessionID uint32, qlogs *qlogTraces, logger *Logger) (*stripeKeyMaterial, error) {
 	// Resolve remote address (same logic as newStripeClientConn)
 	remoteHost := pathCfg.RemoteAddr
 	if remoteHost == "" {
@@ -32,14 +32,11 @@ func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathC
 	if err != nil {
 		return nil, fmt.Errorf("stripe KX: bind resolve: %w", err)
 	}
-	var ifName string
-	if strings.HasPrefix(pathCfg.BindIP, "if:") {
-		ifName = strings.TrimPrefix(pathCfg.BindIP, "if:")
-	}
+	ifName := bindInterfaceName(pathCfg.BindIP)
 
 	// Create UDP socket bound to the same interface as stripe pipes
 	laddr := &net.UDPAddr{IP: net.ParseIP(bindIP), Port: 0}
-	udpConn, err := net.ListenUDP("udp4", laddr)
+	udpConn, err := net.ListenUDP(udpNetworkFor(bindIP), laddr)
 	if err != nil {
 		return nil, fmt.Errorf("stripe KX: listen: %w", err)
 	}
@@ -59,7 +56,7 @@ func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathC
 
 	// Dial QUIC for key exchange
 	tr := &quic.Transport{Conn: udpConn}
-	raddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(remoteHost, fmt.Sprintf("%d", remotePort)))
+	raddr, err := net.ResolveUDPAddr(udpNetworkFor(bindIP), net.JoinHostPort(remoteHost, fmt.Sprintf("%d", remotePort)))
 	if err != nil {
 		tr.Close()
 		return nil, fmt.Errorf("stripe KX: resolve: %w", err)
@@ -68,38 +65,36 @@ func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathC
 	kxCtx, kxCancel := context.WithTimeout(ctx, 10*time.Second)
 	defer kxCancel()
 
-	conn, err := tr.Dial(kxCtx, raddr, tlsCfg, &quic.Config{
+	conn, err := dialEarly(kxCtx, tr, raddr, tlsCfg, &quic.Config{
 		MaxIdleTimeout: 10 * time.Second,
+		Tracer:         qlogs.tracer(pathCfg.Name, "kx", pathCfg.Qlog, nil),
 	})
 	if err != nil {
 		tr.Close()
 		return nil, fmt.Errorf("stripe KX: QUIC dial: %w", err)
 	}
 
-	// Send session ID over a stream so server can associate the key
-	stream, err := conn.OpenStreamSync(kxCtx)
-	if err != nil {
-		conn.CloseWithError(1, "stream open failed")
-		tr.Close()
-		return nil, fmt.Errorf("stripe KX: open stream: %w", err)
-	}
+	// Send session ID over a stream so server can associate the key. On a
+	// resumed session it goes out as 0-RTT; if the server rejects 0-RTT
+	// the stream is reset and the exchange is repeated after the handshake.
 	var sessBytes [4]byte
 	binary.BigEndian.PutUint32(sessBytes[:], sessionID)
-	if _, err := stream.Write(sessBytes[:]); err != nil {
-		conn.CloseWithError(1, "write failed")
-		tr.Close()
-		return nil, fmt.Errorf("stripe KX: write session ID: %w", err)
+	err = stripeKXSendSession(kxCtx, conn, sessBytes[:])
+	if errors.Is(err, quic.Err0RTTRejected) {
+		var next quic.Connection
+		if next, err = conn.NextConnection(kxCtx); err == nil {
+			err = stripeKXSendSession(kxCtx, next, sessBytes[:])
+		}
 	}
-
-	// Wait for server ACK (1 byte) before closing — ensures the server has
-	// accepted the stream and stored the key before we send CONNECTION_CLOSE.
-	var ack [1]byte
-	if _, err := io.ReadFull(stream, ack[:]); err != nil {
-		conn.CloseWithError(1, "ack read failed")
+	if err == nil {
+		// The keying material is only available once the handshake completes.
+		err = waitHandshake(kxCtx, conn)
+	}
+	if err != nil {
+		conn.CloseWithError(1, "kx failed")
 		tr.Close()
-		return nil, fmt.Errorf("stripe KX: server ack: %w", err)
+		return nil, fmt.Errorf("stripe KX: %w", err)
 	}
-	stream.Close()
 
 	// Export keying material from the TLS 1.3 session
 	state := conn.ConnectionState()
@@ -122,6 +117,25 @@ func stripeNegotiateKey(ctx context.Context, cfg *Config, pathCfg MultipathPathC
 	return km, nil
 }
 
+// stripeKXSendSession sends the stripe session ID on a new stream and waits
+// for the server's ACK (1 byte) — the server has stored the key before we
+// send CONNECTION_CLOSE.
+func stripeKXSendSession(ctx context.Context, conn quic.Connection, sessBytes []byte) error {
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
+	if _, err := stream.Write(sessBytes); err != nil {
+		return fmt.Errorf("write session ID: %w", err)
+	}
+	var ack [1]byte
+	if _, err := io.ReadFull(stream, ack[:]); err != nil {
+		return fmt.Errorf("server ack: %w", err)
+	}
+	stream.Close()
+	return nil
+}
+
 // handleStripeKeyExchange handles a QUIC connection with ALPN "mpquic-stripe-kx".
 // It reads the stripe session ID, exports matching keying material, and stores
 // the derived keys in the pending store for the stripe UDP listener to consume.
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ─── Stripe pipe liveness ─────────────────────────────────────────────────
+// Every pipe is its own 5-tuple, hence its own NAT mapping on the dish and
+// on any CGNAT in between. When one mapping dies the session keeps running on
+// the other pipes, but round-robin TX still puts 1/N of the shards on the dead
+// one. Each end therefore tracks, per pipe, the authenticated packets it
+// receives on it:
+//   - client: everything arriving on the pipe socket; at the least the server
+//     echo of the keepalive sent on that pipe every stripeKeepaliveInterval.
+//   - server: everything arriving from the pipe address; at the least the
+//     client keepalive on that pipe.
+//
+// A pipe silent for stripePipeDeadAfter (two keepalives missed) is
+// quarantined: TX skips it, while the keepalives keep probing it. The first
+// packet received on it again brings it back. If every pipe is silent none is
+// excluded; the session timeout handles that case.
+
+const stripePipeDeadAfter = 2*stripeKeepaliveInterval + 2*time.Second
+
+// Pipe states reported by the stats API.
+const (
+	stripePipeStateUp          = "up"
+	stripePipeStateQuarantined = "quarantined"
+)
+
+// stripePipeHealth tracks the liveness of one pipe and the share of our
+// shards that reaches the peer on it (stripe_scheduler.go). Lock-free on the
+// hot paths: recordRx on RX, sentShard on TX.
+type stripePipeHealth struct {
+	rxPkts      uint64 // atomic: authenticated packets received on the pipe
+	lastRx      int64  // atomic: unix-nano of the last one
+	quarantined int32  // atomic: 1 = excluded from TX
+	quarantines uint64 // atomic: times the pipe went into quarantine
+
+	txShards uint64 // atomic: data-plane shards sent on the pipe
+	rxShards uint64 // atomic: data-plane shards received on it
+
+	mu       sync.Mutex // guards the delivery estimate below
+	delivery float64    // smoothed delivery ratio, 1 until measured
+	marked   bool       // txMark/rxMark hold a previous peer report
+	txMark   uint64
+	rxMark   uint32
+}
+
+func newStripePipeHealths(n int, now time.Time) []*stripePipeHealth {
+	h := make([]*stripePipeHealth, n)
+	for i := range h {
+		h[i] = &stripePipeHealth{lastRx: now.UnixNano(), delivery: 1}
+	}
+	return h
+}
+
+// recordRx counts an authenticated packet received on the pipe at now;
+// shard tells whether it carried a data-plane shard (stripeIsShard).
+func (h *stripePipeHealth) recordRx(now int64, shard bool) {
+	atomic.AddUint64(&h.rxPkts, 1)
+	atomic.StoreInt64(&h.lastRx, now)
+	if shard {
+		atomic.AddUint64(&h.rxShards, 1)
+	}
+}
+
+func (h *stripePipeHealth) sentShard() {
+	atomic.AddUint64(&h.txShards, 1)
+}
+
+// encodeDelivery writes the rx_shards field of a keepalive sent on the pipe:
+// the shards received on it so far, wrapping at 32 bits.
+func (h *stripePipeHealth) encodeDelivery(buf []byte) {
+	binary.BigEndian.PutUint32(buf, uint32(atomic.LoadUint64(&h.rxShards)))
+}
+
+// observeDelivery updates the delivery ratio from the rx_shards field of a
+// keepalive received on the pipe. The ratio is measured between two reports
+// once at least stripeDeliveryMinShards were sent in between.
+func (h *stripePipeHealth) observeDelivery(buf []byte) {
+	if len(buf) < stripeDeliveryLen {
+		return
+	}
+	peerRx := binary.BigEndian.Uint32(buf)
+	tx := atomic.LoadUint64(&h.txShards)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.marked {
+		h.txMark, h.rxMark, h.marked = tx, peerRx, true
+		return
+	}
+	sent := tx - h.txMark
+	if sent < stripeDeliveryMinShards {
+		return
+	}
+	// Shards in flight at either report cancel out over windows; more
+	// received than sent means the peer restarted its count.
+	ratio := float64(peerRx-h.rxMark) / float64(sent)
+	if ratio > 1 {
+		ratio = 1
+	}
+	h.delivery += (ratio - h.delivery) * stripeDeliveryEWMA
+	h.txMark, h.rxMark = tx, peerRx
+}
+
+func (h *stripePipeHealth) deliveryRatio() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delivery
+}
+
+func (h *stripePipeHealth) up() bool {
+	return atomic.LoadInt32(&h.quarantined) == 0
+}
+
+func (h *stripePipeHealth) silentFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&h.lastRx)))
+}
+
+// check moves the pipe into or out of quarantine and reports whether its
+// state changed.
+func (h *stripePipeHealth) check(now time.Time) bool {
+	silent := h.silentFor(now) > stripePipeDeadAfter
+	if silent == !h.up() {
+		return false // already in the right state
+	}
+	if silent {
+		atomic.StoreInt32(&h.quarantined, 1)
+		atomic.AddUint64(&h.quarantines, 1)
+	} else {
+		atomic.StoreInt32(&h.quarantined, 0)
+	}
+	return true
+}
+
+// stripeLivePipes returns the indexes of the pipes out of quarantine, or nil
+// when all of them or none are: TX then uses every pipe.
+func stripeLivePipes(health []*stripePipeHealth) []int {
+	live := make([]int, 0, len(health))
+	for i, h := range health {
+		if h.up() {
+			live = append(live, i)
+		}
+	}
+	if len(live) == 0 || len(live) == len(health) {
+		return nil
+	}
+	return live
+}
+
+// stripePipeHealthStats adds the liveness, delivery and TX share of each pipe
+// to its stats and returns how many pipes are in quarantine. schedule is the
+// TX schedule, one entry per slot (nil = round-robin over all pipes). Pipes
+// without an address (not registered yet on the server) have no state.
+func stripePipeHealthStats(pipes []StripePipeStats, health []*stripePipeHealth, schedule []*stripePipeHealth, now time.Time) int {
+	slots := make(map[*stripePipeHealth]int, len(health))
+	for _, h := range schedule {
+		slots[h]++
+	}
+	quarantined := 0
+	for i := range pipes {
+		if i >= len(health) || health[i] == nil || pipes[i].Addr == "" {
+			continue
+		}
+		h := health[i]
+		p := &pipes[i]
+		p.State = stripePipeStateUp
+		if !h.up() {
+			p.State = stripePipeStateQuarantined
+			quarantined++
+		}
+		p.RxPkts = atomic.LoadUint64(&h.rxPkts)
+		p.LastRxSec = h.silentFor(now).Seconds()
+		p.Quarantines = atomic.LoadUint64(&h.quarantines)
+		p.TxShards = atomic.LoadUint64(&h.txShards)
+		p.RxShards = atomic.LoadUint64(&h.rxShards)
+		p.DeliveryPct = 100 * h.deliveryRatio()
+		if schedule == nil {
+			p.SharePct = 100 / float64(len(health))
+		} else {
+			p.SharePct = 100 * float64(slots[h]) / float64(len(schedule))
+		}
+	}
+	return quarantined
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStripePipeHealth_QuarantineAndRecover(t *testing.T) {
+	t0 := time.Now()
+	h := newStripePipeHealths(1, t0)[0]
+
+	if h.check(t0.Add(stripeKeepaliveInterval)) || !h.up() {
+		t.Fatal("pipe quarantined after one keepalive interval")
+	}
+	if !h.check(t0.Add(stripePipeDeadAfter+time.Second)) || h.up() {
+		t.Fatal("silent pipe not quarantined")
+	}
+	if h.check(t0.Add(stripePipeDeadAfter + 2*time.Second)) {
+		t.Fatal("state changed again without traffic")
+	}
+
+	back := t0.Add(stripePipeDeadAfter + 3*time.Second)
+	h.recordRx(back.UnixNano(), false)
+	if !h.check(back) || !h.up() {
+		t.Fatal("pipe not back in service after a packet")
+	}
+	if h.rxPkts != 1 || h.quarantines != 1 {
+		t.Fatalf("rx=%d quarantines=%d, want 1 and 1", h.rxPkts, h.quarantines)
+	}
+}
+
+func TestStripeClientConn_NextTxPipeSkipsQuarantined(t *testing.T) {
+	t0 := time.Now()
+	scc := &stripeClientConn{
+		pipes:      make([]*net.UDPConn, 3),
+		pipeHealth: newStripePipeHealths(3, t0),
+		logger:     newLogger("error"),
+	}
+	now := t0.Add(stripePipeDeadAfter + time.Second)
+	scc.pipeHealth[0].recordRx(now.UnixNano(), false)
+	scc.pipeHealth[2].recordRx(now.UnixNano(), false)
+	scc.checkPipes(now)
+
+	for i := 0; i < 6; i++ {
+		if p := scc.nextTxPipe(); p == 1 {
+			t.Fatal("TX picked the quarantined pipe")
+		}
+	}
+
+	// All silent: no pipe is excluded.
+	scc.checkPipes(now.Add(stripePipeDeadAfter + time.Second))
+	seen := map[int]bool{}
+	for i := 0; i < 3; i++ {
+		seen[scc.nextTxPipe()] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("pipes used = %v, want all three", seen)
+	}
+}
+
+func TestStripeServer_ActivePipesSkipQuarantined(t *testing.T) {
+	t0 := time.Now()
+	ss := &stripeServer{logger: newLogger("error")}
+	sess := &stripeSession{
+		pipes: []*net.UDPAddr{
+			{IP: net.IPv4(198, 51, 100, 1), Port: 1000},
+			{IP: net.IPv4(198, 51, 100, 1), Port: 1001},
+			nil, // not registered
+		},
+		pipeHealth: newStripePipeHealths(3, t0),
+	}
+	now := t0.Add(stripePipeDeadAfter + time.Second)
+	sess.pipeHealth[1].recordRx(now.UnixNano(), false)
+
+	if !ss.checkPipesLocked(sess, now) {
+		t.Fatal("no state change for the silent pipe")
+	}
+	ss.rebuildActivePipesLocked(sess)
+	if len(sess.txActivePipes) != 1 || sess.txActivePipes[0] != sess.pipes[1] {
+		t.Fatalf("active pipes = %v, want only pipe 1", sess.txActivePipes)
+	}
+	sess.nextTxAddr(sess.txActivePipes, sess.txActiveHealth)
+	ss.rebuildActivePipesLocked(sess)
+	if sess.txPipe != 1 {
+		t.Fatalf("txPipe = %d after an unchanged rebuild, want 1", sess.txPipe)
+	}
+
+	_, per := stripeTimingStats(newStripePipeTimings(3), []string{"a", "b", ""})
+	if n := stripePipeHealthStats(per, sess.pipeHealth, nil, now); n != 1 {
+		t.Fatalf("quarantined = %d, want 1", n)
+	}
+	if per[0].State != stripePipeStateQuarantined || per[1].State != stripePipeStateUp || per[2].State != "" {
+		t.Fatalf("states = %q %q %q", per[0].State, per[1].State, per[2].State)
+	}
+}
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
 	"testing"
 )
@@ -96,14 +94,14 @@ func BenchmarkTxShardBufReuse(b *testing.B) {
 // TestStripeEncryptShardReuse_Correctness verifies that stripeEncryptShardReuse
 // produces ciphertext that decrypts to the same plaintext as stripeEncryptShard.
 func TestStripeEncryptShardReuse_Correctness(t *testing.T) {
-	key := make([]byte, 32)
-	rand.Read(key)
-	block, _ := aes.NewCipher(key)
-	aead, _ := cipher.NewGCM(block)
+	var key [32]byte
+	rand.Read(key[:])
 
 	// Two independent ciphers (separate nonce counters)
-	sc1 := &stripeCipher{aead: aead}
-	sc2 := &stripeCipher{aead: aead}
+	sc1, _ := newStripeCipher(key)
+	sc2, _ := newStripeCipher(key)
+	rx, _ := newStripeCipher(key)
+	aead := rx.cur.Load().aead
 
 	shard := make([]byte, 1402)
 	for i := range shard {
@@ -142,11 +140,9 @@ func TestStripeEncryptShardReuse_Correctness(t *testing.T) {
 // BenchmarkEncryptShardReuse compares stripeEncryptShard (allocating) vs
 // stripeEncryptShardReuse (zero-alloc after warmup) for 1400-byte payloads.
 func BenchmarkEncryptShardReuse(b *testing.B) {
-	key := make([]byte, 32)
-	rand.Read(key)
-	block, _ := aes.NewCipher(key)
-	aead, _ := cipher.NewGCM(block)
-	sc := &stripeCipher{aead: aead}
+	var key [32]byte
+	rand.Read(key[:])
+	sc, _ := newStripeCipher(key)
 	shard := make([]byte, 1402) // 2-byte prefix + 1400 payload
 	hdr := &stripeHdr{
 		Magic:      stripeMagic,
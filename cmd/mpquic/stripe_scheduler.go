@@ -0,0 +1,215 @@
+package main
+
+import "math"
+
+// ─── Stripe pipe scheduler ────────────────────────────────────────────────
+// By default (stripe_pipe_scheduler: rr) TX spreads shards round-robin over
+// the pipes out of quarantine. Starlink however often shapes one 5-tuple
+// harder than another: a pipe can lose a few percent or queue tens of ms
+// more while still being alive. In weighted mode each end gives every live
+// pipe a share of its TX, recomputed every keepalive interval from:
+//   - delivery ratio: shards the peer received on the pipe / shards we sent
+//     on it, over the last keepalive window(s). Every keepalive carries the
+//     receiver's count of data-plane shards received on that pipe
+//     (rx_shards, after the timing block).
+//   - delay: the pipe's smoothed RTT against the best pipe's.
+//
+// score = max(0, 1 − stripeDeliveryLossGain·loss) × clamp(bestRTT/RTT, ½, 1)
+//
+// Shares follow the scores but stay within [¼, 2]× the round-robin share: a
+// bad pipe keeps some traffic, which keeps measuring it. They are further
+// capped so that a FEC group (or repair window) never has more shards on one
+// pipe than its parity can rebuild, or than round-robin would put there if
+// that is already more: losing any single pipe stays recoverable whenever it
+// was with round-robin. The cap holds on the schedule itself, over every
+// window of a group's length, not only on the shares. With few pipes and
+// little parity slack this leaves no room to weight, and the schedule is
+// plain round-robin.
+//
+// The shares become a schedule of pipe indexes, each pipe's slots evenly
+// apart (smooth weighted round-robin); TX walks it like the round-robin list.
+
+// Values of stripe_pipe_scheduler.
+const (
+	stripeSchedulerRR       = "rr"
+	stripeSchedulerWeighted = "weighted"
+)
+
+const (
+	stripeDeliveryLen = 4 // rx_shards field of the keepalive
+
+	stripeDeliveryMinShards = 50  // shards sent before a window is measured
+	stripeDeliveryEWMA      = 0.5 // weight of the newest window
+	stripeDeliveryLossGain  = 5.0 // 20% loss scores a pipe 0
+
+	stripeDelayMinFactor = 0.5 // a slow pipe still scores at least half
+	stripeScoreFloor     = 1e-6
+
+	stripeShareMin = 0.25 // × the round-robin share
+	stripeShareMax = 2.0
+
+	stripeScheduleSlots = 8 // schedule entries per pipe at round-robin share
+)
+
+// stripeIsShard reports whether packets of type t carry data-plane shards,
+// the packets the delivery ratio is measured on.
+func stripeIsShard(t uint8) bool {
+	switch t {
+	case stripeDATA, stripePARITY, stripeXOR_REPAIR, stripeRLC_REPAIR, stripeRS_IL_PARITY:
+		return true
+	}
+	return false
+}
+
+// stripeWeightedSchedule returns the TX schedule over the live pipes
+// (indexes into health and timing) for FEC groups of groupLen shards that
+// rebuild up to tolerance lost ones (0, 0 without parity).
+func stripeWeightedSchedule(live []int, health []*stripePipeHealth, timing []*stripePipeTiming, groupLen, tolerance int) []int {
+	delivery := make([]float64, len(live))
+	rttMs := make([]float64, len(live))
+	for i, p := range live {
+		delivery[i] = 1
+		if p < len(health) && health[p] != nil {
+			delivery[i] = health[p].deliveryRatio()
+		}
+		if p < len(timing) && timing[p] != nil {
+			rttMs[i] = timing[p].snapshot().RTTMs
+		}
+	}
+	return stripeSchedule(live, stripePipeShares(delivery, rttMs, groupLen, tolerance), groupLen, tolerance)
+}
+
+// stripePipeShares turns per-pipe delivery ratios and RTTs (0 = unmeasured)
+// into TX shares summing to 1.
+func stripePipeShares(delivery, rttMs []float64, groupLen, tolerance int) []float64 {
+	n := len(delivery)
+	if n == 0 {
+		return nil
+	}
+	fair := 1 / float64(n)
+	lo, hi := fair*stripeShareMin, fair*stripeShareMax
+	if groupLen > 0 && tolerance > 0 {
+		hi = math.Min(hi, math.Max(fair, float64(tolerance)/float64(groupLen)))
+	}
+
+	bestRTT := 0.0
+	for _, r := range rttMs {
+		if r > 0 && (bestRTT == 0 || r < bestRTT) {
+			bestRTT = r
+		}
+	}
+	score := make([]float64, n)
+	for i := range score {
+		score[i] = math.Max(0, 1-stripeDeliveryLossGain*(1-delivery[i]))
+		if bestRTT > 0 && rttMs[i] > 0 {
+			score[i] *= math.Max(stripeDelayMinFactor, bestRTT/rttMs[i])
+		}
+	}
+
+	// share = clamp(λ·score, lo, hi), λ found by bisection so that the
+	// shares sum to 1. Scores are kept above 0 so that one exists: at the
+	// limit every pipe sits at hi, and n·hi ≥ 1.
+	for i := range score {
+		score[i] = math.Max(score[i], stripeScoreFloor)
+	}
+	shares := func(lambda float64) ([]float64, float64) {
+		share := make([]float64, n)
+		sum := 0.0
+		for i := range share {
+			share[i] = math.Min(hi, math.Max(lo, lambda*score[i]))
+			sum += share[i]
+		}
+		return share, sum
+	}
+	low, high := 0.0, hi/stripeScoreFloor
+	for i := 0; i < 100; i++ {
+		if _, sum := shares((low + high) / 2); sum < 1 {
+			low = (low + high) / 2
+		} else {
+			high = (low + high) / 2
+		}
+	}
+	share, sum := shares(high)
+	for i := range share {
+		share[i] /= sum
+	}
+	return share
+}
+
+// stripeSchedule spreads the pipes over a sequence in proportion to their
+// shares, each pipe's entries evenly apart (smooth weighted round-robin).
+// With parity (groupLen, tolerance > 0) TX is not aligned to FEC groups, so
+// every groupLen-long window of the cyclic schedule must hold at most
+// tolerance entries of a pipe, or what round-robin puts there if more.
+// Rounding shares to slots can break that: the pipe over the cap loses a
+// slot until it holds, plain round-robin being the last resort.
+func stripeSchedule(pipes []int, shares []float64, groupLen, tolerance int) []int {
+	n := len(pipes)
+	slots := make([]int, n)
+	for i := range pipes {
+		slots[i] = int(math.Round(shares[i] * float64(n*stripeScheduleSlots)))
+		if slots[i] < 1 {
+			slots[i] = 1
+		}
+	}
+	for {
+		sched := stripeSWRR(slots)
+		over := -1
+		if groupLen > 0 && tolerance > 0 {
+			over = stripeScheduleOverCap(sched, n, groupLen, max(tolerance, (groupLen+n-1)/n))
+		}
+		if over < 0 {
+			for i, idx := range sched {
+				sched[i] = pipes[idx]
+			}
+			return sched
+		}
+		if slots[over] == 1 {
+			return append([]int(nil), pipes...)
+		}
+		slots[over]--
+	}
+}
+
+// stripeSWRR returns a sequence of indexes into slots, index i appearing
+// slots[i] times, evenly apart.
+func stripeSWRR(slots []int) []int {
+	total := 0
+	for _, s := range slots {
+		total += s
+	}
+	cur := make([]int, len(slots))
+	sched := make([]int, 0, total)
+	for len(sched) < total {
+		best := 0
+		for i := range slots {
+			cur[i] += slots[i]
+			if cur[i] > cur[best] {
+				best = i
+			}
+		}
+		cur[best] -= total
+		sched = append(sched, best)
+	}
+	return sched
+}
+
+// stripeScheduleOverCap returns an index (< n) holding more than limit
+// entries in some window of window consecutive entries of the cyclic sched,
+// or -1.
+func stripeScheduleOverCap(sched []int, n, window, limit int) int {
+	count := make([]int, n)
+	for j := 0; j < window; j++ {
+		count[sched[j%len(sched)]]++
+	}
+	for start := 0; start < len(sched); start++ {
+		for i, c := range count {
+			if c > limit {
+				return i
+			}
+		}
+		count[sched[start]]--
+		count[sched[(start+window)%len(sched)]]++
+	}
+	return -1
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStripePipeShares(t *testing.T) {
+	sum := func(shares []float64) float64 {
+		s := 0.0
+		for _, v := range shares {
+			s += v
+		}
+		return s
+	}
+
+	shares := stripePipeShares([]float64{1, 1, 1, 1}, make([]float64, 4), 0, 0)
+	for i, s := range shares {
+		if math.Abs(s-0.25) > 1e-9 {
+			t.Fatalf("equal pipes: share[%d] = %.4f, want 0.25", i, s)
+		}
+	}
+
+	// 20% loss scores 0: the pipe keeps the minimum share.
+	shares = stripePipeShares([]float64{1, 0.8, 1, 1}, make([]float64, 4), 0, 0)
+	if math.Abs(shares[1]-0.25*stripeShareMin) > 1e-9 || math.Abs(sum(shares)-1) > 1e-9 {
+		t.Fatalf("lossy pipe: shares = %v", shares)
+	}
+
+	// Twice the RTT of the best pipe: half its score.
+	shares = stripePipeShares([]float64{1, 1}, []float64{20, 40}, 0, 0)
+	if math.Abs(shares[0]-2.0/3) > 1e-9 {
+		t.Fatalf("slow pipe: shares = %v, want 2/3 and 1/3", shares)
+	}
+
+	// RS 10+2 over 4 pipes: round-robin already puts 3 shards of a group on
+	// each pipe, weighting may not put more.
+	shares = stripePipeShares([]float64{1, 0.8, 1, 1}, make([]float64, 4), 12, 2)
+	for i, s := range shares {
+		if math.Abs(s-0.25) > 1e-9 {
+			t.Fatalf("no parity slack: share[%d] = %.4f, want 0.25", i, s)
+		}
+	}
+}
+
+// TestStripeWeightedSchedule_FECSpread weights 12 pipes, one of them lossy,
+// under RS 10+2: every group of 12 consecutive shards must keep at most 2
+// on any pipe, so that losing one pipe stays recoverable.
+func TestStripeWeightedSchedule_FECSpread(t *testing.T) {
+	const n, k, m = 12, 10, 2
+	health := newStripePipeHealths(n, time.Now())
+	health[3].delivery = 0.85
+	live := make([]int, n)
+	for i := range live {
+		live[i] = i
+	}
+
+	sched := stripeWeightedSchedule(live, health, newStripePipeTimings(n), k+m, m)
+	count := make([]int, n)
+	for _, p := range sched {
+		count[p]++
+	}
+	if count[3] >= count[0] {
+		t.Fatalf("lossy pipe has %d slots, pipe 0 has %d", count[3], count[0])
+	}
+	for start := range sched {
+		inGroup := make([]int, n)
+		for j := 0; j < k+m; j++ {
+			p := sched[(start+j)%len(sched)]
+			if inGroup[p]++; inGroup[p] > m {
+				t.Fatalf("pipe %d carries %d shards of the group starting at slot %d", p, inGroup[p], start)
+			}
+		}
+	}
+}
+
+// TestStripeSchedule_GroupWindow weights 4 pipes, one losing 20%, under
+// 10-shard groups rebuilding 3: the good pipes reach the 0.3 share cap, which
+// rounds to 10 of 33 slots. Every 10 consecutive slots must still keep at
+// most 3 on a pipe.
+func TestStripeSchedule_GroupWindow(t *testing.T) {
+	const n, groupLen, tolerance = 4, 10, 3
+	shares := stripePipeShares([]float64{1, 1, 1, 0.8}, make([]float64, n), groupLen, tolerance)
+	sched := stripeSchedule([]int{0, 1, 2, 3}, shares, groupLen, tolerance)
+	for start := range sched {
+		inWindow := make([]int, n)
+		for j := 0; j < groupLen; j++ {
+			p := sched[(start+j)%len(sched)]
+			if inWindow[p]++; inWindow[p] > tolerance {
+				t.Fatalf("pipe %d has %d slots in the window starting at %d: %v", p, inWindow[p], start, sched)
+			}
+		}
+	}
+	count := make([]int, n)
+	for _, p := range sched {
+		count[p]++
+	}
+	if count[3] >= count[0] {
+		t.Fatalf("schedule %v does not weight the lossy pipe 3 down", sched)
+	}
+}
+
+func TestStripePipeHealth_Delivery(t *testing.T) {
+	h := newStripePipeHealths(1, time.Now())[0]
+	report := func(peerRx uint32) {
+		buf := make([]byte, stripeDeliveryLen)
+		binary.BigEndian.PutUint32(buf, peerRx)
+		h.observeDelivery(buf)
+	}
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			h.sentShard()
+		}
+	}
+
+	report(math.MaxUint32 - 9) // first report only marks; the count wraps below
+	send(100)
+	report(70)
+	if got := h.deliveryRatio(); math.Abs(got-0.9) > 1e-9 {
+		t.Fatalf("delivery = %.3f, want 0.9 (80%% smoothed)", got)
+	}
+	send(stripeDeliveryMinShards - 1)
+	report(70)
+	if got := h.deliveryRatio(); math.Abs(got-0.9) > 1e-9 {
+		t.Fatalf("delivery = %.3f after a short window, want it unchanged", got)
+	}
+}
+
+func TestStripeClientConn_WeightedTx(t *testing.T) {
+	const n = 4
+	scc := &stripeClientConn{
+		pipes:         make([]*net.UDPConn, n),
+		pipeHealth:    newStripePipeHealths(n, time.Now()),
+		pipeTiming:    newStripePipeTimings(n),
+		pipeScheduler: stripeSchedulerWeighted,
+		fecMode:       "off",
+		logger:        newLogger("error"),
+	}
+	scc.pipeHealth[2].delivery = 0.9
+	scc.checkPipes(time.Now())
+
+	picks := make([]int, n)
+	for i := 0; i < 100*n; i++ {
+		picks[scc.nextTxPipe()]++
+	}
+	if picks[2] >= picks[0] {
+		t.Fatalf("picks = %v, want fewer on the lossy pipe 2", picks)
+	}
+	if tx := scc.pipeHealth[2].txShards; tx != uint64(picks[2]) {
+		t.Fatalf("tx shards on pipe 2 = %d, want %d", tx, picks[2])
+	}
+}
+
+func TestStripeClientConn_ScheduleSwapRealigns(t *testing.T) {
+	const n = 4
+	scc := &stripeClientConn{
+		pipes:         make([]*net.UDPConn, n),
+		pipeHealth:    newStripePipeHealths(n, time.Now()),
+		pipeTiming:    newStripePipeTimings(n),
+		pipeScheduler: stripeSchedulerWeighted,
+		fecMode:       "off",
+		logger:        newLogger("error"),
+	}
+	scc.checkPipes(time.Now())
+	first := scc.txLive.Load()
+	for i := 0; i < 5; i++ {
+		scc.nextTxPipe()
+	}
+
+	// Nothing changed: same schedule, round-robin position kept.
+	scc.checkPipes(time.Now())
+	if scc.txLive.Load() != first || atomic.LoadUint32(&scc.txPipe) != 5 {
+		t.Fatalf("unchanged schedule swapped (txPipe = %d)", atomic.LoadUint32(&scc.txPipe))
+	}
+
+	// A new schedule starts from its first slot.
+	scc.pipeHealth[2].delivery = 0.8
+	scc.checkPipes(time.Now())
+	if scc.txLive.Load() == first || atomic.LoadUint32(&scc.txPipe) != 0 {
+		t.Fatalf("changed schedule not realigned (txPipe = %d)", atomic.LoadUint32(&scc.txPipe))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"net/netip"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,7 +28,7 @@ type stripeSession struct {
 	totalPipes int
 	registered int
 	txCipher   *stripeCipher // server→client encryption
-	rxCipher   *stripeCipher // client→server decryption
+	rxCipher   atomic.Pointer[stripeCipher] // client→server decryption; the in-place rekey swaps it
 
 	// FEC
 	dataK   int
@@ -78,6 +79,10 @@ type stripeSession struct {
 	peerLossRate uint32 // atomic: 0-100
 	lastPeerLoss int64  // atomic: unix-nano of last nonzero peer loss report
 
+	// Per-pipe liveness, delivery, RTT, jitter and OWD (parallel to pipes, replaced under ss.mu)
+	pipeHealth []*stripePipeHealth
+	pipeTiming []*stripePipeTiming
+
 	// Loss computation: previous window values
 	rxLossPrevSeqHigh    uint64
 	rxLossPrevDirectCnt  uint64
@@ -91,16 +96,19 @@ type stripeSession struct {
 	txPipe   uint32 // atomic
 	txGroup       [][]byte
 	txGrpSeq      uint32
-	txActivePipes []*net.UDPAddr // cached non-nil pipes, rebuilt on REGISTER (under txMu)
+	txActivePipes  []*net.UDPAddr // TX schedule over the non-nil pipes out of quarantine, see rebuildActivePipesLocked (under txMu)
+	txActiveHealth []*stripePipeHealth // health of each txActivePipes entry (under txMu)
+	txScheduledAt  time.Time // last rebuild of txActivePipes (under ss.mu)
 	txMu          sync.Mutex
 	txTimer       *time.Timer
 	txShardBuf    []byte // reusable M=0 shard buffer (under txMu, avoids alloc/pkt)
 
 	// TX batch (sendmmsg) — reduces per-packet syscall overhead by ~8×.
 	// All fields protected by txMu.
-	txBatchPC   *ipv4.PacketConn // wraps server listener for WriteBatch
-	txBatchMsgs []ipv4.Message   // pre-allocated message slots
-	txBatchN    int              // messages in current batch
+	txBatchPC   stripeBatchConn // wraps server listener for WriteBatch
+	txBatchPC6  stripeBatchConn // wraps the bind_ip6 listener (nil: txBatchPC serves all families)
+	txBatchMsgs []ipv4.Message  // pre-allocated message slots
+	txBatchN    int             // messages in current batch
 
 	// Kernel TX pacing (SO_TXTIME + sch_fq) — per-session EDT tracking.
 	// The server socket has SO_TXTIME set once; each message in the sendmmsg
@@ -122,6 +130,7 @@ type stripeSession struct {
 	logger       *Logger
 
 	securityDecryptFail uint64
+	securityReplayDrop  uint64 // authentic packets rejected by the replay window
 }
 
 // stripeServerDC implements datagramConn for the server→client return path.
@@ -132,9 +141,21 @@ type stripeServerDC struct {
 	conn    *net.UDPConn // server's UDP listener socket
 }
 
+// currentTxCipher returns the session's TX cipher. The in-place rekey in
+// processIncomingPacket replaces it under txMu; paths not already holding
+// txMu read it through here.
+func (sess *stripeSession) currentTxCipher() *stripeCipher {
+	sess.txMu.Lock()
+	defer sess.txMu.Unlock()
+	return sess.txCipher
+}
+
 // getEffectiveM returns the current parity shard count for a server session.
 func (sdc *stripeServerDC) getEffectiveM() int {
-	sess := sdc.session
+	return sdc.session.getEffectiveM()
+}
+
+func (sess *stripeSession) getEffectiveM() int {
 	switch sess.fecMode {
 	case "off":
 		return 0
@@ -145,9 +166,37 @@ func (sdc *stripeServerDC) getEffectiveM() int {
 	}
 }
 
+// fecSpread returns the shards of a FEC group (or repair window) as sent now
+// and how many of them it rebuilds; 0, 0 while no parity is sent.
+func (sess *stripeSession) fecSpread() (groupLen, tolerance int) {
+	switch {
+	case sess.rsilTx != nil:
+		return sess.rsilTx.K + sess.rsilTx.M, sess.rsilTx.M
+	case sess.xorTx != nil && atomic.LoadInt32(&sess.xorActive) == 1:
+		return sess.xorTx.window + 1, 1
+	case sess.rlcTx != nil && atomic.LoadInt32(&sess.rlcActive) == 1:
+		return sess.rlcTx.window + 1, 1
+	}
+	if m := sess.getEffectiveM(); m > 0 {
+		return sess.dataK + m, m
+	}
+	return 0, 0
+}
+
+// nextTxAddr returns the pipe for the next shard, round-robin over the TX
+// schedule activePipes, and counts the shard on it. activeHealth is the
+// txActiveHealth read together with activePipes.
+func (sess *stripeSession) nextTxAddr(activePipes []*net.UDPAddr, activeHealth []*stripePipeHealth) *net.UDPAddr {
+	idx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
+	if idx < len(activeHealth) {
+		activeHealth[idx].sentShard()
+	}
+	return activePipes[idx]
+}
+
 // ─── TX Batch (sendmmsg) ──────────────────────────────────────────────────
 // Server-side batch TX: accumulates encrypted wire packets and flushes them
-// via ipv4.PacketConn.WriteBatch (sendmmsg), reducing per-packet syscall
+// via stripeBatchConn.WriteBatch (sendmmsg), reducing per-packet syscall
 // overhead by up to 8×. All batch methods require txMu to be held.
 
 // txBatchAddLocked enqueues a wire packet for batched transmission.
@@ -190,7 +239,26 @@ func (sdc *stripeServerDC) txBatchFlushLocked() {
 	if sess.txBatchN == 0 {
 		return
 	}
-	_, _ = sess.txBatchPC.WriteBatch(sess.txBatchMsgs[:sess.txBatchN], 0)
+	msgs := sess.txBatchMsgs[:sess.txBatchN]
+	if sess.txBatchPC6 == nil {
+		_, _ = sess.txBatchPC.WriteBatch(msgs, 0)
+	} else {
+		// Separate listeners per family: send each run of same-family
+		// destinations on its own socket, keeping the batch order.
+		for len(msgs) > 0 {
+			v6 := stripeAddrIs6(msgs[0].Addr)
+			n := 1
+			for n < len(msgs) && stripeAddrIs6(msgs[n].Addr) == v6 {
+				n++
+			}
+			pc := sess.txBatchPC
+			if v6 {
+				pc = sess.txBatchPC6
+			}
+			_, _ = pc.WriteBatch(msgs[:n], 0)
+			msgs = msgs[n:]
+		}
+	}
 	// Clear OOB references to avoid stale SCM_TXTIME on reused slots.
 	if sess.txtimeEnabled {
 		for i := 0; i < sess.txBatchN; i++ {
@@ -267,8 +335,7 @@ func (sdc *stripeServerDC) SendDatagram(pkt []byte) error {
 		if sess.pacer != nil {
 			sess.pacer.pace(len(wirePkt))
 		}
-		pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-		sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+		sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 		atomic.AddUint64(&sess.txPkts, 1)
 		atomic.AddUint64(&sess.txBytes, uint64(len(pkt)))
 
@@ -420,8 +487,7 @@ func (sdc *stripeServerDC) sendFECGroupLocked() {
 		if sess.pacer != nil {
 			sess.pacer.pace(len(wirePkt))
 		}
-		pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-		sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+		sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 	}
 
 	// Send parity shards (1 alloc per shard).
@@ -440,8 +506,7 @@ func (sdc *stripeServerDC) sendFECGroupLocked() {
 		if sess.pacer != nil {
 			sess.pacer.pace(len(wirePkt))
 		}
-		pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-		sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+		sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 	}
 
 	sess.txGroup = sess.txGroup[:0]
@@ -465,8 +530,7 @@ func (sdc *stripeServerDC) sendXorRepairLocked(firstSeq uint32, window uint8, re
 	if sess.pacer != nil {
 		sess.pacer.pace(len(wirePkt))
 	}
-	pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-	sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+	sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 }
 
 // sendRLCRepairLocked encrypts and sends a sliding-window RLC repair packet.
@@ -486,8 +550,7 @@ func (sdc *stripeServerDC) sendRLCRepairLocked(firstSeq uint32, window uint8, re
 	if sess.pacer != nil {
 		sess.pacer.pace(len(wirePkt))
 	}
-	pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-	sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+	sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 }
 
 // sendRSILParityLocked sends a single RS interleaved parity shard. Caller must hold sess.txMu.
@@ -506,8 +569,7 @@ func (sdc *stripeServerDC) sendRSILParityLocked(p rsilParity, activePipes []*net
 	if sess.pacer != nil {
 		sess.pacer.pace(len(wirePkt))
 	}
-	pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-	sdc.txBatchAddLocked(wirePkt, activePipes[pipeIdx])
+	sdc.txBatchAddLocked(wirePkt, sess.nextTxAddr(activePipes, sess.txActiveHealth))
 }
 
 func (sdc *stripeServerDC) resetFlushTimer() {
@@ -519,12 +581,19 @@ func (sdc *stripeServerDC) resetFlushTimer() {
 
 // ─── Stripe Server Listener ──────────────────────────────────────────────
 
+// stripePipeRef locates the pipe a client address belongs to.
+type stripePipeRef struct {
+	session uint32
+	pipe    int
+}
+
 // stripeServer manages the server-side UDP listener for stripe connections.
 // Multiple clients can connect; each is identified by session ID.
 type stripeServer struct {
 	conn       *net.UDPConn
+	conn6      *net.UDPConn // bind_ip6 listener (nil: conn serves all families)
 	sessions   map[uint32]*stripeSession
-	addrToSess map[string]uint32 // "IP:port" → sessionID
+	addrToSess map[string]stripePipeRef // "IP:port" → session and pipe
 	mu         sync.RWMutex
 
 	tun            *water.Interface // primary fd (fallback if multiqueue unavailable)
@@ -541,19 +610,25 @@ type stripeServer struct {
 	rsilM           int // RS-IL parity shards per generation (original parityM or default)
 	pacingRate int    // Mbps per session (0 = disabled)
 	arqEnabled bool   // Hybrid ARQ enabled
+	pipeScheduler string // stripe_pipe_scheduler: "rr" or "weighted"
 	txtimeEnabled bool // SO_TXTIME probed OK on listener socket
 	logger     *Logger
 	closeCh    chan struct{}
 
 	pendingKeys *stripePendingKeys
 
+	// TX key epoch limits of the sessions (stripe_rekey_*).
+	rekeyInterval time.Duration
+	rekeyBytes    uint64
+
 	securityDecryptFail uint64
+	securityReplayDrop  uint64
 }
 
 // newStripeServer creates and starts the server-side stripe listener.
 func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *connectionTable, pendingKeys *stripePendingKeys, logger *Logger) (*stripeServer, error) {
 	tunName := cfg.TunName
-	bindIP, err := resolveBindIP(cfg.BindIP)
+	bindIPs, err := serverBindIPs(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("stripe server: resolve bind: %w", err)
 	}
@@ -563,12 +638,22 @@ func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *
 		stripePort = cfg.RemotePort + 1000
 	}
 
-	listenAddr := &net.UDPAddr{IP: net.ParseIP(bindIP), Port: stripePort}
+	listenAddr := &net.UDPAddr{IP: net.ParseIP(bindIPs[0]), Port: stripePort}
 	conn, err := net.ListenUDP("udp", listenAddr)
 	if err != nil {
 		return nil, fmt.Errorf("stripe server: listen %s: %w", listenAddr, err)
 	}
 	setStripeSocketBuffers(conn, logger)
+	var conn6 *net.UDPConn
+	if len(bindIPs) > 1 {
+		listenAddr6 := &net.UDPAddr{IP: net.ParseIP(bindIPs[1]), Port: stripePort}
+		conn6, err = net.ListenUDP("udp6", listenAddr6)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("stripe server: listen %s: %w", listenAddr6, err)
+		}
+		setStripeSocketBuffers(conn6, logger)
+	}
 
 	dataK := cfg.StripeDataShards
 	if dataK <= 0 {
@@ -613,8 +698,9 @@ func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *
 
 	ss := &stripeServer{
 		conn:       conn,
+		conn6:      conn6,
 		sessions:   make(map[uint32]*stripeSession),
-		addrToSess: make(map[string]uint32),
+		addrToSess: make(map[string]stripePipeRef),
 		tun:           tun,
 		tunName:       tunName,
 		tunMultiQueue: tunMultiQueue,
@@ -629,10 +715,12 @@ func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *
 		rsilM:      rsilM,
 		pacingRate: cfg.StripePacingRate,
 		arqEnabled: cfg.StripeARQ,
+		pipeScheduler: cfg.StripePipeScheduler,
 		logger:     logger,
 		closeCh:    make(chan struct{}),
 		pendingKeys: pendingKeys,
 	}
+	ss.rekeyInterval, ss.rekeyBytes = stripeRekeyPolicy(cfg)
 
 	// Probe SO_TXTIME on the server listener socket.
 	// If supported, enable kernel pacing — each session's sendmmsg batch
@@ -643,6 +731,8 @@ func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *
 		// rely on per-packet SCM_TXTIME EDT instead).
 		if err := stripeTxtimeSetup(conn, 0); err != nil {
 			logger.Errorf("stripe server: SO_TXTIME setup failed: %v (using software pacer)", err)
+		} else if conn6 != nil && (!stripeTxtimeProbe(conn6) || stripeTxtimeSetup(conn6, 0) != nil) {
+			logger.Errorf("stripe server: SO_TXTIME unavailable on %s (using software pacer)", conn6.LocalAddr())
 		} else {
 			ss.txtimeEnabled = true
 		}
@@ -668,19 +758,31 @@ func newStripeServer(cfg *Config, tun *water.Interface, tunMultiQueue bool, ct *
 	} else if fecType == "rlc" {
 		fecStr = fmt.Sprintf("FEC=rlc W=%d mode=%s", fecWindow, fecMode)
 	}
+	if conn6 != nil {
+		logger.Infof("stripe server listening on %s (IPv6)", conn6.LocalAddr())
+	}
 	logger.Infof("stripe server listening on %s, %s pacing=%s arq=%s txtime=%s encrypted=AES-256-GCM", listenAddr, fecStr, pacingStr, arqStr, txtimeStr)
 	return ss, nil
 }
 
 // Run is the main receive loop of the stripe server. Call in a goroutine.
-// Uses recvmmsg (via ipv4.PacketConn.ReadBatch) to read up to stripeBatchSize
-// UDP datagrams per syscall, reducing per-packet overhead on the hot path.
+// With bind_ip6 the IPv6 listener gets its own receive loop.
 func (ss *stripeServer) Run(ctx context.Context) {
 	// Periodic GC for stale sessions and incomplete FEC groups
 	go ss.gcLoop(ctx)
 
+	if ss.conn6 != nil {
+		go ss.recvLoop(ctx, ss.conn6)
+	}
+	ss.recvLoop(ctx, ss.conn)
+}
+
+// recvLoop uses recvmmsg (via ReadBatch) to read up to stripeBatchSize
+// UDP datagrams per syscall from conn, reducing per-packet overhead on the
+// hot path.
+func (ss *stripeServer) recvLoop(ctx context.Context, conn *net.UDPConn) {
 	// ── Batch RX: use recvmmsg to read multiple packets per syscall ──
-	pc := ipv4.NewPacketConn(ss.conn)
+	pc := newStripeBatchConn(conn)
 	msgs := make([]ipv4.Message, stripeBatchSize)
 	for i := range msgs {
 		msgs[i].Buffers = make([][]byte, 1)
@@ -696,7 +798,7 @@ func (ss *stripeServer) Run(ctx context.Context) {
 		default:
 		}
 
-		ss.conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
 		numMsgs, err := pc.ReadBatch(msgs, 0)
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
@@ -736,11 +838,21 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 		return
 	}
 
-	// Decrypt: look up session or pending key
+	// Decrypt: look up session or pending key. Every packet reaching dispatch
+	// has passed the replay check of the cipher that authenticated it.
 	var payload []byte
-	sess := ss.lookupSession(hdr.Session, from)
-	if sess != nil && sess.rxCipher != nil {
-		decrypted, decOK := stripeDecryptPkt(sess.rxCipher.aead, raw)
+	var regCipher *stripeCipher
+	sess, pipeHealth := ss.lookupSessionPipe(hdr.Session, from)
+	var rxCipher *stripeCipher
+	if sess != nil {
+		rxCipher = sess.rxCipher.Load()
+	}
+	if rxCipher != nil {
+		decrypted, decOK, replayed := stripeDecryptRx(rxCipher, raw)
+		if replayed {
+			ss.countReplayDrop(sess, hdr, from)
+			return
+		}
 		if !decOK {
 			// Decrypt failed with current key. Check if client re-keyed
 			// (new KX stored in pendingKeys). If so, update ciphers in-place.
@@ -748,17 +860,21 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 			if km != nil {
 				tmpCipher, err := newStripeCipher(km.c2sKey)
 				if err == nil {
-					decrypted2, decOK2 := stripeDecryptPkt(tmpCipher.aead, raw)
+					// tmpCipher's replay window is fresh: the new key has its
+					// own sequence space, starting again from 0.
+					decrypted2, decOK2, _ := stripeDecryptRx(tmpCipher, raw)
 					if decOK2 {
 						// Re-key succeeded — update session ciphers
 						newTx, errTx := newStripeCipher(km.s2cKey)
 						if errTx == nil {
-							sess.rxCipher = tmpCipher
+							newTx.setRekeyPolicy(ss.rekeyInterval, ss.rekeyBytes)
+							sess.rxCipher.Store(tmpCipher)
 							// Update txCipher under txMu to prevent data race
 							// with concurrent SendDatagram reads.
 							sess.txMu.Lock()
 							sess.txCipher = newTx
 							sess.txActivePipes = nil
+							sess.txActiveHealth = nil
 							sess.txGroup = sess.txGroup[:0]
 							atomic.StoreUint32(&sess.txSeq, 0)
 							atomic.StoreUint32(&sess.txPipe, 0)
@@ -792,8 +908,8 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 							// Clear stale pipe addresses so return traffic doesn't
 							// go to dead NAT endpoints from the previous connection.
 							ss.mu.Lock()
-							for addr, sid := range ss.addrToSess {
-								if sid == hdr.Session {
+							for addr, ref := range ss.addrToSess {
+								if ref.session == hdr.Session {
 									delete(ss.addrToSess, addr)
 								}
 							}
@@ -808,7 +924,7 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 							// Without this, dispatch() may consider the path stale
 							// (lastRecv from before client restart) and skip it,
 							// causing return traffic to be silently dropped.
-							sdc := &stripeServerDC{session: sess, conn: ss.conn}
+							sdc := &stripeServerDC{session: sess, conn: ss.connFor(from)}
 							_, cancel := context.WithCancel(context.Background())
 							remoteID := fmt.Sprintf("stripe:%08x", hdr.Session)
 							ss.ct.registerStripe(sess.peerIP, remoteID, sdc, cancel)
@@ -825,10 +941,18 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 			return
 		}
 		payload = decrypted[stripeHdrLen:]
+		if pipeHealth != nil {
+			pipeHealth.recordRx(time.Now().UnixNano(), stripeIsShard(hdr.Type))
+		}
 	} else if sess == nil {
 		// Unknown session — try pre-negotiated key from QUIC KX
 		km := ss.pendingKeys.Get(hdr.Session)
 		if km == nil {
+			// The REGISTER creating the session consumes the key: one
+			// racing it on another pipe finds the session now.
+			if s, _ := ss.lookupSessionPipe(hdr.Session, from); s != nil {
+				ss.processIncomingPacket(raw, from)
+			}
 			return // no key yet; client will retry
 		}
 		if hdr.Type != stripeREGISTER {
@@ -838,11 +962,15 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 		if err != nil {
 			return
 		}
-		decrypted, decOK := stripeDecryptPkt(tmpCipher.aead, raw)
+		decrypted, decOK, _ := stripeDecryptRx(tmpCipher, raw)
 		if !decOK {
 			atomic.AddUint64(&ss.securityDecryptFail, 1)
 			return
 		}
+		// The session created by this REGISTER keeps tmpCipher, so the
+		// REGISTER itself cannot be replayed into it; handleRegister then
+		// consumes the key, so it cannot recreate the session after GC.
+		regCipher = tmpCipher
 		payload = decrypted[stripeHdrLen:]
 	} else {
 		// Session exists but no cipher (shouldn't happen)
@@ -852,13 +980,15 @@ func (ss *stripeServer) processIncomingPacket(raw []byte, from *net.UDPAddr) {
 dispatch:
 	switch hdr.Type {
 	case stripeREGISTER:
-		ss.handleRegister(hdr, payload, from)
+		ss.handleRegister(hdr, payload, from, regCipher)
 	case stripeDATA:
 		ss.handleDataShard(hdr, payload, from)
 	case stripePARITY:
 		ss.handleParityShard(hdr, payload, from)
 	case stripeKEEPALIVE:
 		ss.handleKeepalive(hdr, payload, from)
+	case stripePROBE:
+		ss.handleProbe(hdr, payload, from)
 	case stripeNACK:
 		ss.handleNack(hdr, payload, from)
 	case stripeXOR_REPAIR:
@@ -870,20 +1000,37 @@ dispatch:
 	}
 }
 
-func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.UDPAddr) {
-	if len(payload) < 6 {
-		return
+// countReplayDrop accounts for an authentic packet rejected by the replay
+// window. Nothing in it is acted upon, in particular its source address.
+func (ss *stripeServer) countReplayDrop(sess *stripeSession, hdr stripeHdr, from *net.UDPAddr) {
+	atomic.AddUint64(&sess.securityReplayDrop, 1)
+	count := atomic.AddUint64(&ss.securityReplayDrop, 1)
+	if count <= 3 || count%1000 == 0 {
+		ss.logger.Errorf("stripe: session %08x replayed packet type=%d from=%s dropped (total=%d)",
+			hdr.Session, hdr.Type, from, count)
 	}
+}
 
-	peerIP := netip.AddrFrom4([4]byte{payload[0], payload[1], payload[2], payload[3]})
-	pipeIdx := int(payload[4])
-	totalPipes := int(payload[5])
+// handleRegister registers a client pipe. rxCipher is the cipher that
+// authenticated a REGISTER creating the session (nil for known sessions):
+// the new session adopts it together with its replay window.
+func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.UDPAddr, rxCipher *stripeCipher) {
+	peerIP, pipeIdx, totalPipes, ok := decodeStripeRegister(payload)
+	if !ok {
+		return
+	}
 	sessionID := hdr.Session
 
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	sess, exists := ss.sessions[sessionID]
+	if exists && rxCipher != nil {
+		// Authenticated by the pending key while another pipe's REGISTER
+		// created the session: the session's replay window has not seen
+		// it. Drop it; the pipe's next keepalive registers its address.
+		return
+	}
 	if !exists {
 		var enc reedsolomon.Encoder
 		// Create FEC encoder unless mode is "off" or fecType is a sliding-window codec.
@@ -910,19 +1057,23 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 			ss.logger.Errorf("stripe: TX cipher init session %08x: %v", sessionID, err)
 			return
 		}
-		rxCipher, err := newStripeCipher(km.c2sKey)
-		if err != nil {
-			ss.logger.Errorf("stripe: RX cipher init session %08x: %v", sessionID, err)
-			return
+		txCipher.setRekeyPolicy(ss.rekeyInterval, ss.rekeyBytes)
+		if rxCipher == nil {
+			rxCipher, err = newStripeCipher(km.c2sKey)
+			if err != nil {
+				ss.logger.Errorf("stripe: RX cipher init session %08x: %v", sessionID, err)
+				return
+			}
 		}
 
 		sess = &stripeSession{
 			sessionID:    sessionID,
 			peerIP:       peerIP,
 			pipes:        make([]*net.UDPAddr, totalPipes),
+			pipeHealth:   newStripePipeHealths(totalPipes, time.Now()),
+			pipeTiming:   newStripePipeTimings(totalPipes),
 			totalPipes:   totalPipes,
 			txCipher:     txCipher,
-			rxCipher:     rxCipher,
 			dataK:        ss.dataK,
 			parityM:      ss.parityM,
 			fecMode:      ss.fecMode,
@@ -935,6 +1086,7 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 			createdAt:    time.Now(),
 			logger:       ss.logger,
 		}
+		sess.rxCipher.Store(rxCipher)
 		// Initialize sliding-window FEC sender/receiver when fec_type=xor|rlc and not off.
 		if ss.fecType == "xor" && ss.fecMode != "off" {
 			sess.xorTx = newXorFECSender(ss.fecWindow)
@@ -993,16 +1145,24 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 
 		// Initialize TX batch (sendmmsg) — pre-allocate message slots to avoid
 		// per-call allocations on the hot path.
-		sess.txBatchPC = ipv4.NewPacketConn(ss.conn)
+		// A pipe may re-register from the other family, so the socket is
+		// picked per destination at flush time.
+		sess.txBatchPC = newStripeBatchConn(ss.conn)
+		if ss.conn6 != nil {
+			sess.txBatchPC6 = newStripeBatchConn(ss.conn6)
+		}
 		sess.txBatchMsgs = make([]ipv4.Message, stripeBatchSize)
 		for i := range sess.txBatchMsgs {
 			sess.txBatchMsgs[i].Buffers = make([][]byte, 1)
 		}
 
 		ss.sessions[sessionID] = sess
+		// The key now lives in the session only. A new KX is needed to
+		// create the session again, e.g. after GC expired it.
+		ss.pendingKeys.Delete(sessionID)
 
 		// Create server-to-client datagramConn and register in connectionTable
-		sdc := &stripeServerDC{session: sess, conn: ss.conn}
+		sdc := &stripeServerDC{session: sess, conn: ss.connFor(from)}
 		sess.txTimer = time.AfterFunc(stripeFlushInterval, func() {
 			sess.txMu.Lock()
 			if len(sess.txGroup) > 0 {
@@ -1091,8 +1251,8 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 			ss.logger.Infof("stripe session %08x: reconnect detected (pipes %d→%d), resetting pipe state",
 				sessionID, sess.totalPipes, totalPipes)
 			// Remove all old addr→session mappings
-			for addr, sid := range ss.addrToSess {
-				if sid == sessionID {
+			for addr, ref := range ss.addrToSess {
+				if ref.session == sessionID {
 					delete(ss.addrToSess, addr)
 				}
 			}
@@ -1105,9 +1265,12 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 					sess.pipes[i] = nil
 				}
 			}
+			sess.pipeHealth = newStripePipeHealths(totalPipes, time.Now())
+			sess.pipeTiming = newStripePipeTimings(totalPipes)
 			sess.registered = 0
 			sess.txMu.Lock()
 			sess.txActivePipes = nil
+			sess.txActiveHealth = nil
 			sess.txMu.Unlock()
 
 			// Reset ARQ / FEC state: new client starts at txSeq=0
@@ -1123,7 +1286,7 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 			sess.rxMu.Unlock()
 
 			// Re-register in connectionTable with fresh pathConn
-			sdc := &stripeServerDC{session: sess, conn: ss.conn}
+			sdc := &stripeServerDC{session: sess, conn: ss.connFor(from)}
 			_, cancel := context.WithCancel(context.Background())
 			remoteID := fmt.Sprintf("stripe:%08x", sessionID)
 			ss.ct.registerStripe(sess.peerIP, remoteID, sdc, cancel)
@@ -1131,20 +1294,16 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 	}
 
 	if pipeIdx >= 0 && pipeIdx < len(sess.pipes) {
+		if sess.pipes[pipeIdx] == nil {
+			// Packets from this address were not attributed to the pipe yet.
+			sess.pipeHealth[pipeIdx].recordRx(time.Now().UnixNano(), false)
+		}
 		sess.pipes[pipeIdx] = from
 		sess.registered++
-		ss.addrToSess[from.String()] = sessionID
-
-		// Rebuild cached active pipes under txMu for thread-safe TX access
-		sess.txMu.Lock()
-		ap := make([]*net.UDPAddr, 0, len(sess.pipes))
-		for _, p := range sess.pipes {
-			if p != nil {
-				ap = append(ap, p)
-			}
-		}
-		sess.txActivePipes = ap
-		sess.txMu.Unlock()
+		ss.addrToSess[from.String()] = stripePipeRef{session: sessionID, pipe: pipeIdx}
+
+		ss.checkPipesLocked(sess, time.Now())
+		ss.rebuildActivePipesLocked(sess)
 
 		ss.logger.Infof("stripe pipe registered: session=%08x pipe=%d/%d from=%s",
 			sessionID, pipeIdx, totalPipes, from)
@@ -1160,8 +1319,8 @@ func (ss *stripeServer) handleRegister(hdr stripeHdr, payload []byte, from *net.
 		Type:    stripeKEEPALIVE,
 		Session: sessionID,
 	})
-	reply = stripeEncrypt(sess.txCipher, reply)
-	_, _ = ss.conn.WriteToUDP(reply, from)
+	reply = stripeEncrypt(sess.currentTxCipher(), reply)
+	_, _ = ss.connFor(from).WriteToUDP(reply, from)
 }
 
 // tunFdReader reads IP packets from a per-session multiqueue TUN fd and
@@ -1182,15 +1341,8 @@ func (ss *stripeServer) tunFdReader(sess *stripeSession) {
 		if len(pkt) < 20 {
 			continue
 		}
-		version := pkt[0] >> 4
-		var dstIP netip.Addr
-		if version == 4 && len(pkt) >= 20 {
-			dstIP = netip.AddrFrom4([4]byte{pkt[16], pkt[17], pkt[18], pkt[19]})
-		} else if version == 6 && len(pkt) >= 40 {
-			var b [16]byte
-			copy(b[:], pkt[24:40])
-			dstIP = netip.AddrFrom16(b)
-		} else {
+		dstIP, ok := packetDstIP(pkt)
+		if !ok {
 			continue
 		}
 		pktCopy := append([]byte(nil), pkt...)
@@ -1215,14 +1367,12 @@ func (ss *stripeServer) tunWriter(sess *stripeSession) {
 		if doTouch {
 			ss.ct.touchPath(sess.peerIP, remoteID)
 		}
-		if len(pkt) >= 20 {
-			version := pkt[0] >> 4
-			if version == 4 {
-				srcIP := netip.AddrFrom4([4]byte{pkt[12], pkt[13], pkt[14], pkt[15]})
-				if srcIP != sess.peerIP {
-					ss.ct.learnRoute(srcIP, sess.peerIP)
-				}
-			}
+		if isPeerCtlPacket(pkt) {
+			handlePeerControl(ss.ct, sess.peerIP, remoteID, pkt, ss.logger)
+			return
+		}
+		if srcIP, ok := packetSrcIP(pkt); ok && srcIP != sess.peerIP {
+			ss.ct.learnRoute(srcIP, sess.peerIP)
 		}
 		if _, err := sess.tunFd.Write(pkt); err != nil {
 			ss.logger.Errorf("stripe: TUN write error: %v", err)
@@ -1607,6 +1757,7 @@ func (ss *stripeServer) updateSessionAdaptiveM(sess *stripeSession) {
 
 	peerLoss := atomic.LoadUint32(&sess.peerLossRate)
 	lastLoss := time.Unix(0, atomic.LoadInt64(&sess.lastPeerLoss))
+	lossThreshold := stripeFECLossThreshold(ss.sessionTiming(sess))
 
 	// Step 4.28 Anti-waste sentinel: Client says "your XOR is useless"
 	if peerLoss == 255 {
@@ -1622,7 +1773,7 @@ func (ss *stripeServer) updateSessionAdaptiveM(sess *stripeSession) {
 	// ── RS adaptive (parityM > 0) ──
 	if sess.parityM > 0 {
 		currentM := atomic.LoadInt32(&sess.adaptiveM)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentM == 0 {
 				atomic.StoreInt32(&sess.adaptiveM, int32(sess.parityM))
 				ss.logger.Infof("adaptive FEC: server TX M=0→%d session=%08x (client reports %d%% loss)",
@@ -1640,7 +1791,7 @@ func (ss *stripeServer) updateSessionAdaptiveM(sess *stripeSession) {
 	// ── XOR adaptive (xorTx != nil) ──
 	if sess.xorTx != nil {
 		currentXor := atomic.LoadInt32(&sess.xorActive)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentXor == 0 {
 				atomic.StoreInt32(&sess.xorActive, 1)
 				ss.logger.Infof("adaptive XOR FEC: ON session=%08x (client reports %d%% loss)", sess.sessionID, peerLoss)
@@ -1655,7 +1806,7 @@ func (ss *stripeServer) updateSessionAdaptiveM(sess *stripeSession) {
 
 	if sess.rlcTx != nil {
 		currentRLC := atomic.LoadInt32(&sess.rlcActive)
-		if peerLoss > uint32(adaptiveFECLossThreshold) {
+		if peerLoss > lossThreshold {
 			if currentRLC == 0 {
 				atomic.StoreInt32(&sess.rlcActive, 1)
 				ss.logger.Infof("adaptive RLC FEC: ON session=%08x (client reports %d%% loss)", sess.sessionID, peerLoss)
@@ -1669,6 +1820,16 @@ func (ss *stripeServer) updateSessionAdaptiveM(sess *stripeSession) {
 	}
 }
 
+// sessionTiming returns the delay measurements of a session, averaged over
+// its pipes (nil before the first timed keepalive).
+func (ss *stripeServer) sessionTiming(sess *stripeSession) *StripeTiming {
+	ss.mu.RLock()
+	pipeTiming := sess.pipeTiming
+	ss.mu.RUnlock()
+	timing, _ := stripeTimingStats(pipeTiming, nil)
+	return timing
+}
+
 func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net.UDPAddr) {
 	sess := ss.lookupSession(hdr.Session, from)
 	if sess == nil {
@@ -1677,12 +1838,22 @@ func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net
 	}
 	sess.lastActivity = time.Now()
 
+	// Key epochs: switch the TX key when due, drop the old RX key.
+	sess.currentTxCipher().rekeyTick(sess.lastActivity)
+	sess.rxCipher.Load().rekeyTick(sess.lastActivity)
+
 	// Update pipe address if the client included a pipe index byte.
 	// This handles CGNAT rebind: the client's public IP:port changed,
 	// so we update sess.pipes and addrToSess to the new source address.
+	var timing *stripePipeTiming
+	var health *stripePipeHealth
 	if len(payload) >= 1 {
 		pipeIdx := int(payload[0])
 		ss.mu.Lock()
+		if pipeIdx < len(sess.pipeTiming) {
+			timing = sess.pipeTiming[pipeIdx]
+			health = sess.pipeHealth[pipeIdx]
+		}
 		if pipeIdx >= 0 && pipeIdx < len(sess.pipes) {
 			old := sess.pipes[pipeIdx]
 			if old == nil || old.String() != from.String() {
@@ -1691,24 +1862,23 @@ func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net
 					delete(ss.addrToSess, old.String())
 				}
 				sess.pipes[pipeIdx] = from
-				ss.addrToSess[from.String()] = hdr.Session
+				ss.addrToSess[from.String()] = stripePipeRef{session: hdr.Session, pipe: pipeIdx}
 				if old != nil {
 					ss.logger.Infof("stripe: pipe %d/%d address updated %s → %s (session=%08x)",
 						pipeIdx, len(sess.pipes), old, from, hdr.Session)
 				}
-
-				// Rebuild cached active pipes
-				sess.txMu.Lock()
-				ap := make([]*net.UDPAddr, 0, len(sess.pipes))
-				for _, p := range sess.pipes {
-					if p != nil {
-						ap = append(ap, p)
-					}
-				}
-				sess.txActivePipes = ap
-				sess.txMu.Unlock()
+				// This keepalive was not attributed to the pipe yet.
+				sess.pipeHealth[pipeIdx].recordRx(time.Now().UnixNano(), false)
+				ss.rebuildActivePipesLocked(sess)
 			}
 		}
+		// Every keepalive is a chance to bring back a recovered pipe or to
+		// notice a silent one. The weighted schedule also follows the
+		// delivery and delay measurements, once per keepalive interval.
+		if ss.checkPipesLocked(sess, time.Now()) ||
+			(ss.pipeScheduler == stripeSchedulerWeighted && time.Since(sess.txScheduledAt) >= stripeKeepaliveInterval) {
+			ss.rebuildActivePipesLocked(sess)
+		}
 		ss.mu.Unlock()
 	}
 
@@ -1722,6 +1892,12 @@ func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net
 		}
 	}
 
+	// Timing block (byte 2 on): echoes our previous reply on this pipe.
+	// rx_shards follows it: the shards the client received on the pipe.
+	if timing != nil && len(payload) >= 2 && timing.observe(payload[2:], monoNowNs()) {
+		health.observeDelivery(payload[2+stripeTimingLen:])
+	}
+
 	// Compute server-side RX loss (loss on data FROM client) and update adaptive M
 	rxLoss := ss.computeSessionRxLoss(sess)
 	ss.updateSessionAdaptiveM(sess)
@@ -1729,7 +1905,16 @@ func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net
 	ss.tuneSessionRLCRuntime(sess)
 
 	// Reply with server-measured RX loss (tells client about loss on data CLIENT sent)
-	reply := make([]byte, stripeHdrLen+1)
+	// and, to clients that sent them, our timing block and shard count:
+	// [rx_loss_pct: 1B][timing: 24B][rx_shards: 4B]
+	replyLen := stripeHdrLen + 1
+	if timing != nil && len(payload) >= 2+stripeTimingLen {
+		replyLen += stripeTimingLen
+		if len(payload) >= 2+stripeTimingLen+stripeDeliveryLen {
+			replyLen += stripeDeliveryLen
+		}
+	}
+	reply := make([]byte, replyLen)
 	encodeStripeHdr(reply, &stripeHdr{
 		Magic:   stripeMagic,
 		Version: stripeVersion,
@@ -1737,8 +1922,14 @@ func (ss *stripeServer) handleKeepalive(hdr stripeHdr, payload []byte, from *net
 		Session: hdr.Session,
 	})
 	reply[stripeHdrLen] = rxLoss
-	reply = stripeEncrypt(sess.txCipher, reply)
-	_, _ = ss.conn.WriteToUDP(reply, from)
+	if replyLen > stripeHdrLen+1 {
+		timing.encode(reply[stripeHdrLen+1:], monoNowNs())
+	}
+	if replyLen > stripeHdrLen+1+stripeTimingLen {
+		health.encodeDelivery(reply[stripeHdrLen+1+stripeTimingLen:])
+	}
+	reply = stripeEncrypt(sess.currentTxCipher(), reply)
+	_, _ = ss.connFor(from).WriteToUDP(reply, from)
 }
 
 func (ss *stripeServer) tuneSessionXorRuntime(sess *stripeSession) {
@@ -1819,6 +2010,31 @@ func (ss *stripeServer) tuneSessionRLCRuntime(sess *stripeSession) {
 	}
 }
 
+// handleProbe echoes a client path probe back to the pipe it came from.
+func (ss *stripeServer) handleProbe(hdr stripeHdr, payload []byte, from *net.UDPAddr) {
+	sess := ss.lookupSession(hdr.Session, from)
+	if sess == nil {
+		return
+	}
+	echo := probeEchoReply(payload)
+	if echo == nil {
+		return
+	}
+	sess.lastActivity = time.Now()
+
+	reply := make([]byte, stripeHdrLen+len(echo))
+	encodeStripeHdr(reply, &stripeHdr{
+		Magic:   stripeMagic,
+		Version: stripeVersion,
+		Type:    stripePROBE,
+		Session: hdr.Session,
+		DataLen: uint16(len(echo)),
+	})
+	copy(reply[stripeHdrLen:], echo)
+	reply = stripeEncrypt(sess.currentTxCipher(), reply)
+	_, _ = ss.connFor(from).WriteToUDP(reply, from)
+}
+
 // ─── Server ARQ: NACK handler + generation ─────────────────────────────
 
 // handleNack processes a NACK from a client requesting retransmission of
@@ -1836,7 +2052,8 @@ func (ss *stripeServer) handleNack(hdr stripeHdr, payload []byte, from *net.UDPA
 
 	// Use cached active pipes for round-robin retransmission
 	sess.txMu.Lock()
-	activePipes := sess.txActivePipes
+	activePipes, activeHealth := sess.txActivePipes, sess.txActiveHealth
+	txCipher := sess.txCipher
 	sess.txMu.Unlock()
 	if len(activePipes) == 0 {
 		return
@@ -1853,7 +2070,7 @@ func (ss *stripeServer) handleNack(hdr stripeHdr, payload []byte, from *net.UDPA
 			continue
 		}
 		// Re-encrypt with fresh nonce and send on round-robin pipe
-		wirePkt := stripeEncryptShard(sess.txCipher, &stripeHdr{
+		wirePkt := stripeEncryptShard(txCipher, &stripeHdr{
 			Magic:      stripeMagic,
 			Version:    stripeVersion,
 			Type:       stripeDATA,
@@ -1863,8 +2080,8 @@ func (ss *stripeServer) handleNack(hdr stripeHdr, payload []byte, from *net.UDPA
 			GroupDataN: 1,
 			DataLen:    dataLen,
 		}, shardData)
-		pipeIdx := int(atomic.AddUint32(&sess.txPipe, 1)-1) % len(activePipes)
-		_, _ = ss.conn.WriteToUDP(wirePkt, activePipes[pipeIdx])
+		addr := sess.nextTxAddr(activePipes, activeHealth)
+		_, _ = ss.connFor(addr).WriteToUDP(wirePkt, addr)
 		retxCount++
 	}
 
@@ -1916,8 +2133,8 @@ func (ss *stripeServer) startArqNackLoop(ctx context.Context, sess *stripeSessio
 				Session: sess.sessionID,
 			})
 			encodeNackPayload(pkt[stripeHdrLen:], baseSeq, bitmap)
-			pkt = stripeEncrypt(sess.txCipher, pkt)
-			_, _ = ss.conn.WriteToUDP(pkt, peerAddr)
+			pkt = stripeEncrypt(sess.currentTxCipher(), pkt)
+			_, _ = ss.connFor(peerAddr).WriteToUDP(pkt, peerAddr)
 			sess.arqRx.addNacksSent(1)
 			sess.arqRx.recordNackSent()
 			ss.logger.Debugf("stripe ARQ: NACK sent to %s base=%d count=%d session=%08x", peerAddr, baseSeq, count, sess.sessionID)
@@ -1927,17 +2144,89 @@ func (ss *stripeServer) startArqNackLoop(ctx context.Context, sess *stripeSessio
 
 // lookupSession finds a session by header session ID or source address.
 func (ss *stripeServer) lookupSession(sessionID uint32, from *net.UDPAddr) *stripeSession {
+	sess, _ := ss.lookupSessionPipe(sessionID, from)
+	return sess
+}
+
+// lookupSessionPipe is lookupSession that also returns the liveness of the
+// pipe from belongs to (nil if from is not a registered pipe address).
+func (ss *stripeServer) lookupSessionPipe(sessionID uint32, from *net.UDPAddr) (*stripeSession, *stripePipeHealth) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	// Try by source address first (faster, handles NAT)
-	if sessID, ok := ss.addrToSess[from.String()]; ok {
-		if sess, ok := ss.sessions[sessID]; ok {
-			return sess
+	if ref, ok := ss.addrToSess[from.String()]; ok {
+		if sess, ok := ss.sessions[ref.session]; ok {
+			var health *stripePipeHealth
+			if ref.pipe < len(sess.pipeHealth) {
+				health = sess.pipeHealth[ref.pipe]
+			}
+			return sess, health
 		}
 	}
 	// Fallback: by session ID from header
-	return ss.sessions[sessionID]
+	return ss.sessions[sessionID], nil
+}
+
+// checkPipesLocked quarantines the pipes of sess that went silent and brings
+// back those that recovered. It reports whether any pipe changed state.
+// Caller must hold ss.mu.
+func (ss *stripeServer) checkPipesLocked(sess *stripeSession, now time.Time) bool {
+	changed := false
+	for i, h := range sess.pipeHealth {
+		if i >= len(sess.pipes) || sess.pipes[i] == nil || !h.check(now) {
+			continue
+		}
+		changed = true
+		if h.up() {
+			ss.logger.Infof("stripe: session %08x pipe %d/%d back in service", sess.sessionID, i, len(sess.pipes))
+		} else {
+			ss.logger.Infof("stripe: session %08x pipe %d/%d quarantined (silent for %v)",
+				sess.sessionID, i, len(sess.pipes), h.silentFor(now).Round(time.Second))
+		}
+	}
+	return changed
+}
+
+// rebuildActivePipesLocked recomputes the TX schedule of sess over the
+// registered pipes out of quarantine, or over all registered pipes if every
+// one of them is in quarantine: round-robin, or weighted as configured by
+// stripe_pipe_scheduler. Caller must hold ss.mu.
+func (ss *stripeServer) rebuildActivePipesLocked(sess *stripeSession) {
+	live := make([]int, 0, len(sess.pipes))
+	all := make([]int, 0, len(sess.pipes))
+	for i, p := range sess.pipes {
+		if p == nil || i >= len(sess.pipeHealth) {
+			continue
+		}
+		all = append(all, i)
+		if sess.pipeHealth[i].up() {
+			live = append(live, i)
+		}
+	}
+	if len(live) == 0 {
+		live = all
+	}
+	if ss.pipeScheduler == stripeSchedulerWeighted && len(live) > 0 {
+		groupLen, tolerance := sess.fecSpread()
+		live = stripeWeightedSchedule(live, sess.pipeHealth, sess.pipeTiming, groupLen, tolerance)
+	}
+	ap := make([]*net.UDPAddr, len(live))
+	health := make([]*stripePipeHealth, len(live))
+	for i, p := range live {
+		ap[i], health[i] = sess.pipes[p], sess.pipeHealth[p]
+	}
+	sess.txScheduledAt = time.Now()
+	sess.txMu.Lock()
+	defer sess.txMu.Unlock()
+	if slices.Equal(ap, sess.txActivePipes) && slices.Equal(health, sess.txActiveHealth) {
+		return // unchanged: keep the round-robin position
+	}
+	// Restart the round-robin at the first slot: groups are sent under txMu,
+	// so none straddles the swap and the spread cap holds for each of them.
+	sess.txActivePipes = ap
+	sess.txActiveHealth = health
+	atomic.StoreUint32(&sess.txPipe, 0)
 }
 
 // gcLoop periodically cleans up stale sessions and incomplete FEC groups.
@@ -1976,8 +2265,8 @@ func (ss *stripeServer) runGC() {
 				sess.tunFd.Close()
 			}
 			// Remove addr→session mappings
-			for addr, sid := range ss.addrToSess {
-				if sid == sessID {
+			for addr, ref := range ss.addrToSess {
+				if ref.session == sessID {
 					delete(ss.addrToSess, addr)
 				}
 			}
@@ -1987,6 +2276,11 @@ func (ss *stripeServer) runGC() {
 			continue
 		}
 
+		// Quarantine pipes gone silent even if no keepalive arrives to notice.
+		if ss.checkPipesLocked(sess, now) {
+			ss.rebuildActivePipesLocked(sess)
+		}
+
 		// GC incomplete FEC groups older than 2s
 		sess.rxMu.Lock()
 		for seq, grp := range sess.rxGroups {
@@ -2005,8 +2299,10 @@ func (ss *stripeServer) runGC() {
 		}
 	}
 
-	if df := atomic.LoadUint64(&ss.securityDecryptFail); df > 0 {
-		ss.logger.Infof("stripe security metrics decrypt_fail=%d", df)
+	df := atomic.LoadUint64(&ss.securityDecryptFail)
+	rd := atomic.LoadUint64(&ss.securityReplayDrop)
+	if df > 0 || rd > 0 {
+		ss.logger.Infof("stripe security metrics decrypt_fail=%d replay_drop=%d", df, rd)
 	}
 }
 
@@ -2021,9 +2317,26 @@ func (ss *stripeServer) Close() error {
 		}
 	}
 	ss.mu.RUnlock()
+	if ss.conn6 != nil {
+		ss.conn6.Close()
+	}
 	return ss.conn.Close()
 }
 
+// connFor returns the listener that serves addr's family.
+func (ss *stripeServer) connFor(addr *net.UDPAddr) *net.UDPConn {
+	if ss.conn6 != nil && stripeAddrIs6(addr) {
+		return ss.conn6
+	}
+	return ss.conn
+}
+
+// stripeAddrIs6 reports whether addr is an IPv6 (not IPv4-mapped) UDP address.
+func stripeAddrIs6(addr net.Addr) bool {
+	ua, ok := addr.(*net.UDPAddr)
+	return ok && ua.IP.To4() == nil
+}
+
 // ─── Helpers ──────────────────────────────────────────────────────────────
 
 // parseTUNIP extracts the IP address from a CIDR string like "10.200.17.1/30".
@@ -2051,8 +2364,14 @@ func ipToUint32(ip net.IP) uint32 {
 // pathSessionID generates a unique session ID per (TUN IP, path name) pair.
 // This ensures that multiple stripe paths from the same client (e.g. wan5 and
 // wan6) get distinct sessions on the server, so their pipes don't collide.
+// IPv6 tunnel addresses are folded to 32 bits with FNV-1a.
 func pathSessionID(tunIP net.IP, pathName string) uint32 {
 	base := ipToUint32(tunIP)
+	if tunIP.To4() == nil && len(tunIP) == net.IPv6len {
+		f := fnv.New32a()
+		f.Write(tunIP)
+		base = f.Sum32()
+	}
 	h := fnv.New32a()
 	h.Write([]byte(pathName))
 	return base ^ h.Sum32()
@@ -2061,7 +2380,8 @@ func pathSessionID(tunIP net.IP, pathName string) uint32 {
 // Ensure stripeClientConn implements io.Closer for clean shutdown.
 var _ io.Closer = (*stripeClientConn)(nil)
 
-// dynamicPacingLoop adjusts session pacing speed based on reported peer loss.
+// dynamicPacingLoop adjusts session pacing speed based on reported peer loss
+// and queueing delay.
 func (ss *stripeServer) dynamicPacingLoop(ctx context.Context, sess *stripeSession) {
 	if ss.pacingRate <= 0 {
 		return // pacing disabled, nothing to do
@@ -2078,14 +2398,14 @@ func (ss *stripeServer) dynamicPacingLoop(ctx context.Context, sess *stripeSessi
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			targetNs := baseNs
-			// Read peer loss from keepalives.
-			loss := atomic.LoadUint32(&sess.peerLossRate)
-			if loss > 0 && loss < 255 {
-				// E.g., at 10% loss (25), pacing slows by 25% to relieve congestion.
-				targetNs += targetNs * int64(loss) / 100
+			// Peer loss and the queueing delay the client measures on our
+			// packets, both from keepalives.
+			var txOWDMs float64
+			if timing := ss.sessionTiming(sess); timing != nil {
+				txOWDMs = timing.TxOWDMs
 			}
-			atomic.StoreInt64(&sess.txtimeGapNs, targetNs)
+			loss := atomic.LoadUint32(&sess.peerLossRate)
+			atomic.StoreInt64(&sess.txtimeGapNs, stripePacingGapNs(baseNs, loss, txOWDMs))
 		}
 	}
 }
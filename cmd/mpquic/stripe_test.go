@@ -2,7 +2,12 @@ package main
 
 import (
 	"encoding/binary"
+	"net"
+	"net/netip"
 	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
 )
 
 // ─── Wire Protocol Tests ──────────────────────────────────────────────────
@@ -281,7 +286,7 @@ func TestStripeEncryptDecrypt(t *testing.T) {
 	}
 
 	// Decrypt
-	decrypted, ok := stripeDecryptPkt(rxCipher.aead, encrypted)
+	decrypted, ok := stripeDecryptPkt(rxCipher.cur.Load().aead, encrypted)
 	if !ok {
 		t.Fatal("decrypt failed")
 	}
@@ -298,7 +303,7 @@ func TestStripeEncryptDecrypt(t *testing.T) {
 
 	// Tamper → must fail
 	encrypted[len(encrypted)-1] ^= 0x01
-	_, ok = stripeDecryptPkt(rxCipher.aead, encrypted)
+	_, ok = stripeDecryptPkt(rxCipher.cur.Load().aead, encrypted)
 	if ok {
 		t.Fatal("expected decrypt to fail after tamper")
 	}
@@ -329,3 +334,334 @@ func TestStripeEncryptSequenceUniqueness(t *testing.T) {
 		t.Fatal("sequential encryptions should produce different ciphertexts")
 	}
 }
+
+func TestStripeReplayFilter(t *testing.T) {
+	var f stripeReplayFilter
+
+	for _, seq := range []uint64{0, 1, 5, 3, 2} {
+		if !f.accept(seq) {
+			t.Fatalf("seq %d: fresh sequence number rejected", seq)
+		}
+	}
+	for _, seq := range []uint64{0, 3, 5} {
+		if f.accept(seq) {
+			t.Fatalf("seq %d: duplicate accepted", seq)
+		}
+	}
+
+	// Jump ahead: reordered packets within the window are still accepted,
+	// those that fell behind it are not.
+	top := uint64(100000)
+	if !f.accept(top) {
+		t.Fatal("window did not slide forward")
+	}
+	if !f.accept(top - stripeReplayWindow) {
+		t.Fatal("oldest sequence number in the window rejected")
+	}
+	if f.accept(top - stripeReplayWindow - 1) {
+		t.Fatal("sequence number behind the window accepted")
+	}
+	if f.accept(4) {
+		t.Fatal("stale sequence number accepted after the jump")
+	}
+	// Bits cleared by the slide must not leak into new words.
+	for seq := top + 1; seq < top+200; seq++ {
+		if !f.accept(seq) {
+			t.Fatalf("seq %d: fresh sequence number rejected after slide", seq)
+		}
+	}
+}
+
+func TestStripeDecryptRx_RejectsReplay(t *testing.T) {
+	var key [32]byte
+	key[0] = 0x42
+	tx, _ := newStripeCipher(key)
+	rx, _ := newStripeCipher(key)
+
+	pkt := make([]byte, stripeHdrLen+1)
+	encodeStripeHdr(pkt, &stripeHdr{Magic: stripeMagic, Version: stripeVersion, Type: stripeKEEPALIVE, Session: 1})
+	first := stripeEncrypt(tx, pkt)
+	second := stripeEncrypt(tx, pkt)
+
+	// Out of order across pipes is fine.
+	if _, ok, replayed := stripeDecryptRx(rx, second); !ok || replayed {
+		t.Fatalf("second: ok=%v replayed=%v", ok, replayed)
+	}
+	if _, ok, replayed := stripeDecryptRx(rx, first); !ok || replayed {
+		t.Fatalf("first: ok=%v replayed=%v", ok, replayed)
+	}
+	if _, ok, replayed := stripeDecryptRx(rx, first); ok || !replayed {
+		t.Fatalf("replay: ok=%v replayed=%v, want rejected as replay", ok, replayed)
+	}
+
+	// A forged packet is a decrypt failure and must not touch the window.
+	forged := append([]byte(nil), first...)
+	binary.BigEndian.PutUint64(forged[stripeHdrLen:], 1<<40)
+	if _, ok, replayed := stripeDecryptRx(rx, forged); ok || replayed {
+		t.Fatalf("forged: ok=%v replayed=%v, want decrypt failure", ok, replayed)
+	}
+	third := stripeEncrypt(tx, pkt)
+	if _, ok, _ := stripeDecryptRx(rx, third); !ok {
+		t.Fatal("forged sequence number slid the window")
+	}
+
+	// A new key starts with an empty window.
+	rekeyed, _ := newStripeCipher(key)
+	if _, ok, _ := stripeDecryptRx(rekeyed, first); !ok {
+		t.Fatal("fresh cipher rejected a packet")
+	}
+}
+
+// TestStripeCipher_RekeyEpochs switches the TX key in the middle of an FEC
+// group: every shard still decrypts, the receiver follows the new epoch and
+// drops the old key after the overlap.
+func TestStripeCipher_RekeyEpochs(t *testing.T) {
+	var key [32]byte
+	key[0] = 0x17
+	tx, _ := newStripeCipher(key)
+	rx, _ := newStripeCipher(key)
+	tx.setRekeyPolicy(time.Hour, 0)
+
+	shard := func(idx uint8) []byte {
+		return stripeEncryptShard(tx, &stripeHdr{Magic: stripeMagic, Version: stripeVersion, Type: stripeDATA,
+			Session: 1, GroupSeq: 7, ShardIdx: idx, GroupDataN: 4}, []byte{idx})
+	}
+	group := [][]byte{shard(0), shard(1)}
+	stale := shard(5) // delivered only after the overlap
+
+	now := time.Now()
+	tx.rekeyTick(now)
+	if epoch, _ := tx.epochStats(); epoch != 0 {
+		t.Fatal("switched before the interval")
+	}
+	tx.cur.Load().created = now.Add(-time.Hour)
+	tx.rekeyTick(now)
+	if epoch, rekeys := tx.epochStats(); epoch != 1 || rekeys != 1 {
+		t.Fatalf("tx epoch=%d rekeys=%d, want 1/1", epoch, rekeys)
+	}
+	group = append(group, shard(2), shard(3))
+	if group[2][stripeHdrLen] != 1 {
+		t.Fatalf("epoch byte = %d, want 1", group[2][stripeHdrLen])
+	}
+
+	// New-epoch shards arrive first on a faster pipe.
+	for _, i := range []int{2, 0, 3, 1} {
+		pt, ok, _ := stripeDecryptRx(rx, group[i])
+		if !ok || pt[stripeHdrLen] != byte(i) {
+			t.Fatalf("shard %d: ok=%v", i, ok)
+		}
+	}
+	if epoch, rekeys := rx.epochStats(); epoch != 1 || rekeys != 1 {
+		t.Fatalf("rx epoch=%d rekeys=%d, want 1/1", epoch, rekeys)
+	}
+
+	// After the overlap the old key is gone.
+	rx.rekeyTick(time.Now().Add(stripeRekeyOverlap + time.Second))
+	if _, ok, _ := stripeDecryptRx(rx, stale); ok {
+		t.Fatal("epoch 0 packet accepted after the overlap")
+	}
+}
+
+// TestStripeCipher_RekeyVolume switches key once the volume limit is reached.
+func TestStripeCipher_RekeyVolume(t *testing.T) {
+	var key [32]byte
+	tx, _ := newStripeCipher(key)
+	tx.setRekeyPolicy(0, 1000)
+	first := tx.cur.Load()
+	first.created = time.Now().Add(-stripeRekeyOverlap)
+
+	pkt := make([]byte, stripeHdrLen+600)
+	encodeStripeHdr(pkt, &stripeHdr{Magic: stripeMagic, Version: stripeVersion, Type: stripeDATA})
+	stripeEncrypt(tx, pkt)
+	stripeEncrypt(tx, pkt)
+	if epoch, _ := tx.epochStats(); epoch != 0 {
+		t.Fatal("switched before the limit was reached")
+	}
+	if out := stripeEncrypt(tx, pkt); out[stripeHdrLen] != 1 {
+		t.Fatalf("epoch byte = %d after the limit, want 1", out[stripeHdrLen])
+	}
+	if first.secret != ([32]byte{}) {
+		t.Fatal("old key secret not wiped")
+	}
+}
+
+func TestStripeRegisterPayload_DualStack(t *testing.T) {
+	for _, s := range []string{"10.200.17.1", "fd00:200::1"} {
+		ip := netip.MustParseAddr(s)
+		got, idx, total, ok := decodeStripeRegister(encodeStripeRegister(ip, 2, 4))
+		if !ok || got != ip || idx != 2 || total != 4 {
+			t.Errorf("%s: decoded %s pipe %d/%d ok=%t", s, got, idx, total, ok)
+		}
+	}
+	// A v4-mapped address (net.ParseCIDR form) is sent as 4 bytes.
+	if n := len(encodeStripeRegister(netip.MustParseAddr("::ffff:10.200.17.1"), 0, 1)); n != 6 {
+		t.Errorf("v4-mapped payload = %d bytes, want 6", n)
+	}
+	// A trailing field on an IPv4 REGISTER must not turn it into IPv6.
+	long := append(encodeStripeRegister(netip.MustParseAddr("10.200.17.1"), 1, 2), make([]byte, 16)...)
+	if got, idx, total, ok := decodeStripeRegister(long); !ok || got != netip.MustParseAddr("10.200.17.1") || idx != 1 || total != 2 {
+		t.Errorf("extended v4 payload decoded as %s pipe %d/%d ok=%t", got, idx, total, ok)
+	}
+	if _, _, _, ok := decodeStripeRegister([]byte{stripeRegisterTagged, 7, 1, 2, 3}); ok {
+		t.Error("tagged payload with a bad address length accepted")
+	}
+}
+
+func TestPathSessionID_IPv6Distinct(t *testing.T) {
+	a, _ := parseTUNIP("fd00:200::1/64")
+	b, _ := parseTUNIP("fd00:200::2/64")
+	if pathSessionID(a, "wan1") == pathSessionID(b, "wan1") {
+		t.Error("IPv6 clients share a stripe session ID")
+	}
+	v4, _ := parseTUNIP("10.200.17.1/30")
+	if got, want := pathSessionID(v4, "wan1"), ipToUint32(v4)^fnv1aHash([]byte("wan1")); got != want {
+		t.Errorf("IPv4 session ID changed: 0x%08X, want 0x%08X", got, want)
+	}
+}
+
+func TestStripeBatchConn_IPv6(t *testing.T) {
+	rx, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	defer rx.Close()
+	tx, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Close()
+
+	out := make([]ipv4.Message, 3)
+	for i := range out {
+		out[i].Buffers = [][]byte{{byte(i)}}
+		out[i].Addr = rx.LocalAddr()
+	}
+	if n, err := newStripeBatchConn(tx).WriteBatch(out, 0); err != nil || n != len(out) {
+		t.Fatalf("WriteBatch = %d, %v", n, err)
+	}
+
+	in := make([]ipv4.Message, stripeBatchSize)
+	for i := range in {
+		in[i].Buffers = [][]byte{make([]byte, 64)}
+	}
+	rx.SetReadDeadline(time.Now().Add(time.Second))
+	got := 0
+	for got < len(out) {
+		n, err := newStripeBatchConn(rx).ReadBatch(in, 0)
+		if err != nil {
+			t.Fatalf("ReadBatch after %d: %v", got, err)
+		}
+		for _, m := range in[:n] {
+			from, ok := m.Addr.(*net.UDPAddr)
+			if !ok || !from.IP.Equal(net.IPv6loopback) {
+				t.Fatalf("from = %v", m.Addr)
+			}
+		}
+		got += n
+	}
+}
+
+// TestStripeServerDC_BatchPerFamily flushes one batch to pipes of both
+// families with separate v4 and v6 listeners: each packet must leave from
+// the listener of its destination's family.
+func TestStripeServerDC_BatchPerFamily(t *testing.T) {
+	listen := func(network string, ip net.IP) *net.UDPConn {
+		c, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+		if err != nil {
+			t.Skipf("no %s loopback: %v", network, err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	tx4, rx4 := listen("udp4", net.IPv4(127, 0, 0, 1)), listen("udp4", net.IPv4(127, 0, 0, 1))
+	tx6, rx6 := listen("udp6", net.IPv6loopback), listen("udp6", net.IPv6loopback)
+
+	sess := &stripeSession{
+		txBatchPC:   newStripeBatchConn(tx4),
+		txBatchPC6:  newStripeBatchConn(tx6),
+		txBatchMsgs: make([]ipv4.Message, stripeBatchSize),
+	}
+	for i := range sess.txBatchMsgs {
+		sess.txBatchMsgs[i].Buffers = make([][]byte, 1)
+	}
+	sdc := &stripeServerDC{session: sess}
+	to4, to6 := rx4.LocalAddr().(*net.UDPAddr), rx6.LocalAddr().(*net.UDPAddr)
+	for i, to := range []*net.UDPAddr{to4, to6, to6, to4} {
+		sdc.txBatchAddLocked([]byte{byte(i)}, to)
+	}
+	sdc.txBatchFlushLocked()
+
+	for _, c := range []struct {
+		rx   *net.UDPConn
+		want []byte
+	}{{rx4, []byte{0, 3}}, {rx6, []byte{1, 2}}} {
+		c.rx.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 16)
+		for _, want := range c.want {
+			n, _, err := c.rx.ReadFromUDP(buf)
+			if err != nil || n != 1 || buf[0] != want {
+				t.Fatalf("%s: read %v (n=%d, err=%v), want packet %d", c.rx.LocalAddr(), buf[:n], n, err, want)
+			}
+		}
+	}
+}
+
+// TestStripeServer_RegisterReplayAfterGC replays a captured REGISTER from
+// another address once GC has expired the session it created: the session
+// must not come back pointing at the sender.
+func TestStripeServer_RegisterReplayAfterGC(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const sessionID = 0x0AC81101
+	km := &stripeKeyMaterial{}
+	km.c2sKey[0], km.s2cKey[0] = 1, 2
+	ss := &stripeServer{
+		conn:        conn,
+		sessions:    make(map[uint32]*stripeSession),
+		addrToSess:  make(map[string]stripePipeRef),
+		ct:          newConnectionTable(),
+		dataK:       stripeDefaultDataShards,
+		fecMode:     "off",
+		fecType:     "rs",
+		logger:      newLogger("error"),
+		pendingKeys: newStripePendingKeys(),
+	}
+	defer ss.ct.closeAll()
+	ss.pendingKeys.Store(sessionID, km)
+
+	clientTx, _ := newStripeCipher(km.c2sKey)
+	regPayload := encodeStripeRegister(netip.MustParseAddr("10.200.17.1"), 0, 1)
+	pkt := make([]byte, stripeHdrLen+len(regPayload))
+	encodeStripeHdr(pkt, &stripeHdr{
+		Magic:   stripeMagic,
+		Version: stripeVersion,
+		Type:    stripeREGISTER,
+		Session: sessionID,
+		DataLen: uint16(len(regPayload)),
+	})
+	copy(pkt[stripeHdrLen:], regPayload)
+	register := stripeEncrypt(clientTx, pkt)
+
+	client := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40001}
+	ss.processIncomingPacket(append([]byte(nil), register...), client)
+	sess := ss.sessions[sessionID]
+	if sess == nil || sess.pipes[0].String() != client.String() {
+		t.Fatal("REGISTER did not create the session")
+	}
+
+	sess.lastActivity = time.Now().Add(-2 * stripeSessionTimeout)
+	ss.runGC()
+	if len(ss.sessions) != 0 {
+		t.Fatal("session not expired")
+	}
+
+	attacker := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40002}
+	ss.processIncomingPacket(append([]byte(nil), register...), attacker)
+	if len(ss.sessions) != 0 || len(ss.addrToSess) != 0 {
+		t.Fatal("replayed REGISTER recreated the session")
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// ─── Stripe delay measurement ─────────────────────────────────────────────
+// Keepalives carry a timing block after their loss fields:
+//
+//	[ts 8B][echo_ts 8B][echo_hold_us 4B][owd_us 4B]
+//
+//   - ts: sender's monotonic clock (monoNowNs) when the keepalive leaves
+//   - echo_ts: last ts received from the peer on this pipe (0 = none yet)
+//   - echo_hold_us: time between receiving echo_ts and sending this keepalive
+//   - owd_us: one-way-delay trend the sender measures on this pipe, i.e. the
+//     queueing the receiver's packets meet on their way out
+//
+// Each end derives per pipe:
+//   - RTT = now − echo_ts − echo_hold, smoothed as in RFC 6298 (srtt/rttvar).
+//     The client gets a sample per pipe on every keepalive reply, the server
+//     one on the next client keepalive (echo_hold ≈ stripeKeepaliveInterval).
+//   - jitter: RFC 3550 interarrival jitter of the transit time now − ts.
+//   - OWD trend: transit time minus its minimum over stripeOWDWindow. The
+//     offset between the two clocks cancels out; what remains is the delay
+//     added by queues on the way in.
+//
+// Older peers read only the loss fields and ignore the block.
+
+const (
+	stripeTimingLen = 24
+	stripeOWDWindow = 2 * time.Minute // minimum transit is forgotten after this
+
+	// A peer-reported OWD above the target stretches the pacing gap
+	// proportionally, by at most half.
+	stripePacingOWDTargetMs = 20.0
+	stripePacingOWDMaxExtra = 0.5
+
+	// Above this RTT, ARQ retransmits arrive too late for real-time traffic:
+	// adaptive FEC engages on lower loss.
+	stripeFECHighRTTMs                  = 150.0
+	stripeFECHighRTTLossThreshold uint8 = 1
+)
+
+// StripeTiming holds delay measurements of a stripe pipe or session.
+type StripeTiming struct {
+	RTTMs    float64 `json:"rtt_ms"`
+	RTTVarMs float64 `json:"rttvar_ms"`
+	JitterMs float64 `json:"jitter_ms"` // RX interarrival jitter
+	RxOWDMs  float64 `json:"rx_owd_ms"` // queueing delay on packets we receive
+	TxOWDMs  float64 `json:"tx_owd_ms"` // queueing delay on packets we send, as seen by the peer
+	Samples  uint64  `json:"samples"`   // timed keepalives received
+}
+
+// StripePipeStats holds per-pipe statistics of a stripe session: liveness
+// and delivery (stripe_pipehealth.go), TX share (stripe_scheduler.go) and
+// delay.
+type StripePipeStats struct {
+	Pipe        int     `json:"pipe"`
+	Addr        string  `json:"addr,omitempty"`
+	State       string  `json:"state,omitempty"` // "up" or "quarantined"
+	RxPkts      uint64  `json:"rx_pkts"`
+	LastRxSec   float64 `json:"last_rx_sec"` // seconds since the last packet received
+	Quarantines uint64  `json:"quarantines"`
+	TxShards    uint64  `json:"tx_shards"`
+	RxShards    uint64  `json:"rx_shards"`
+	DeliveryPct float64 `json:"delivery_pct"` // smoothed share of our shards the peer received
+	SharePct    float64 `json:"share_pct"`    // share of the TX schedule
+	StripeTiming
+}
+
+// stripePipeTiming tracks the delay of one pipe. All values in ns.
+type stripePipeTiming struct {
+	mu      sync.Mutex
+	srtt    float64
+	rttvar  float64
+	hasRTT  bool
+	jitter  float64
+	owd     float64
+	peerOWD float64
+	samples uint64
+
+	lastTransit int64
+	minTransit  int64
+	minAt       int64
+
+	echoTs int64 // last peer ts and when it arrived
+	echoAt int64
+}
+
+func newStripePipeTimings(n int) []*stripePipeTiming {
+	t := make([]*stripePipeTiming, n)
+	for i := range t {
+		t[i] = &stripePipeTiming{}
+	}
+	return t
+}
+
+// encode writes the timing block of a keepalive sent at now into buf.
+func (t *stripePipeTiming) encode(buf []byte, now int64) {
+	t.mu.Lock()
+	echoTs, echoAt, owd := t.echoTs, t.echoAt, t.owd
+	t.mu.Unlock()
+
+	var hold int64
+	if echoTs != 0 {
+		hold = (now - echoAt) / 1000
+	}
+	binary.BigEndian.PutUint64(buf[0:8], uint64(now))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(echoTs))
+	binary.BigEndian.PutUint32(buf[16:20], clampUint32(hold))
+	binary.BigEndian.PutUint32(buf[20:24], clampUint32(int64(owd)/1000))
+}
+
+// observe updates the measurements from the timing block of a keepalive
+// received at now. It reports false if buf holds no timing block.
+func (t *stripePipeTiming) observe(buf []byte, now int64) bool {
+	if len(buf) < stripeTimingLen {
+		return false
+	}
+	ts := int64(binary.BigEndian.Uint64(buf[0:8]))
+	echoTs := int64(binary.BigEndian.Uint64(buf[8:16]))
+	hold := int64(binary.BigEndian.Uint32(buf[16:20])) * 1000
+	peerOWD := int64(binary.BigEndian.Uint32(buf[20:24])) * 1000
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.echoTs, t.echoAt = ts, now
+	t.peerOWD = float64(peerOWD)
+
+	transit := now - ts
+	if t.samples > 0 {
+		d := transit - t.lastTransit
+		if d < 0 {
+			d = -d
+		}
+		t.jitter += (float64(d) - t.jitter) / 16
+	}
+	if t.samples == 0 || transit <= t.minTransit || now-t.minAt > int64(stripeOWDWindow) {
+		t.minTransit, t.minAt = transit, now
+	}
+	t.lastTransit = transit
+	t.owd = float64(transit - t.minTransit)
+	t.samples++
+
+	// The echo is our own clock; anything older than a session lifetime
+	// comes from before a restart and is not a round trip.
+	if echoTs != 0 {
+		rtt := float64(now - echoTs - hold)
+		if rtt > 0 && rtt < float64(stripeSessionTimeout) {
+			t.addRTT(rtt)
+		}
+	}
+	return true
+}
+
+// addRTT folds an RTT sample into srtt/rttvar (RFC 6298). Caller holds t.mu.
+func (t *stripePipeTiming) addRTT(rtt float64) {
+	if !t.hasRTT {
+		t.srtt, t.rttvar, t.hasRTT = rtt, rtt/2, true
+		return
+	}
+	t.rttvar = 0.75*t.rttvar + 0.25*math.Abs(t.srtt-rtt)
+	t.srtt = 0.875*t.srtt + 0.125*rtt
+}
+
+func (t *stripePipeTiming) snapshot() StripeTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return StripeTiming{
+		RTTMs:    t.srtt / 1e6,
+		RTTVarMs: t.rttvar / 1e6,
+		JitterMs: t.jitter / 1e6,
+		RxOWDMs:  t.owd / 1e6,
+		TxOWDMs:  t.peerOWD / 1e6,
+		Samples:  t.samples,
+	}
+}
+
+// stripeTimingStats snapshots the pipes and averages them into the session
+// timing. The session timing is nil until a timed keepalive arrives.
+func stripeTimingStats(timings []*stripePipeTiming, addrs []string) (*StripeTiming, []StripePipeStats) {
+	if len(timings) == 0 {
+		return nil, nil
+	}
+	pipes := make([]StripePipeStats, len(timings))
+	var sum StripeTiming
+	var n, nRTT int
+	for i, t := range timings {
+		pipes[i].Pipe = i
+		if i < len(addrs) {
+			pipes[i].Addr = addrs[i]
+		}
+		if t == nil {
+			continue
+		}
+		s := t.snapshot()
+		pipes[i].StripeTiming = s
+		if s.Samples == 0 {
+			continue
+		}
+		n++
+		sum.JitterMs += s.JitterMs
+		sum.RxOWDMs += s.RxOWDMs
+		sum.TxOWDMs += s.TxOWDMs
+		sum.Samples += s.Samples
+		if s.RTTMs > 0 {
+			nRTT++
+			sum.RTTMs += s.RTTMs
+			sum.RTTVarMs += s.RTTVarMs
+		}
+	}
+	if n == 0 {
+		return nil, pipes
+	}
+	sum.JitterMs /= float64(n)
+	sum.RxOWDMs /= float64(n)
+	sum.TxOWDMs /= float64(n)
+	if nRTT > 0 {
+		sum.RTTMs /= float64(nRTT)
+		sum.RTTVarMs /= float64(nRTT)
+	}
+	return &sum, pipes
+}
+
+// stripePacingGapNs returns the inter-packet gap for the base gap given the
+// peer-reported loss and the peer-reported OWD on our TX direction: queues
+// building up beyond stripePacingOWDTargetMs slow pacing down before they
+// turn into loss.
+func stripePacingGapNs(baseNs int64, loss uint32, txOWDMs float64) int64 {
+	targetNs := baseNs
+	if loss > 0 && loss < 255 {
+		targetNs += baseNs * int64(loss) / 100
+	}
+	if txOWDMs > stripePacingOWDTargetMs {
+		extra := (txOWDMs - stripePacingOWDTargetMs) / stripePacingOWDTargetMs
+		if extra > stripePacingOWDMaxExtra {
+			extra = stripePacingOWDMaxExtra
+		}
+		targetNs += int64(float64(baseNs) * extra)
+	}
+	return targetNs
+}
+
+// stripeFECLossThreshold returns the peer loss above which adaptive FEC
+// turns parity on: lower on long round trips, where ARQ repairs too late.
+func stripeFECLossThreshold(t *StripeTiming) uint32 {
+	if t != nil && t.RTTMs >= stripeFECHighRTTMs {
+		return uint32(stripeFECHighRTTLossThreshold)
+	}
+	return uint32(adaptiveFECLossThreshold)
+}
+
+func clampUint32(v int64) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func approxMs(got, want float64) bool { return math.Abs(got-want) < 0.01 }
+
+// TestStripePipeTiming_Echo runs two keepalive exchanges over one pipe with
+// clocks 1h apart: 10 ms each way, then 30 ms of queueing client → server.
+func TestStripePipeTiming_Echo(t *testing.T) {
+	const ms = int64(time.Millisecond)
+	client, server := &stripePipeTiming{}, &stripePipeTiming{}
+	clientNow := int64(time.Second)
+	serverNow := clientNow + int64(time.Hour)
+	buf := make([]byte, stripeTimingLen)
+
+	exchange := func(upMs int64) {
+		client.encode(buf, clientNow)
+		clientNow += upMs * ms
+		serverNow += upMs * ms
+		if !server.observe(buf, serverNow) {
+			t.Fatal("server: no timing block")
+		}
+		serverNow += ms / 10 // reply hold
+		clientNow += ms / 10
+		server.encode(buf, serverNow)
+		clientNow += 10 * ms
+		serverNow += 10 * ms
+		if !client.observe(buf, clientNow) {
+			t.Fatal("client: no timing block")
+		}
+		clientNow += int64(stripeKeepaliveInterval)
+		serverNow += int64(stripeKeepaliveInterval)
+	}
+
+	exchange(10)
+	c := client.snapshot()
+	if !approxMs(c.RTTMs, 20) || c.Samples != 1 {
+		t.Fatalf("client after one exchange: %+v, want rtt 20ms", c)
+	}
+	if s := server.snapshot(); s.RTTMs != 0 || s.RxOWDMs != 0 {
+		t.Fatalf("server after one exchange: %+v, want no RTT and no queueing yet", s)
+	}
+
+	exchange(40)
+	s := server.snapshot()
+	if !approxMs(s.RxOWDMs, 30) {
+		t.Fatalf("server rx owd = %.3f, want 30", s.RxOWDMs)
+	}
+	if !approxMs(s.RTTMs, 50) {
+		t.Fatalf("server rtt = %.3f, want 50 (10 back + 40 up)", s.RTTMs)
+	}
+	if !approxMs(s.JitterMs, 30.0/16) {
+		t.Fatalf("server jitter = %.3f, want %.3f", s.JitterMs, 30.0/16)
+	}
+	c = client.snapshot()
+	if !approxMs(c.TxOWDMs, 30) {
+		t.Fatalf("client tx owd = %.3f, want 30 reported by the server", c.TxOWDMs)
+	}
+	if want := 0.875*20 + 0.125*50; !approxMs(c.RTTMs, want) {
+		t.Fatalf("client srtt = %.3f, want %.3f", c.RTTMs, want)
+	}
+
+	if client.observe(buf[:stripeTimingLen-1], clientNow) {
+		t.Fatal("short block accepted")
+	}
+}
+
+func TestStripeTimingStats(t *testing.T) {
+	pipes := newStripePipeTimings(3)
+	pipes[0].srtt, pipes[0].hasRTT, pipes[0].samples = float64(20*time.Millisecond), true, 4
+	pipes[1].srtt, pipes[1].hasRTT, pipes[1].samples = float64(40*time.Millisecond), true, 2
+
+	timing, per := stripeTimingStats(pipes, []string{"a", "b"})
+	if timing == nil || !approxMs(timing.RTTMs, 30) || timing.Samples != 6 {
+		t.Fatalf("session timing = %+v, want the mean of the measured pipes", timing)
+	}
+	if len(per) != 3 || per[1].Addr != "b" || per[2].Samples != 0 {
+		t.Fatalf("pipes = %+v", per)
+	}
+	if timing, _ := stripeTimingStats(newStripePipeTimings(2), nil); timing != nil {
+		t.Fatalf("timing without samples = %+v, want nil", timing)
+	}
+}
+
+func TestStripePacingGapNs(t *testing.T) {
+	for _, tc := range []struct {
+		loss  uint32
+		owdMs float64
+		want  int64
+	}{
+		{0, 0, 1000},
+		{10, 0, 1100},
+		{255, 0, 1000}, // anti-waste sentinel, not a loss rate
+		{0, 20, 1000},
+		{0, 30, 1500},
+		{0, 500, 1500},
+		{10, 25, 1350},
+	} {
+		if got := stripePacingGapNs(1000, tc.loss, tc.owdMs); got != tc.want {
+			t.Errorf("loss=%d owd=%.0fms: gap = %d, want %d", tc.loss, tc.owdMs, got, tc.want)
+		}
+	}
+}
@@ -16,7 +16,7 @@ func runTunnel(ctx context.Context, cfg *Config, conn datagramConn, logger *Logg
 	if err != nil {
 		return err
 	}
-	if err := configureTUN(cfg.TunName, cfg.TunCIDR, cfg.TunMTU, logger); err != nil {
+	if err := configureTUN(cfg.TunName, cfg.tunCIDRs(), cfg.TunMTU, logger); err != nil {
 		return fmt.Errorf("configure TUN: %w", err)
 	}
 	var tunCloseOnce sync.Once
@@ -143,7 +143,8 @@ func expandMultipathPipes(paths []MultipathPathConfig, cfg *Config, logger *Logg
 }
 
 // resolvePathTransport determines the effective transport mode for a path.
-// Returns "stripe" for Starlink paths or "quic" (default).
+// Returns "stripe" for Starlink paths, the explicit per-path transport
+// (e.g. "quic-mp"), or "quic" (default).
 // Priority: explicit per-path → global starlink_transport → auto-detect.
 func resolvePathTransport(p MultipathPathConfig, cfg *Config, logger *Logger) string {
 	// Explicit per-path transport
@@ -250,9 +251,13 @@ func getWANIPViaDNS(localIP string, logger *Logger) string {
 	return ips[0]
 }
 
+// resolveBindIP resolves a bind_ip value to a literal address. Besides a
+// literal IP it accepts "if:<name>", the first IPv4 address of the
+// interface or, on an IPv6-only interface, its first global IPv6 address.
+// A "/ipv4" or "/ipv6" suffix ("if:wwan0/ipv6") selects the family.
 func resolveBindIP(value string) (string, error) {
 	if strings.HasPrefix(value, "if:") {
-		ifName := strings.TrimPrefix(value, "if:")
+		ifName, family := splitBindInterface(value)
 		iface, err := net.InterfaceByName(ifName)
 		if err != nil {
 			return "", err
@@ -261,21 +266,19 @@ func resolveBindIP(value string) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		var ips []net.IP
 		for _, addr := range addrs {
-			ipNet, ok := addr.(*net.IPNet)
-			if !ok {
-				continue
-			}
-			ip := ipNet.IP.To4()
-			if ip == nil {
-				continue
-			}
-			if ip.IsLoopback() {
-				continue
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				ips = append(ips, ipNet.IP)
 			}
+		}
+		if ip := pickBindIP(ips, family); ip != nil {
 			return ip.String(), nil
 		}
-		return "", fmt.Errorf("no ipv4 found on %s", ifName)
+		if family == "" {
+			return "", fmt.Errorf("no ipv4 or global ipv6 found on %s", ifName)
+		}
+		return "", fmt.Errorf("no %s found on %s", family, ifName)
 	}
 	ip := net.ParseIP(value)
 	if ip == nil {
@@ -283,3 +286,75 @@ func resolveBindIP(value string) (string, error) {
 	}
 	return value, nil
 }
+
+// splitBindInterface splits "if:<name>[/ipv4|/ipv6]" into the interface
+// name and the requested family ("" = IPv4 first). Interface names cannot
+// contain '/'.
+func splitBindInterface(value string) (ifName, family string) {
+	ifName = strings.TrimPrefix(value, "if:")
+	if i := strings.LastIndexByte(ifName, '/'); i >= 0 {
+		ifName, family = ifName[:i], ifName[i+1:]
+	}
+	return ifName, family
+}
+
+// withBindFamily forces family on an "if:" bind_ip without one; literal
+// addresses are returned unchanged.
+func withBindFamily(value, family string) string {
+	if !strings.HasPrefix(value, "if:") {
+		return value
+	}
+	ifName, _ := splitBindInterface(value)
+	return "if:" + ifName + "/" + family
+}
+
+// bindInterfaceName returns the interface of an "if:" bind_ip, "" for a
+// literal address.
+func bindInterfaceName(value string) string {
+	if !strings.HasPrefix(value, "if:") {
+		return ""
+	}
+	ifName, _ := splitBindInterface(value)
+	return ifName
+}
+
+// pickBindIP chooses the bind address among an interface's addresses.
+// IPv6 candidates are global unicast only (no link-local: they need a
+// zone and never reach the server); public ones win over ULAs.
+func pickBindIP(ips []net.IP, family string) net.IP {
+	var v6, ula net.IP
+	for _, ip := range ips {
+		if ip.IsLoopback() {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			if family != "ipv6" {
+				return ip4
+			}
+			continue
+		}
+		if family == "ipv4" || !ip.IsGlobalUnicast() {
+			continue
+		}
+		if ip.IsPrivate() {
+			if ula == nil {
+				ula = ip
+			}
+		} else if v6 == nil {
+			v6 = ip
+		}
+	}
+	if v6 != nil {
+		return v6
+	}
+	return ula
+}
+
+// udpNetworkFor returns "udp4" or "udp6" matching a resolved bind address,
+// so a remote_addr hostname resolves to the family the socket can reach.
+func udpNetworkFor(bindIP string) string {
+	if ip := net.ParseIP(bindIP); ip != nil && ip.To4() == nil {
+		return "udp6"
+	}
+	return "udp4"
+}
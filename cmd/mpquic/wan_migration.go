@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ─── WAN address change (single-path client) ──────────────────────────────
+//
+// A single-path client bound to "if:<name>" watches the interface for
+// address changes (netlink address events, see addr_watch_linux.go). When
+// the address bind_ip resolves to changes — a DHCP WAN getting a new lease,
+// a modem redialling — the client opens a UDP socket on the new address and
+// migrates the QUIC connection to it. The server validates the new path
+// and the tunnel keeps its connection instead of tearing it down and
+// redialling. If the migration fails the connection is closed and
+// runClientLoop reconnects as before.
+
+// wanMigrateTimeout bounds the validation of the new path.
+const wanMigrateTimeout = 5 * time.Second
+
+// wanEventSettle lets a burst of address events (the old address removed,
+// the new one added) settle before bind_ip is resolved again.
+const wanEventSettle = 500 * time.Millisecond
+
+// wanMigrator owns the socket of a single-path client connection and moves
+// the connection to a new one when the WAN address changes.
+type wanMigrator struct {
+	cfg    *Config
+	conn   quic.Connection
+	logger *Logger
+
+	mu        sync.Mutex
+	bindIP    string
+	udpConn   *net.UDPConn
+	transport *quic.Transport
+	closed    bool
+}
+
+func newWANMigrator(cfg *Config, bindIP string, udpConn *net.UDPConn, logger *Logger) *wanMigrator {
+	return &wanMigrator{
+		cfg:       cfg,
+		logger:    logger,
+		bindIP:    bindIP,
+		udpConn:   udpConn,
+		transport: &quic.Transport{Conn: udpConn},
+	}
+}
+
+// watch starts following the bind interface if bind_ip names one.
+func (w *wanMigrator) watch(ctx context.Context) {
+	if !strings.HasPrefix(w.cfg.BindIP, "if:") {
+		return
+	}
+	ifName, _ := splitBindInterface(w.cfg.BindIP)
+	events, err := watchInterfaceAddrs(ctx, ifName)
+	if err != nil {
+		w.logger.Infof("wan watch disabled bind=%s err=%v", w.cfg.BindIP, err)
+		return
+	}
+	go w.run(ctx, events)
+}
+
+// run handles address events until ctx is done or a migration fails; a
+// failed migration closes the connection so that the client reconnects.
+func (w *wanMigrator) run(ctx context.Context, events <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		}
+		settle := time.NewTimer(wanEventSettle)
+	drain:
+		for {
+			select {
+			case <-events:
+			case <-settle.C:
+				break drain
+			case <-ctx.Done():
+				settle.Stop()
+				return
+			}
+		}
+
+		ip, err := resolveBindIP(w.cfg.BindIP)
+		if err != nil {
+			// Address gone: wait for the next one, keepalives notice if
+			// it never comes back.
+			w.logger.Infof("wan address lost bind=%s err=%v", w.cfg.BindIP, err)
+			continue
+		}
+		if ip == w.currentIP() {
+			continue
+		}
+		if err := w.migrate(ctx, ip); err != nil {
+			w.logger.Errorf("wan migration failed bind=%s new=%s err=%v", w.cfg.BindIP, ip, err)
+			_ = w.conn.CloseWithError(0, "migration failed")
+			return
+		}
+	}
+}
+
+func (w *wanMigrator) currentIP() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bindIP
+}
+
+// migrate moves the connection to a new socket bound to ip.
+func (w *wanMigrator) migrate(ctx context.Context, ip string) error {
+	old := w.currentIP()
+	if udpNetworkFor(ip) != udpNetworkFor(old) {
+		return fmt.Errorf("address family changed (%s -> %s)", old, ip)
+	}
+	mc, ok := w.conn.(quic.MigratableConnection)
+	if !ok {
+		return errors.New("connection does not support migration")
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(ip), Port: 0})
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	transport := &quic.Transport{Conn: udpConn}
+
+	migrateCtx, cancel := context.WithTimeout(ctx, wanMigrateTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := mc.Migrate(migrateCtx, transport); err != nil {
+		_ = transport.Close()
+		_ = udpConn.Close()
+		return err
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		_ = transport.Close()
+		_ = udpConn.Close()
+		return nil
+	}
+	oldConn, oldTransport := w.udpConn, w.transport
+	w.bindIP, w.udpConn, w.transport = ip, udpConn, transport
+	w.mu.Unlock()
+	_ = oldTransport.Close()
+	_ = oldConn.Close()
+
+	w.logger.Infof("wan migrated bind=%s old=%s local=%s remote=%s took=%s",
+		w.cfg.BindIP, old, udpConn.LocalAddr(), w.conn.RemoteAddr(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// close releases the current socket. The connection must be closed first:
+// closing the transport tears down the connections still using it.
+func (w *wanMigrator) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	_ = w.transport.Close()
+	_ = w.udpConn.Close()
+}
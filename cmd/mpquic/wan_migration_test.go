@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// TestWANMigrator_Migrate moves a live connection to a new socket and checks
+// that datagrams still flow and the old socket is released.
+func TestWANMigrator_Migrate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := quic.ListenAddr("127.0.0.1:0", testServerTLSConfig(t), &quic.Config{EnableDatagrams: true, AllowActiveMigration: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept(ctx)
+		if err != nil {
+			return
+		}
+		for {
+			pkt, err := conn.ReceiveDatagram(ctx)
+			if err != nil {
+				return
+			}
+			_ = conn.SendDatagram(pkt)
+		}
+	}()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{BindIP: "if:lo", TLSInsecureSkipVerify: true}
+	w := newWANMigrator(cfg, "127.0.0.1", udpConn, newLogger("error"))
+	defer w.close()
+	tlsConf, err := loadClientTLSConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := w.transport.Dial(ctx, ln.Addr(), tlsConf, &quic.Config{EnableDatagrams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "done")
+	w.conn = conn
+
+	echo := func(msg string) {
+		t.Helper()
+		if err := conn.SendDatagram([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		pkt, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(pkt) != msg {
+			t.Fatalf("echo = %q, want %q", pkt, msg)
+		}
+	}
+	echo("before")
+
+	// A new address of another family cannot reach the same server.
+	if err := w.migrate(ctx, "::1"); err == nil || !strings.Contains(err.Error(), "address family") {
+		t.Fatalf("err = %v, want address family error", err)
+	}
+
+	if err := w.migrate(ctx, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if w.udpConn == udpConn {
+		t.Fatal("socket not replaced")
+	}
+	if conn.LocalAddr().String() != w.udpConn.LocalAddr().String() {
+		t.Fatalf("local addr = %s, want %s", conn.LocalAddr(), w.udpConn.LocalAddr())
+	}
+	if _, err := udpConn.WriteTo([]byte("x"), ln.Addr()); err == nil {
+		t.Fatal("old socket still open")
+	}
+	echo("after")
+}
@@ -31,13 +31,14 @@ var PARAM_META = {
 
 	/* Category B — requires restart */
 	tun_mtu:               { cat: 'B', label: 'TUN MTU',              type: 'number', min: 1280, max: 9000 },
-	congestion_algorithm:  { cat: 'B', label: 'Congestion Algorithm',  type: 'select', choices: ['cubic', 'bbr'] },
+	congestion_algorithm:  { cat: 'B', label: 'Congestion Algorithm',  type: 'select', choices: ['cubic', 'bbr', 'bbr3', 'lia'] },
 	transport_mode:        { cat: 'B', label: 'Transport Mode',        type: 'select', choices: ['quic', 'quic-dgram'] },
 	stripe_arq:            { cat: 'B', label: 'ARQ Enabled',           type: 'bool' },
 	stripe_fec_type:       { cat: 'B', label: 'FEC Type',             type: 'select', choices: ['xor', 'reed-solomon'] },
 	stripe_fec_window:     { cat: 'B', label: 'FEC Window',           type: 'number', min: 1, max: 64 },
 	stripe_fec_interleave: { cat: 'B', label: 'FEC Interleave',       type: 'number', min: 0, max: 64 },
 	stripe_disable_gso:    { cat: 'B', label: 'Disable GSO',          type: 'bool' },
+	stripe_pipe_scheduler: { cat: 'B', label: 'Pipe Scheduler',       type: 'select', choices: ['rr', 'weighted'] },
 	detect_starlink:       { cat: 'B', label: 'Detect Starlink',      type: 'bool' },
 	starlink_default_pipes: { cat: 'B', label: 'Starlink Default Pipes', type: 'number', min: 1, max: 32 },
 	starlink_transport:    { cat: 'B', label: 'Starlink Transport',    type: 'select', choices: ['quic', 'quic-dgram'] },
@@ -2,8 +2,11 @@ package quic
 
 import (
 	"fmt"
+	"net"
 	"time"
 
+	"github.com/quic-go/quic-go/congestion"
+	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/quicvarint"
 )
@@ -45,6 +48,14 @@ func validateConfig(config *Config) error {
 	if config.InitialPacketSize > protocol.MaxPacketBufferSize {
 		config.InitialPacketSize = protocol.MaxPacketBufferSize
 	}
+	if config.MaxPaths > protocol.MaxPaths {
+		config.MaxPaths = protocol.MaxPaths
+	}
+	switch config.CongestionAlgorithm {
+	case "", "cubic", "bbr", "bbr3":
+	default:
+		return fmt.Errorf("invalid congestion algorithm: %q", config.CongestionAlgorithm)
+	}
 	// check that all QUIC versions are actually supported
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
@@ -54,6 +65,24 @@ func validateConfig(config *Config) error {
 	return nil
 }
 
+// congestionControl returns the constructor of the congestion controller of a path to remoteAddr.
+func (c *Config) congestionControl(remoteAddr net.Addr) ackhandler.NewCongestionController {
+	if c.CongestionControl != nil {
+		return func(info congestion.PathInfo) congestion.SendAlgorithm {
+			info.RemoteAddr = remoteAddr
+			return c.CongestionControl(info)
+		}
+	}
+	switch c.CongestionAlgorithm {
+	case "bbr":
+		return congestion.NewBBR
+	case "bbr3":
+		return congestion.NewBBR3
+	default:
+		return nil // the ack handler's default
+	}
+}
+
 // populateConfig populates fields in the quic.Config with their default values, if none are set
 // it may be called with nil
 func populateConfig(config *Config) *Config {
@@ -121,9 +150,12 @@ func populateConfig(config *Config) *Config {
 		TokenStore:                     config.TokenStore,
 		EnableDatagrams:                config.EnableDatagrams,
 		CongestionAlgorithm:            config.CongestionAlgorithm,
+		CongestionControl:              config.CongestionControl,
+		MaxPaths:                       config.MaxPaths,
 		InitialPacketSize:              initialPacketSize,
 		DisablePathMTUDiscovery:        config.DisablePathMTUDiscovery,
 		Allow0RTT:                      config.Allow0RTT,
+		AllowActiveMigration:           config.AllowActiveMigration,
 		Tracer:                         config.Tracer,
 	}
 }
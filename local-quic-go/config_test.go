@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/quic-go/quic-go/congestion"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/logging"
 	"github.com/quic-go/quic-go/quicvarint"
@@ -63,6 +64,17 @@ var _ = Describe("Config", func() {
 			Expect(conf.InitialPacketSize).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
 		})
 
+		It("clips too large values for the number of paths", func() {
+			conf := &Config{MaxPaths: protocol.MaxPaths + 1}
+			Expect(validateConfig(conf)).To(Succeed())
+			Expect(conf.MaxPaths).To(Equal(protocol.MaxPaths))
+		})
+
+		It("rejects unknown congestion algorithms", func() {
+			Expect(validateConfig(&Config{CongestionAlgorithm: "bbr3"})).To(Succeed())
+			Expect(validateConfig(&Config{CongestionAlgorithm: "vegas"})).To(MatchError(`invalid congestion algorithm: "vegas"`))
+		})
+
 		It("doesn't modify the InitialPacketSize if it is unset", func() {
 			conf := &Config{InitialPacketSize: 0}
 			Expect(validateConfig(conf)).To(Succeed())
@@ -83,7 +95,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer":
+			case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer", "CongestionControl":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]Version{1, 2, 3}))
@@ -123,6 +135,12 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(true))
 			case "Allow0RTT":
 				f.Set(reflect.ValueOf(true))
+			case "AllowActiveMigration":
+				f.Set(reflect.ValueOf(true))
+			case "CongestionAlgorithm":
+				f.Set(reflect.ValueOf("bbr"))
+			case "MaxPaths":
+				f.Set(reflect.ValueOf(4))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -137,7 +155,7 @@ var _ = Describe("Config", func() {
 
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAllowConnectionWindowIncrease, calledTracer bool
+			var calledAllowConnectionWindowIncrease, calledTracer, calledCongestionControl bool
 			c1 := &Config{
 				GetConfigForClient:            func(info *ClientHelloInfo) (*Config, error) { return nil, errors.New("nope") },
 				AllowConnectionWindowIncrease: func(Connection, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
@@ -145,6 +163,10 @@ var _ = Describe("Config", func() {
 					calledTracer = true
 					return nil
 				},
+				CongestionControl: func(congestion.PathInfo) congestion.SendAlgorithm {
+					calledCongestionControl = true
+					return nil
+				},
 			}
 			c2 := c1.Clone()
 			c2.AllowConnectionWindowIncrease(nil, 1234)
@@ -153,6 +175,8 @@ var _ = Describe("Config", func() {
 			Expect(err).To(MatchError("nope"))
 			c2.Tracer(context.Background(), logging.PerspectiveClient, protocol.ConnectionID{})
 			Expect(calledTracer).To(BeTrue())
+			c2.CongestionControl(congestion.PathInfo{})
+			Expect(calledCongestionControl).To(BeTrue())
 		})
 
 		It("clones non-function fields", func() {
@@ -0,0 +1,98 @@
+// Package congestion defines the interface between quic-go and congestion controllers.
+// Applications can plug in their own controller using quic.Config.CongestionControl.
+package congestion
+
+import (
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/congestion"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// A PacketNumber is a QUIC packet number.
+	PacketNumber = protocol.PacketNumber
+	// RTTStats provides the RTT estimate of a path.
+	RTTStats = utils.RTTStats
+	// Bandwidth of a connection, in bits/s.
+	Bandwidth = congestion.Bandwidth
+	// A Pacer paces packets using a token bucket, allowing bursts of up to 10 packets.
+	Pacer = congestion.Pacer
+)
+
+const (
+	// BitsPerSecond is 1 bit per second.
+	BitsPerSecond = congestion.BitsPerSecond
+	// BytesPerSecond is 1 byte per second.
+	BytesPerSecond = congestion.BytesPerSecond
+)
+
+// A SendAlgorithm performs congestion control on a path.
+// Its methods are called from the connection's run loop, and never concurrently.
+// Controllers shared between paths or connections need to do their own synchronization.
+type SendAlgorithm interface {
+	// TimeUntilSend returns when the next packet may be sent, for pacing.
+	TimeUntilSend(bytesInFlight ByteCount) time.Time
+	// HasPacingBudget says if the pacer allows sending a packet at time now.
+	HasPacingBudget(now time.Time) bool
+	// OnPacketSent is called for every packet sent. bytesInFlight includes the packet, if isRetransmittable.
+	OnPacketSent(sentTime time.Time, bytesInFlight ByteCount, packetNumber PacketNumber, bytes ByteCount, isRetransmittable bool)
+	// CanSend says if the congestion window allows sending another packet.
+	CanSend(bytesInFlight ByteCount) bool
+	// MaybeExitSlowStart is called when an ACK frame updated the RTT estimate,
+	// before the packets it acknowledges are passed to OnPacketAcked.
+	MaybeExitSlowStart()
+	// OnPacketAcked is called for every packet newly acknowledged.
+	OnPacketAcked(number PacketNumber, ackedBytes ByteCount, priorInFlight ByteCount, eventTime time.Time)
+	// OnCongestionEvent is called for every packet declared lost.
+	// lostBytes is 0 if the peer reported new ECN-CE marks, in which case number is the largest acknowledged packet.
+	OnCongestionEvent(number PacketNumber, lostBytes ByteCount, priorInFlight ByteCount)
+	OnRetransmissionTimeout(packetsRetransmitted bool)
+	// SetMaxDatagramSize is called when path MTU discovery raises the maximum datagram size.
+	SetMaxDatagramSize(ByteCount)
+	InSlowStart() bool
+	InRecovery() bool
+	GetCongestionWindow() ByteCount
+}
+
+var _ congestion.SendAlgorithmWithDebugInfos = SendAlgorithm(nil)
+
+// PathInfo describes the path a congestion controller is created for.
+type PathInfo struct {
+	// RTTStats is the RTT estimate of the path, updated by quic-go.
+	RTTStats *RTTStats
+	// InitialMaxDatagramSize is the maximum datagram size before path MTU discovery.
+	InitialMaxDatagramSize ByteCount
+	// Tracer is the connection's tracer. It is nil for additional multipath paths.
+	Tracer *logging.ConnectionTracer
+	// RemoteAddr is the peer's address on the path.
+	// It is only set for controllers created by quic.Config.CongestionControl.
+	RemoteAddr net.Addr
+}
+
+// NewCubic creates a CUBIC congestion controller, or a NewReno controller if reno is set.
+func NewCubic(info PathInfo, reno bool) SendAlgorithm {
+	return congestion.NewCubicSender(congestion.DefaultClock{}, info.RTTStats, info.InitialMaxDatagramSize, reno, info.Tracer)
+}
+
+// NewBBR creates a BBRv1 congestion controller.
+func NewBBR(info PathInfo) SendAlgorithm {
+	return congestion.NewBBRSender(congestion.DefaultClock{}, info.RTTStats, info.InitialMaxDatagramSize, info.Tracer)
+}
+
+// NewBBR3 creates a BBRv3 congestion controller.
+func NewBBR3(info PathInfo) SendAlgorithm {
+	return congestion.NewBBR3Sender(congestion.DefaultClock{}, info.RTTStats, info.InitialMaxDatagramSize, info.Tracer)
+}
+
+// NewPacer creates the pacer used by the built-in congestion controllers.
+// It paces at 5/4 of the bandwidth returned by getBandwidth, so that RTT variations don't leave the congestion window unused.
+// Its maximum datagram size needs to be set using SetMaxDatagramSize.
+func NewPacer(getBandwidth func() Bandwidth) *Pacer {
+	return congestion.NewPacer(getBandwidth)
+}
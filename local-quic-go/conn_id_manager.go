@@ -209,6 +209,15 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 	return h.activeConnectionID
 }
 
+// SwitchToNextConnID switches to the next connection ID, if the peer provided one.
+// It is called when the connection migrates to a new path,
+// so that packets sent on the new path can't be linked to the old one.
+func (h *connIDManager) SwitchToNextConnID() {
+	if h.handshakeComplete && h.queue.Len() > 0 {
+		h.updateConnectionID()
+	}
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
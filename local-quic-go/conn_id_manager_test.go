@@ -347,6 +347,28 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("switches to the next connection ID when migrating", func() {
+		m.SwitchToNextConnID()
+		Expect(m.Get()).To(Equal(initialConnID))
+		m.SetHandshakeComplete()
+		for i := uint8(1); i <= 2; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+				StatelessResetToken: protocol.StatelessResetToken{i},
+			})).To(Succeed())
+		}
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 1, 1, 1})))
+		frameQueue = nil
+		m.SwitchToNextConnID()
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 2, 2, 2})))
+		Expect(*tokenAdded).To(Equal(protocol.StatelessResetToken{2}))
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		// no more connection IDs available
+		m.SwitchToNextConnID()
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 2, 2, 2})))
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(removedTokens).To(BeEmpty())
@@ -88,6 +88,8 @@ type connRunner interface {
 	GetStatelessResetToken(protocol.ConnectionID) protocol.StatelessResetToken
 	Retire(protocol.ConnectionID)
 	Remove(protocol.ConnectionID)
+	// RemoveHandler removes all connection IDs (including retired ones) and stateless reset tokens of a handler
+	RemoveHandler(packetHandler)
 	ReplaceWithClosed([]protocol.ConnectionID, []byte)
 	AddResetToken(protocol.StatelessResetToken, packetHandler)
 	RemoveResetToken(protocol.StatelessResetToken)
@@ -128,6 +130,8 @@ type connection struct {
 
 	conn      sendConn
 	sendQueue sender
+	// the packet handler manager of the Transport the connection uses, only set for the client
+	runner connRunner
 
 	streamsMap      streamManager
 	connIDManager   *connIDManager
@@ -199,6 +203,8 @@ type connection struct {
 	keepAliveInterval time.Duration
 
 	datagramQueue *datagramQueue
+	multipath     *multipathState // only set if Config.MaxPaths > 1
+	migration     *migrationState
 
 	connStateMutex sync.Mutex
 	connState      ConnectionState
@@ -209,9 +215,11 @@ type connection struct {
 }
 
 var (
-	_ Connection      = &connection{}
-	_ EarlyConnection = &connection{}
-	_ streamSender    = &connection{}
+	_ Connection           = &connection{}
+	_ EarlyConnection      = &connection{}
+	_ MultipathConnection  = &connection{}
+	_ MigratableConnection = &connection{}
+	_ streamSender         = &connection{}
 )
 
 var newConnection = func(
@@ -234,10 +242,11 @@ var newConnection = func(
 	logger utils.Logger,
 	v protocol.Version,
 ) quicConn {
+	pc := newPathConn(conn)
 	s := &connection{
 		ctx:                 ctx,
 		ctxCancel:           ctxCancel,
-		conn:                conn,
+		conn:                pc,
 		config:              conf,
 		handshakeDestConnID: destConnID,
 		srcConnIDLen:        srcConnID.Len(),
@@ -253,6 +262,7 @@ var newConnection = func(
 	} else {
 		s.logID = destConnID.String()
 	}
+	s.migration = newMigrationState(s, pc)
 	s.connIDManager = newConnIDManager(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
@@ -280,7 +290,7 @@ var newConnection = func(
 		s.perspective,
 		s.tracer,
 		s.logger,
-		s.config.CongestionAlgorithm,
+		s.config.congestionControl(s.conn.RemoteAddr()),
 	)
 	s.maxPayloadSizeEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	params := &wire.TransportParameters{
@@ -294,7 +304,7 @@ var newConnection = func(
 		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:                protocol.AckDelayExponent,
 		MaxUDPPayloadSize:               protocol.MaxPacketBufferSize,
-		DisableActiveMigration:          true,
+		DisableActiveMigration:          !s.config.AllowActiveMigration,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
 		// For interoperability with quic-go versions before May 2023, this value must be set to a value
@@ -311,6 +321,11 @@ var newConnection = func(
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	if s.config.MaxPaths > 1 {
+		params.MaxPathID = protocol.PathID(s.config.MaxPaths - 1)
+		// additional paths use other addresses
+		params.DisableActiveMigration = false
+	}
 	if s.tracer != nil && s.tracer.SentTransportParameters != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -350,8 +365,10 @@ var newClientConnection = func(
 	logger utils.Logger,
 	v protocol.Version,
 ) quicConn {
+	pc := newPathConn(conn)
 	s := &connection{
-		conn:                conn,
+		conn:                pc,
+		runner:              runner,
 		config:              conf,
 		origDestConnID:      destConnID,
 		handshakeDestConnID: destConnID,
@@ -363,20 +380,24 @@ var newClientConnection = func(
 		versionNegotiated:   hasNegotiatedVersion,
 		version:             v,
 	}
+	s.migration = newMigrationState(s, pc)
+	// the runner changes when the connection migrates to a new Transport
 	s.connIDManager = newConnIDManager(
 		destConnID,
-		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
-		runner.RemoveResetToken,
+		func(token protocol.StatelessResetToken) { s.runner.AddResetToken(token, s) },
+		func(token protocol.StatelessResetToken) { s.runner.RemoveResetToken(token) },
 		s.queueControlFrame,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
-		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
-		runner.GetStatelessResetToken,
-		runner.Remove,
-		runner.Retire,
-		runner.ReplaceWithClosed,
+		func(connID protocol.ConnectionID) { s.runner.Add(connID, s) },
+		func(connID protocol.ConnectionID) protocol.StatelessResetToken {
+			return s.runner.GetStatelessResetToken(connID)
+		},
+		func(connID protocol.ConnectionID) { s.runner.Remove(connID) },
+		func(connID protocol.ConnectionID) { s.runner.Retire(connID) },
+		func(connIDs []protocol.ConnectionID, b []byte) { s.runner.ReplaceWithClosed(connIDs, b) },
 		s.queueControlFrame,
 		connIDGenerator,
 	)
@@ -391,7 +412,7 @@ var newClientConnection = func(
 		s.perspective,
 		s.tracer,
 		s.logger,
-		s.config.CongestionAlgorithm,
+		s.config.congestionControl(s.conn.RemoteAddr()),
 	)
 	s.maxPayloadSizeEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	oneRTTStream := newCryptoStream()
@@ -420,6 +441,11 @@ var newClientConnection = func(
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	if s.config.MaxPaths > 1 {
+		params.MaxPathID = protocol.PathID(s.config.MaxPaths - 1)
+		// additional paths use other addresses
+		params.DisableActiveMigration = false
+	}
 	if s.tracer != nil && s.tracer.SentTransportParameters != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -456,6 +482,10 @@ func (s *connection) preSetup() {
 	s.sendQueue = newSendQueue(s.conn)
 	s.retransmissionQueue = newRetransmissionQueue()
 	s.frameParser = *wire.NewFrameParser(s.config.EnableDatagrams)
+	if s.config.MaxPaths > 1 {
+		s.frameParser.EnableMultipath()
+		s.multipath = newMultipathState(s)
+	}
 	s.rttStats = &utils.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
@@ -601,6 +631,10 @@ runLoop:
 		}
 
 		now := time.Now()
+		if s.multipath != nil {
+			s.multipath.onTimers(now)
+		}
+		s.migration.onTimers(now)
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
@@ -626,6 +660,10 @@ runLoop:
 			}
 		}
 
+		// Additional paths have their own send queues.
+		if s.multipath.active() {
+			s.multipath.sendPackets(now)
+		}
 		if s.sendQueue.WouldBlock() {
 			// The send queue is still busy sending out packets.
 			// Wait until there's space to enqueue new packets.
@@ -682,6 +720,24 @@ func (s *connection) ConnectionState() ConnectionState {
 	return s.connState
 }
 
+func (s *connection) Stats() ConnectionStats {
+	return newConnectionStats(s.sentPacketHandler.Stats())
+}
+
+func newConnectionStats(st ackhandler.Stats) ConnectionStats {
+	return ConnectionStats{
+		SmoothedRTT:          st.SmoothedRTT,
+		MeanDeviation:        st.MeanDeviation,
+		MinRTT:               st.MinRTT,
+		LatestRTT:            st.LatestRTT,
+		CongestionWindow:     uint64(st.CongestionWindow),
+		BytesInFlight:        uint64(st.BytesInFlight),
+		PacketsSent:          st.PacketsSent,
+		PacketsLost:          st.PacketsLost,
+		PacketsRetransmitted: st.PacketsRetransmitted,
+	}
+}
+
 // Time when the connection should time out
 func (s *connection) nextIdleTimeoutTime() time.Time {
 	idleTimeout := max(s.idleTimeout, s.rttStats.PTO(true)*3)
@@ -713,11 +769,18 @@ func (s *connection) maybeResetTimer() {
 		}
 	}
 
+	lossTime, pacingDeadline := s.sentPacketHandler.GetLossDetectionTimeout(), s.pacingDeadline
+	if s.multipath.active() {
+		lossTime, pacingDeadline = s.multipath.addDeadlines(lossTime, pacingDeadline)
+	}
+	if t := s.migration.deadline(); !t.IsZero() && (lossTime.IsZero() || t.Before(lossTime)) {
+		lossTime = t
+	}
 	s.timer.SetTimer(
 		deadline,
 		s.receivedPacketHandler.GetAlarmTimeout(),
-		s.sentPacketHandler.GetLossDetectionTimeout(),
-		s.pacingDeadline,
+		lossTime,
+		pacingDeadline,
 	)
 }
 
@@ -780,6 +843,9 @@ func (s *connection) handleHandshakeConfirmed() error {
 
 	s.handshakeConfirmed = true
 	s.sentPacketHandler.SetHandshakeConfirmed()
+	if err := s.multipath.handshakeConfirmed(); err != nil {
+		return err
+	}
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
 
 	if !s.config.DisablePathMTUDiscovery && s.conn.capabilities().DF {
@@ -892,6 +958,9 @@ func (s *connection) handleShortHeaderPacket(p receivedPacket) bool {
 		s.tracer.DroppedPacket(logging.PacketType1RTT, protocol.InvalidPacketNumber, protocol.ByteCount(len(p.data)), logging.PacketDropHeaderParseError)
 		return false
 	}
+	if pathID, ok := s.multipath.pathForConnID(destConnID); ok {
+		return s.multipath.handlePacket(p, destConnID, pathID)
+	}
 	pn, pnLen, keyPhase, data, err := s.unpacker.UnpackShortHeader(p.rcvTime, p.data)
 	if err != nil {
 		wasQueued = s.handleUnpackError(err, p, logging.PacketType1RTT)
@@ -927,10 +996,12 @@ func (s *connection) handleShortHeaderPacket(p receivedPacket) bool {
 			)
 		}
 	}
-	if err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log); err != nil {
+	isNonProbing, err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log)
+	if err != nil {
 		s.closeLocal(err)
 		return false
 	}
+	s.migration.receivedPacket(p, pn, isNonProbing)
 	return true
 }
 
@@ -1212,7 +1283,7 @@ func (s *connection) handleUnpackedLongHeaderPacket(
 			s.tracer.ReceivedLongHeaderPacket(packet.hdr, packetSize, ecn, frames)
 		}
 	}
-	isAckEliciting, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
+	isAckEliciting, _, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
 	if err != nil {
 		return err
 	}
@@ -1226,16 +1297,16 @@ func (s *connection) handleUnpackedShortHeaderPacket(
 	ecn protocol.ECN,
 	rcvTime time.Time,
 	log func([]logging.Frame),
-) error {
+) (isNonProbing bool, _ error) {
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
-	isAckEliciting, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
+	isAckEliciting, isNonProbing, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
+	return isNonProbing, s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
 }
 
 func (s *connection) handleFrames(
@@ -1243,7 +1314,7 @@ func (s *connection) handleFrames(
 	destConnID protocol.ConnectionID,
 	encLevel protocol.EncryptionLevel,
 	log func([]logging.Frame),
-) (isAckEliciting bool, _ error) {
+) (isAckEliciting, isNonProbing bool, _ error) {
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []logging.Frame
@@ -1255,7 +1326,7 @@ func (s *connection) handleFrames(
 	for len(data) > 0 {
 		l, frame, err := s.frameParser.ParseNext(data, encLevel, s.version)
 		if err != nil {
-			return false, err
+			return false, false, err
 		}
 		data = data[l:]
 		if frame == nil {
@@ -1264,6 +1335,9 @@ func (s *connection) handleFrames(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !wire.IsProbingFrame(frame) {
+			isNonProbing = true
+		}
 		if log != nil {
 			frames = append(frames, toLoggingFrame(frame))
 		}
@@ -1274,7 +1348,7 @@ func (s *connection) handleFrames(
 		}
 		if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
 			if log == nil {
-				return false, err
+				return false, false, err
 			}
 			// If we're logging, we need to keep parsing (but not handling) all frames.
 			handleErr = err
@@ -1284,7 +1358,7 @@ func (s *connection) handleFrames(
 	if log != nil {
 		log(frames)
 		if handleErr != nil {
-			return false, handleErr
+			return false, false, handleErr
 		}
 	}
 
@@ -1294,7 +1368,7 @@ func (s *connection) handleFrames(
 	// and an ACK serialized after that CRYPTO frame. In this case, we still want to process the ACK frame.
 	if !handshakeWasComplete && s.handshakeComplete {
 		if err := s.handleHandshakeComplete(); err != nil {
-			return false, err
+			return false, false, err
 		}
 	}
 
@@ -1330,7 +1404,14 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
+		if s.multipath.active() {
+			s.multipath.handlePathResponseFrame(frame)
+			break
+		}
+		if s.migration.handlePathResponseFrame(frame) {
+			break
+		}
+		// we only send PATH_CHALLENGEs when migrating
 		err = errors.New("unexpected PATH_RESPONSE frame")
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
@@ -1342,6 +1423,14 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 		err = s.handleHandshakeDoneFrame()
 	case *wire.DatagramFrame:
 		err = s.handleDatagramFrame(frame)
+	case *wire.PathAbandonFrame:
+		err = s.multipath.handlePathAbandonFrame(frame)
+	case *wire.PathNewConnectionIDFrame:
+		err = s.multipath.handlePathNewConnectionIDFrame(frame)
+	case *wire.PathRetireConnectionIDFrame:
+		err = s.multipath.handlePathRetireConnectionIDFrame(frame)
+	case *wire.MaxPathIDFrame:
+		err = s.multipath.handleMaxPathIDFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1487,6 +1576,11 @@ func (s *connection) handleStopSendingFrame(frame *wire.StopSendingFrame) error
 }
 
 func (s *connection) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
+	// PATH_RESPONSE frames are sent on the path the PATH_CHALLENGE was received on.
+	if p := s.multipath.receivingPath(); p != nil {
+		p.frames.queue(&wire.PathResponseFrame{Data: frame.Data})
+		return
+	}
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
@@ -1525,6 +1619,9 @@ func (s *connection) handleHandshakeDoneFrame() error {
 }
 
 func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.EncryptionLevel) error {
+	if frame.PathID != protocol.InitialPathID {
+		return s.multipath.handlePathAckFrame(frame, s.lastPacketReceivedTime)
+	}
 	acked1RTTPacket, err := s.sentPacketHandler.ReceivedAck(frame, encLevel, s.lastPacketReceivedTime)
 	if err != nil {
 		return err
@@ -1550,6 +1647,10 @@ func (s *connection) handleDatagramFrame(f *wire.DatagramFrame) error {
 			ErrorMessage: "DATAGRAM frame too large",
 		}
 	}
+	if p := s.multipath.receivingPath(); p != nil {
+		p.datagramQueue.HandleDatagramFrame(f)
+		return nil
+	}
 	s.datagramQueue.HandleDatagramFrame(f)
 	return nil
 }
@@ -1641,6 +1742,9 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	if s.datagramQueue != nil {
 		s.datagramQueue.CloseWithError(e)
 	}
+	if s.multipath != nil {
+		s.multipath.close(e)
+	}
 
 	if s.tracer != nil && s.tracer.ClosedConnection != nil && !errors.As(e, &recreateErr) {
 		s.tracer.ClosedConnection(e)
@@ -1780,6 +1884,9 @@ func (s *connection) applyTransportParameters() {
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
+	if s.multipath != nil {
+		s.multipath.negotiate(params)
+	}
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
@@ -1804,7 +1911,7 @@ func (s *connection) applyTransportParameters() {
 func (s *connection) triggerSending(now time.Time) error {
 	s.pacingDeadline = time.Time{}
 
-	sendMode := s.sentPacketHandler.SendMode(now)
+	sendMode := s.sendMode(now)
 	//nolint:exhaustive // No need to handle pacing limited here.
 	switch sendMode {
 	case ackhandler.SendAny:
@@ -1858,6 +1965,15 @@ func (s *connection) triggerSending(now time.Time) error {
 	}
 }
 
+// sendMode is the send mode of the sent packet handler,
+// unless the anti-amplification limit of an address the connection migrated to blocks sending.
+func (s *connection) sendMode(now time.Time) ackhandler.SendMode {
+	if s.migration.amplificationLimited() {
+		return ackhandler.SendNone
+	}
+	return s.sentPacketHandler.SendMode(now)
+}
+
 func (s *connection) sendPackets(now time.Time) error {
 	// Path MTU Discovery
 	// Can't use GSO, since we need to send a single packet that's larger than our current maximum size.
@@ -1897,7 +2013,7 @@ func (s *connection) sendPackets(now time.Time) error {
 		if err := s.sendPackedCoalescedPacket(packet, s.sentPacketHandler.ECNMode(packet.IsOnlyShortHeaderPacket()), now); err != nil {
 			return err
 		}
-		sendMode := s.sentPacketHandler.SendMode(now)
+		sendMode := s.sendMode(now)
 		if sendMode == ackhandler.SendPacingLimited {
 			s.resetPacingDeadline()
 		} else if sendMode == ackhandler.SendAny {
@@ -1929,7 +2045,7 @@ func (s *connection) sendPacketsWithoutGSO(now time.Time) error {
 		if s.sendQueue.WouldBlock() {
 			return nil
 		}
-		sendMode := s.sentPacketHandler.SendMode(now)
+		sendMode := s.sendMode(now)
 		if sendMode == ackhandler.SendPacingLimited {
 			s.resetPacingDeadline()
 			return nil
@@ -1965,7 +2081,7 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 		}
 
 		if !dontSendMore {
-			sendMode := s.sentPacketHandler.SendMode(now)
+			sendMode := s.sendMode(now)
 			if sendMode == ackhandler.SendPacingLimited {
 				s.resetPacingDeadline()
 			}
@@ -2096,6 +2212,7 @@ func (s *connection) registerPackedShortHeaderPacket(p shortHeaderPacket, ecn pr
 	}
 	s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
 	s.connIDManager.SentPacket()
+	s.migration.sentBytes(p.Length)
 }
 
 func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn protocol.ECN, now time.Time) error {
@@ -2129,6 +2246,7 @@ func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn prot
 		s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
 	}
 	s.connIDManager.SentPacket()
+	s.migration.sentBytes(protocol.ByteCount(packet.buffer.Len()))
 	s.sendQueue.Send(packet.buffer, 0, ecn)
 	return nil
 }
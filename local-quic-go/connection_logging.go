@@ -41,6 +41,7 @@ func toLoggingFrame(frame wire.Frame) logging.Frame {
 
 func toLoggingAckFrame(f *wire.AckFrame) *logging.AckFrame {
 	ack := &logging.AckFrame{
+		PathID:    f.PathID,
 		AckRanges: slices.Clone(f.AckRanges),
 		DelayTime: f.DelayTime,
 		ECNCE:     f.ECNCE,
@@ -0,0 +1,344 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// This file implements connection migration (RFC 9000, section 9) for connections that don't use multipath:
+//   - The client migrates using MigratableConnection.Migrate, moving the connection to a new Transport
+//     (i.e. a new local socket). It switches over at once, since the old path is usually gone when this
+//     is needed, and validates the new path with PATH_CHALLENGE frames.
+//   - If Config.AllowActiveMigration is set, the server follows the client to a new address when it receives
+//     the non-probing packet with the highest packet number so far from it, and validates the new address.
+//     Until the address is validated, it sends at most 3 times the bytes it received from it (RFC 9000, section 9.3).
+//   - RTT and congestion state start from scratch on a new path, unless only the port changed (a NAT rebinding).
+//   - If validation fails, the endpoint moves back to the previous path.
+
+// ErrMigrationDisabled is returned by Migrate if the peer disabled active migration.
+var ErrMigrationDisabled = errors.New("peer disabled active migration")
+
+var errMigrationInProgress = errors.New("migration already in progress")
+
+var errMigrationBeforeHandshake = errors.New("handshake not confirmed")
+
+// A pathConn is the sendConn of a connection.
+// The sendConn it forwards to is replaced when the connection migrates to a new path.
+type pathConn struct {
+	cur atomic.Pointer[sendConn]
+}
+
+var _ sendConn = &pathConn{}
+
+func newPathConn(c sendConn) *pathConn {
+	pc := &pathConn{}
+	pc.set(c)
+	return pc
+}
+
+func (c *pathConn) get() sendConn     { return *c.cur.Load() }
+func (c *pathConn) set(conn sendConn) { c.cur.Store(&conn) }
+
+func (c *pathConn) Write(b []byte, gsoSize uint16, ecn protocol.ECN) error {
+	return c.get().Write(b, gsoSize, ecn)
+}
+func (c *pathConn) Close() error                   { return c.get().Close() }
+func (c *pathConn) LocalAddr() net.Addr            { return c.get().LocalAddr() }
+func (c *pathConn) RemoteAddr() net.Addr           { return c.get().RemoteAddr() }
+func (c *pathConn) capabilities() connCapabilities { return c.get().capabilities() }
+
+// migrationPath is a path the connection used before migrating.
+type migrationPath struct {
+	conn   sendConn
+	runner connRunner // only set for the client
+}
+
+// migrationState is the migration state of a connection.
+// Apart from queueOp, it is only accessed from the connection's run loop.
+type migrationState struct {
+	conn     *connection
+	pathConn *pathConn
+
+	// set while the current path is being validated
+	validating        bool
+	prev              migrationPath
+	challenges        [][8]byte
+	challengeDeadline time.Time
+	result            chan<- error // only set for the client, reports the outcome to Migrate
+
+	// PATH_RESPONSEs might arrive late, after the path was validated
+	migrated bool
+	// the largest packet number of a non-probing 1-RTT packet received, the server migrates on packets above it
+	largestPN protocol.PacketNumber
+	// set while the server sends to an address it didn't validate yet
+	amplificationLimit bool
+	bytesReceived      protocol.ByteCount
+	bytesSent          protocol.ByteCount
+
+	opsMutex sync.Mutex
+	ops      []func()
+}
+
+func newMigrationState(s *connection, pc *pathConn) *migrationState {
+	return &migrationState{
+		conn:      s,
+		pathConn:  pc,
+		largestPN: protocol.InvalidPacketNumber,
+	}
+}
+
+func (m *migrationState) queueOp(op func()) {
+	m.opsMutex.Lock()
+	m.ops = append(m.ops, op)
+	m.opsMutex.Unlock()
+	m.conn.scheduleSending()
+}
+
+func (m *migrationState) onTimers(now time.Time) {
+	m.opsMutex.Lock()
+	ops := m.ops
+	m.ops = nil
+	m.opsMutex.Unlock()
+	for _, op := range ops {
+		op()
+	}
+	if !m.validating || now.Before(m.challengeDeadline) {
+		return
+	}
+	if len(m.challenges) >= protocol.MaxPathChallenges {
+		m.failed(now, errPathValidationFailed)
+		return
+	}
+	m.queueChallenge(now)
+}
+
+// deadline returns the time when the next PATH_CHALLENGE is due.
+// It returns a zero time if no path is being validated.
+func (m *migrationState) deadline() time.Time {
+	if !m.validating {
+		return time.Time{}
+	}
+	return m.challengeDeadline
+}
+
+func (m *migrationState) queueChallenge(now time.Time) {
+	var data [8]byte
+	_, _ = rand.Read(data[:])
+	m.challenges = append(m.challenges, data)
+	m.conn.queueControlFrame(&wire.PathChallengeFrame{Data: data})
+	// back off exponentially, the new path might be lossy
+	m.challengeDeadline = now.Add(m.conn.rttStats.PTO(false) << (len(m.challenges) - 1))
+}
+
+// switchPath moves the connection to conn, and starts validating it.
+// prev is the path to move back to if validation fails.
+func (m *migrationState) switchPath(conn sendConn, prev migrationPath, resetCongestion bool, now time.Time) {
+	s := m.conn
+	if !m.validating {
+		m.prev = prev
+	}
+	s.logger.Debugf("Migrating from %s -> %s to %s -> %s", s.conn.LocalAddr(), s.conn.RemoteAddr(), conn.LocalAddr(), conn.RemoteAddr())
+	s.connIDManager.SwitchToNextConnID()
+	m.pathConn.set(conn)
+	if resetCongestion {
+		s.sentPacketHandler.MigratedPath(now, protocol.ByteCount(s.config.InitialPacketSize))
+	}
+	m.validating = true
+	m.migrated = true
+	m.challenges = nil
+	m.queueChallenge(now)
+}
+
+// handlePathResponseFrame handles a PATH_RESPONSE frame.
+// It returns false if the frame doesn't belong to a migration.
+func (m *migrationState) handlePathResponseFrame(f *wire.PathResponseFrame) bool {
+	if !m.migrated {
+		return false
+	}
+	if !m.validating || !slices.Contains(m.challenges, f.Data) {
+		// a late response for a path that was already validated
+		return true
+	}
+	s := m.conn
+	s.logger.Debugf("Validated path %s -> %s", s.conn.LocalAddr(), s.conn.RemoteAddr())
+	if m.prev.runner != nil {
+		m.prev.runner.RemoveHandler(s)
+	}
+	m.validating = false
+	m.challenges = nil
+	m.prev = migrationPath{}
+	m.amplificationLimit = false
+	m.report(nil)
+	return true
+}
+
+// failed moves the connection back to the path it used before the migration.
+func (m *migrationState) failed(now time.Time, err error) {
+	s := m.conn
+	s.logger.Debugf("Migration to %s -> %s failed: %s", s.conn.LocalAddr(), s.conn.RemoteAddr(), err)
+	if m.prev.runner != nil {
+		s.registerConnIDs(m.prev.runner)
+		s.runner.RemoveHandler(s)
+		s.runner = m.prev.runner
+	}
+	m.pathConn.set(m.prev.conn)
+	s.sentPacketHandler.MigratedPath(now, protocol.ByteCount(s.config.InitialPacketSize))
+	m.validating = false
+	m.challenges = nil
+	m.prev = migrationPath{}
+	m.amplificationLimit = false
+	m.report(err)
+}
+
+func (m *migrationState) report(err error) {
+	if m.result != nil {
+		m.result <- err
+		m.result = nil
+	}
+}
+
+// migrate moves a client connection to the Transport tr.
+func (m *migrationState) migrate(tr *Transport, result chan<- error) {
+	s := m.conn
+	switch {
+	case s.multipath.active():
+		result <- errors.New("multipath connections open new paths using OpenPath")
+		return
+	// RFC 9000, section 9: no migration before the handshake is confirmed
+	case !s.handshakeConfirmed:
+		result <- errMigrationBeforeHandshake
+		return
+	case s.peerParams.DisableActiveMigration:
+		result <- ErrMigrationDisabled
+		return
+	case m.validating:
+		result <- errMigrationInProgress
+		return
+	// the ack handler decided whether to use ECN when the connection was created
+	case tr.conn.capabilities().ECN != s.conn.capabilities().ECN:
+		result <- errors.New("the transport's ECN support differs from the connection's")
+		return
+	}
+	prev := migrationPath{conn: m.pathConn.get(), runner: s.runner}
+	conn := newSendConn(tr.conn, s.conn.RemoteAddr(), packetInfo{}, s.logger)
+	m.result = result
+	m.switchPath(conn, prev, true, time.Now())
+	s.registerConnIDs(tr.handlerMap)
+	s.runner = tr.handlerMap
+}
+
+// abort is called when Migrate returns before the new path was validated.
+func (m *migrationState) abort(result chan<- error, err error) {
+	if m.validating && m.result == result {
+		m.failed(time.Now(), err)
+	}
+}
+
+// receivedPacket is called for every 1-RTT packet that was processed successfully.
+// The server follows the client if a non-probing packet arrived from a new address.
+// Probing packets (RFC 9000, section 9.1) never move the connection.
+func (m *migrationState) receivedPacket(p receivedPacket, pn protocol.PacketNumber, isNonProbing bool) {
+	if m.amplificationLimit && p.remoteAddr != nil && sameAddr(p.remoteAddr, m.pathConn.RemoteAddr()) {
+		m.bytesReceived += p.Size()
+	}
+	if !isNonProbing || pn <= m.largestPN {
+		return
+	}
+	m.largestPN = pn
+	s := m.conn
+	if s.perspective != protocol.PerspectiveServer || !s.config.AllowActiveMigration || s.multipath.active() {
+		return
+	}
+	cur, ok := m.pathConn.get().(*sconn)
+	if !ok || p.remoteAddr == nil || sameAddr(p.remoteAddr, cur.RemoteAddr()) {
+		return
+	}
+	conn := newSendConn(cur.rawConn, p.remoteAddr, p.info, s.logger)
+	m.switchPath(conn, migrationPath{conn: cur}, !sameIP(p.remoteAddr, cur.RemoteAddr()), p.rcvTime)
+	// The packet might have been sent by an attacker, spoofing the source address (RFC 9000, section 9.3).
+	m.amplificationLimit = true
+	m.bytesReceived = p.Size()
+	m.bytesSent = 0
+}
+
+// sentBytes is called for every packet sent.
+func (m *migrationState) sentBytes(n protocol.ByteCount) {
+	if m.amplificationLimit {
+		m.bytesSent += n
+	}
+}
+
+// amplificationLimited says if the server has to stop sending to an address it migrated to,
+// since it sent 3 times the bytes it received from it, and the address is not validated yet.
+func (m *migrationState) amplificationLimited() bool {
+	return m.amplificationLimit && m.bytesSent >= protocol.AmplificationFactor*m.bytesReceived
+}
+
+// registerConnIDs makes the connection reachable on the connection IDs it issued using runner.
+func (s *connection) registerConnIDs(runner connRunner) {
+	for _, connID := range s.connIDGenerator.activeSrcConnIDs {
+		runner.Add(connID, s)
+	}
+	if token := s.connIDManager.activeStatelessResetToken; token != nil {
+		runner.AddResetToken(*token, s)
+	}
+}
+
+func (s *connection) Migrate(ctx context.Context, tr *Transport) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only the client can migrate")
+	}
+	select {
+	case <-s.HandshakeComplete():
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
+	if err := tr.init(false); err != nil {
+		return err
+	}
+	if tr.connIDLen != s.srcConnIDLen {
+		return fmt.Errorf("transport uses %d byte connection IDs, the connection %d byte connection IDs", tr.connIDLen, s.srcConnIDLen)
+	}
+	m := s.migration
+	result := make(chan error, 1)
+	m.queueOp(func() { m.migrate(tr, result) })
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		m.queueOp(func() { m.abort(result, ctx.Err()) })
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
+}
+
+func sameAddr(a, b net.Addr) bool {
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if !ok1 || !ok2 {
+		return a.String() == b.String()
+	}
+	return ua.Port == ub.Port && ua.IP.Equal(ub.IP)
+}
+
+func sameIP(a, b net.Addr) bool {
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if !ok1 || !ok2 {
+		return false
+	}
+	return ua.IP.Equal(ub.IP)
+}
@@ -2519,6 +2519,59 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
+	Context("migration", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 7331}
+		packetFrom := func(addr net.Addr, size int) receivedPacket {
+			return receivedPacket{remoteAddr: addr, data: make([]byte, size), rcvTime: time.Now()}
+		}
+
+		BeforeEach(func() {
+			rawConn := NewMockRawConn(mockCtrl)
+			rawConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			conn.migration.pathConn.set(newSendConn(rawConn, remoteAddr, packetInfo{}, utils.DefaultLogger))
+			conn.config.AllowActiveMigration = true
+			// a new path starts with a new congestion controller
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+		})
+
+		It("doesn't follow the client unless active migration is allowed", func() {
+			conn.config.AllowActiveMigration = false
+			conn.migration.receivedPacket(packetFrom(newAddr, 100), 10, true)
+			Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
+		})
+
+		It("follows the client on non-probing packets only", func() {
+			conn.migration.receivedPacket(packetFrom(newAddr, 100), 10, false)
+			Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
+			conn.migration.receivedPacket(packetFrom(newAddr, 100), 9, true)
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+			// packets with a lower packet number don't move the connection back
+			conn.migration.receivedPacket(packetFrom(remoteAddr, 100), 8, true)
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+		})
+
+		It("limits sending to the new address until it is validated", func() {
+			conn.migration.receivedPacket(packetFrom(newAddr, 100), 10, true)
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+			conn.migration.sentBytes(299)
+			Expect(conn.migration.amplificationLimited()).To(BeFalse())
+			conn.migration.sentBytes(1)
+			Expect(conn.migration.amplificationLimited()).To(BeTrue())
+			Expect(conn.sendMode(time.Now())).To(Equal(ackhandler.SendNone))
+			// bytes received from other addresses don't count
+			conn.migration.receivedPacket(packetFrom(remoteAddr, 100), 5, true)
+			Expect(conn.migration.amplificationLimited()).To(BeTrue())
+			conn.migration.receivedPacket(packetFrom(newAddr, 100), 11, false)
+			Expect(conn.migration.amplificationLimited()).To(BeFalse())
+			conn.migration.sentBytes(300)
+			Expect(conn.migration.amplificationLimited()).To(BeTrue())
+			// a PATH_RESPONSE validates the address
+			Expect(conn.migration.challenges).To(HaveLen(1))
+			Expect(conn.handleFrame(&wire.PathResponseFrame{Data: conn.migration.challenges[0]}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(conn.migration.amplificationLimited()).To(BeFalse())
+		})
+	})
+
 	It("returns the local address", func() {
 		Expect(conn.LocalAddr()).To(Equal(localAddr))
 	})
@@ -2697,6 +2750,13 @@ var _ = Describe("Client Connection", func() {
 		Expect(conn.handleAckFrame(ack, protocol.Encryption1RTT)).To(Succeed())
 	})
 
+	It("doesn't migrate before the handshake is confirmed", func() {
+		conn.peerParams = &wire.TransportParameters{}
+		result := make(chan error, 1)
+		conn.migration.migrate(&Transport{}, result)
+		Expect(result).To(Receive(MatchError(errMigrationBeforeHandshake)))
+	})
+
 	It("doesn't send a CONNECTION_CLOSE when no packet was sent", func() {
 		conn.sentFirstPacket = false
 		tracer.EXPECT().ClosedConnection(gomock.Any())
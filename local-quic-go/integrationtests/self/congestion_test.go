@@ -0,0 +1,130 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/congestion"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countingSender counts the packets acknowledged on a path
+type countingSender struct {
+	congestion.SendAlgorithm
+	acked *atomic.Int64
+}
+
+func (s *countingSender) OnPacketAcked(pn congestion.PacketNumber, ackedBytes, priorInFlight congestion.ByteCount, eventTime time.Time) {
+	s.acked.Add(1)
+	s.SendAlgorithm.OnPacketAcked(pn, ackedBytes, priorInFlight, eventTime)
+}
+
+var _ = Describe("Custom congestion control", func() {
+	var (
+		created    atomic.Int32
+		acked      atomic.Int64
+		remoteAddr atomic.Value
+	)
+
+	BeforeEach(func() {
+		created.Store(0)
+		acked.Store(0)
+	})
+
+	newCongestionControl := func(info congestion.PathInfo) congestion.SendAlgorithm {
+		created.Add(1)
+		remoteAddr.Store(info.RemoteAddr)
+		return &countingSender{SendAlgorithm: congestion.NewBBR3(info), acked: &acked}
+	}
+
+	It("uses the congestion controller of the config", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(&quic.Config{
+			CongestionAlgorithm: "cubic",
+			CongestionControl:   newCongestionControl,
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		_, err = io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(created.Load()).To(BeEquivalentTo(1))
+		Expect(remoteAddr.Load().(*net.UDPAddr).Port).To(Equal(ln.Addr().(*net.UDPAddr).Port))
+		Expect(acked.Load()).To(BeNumerically(">=", len(PRData)/1500))
+	})
+
+	It("creates a congestion controller for every path", func() {
+		conf := getQuicConfig(&quic.Config{EnableDatagrams: true, MaxPaths: 2})
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			path, err := conn.(quic.MultipathConnection).AcceptPath(context.Background())
+			if err != nil {
+				return
+			}
+			b, err := path.ReceiveDatagram(context.Background())
+			if err != nil {
+				return
+			}
+			Expect(path.SendDatagram(b)).To(Succeed())
+		}()
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &quic.Transport{Conn: udpConn}
+		defer tr.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		clientConf := conf.Clone()
+		clientConf.CongestionControl = newCongestionControl
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), clientConf)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(created.Load()).To(BeEquivalentTo(1))
+
+		udpConn1, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		tr1 := &quic.Transport{Conn: udpConn1}
+		defer tr1.Close()
+		path, err := conn.(quic.MultipathConnection).OpenPath(ctx, tr1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Load()).To(BeEquivalentTo(2))
+		Expect(path.SendDatagram([]byte("foobar"))).To(Succeed())
+		b, err := path.ReceiveDatagram(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foobar")))
+		Expect(path.Stats().PacketsSent).ToNot(BeZero())
+		Expect(path.Stats().CongestionWindow).ToNot(BeZero())
+	})
+})
@@ -0,0 +1,90 @@
+package self_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// blackholeConn drops all packets written to it
+type blackholeConn struct {
+	*net.UDPConn
+}
+
+func (c *blackholeConn) WriteTo(p []byte, _ net.Addr) (int, error) { return len(p), nil }
+
+func (c *blackholeConn) WriteMsgUDP(p, _ []byte, _ *net.UDPAddr) (int, int, error) {
+	return len(p), 0, nil
+}
+
+var _ = Describe("Connection migration", func() {
+	newUDPConn := func() *net.UDPConn {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		return udpConn
+	}
+
+	It("migrates to a new local address", func() {
+		conf := getQuicConfig(&quic.Config{EnableDatagrams: true, AllowActiveMigration: true})
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverConn := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConn <- conn
+			for {
+				b, err := conn.ReceiveDatagram(context.Background())
+				if err != nil {
+					return
+				}
+				Expect(conn.SendDatagram(b)).To(Succeed())
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		echo := func(conn quic.Connection, msg string) {
+			ExpectWithOffset(1, conn.SendDatagram([]byte(msg))).To(Succeed())
+			b, err := conn.ReceiveDatagram(ctx)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, string(b)).To(Equal(msg))
+		}
+
+		tr1 := &quic.Transport{Conn: newUDPConn()}
+		defer tr1.Close()
+		conn, err := tr1.Dial(ctx, ln.Addr(), getTLSClientConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		var sconn quic.Connection
+		Eventually(serverConn).Should(Receive(&sconn))
+		echo(conn, "foo")
+
+		tr2 := &quic.Transport{Conn: newUDPConn()}
+		defer tr2.Close()
+		Expect(conn.(quic.MigratableConnection).Migrate(ctx, tr2)).To(Succeed())
+		Expect(conn.LocalAddr()).To(Equal(tr2.Conn.LocalAddr()))
+		// the old socket is not needed anymore
+		Expect(tr1.Close()).To(Succeed())
+		echo(conn, "bar")
+		Eventually(sconn.RemoteAddr).Should(Equal(tr2.Conn.LocalAddr()))
+
+		// if the new path can't be validated, the connection stays on the old one
+		tr3 := &quic.Transport{Conn: &blackholeConn{UDPConn: newUDPConn()}}
+		defer tr3.Close()
+		shortCtx, shortCancel := context.WithTimeout(ctx, scaleDuration(200*time.Millisecond))
+		defer shortCancel()
+		Expect(conn.(quic.MigratableConnection).Migrate(shortCtx, tr3)).To(MatchError(context.DeadlineExceeded))
+		Eventually(conn.LocalAddr).Should(Equal(tr2.Conn.LocalAddr()))
+		echo(conn, "baz")
+		Expect(conn.Context().Err()).ToNot(HaveOccurred())
+	})
+})
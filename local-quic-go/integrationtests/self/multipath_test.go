@@ -0,0 +1,184 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multipath", func() {
+	newTransport := func() *quic.Transport {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		return &quic.Transport{Conn: udpConn}
+	}
+
+	// echo sends back all datagrams received on the path
+	echo := func(path *quic.Path) {
+		defer GinkgoRecover()
+		for {
+			b, err := path.ReceiveDatagram(context.Background())
+			if err != nil {
+				return
+			}
+			Expect(path.SendDatagram(b)).To(Succeed())
+		}
+	}
+
+	It("sends datagrams on additional paths", func() {
+		conf := getQuicConfig(&quic.Config{EnableDatagrams: true, MaxPaths: 2})
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverPaths := make(chan *quic.Path, 2)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			mc := conn.(quic.MultipathConnection)
+			for {
+				path, err := mc.AcceptPath(context.Background())
+				if err != nil {
+					return
+				}
+				serverPaths <- path
+				go echo(path)
+			}
+		}()
+
+		tr := newTransport()
+		defer tr.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		mc, ok := conn.(quic.MultipathConnection)
+		Expect(ok).To(BeTrue())
+
+		tr1 := newTransport()
+		defer tr1.Close()
+		path, err := mc.OpenPath(ctx, tr1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path.ID()).ToNot(BeZero())
+		Expect(path.LocalAddr()).To(Equal(tr1.Conn.LocalAddr()))
+
+		var serverPath *quic.Path
+		Eventually(serverPaths).Should(Receive(&serverPath))
+		Expect(serverPath.ID()).To(Equal(path.ID()))
+		Expect(serverPath.RemoteAddr()).To(Equal(tr1.Conn.LocalAddr()))
+
+		Expect(path.SendDatagram([]byte("foobar"))).To(Succeed())
+		b, err := path.ReceiveDatagram(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foobar")))
+		Eventually(path.SmoothedRTT).ShouldNot(BeZero())
+
+		// An abandoned path makes room for a new one, using a new path ID.
+		firstID := path.ID()
+		Expect(path.Close()).To(Succeed())
+		Eventually(path.Context().Done()).Should(BeClosed())
+		Eventually(serverPath.Context().Done()).Should(BeClosed())
+		Expect(path.SendDatagram([]byte("foo"))).ToNot(Succeed())
+
+		tr2 := newTransport()
+		defer tr2.Close()
+		path, err = mc.OpenPath(ctx, tr2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path.ID()).ToNot(BeZero())
+		Expect(path.ID()).ToNot(Equal(firstID))
+		Expect(path.SendDatagram([]byte("raboof"))).To(Succeed())
+		b, err = path.ReceiveDatagram(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("raboof")))
+
+		// closing the path's transport only abandons the path
+		Expect(tr2.Close()).To(Succeed())
+		Eventually(path.Context().Done()).Should(BeClosed())
+		Expect(conn.Context().Err()).ToNot(HaveOccurred())
+	})
+
+	It("updates keys on all paths", func() {
+		origKeyUpdateInterval := handshake.KeyUpdateInterval
+		defer func() { handshake.KeyUpdateInterval = origKeyUpdateInterval }()
+		handshake.KeyUpdateInterval = 1 // update keys as frequently as possible
+
+		var keyUpdates atomic.Int64
+		conf := getQuicConfig(&quic.Config{
+			EnableDatagrams: true,
+			MaxPaths:        2,
+			Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+				return &logging.ConnectionTracer{UpdatedKey: func(logging.KeyPhase, bool) { keyUpdates.Add(1) }}
+			},
+		})
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			path, err := conn.(quic.MultipathConnection).AcceptPath(context.Background())
+			if err != nil {
+				return
+			}
+			echo(path)
+		}()
+
+		tr := newTransport()
+		defer tr.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Second))
+		defer cancel()
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		tr1 := newTransport()
+		defer tr1.Close()
+		path, err := conn.(quic.MultipathConnection).OpenPath(ctx, tr1)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 500; i++ {
+			msg := []byte(fmt.Sprintf("datagram %d", i))
+			Expect(path.SendDatagram(msg)).To(Succeed())
+			b, err := path.ReceiveDatagram(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(msg))
+		}
+		Expect(conn.Context().Err()).ToNot(HaveOccurred())
+		fmt.Fprintf(GinkgoWriter, "Updated keys %d times.\n", keyUpdates.Load())
+		Expect(keyUpdates.Load()).To(BeNumerically(">", 10))
+	})
+
+	It("doesn't open paths if the peer doesn't support multipath", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			_, _ = ln.Accept(context.Background())
+		}()
+
+		tr := newTransport()
+		defer tr.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(&quic.Config{MaxPaths: 2}))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		tr1 := newTransport()
+		defer tr1.Close()
+		_, err = conn.(quic.MultipathConnection).OpenPath(ctx, tr1)
+		Expect(err).To(MatchError(quic.ErrMultipathNotNegotiated))
+	})
+})
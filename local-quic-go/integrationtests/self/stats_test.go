@@ -0,0 +1,54 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection statistics", func() {
+	It("reports the transport statistics", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		stats := conn.Stats()
+		Expect(stats.SmoothedRTT).To(BeNumerically(">", 0))
+		Expect(stats.MinRTT).To(BeNumerically(">", 0))
+		Expect(stats.MinRTT).To(BeNumerically("<=", stats.SmoothedRTT))
+		Expect(stats.CongestionWindow).To(BeNumerically(">", 0))
+		Expect(stats.PacketsSent).To(BeNumerically(">=", len(PRData)/1500))
+	})
+})
@@ -8,6 +8,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/quic-go/quic-go/congestion"
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/logging"
@@ -197,6 +198,10 @@ type Connection interface {
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// Stats returns the transport statistics of the connection.
+	// For a multipath connection, these are the statistics of its first path, see Path.Stats.
+	// It is cheap and can be called from any goroutine.
+	Stats() ConnectionStats
 
 	// SendDatagram sends a message using a QUIC datagram, as specified in RFC 9221.
 	// There is no delivery guarantee for DATAGRAM frames, they are not retransmitted if lost.
@@ -224,6 +229,37 @@ type EarlyConnection interface {
 	NextConnection(context.Context) (Connection, error)
 }
 
+// A PathID identifies a path of a multipath connection.
+type PathID = protocol.PathID
+
+// A MultipathConnection is a connection using the multipath extension (draft-ietf-quic-multipath).
+// Multipath is enabled using Config.MaxPaths, and is only used if the peer supports it.
+type MultipathConnection interface {
+	Connection
+
+	// OpenPath opens a new path from the local address of the Transport.
+	// The Transport must use connection IDs of the same length as the connection.
+	// It returns once the peer validated the path.
+	// Only the client can open paths.
+	OpenPath(context.Context, *Transport) (*Path, error)
+	// AcceptPath returns the next path opened by the peer, once validated.
+	AcceptPath(context.Context) (*Path, error)
+}
+
+// A MigratableConnection is a connection that can migrate to a new path (RFC 9000, section 9).
+// Connections that don't use multipath migrate, multipath connections open additional paths instead.
+type MigratableConnection interface {
+	Connection
+
+	// Migrate moves the connection to the local address of the Transport,
+	// e.g. after the address it used so far went away.
+	// The Transport must use connection IDs of the same length as the connection.
+	// It returns once the peer validated the new path. If validation fails, the connection moves back
+	// to its previous path, so the previous Transport must only be closed once Migrate succeeded.
+	// Only the client can migrate, and only if the server didn't disable active migration.
+	Migrate(context.Context, *Transport) error
+}
+
 // StatelessResetKey is a key used to derive stateless reset tokens.
 type StatelessResetKey [32]byte
 
@@ -337,12 +373,25 @@ type Config struct {
 	// Allow0RTT allows the application to decide if a 0-RTT connection attempt should be accepted.
 	// Only valid for the server.
 	Allow0RTT bool
+	// AllowActiveMigration allows the client to move the connection to a new address (RFC 9000, section 9).
+	// If not set, the server sends the disable_active_migration transport parameter,
+	// and keeps sending to the address the handshake was performed on.
+	// Only valid for the server.
+	AllowActiveMigration bool
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
 	// CongestionAlgorithm selects the congestion control algorithm.
-	// Supported values: "cubic" (default), "bbr".
+	// Supported values: "cubic" (default), "bbr" (BBRv1), "bbr3" (BBRv3).
 	CongestionAlgorithm string
-	Tracer              func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer
+	// CongestionControl creates the congestion controller of a path.
+	// If set, it takes precedence over CongestionAlgorithm.
+	// It is called for every path of a multipath connection, and again when the connection migrates to a new path.
+	CongestionControl func(congestion.PathInfo) congestion.SendAlgorithm
+	// MaxPaths enables the multipath extension (draft-ietf-quic-multipath) if larger than 1.
+	// It is the number of paths, including the path used for the handshake, that can be open at the same time.
+	// Additional paths are opened using MultipathConnection.OpenPath.
+	MaxPaths int
+	Tracer   func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer
 }
 
 // ClientHelloInfo contains information about an incoming connection attempt.
@@ -372,3 +421,25 @@ type ConnectionState struct {
 	// GSO says if generic segmentation offload is used
 	GSO bool
 }
+
+// ConnectionStats are the transport statistics of a connection, or of a path of a multipath connection.
+// The RTT values are 0 until the first RTT sample.
+type ConnectionStats struct {
+	// SmoothedRTT is the smoothed RTT (RFC 9002, section 5.3).
+	SmoothedRTT time.Duration
+	// MeanDeviation is the RTT variation (rttvar).
+	MeanDeviation time.Duration
+	MinRTT        time.Duration
+	LatestRTT     time.Duration
+
+	// CongestionWindow and BytesInFlight are in bytes.
+	CongestionWindow uint64
+	BytesInFlight    uint64
+
+	PacketsSent uint64
+	// PacketsLost counts the packets declared lost.
+	PacketsLost uint64
+	// PacketsRetransmitted counts the lost packets and PTO probes whose frames were queued for retransmission.
+	// Packets that only carried DATAGRAM frames are never retransmitted.
+	PacketsRetransmitted uint64
+}
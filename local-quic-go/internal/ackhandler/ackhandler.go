@@ -1,11 +1,16 @@
 package ackhandler
 
 import (
+	qcongestion "github.com/quic-go/quic-go/congestion"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/logging"
 )
 
+// NewCongestionController creates the congestion controller of a path.
+// If nil, NewReno is used.
+type NewCongestionController func(qcongestion.PathInfo) qcongestion.SendAlgorithm
+
 // NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
 // clientAddressValidated indicates whether the address was validated beforehand by an address validation token.
 // clientAddressValidated has no effect for a client.
@@ -18,8 +23,28 @@ func NewAckHandler(
 	pers protocol.Perspective,
 	tracer *logging.ConnectionTracer,
 	logger utils.Logger,
-	congestionAlgo string,
+	newCongestion NewCongestionController,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clientAddressValidated, enableECN, pers, tracer, logger, congestionAlgo)
+	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clientAddressValidated, enableECN, pers, tracer, logger, newCongestion)
 	return sph, newReceivedPacketHandler(sph, logger)
 }
+
+// NewPathAckHandler creates the SentPacketHandler and ReceivedPacketHandler of an additional multipath path.
+// A path is opened after the handshake is confirmed: it only has an application data packet number space,
+// and its own RTT estimate and congestion controller.
+func NewPathAckHandler(
+	initialMaxDatagramSize protocol.ByteCount,
+	rttStats *utils.RTTStats,
+	pers protocol.Perspective,
+	logger utils.Logger,
+	newCongestion NewCongestionController,
+) (SentPacketHandler, ReceivedPacketHandler) {
+	sph := newSentPacketHandler(0, initialMaxDatagramSize, rttStats, true, false, pers, nil, logger, newCongestion)
+	rph := newReceivedPacketHandler(sph, logger)
+	for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake} {
+		sph.DropPackets(encLevel)
+		rph.DropPackets(encLevel)
+	}
+	sph.SetHandshakeConfirmed()
+	return sph, rph
+}
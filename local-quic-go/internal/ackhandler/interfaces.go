@@ -17,6 +17,8 @@ type SentPacketHandler interface {
 	ReceivedBytes(protocol.ByteCount)
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry(rcvTime time.Time) error
+	// MigratedPath resets the RTT estimate and the congestion controller after a path migration
+	MigratedPath(now time.Time, initialMaxDatagramSize protocol.ByteCount)
 	SetHandshakeConfirmed()
 
 	// The SendMode determines if and what kind of packets can be sent.
@@ -35,6 +37,9 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+
+	// Stats returns the transport statistics. It is safe to call concurrently.
+	Stats() Stats
 }
 
 type sentPacketTracker interface {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	qcongestion "github.com/quic-go/quic-go/congestion"
 	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
@@ -79,8 +80,9 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
+	congestion    congestion.SendAlgorithmWithDebugInfos
+	newCongestion NewCongestionController // to create a fresh congestion controller after a path migration
+	rttStats      *utils.RTTStats
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -97,6 +99,8 @@ type sentPacketHandler struct {
 
 	perspective protocol.Perspective
 
+	stats statsPublisher
+
 	tracer *logging.ConnectionTracer
 	logger utils.Logger
 }
@@ -117,27 +121,8 @@ func newSentPacketHandler(
 	pers protocol.Perspective,
 	tracer *logging.ConnectionTracer,
 	logger utils.Logger,
-	congestionAlgo string,
+	newCongestion NewCongestionController,
 ) *sentPacketHandler {
-	var cc congestion.SendAlgorithmWithDebugInfos
-	switch congestionAlgo {
-	case "bbr":
-		cc = congestion.NewBBRSender(
-			congestion.DefaultClock{},
-			rttStats,
-			initialMaxDatagramSize,
-			tracer,
-		)
-	default:
-		cc = congestion.NewCubicSender(
-			congestion.DefaultClock{},
-			rttStats,
-			initialMaxDatagramSize,
-			true, // use Reno
-			tracer,
-		)
-	}
-
 	h := &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient || clientAddressValidated,
@@ -145,7 +130,8 @@ func newSentPacketHandler(
 		handshakePackets:               newPacketNumberSpace(0, false),
 		appDataPackets:                 newPacketNumberSpace(0, true),
 		rttStats:                       rttStats,
-		congestion:                     cc,
+		congestion:                     newCongestionController(newCongestion, rttStats, initialMaxDatagramSize, tracer),
+		newCongestion:                  newCongestion,
 		perspective:                    pers,
 		tracer:                         tracer,
 		logger:                         logger,
@@ -154,9 +140,42 @@ func newSentPacketHandler(
 		h.enableECN = true
 		h.ecnTracker = newECNTracker(logger, tracer)
 	}
+	h.publishStats()
 	return h
 }
 
+func newCongestionController(
+	newCongestion NewCongestionController,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	tracer *logging.ConnectionTracer,
+) congestion.SendAlgorithmWithDebugInfos {
+	if newCongestion != nil {
+		return newCongestion(qcongestion.PathInfo{
+			RTTStats:               rttStats,
+			InitialMaxDatagramSize: initialMaxDatagramSize,
+			Tracer:                 tracer,
+		})
+	}
+	return congestion.NewCubicSender(
+		congestion.DefaultClock{},
+		rttStats,
+		initialMaxDatagramSize,
+		true, // use Reno
+		tracer,
+	)
+}
+
+// Stats returns the transport statistics of the path.
+// It can be called from any goroutine.
+func (h *sentPacketHandler) Stats() Stats {
+	return h.stats.stats()
+}
+
+func (h *sentPacketHandler) publishStats() {
+	h.stats.publish(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight)
+}
+
 func (h *sentPacketHandler) removeFromBytesInFlight(p *packet) {
 	if p.includedInBytesInFlight {
 		if p.Length > h.bytesInFlight {
@@ -214,6 +233,7 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	h.ptoCount = 0
 	h.numProbesToSend = 0
 	h.ptoMode = SendNone
+	h.publishStats()
 	h.setLossDetectionTimer()
 }
 
@@ -273,6 +293,8 @@ func (h *sentPacketHandler) SentPacket(
 		}
 	}
 	h.congestion.OnPacketSent(t, h.bytesInFlight, pn, size, isAckEliciting)
+	h.stats.packetsSent.Add(1)
+	h.stats.bytesInFlight.Store(int64(h.bytesInFlight))
 
 	if encLevel == protocol.Encryption1RTT && h.ecnTracker != nil {
 		h.ecnTracker.SentPacket(pn, ecn)
@@ -398,6 +420,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if h.tracer != nil && h.tracer.UpdatedMetrics != nil {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 	}
+	h.publishStats()
 
 	h.setLossDetectionTimer()
 	return acked1RTTPacket, nil
@@ -666,6 +689,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 		if packetLost {
 			pnSpace.history.DeclareLost(p.PacketNumber)
 			if !p.skippedPacket {
+				h.stats.packetsLost.Add(1)
 				// the bytes in flight need to be reduced no matter if the frames in this packet will be retransmitted
 				h.removeFromBytesInFlight(p)
 				h.queueFramesForRetransmission(p)
@@ -683,6 +707,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 
 func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 	defer h.setLossDetectionTimer()
+	defer h.publishStats()
 	earliestLossTime, encLevel := h.getLossTimeAndSpace()
 	if !earliestLossTime.IsZero() {
 		if h.logger.Debug() {
@@ -855,6 +880,7 @@ func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel)
 	// Keep track of acknowledged frames instead.
 	h.removeFromBytesInFlight(p)
 	pnSpace.history.DeclareLost(p.PacketNumber)
+	h.publishStats()
 	return true
 }
 
@@ -862,20 +888,52 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *packet) {
 	if len(p.Frames) == 0 && len(p.StreamFrames) == 0 {
 		panic("no frames")
 	}
+	var retransmitted bool
 	for _, f := range p.Frames {
 		if f.Handler != nil {
 			f.Handler.OnLost(f.Frame)
+			retransmitted = true
 		}
 	}
 	for _, f := range p.StreamFrames {
 		if f.Handler != nil {
 			f.Handler.OnLost(f.Frame)
+			retransmitted = true
 		}
 	}
+	if retransmitted {
+		h.stats.packetsRetransmitted.Add(1)
+	}
 	p.StreamFrames = nil
 	p.Frames = nil
 }
 
+// MigratedPath is called when the connection moved to a new path.
+// The RTT estimate and the congestion controller start from scratch, and all packets
+// still in flight on the old path are declared lost, their frames are retransmitted on the new path.
+func (h *sentPacketHandler) MigratedPath(now time.Time, initialMaxDatagramSize protocol.ByteCount) {
+	h.rttStats.ResetForPathMigration()
+	pnSpace := h.appDataPackets
+	pnSpace.history.Iterate(func(p *packet) (bool, error) {
+		if p.declaredLost {
+			return true, nil
+		}
+		pnSpace.history.DeclareLost(p.PacketNumber)
+		if !p.skippedPacket {
+			h.removeFromBytesInFlight(p)
+			h.queueFramesForRetransmission(p)
+		}
+		return true, nil
+	})
+	pnSpace.lossTime = time.Time{}
+	h.congestion = newCongestionController(h.newCongestion, h.rttStats, initialMaxDatagramSize, h.tracer)
+	h.ptoCount = 0
+	h.numProbesToSend = 0
+	h.ptoMode = SendNone
+	h.publishStats()
+	h.setLossDetectionTimer()
+}
+
 func (h *sentPacketHandler) ResetForRetry(now time.Time) error {
 	h.bytesInFlight = 0
 	var firstPacketSendTime time.Time
@@ -923,6 +981,7 @@ func (h *sentPacketHandler) ResetForRetry(now time.Time) error {
 		}
 	}
 	h.ptoCount = 0
+	h.publishStats()
 	return nil
 }
 
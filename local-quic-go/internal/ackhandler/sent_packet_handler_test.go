@@ -44,7 +44,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		var rttStats utils.RTTStats
-		handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, false, false, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, false, false, perspective, nil, utils.DefaultLogger, nil)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -521,6 +521,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 		JustBeforeEach(func() {
 			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().GetCongestionWindow().AnyTimes() // for Stats
 			handler.congestion = cong
 		})
 
@@ -695,6 +696,48 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 	})
 
+	Context("statistics", func() {
+		It("reports RTT, congestion window, bytes in flight and lost packets", func() {
+			Expect(handler.Stats().CongestionWindow).To(Equal(handler.congestion.GetCongestionWindow()))
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 100, SendTime: now.Add(-time.Hour)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, Length: 100, SendTime: now.Add(-time.Second)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3, Length: 100, SendTime: now.Add(-time.Second)}))
+			sentPacket(nonAckElicitingPacket(&packet{PacketNumber: 4, Length: 100, SendTime: now.Add(-time.Second)}))
+			Expect(handler.Stats().PacketsSent).To(BeEquivalentTo(4))
+			Expect(handler.Stats().BytesInFlight).To(BeEquivalentTo(300))
+
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			stats := handler.Stats()
+			Expect(stats.SmoothedRTT).To(Equal(time.Second))
+			Expect(stats.MeanDeviation).To(Equal(time.Second / 2))
+			Expect(stats.MinRTT).To(Equal(time.Second))
+			Expect(stats.LatestRTT).To(Equal(time.Second))
+			Expect(stats.CongestionWindow).To(Equal(handler.congestion.GetCongestionWindow()))
+			Expect(stats.BytesInFlight).To(BeEquivalentTo(100))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(1))
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(1))
+		})
+
+		It("counts packets without retransmittable frames as lost, but not as retransmitted", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{
+				PacketNumber: 1,
+				SendTime:     now.Add(-time.Hour),
+				Frames:       []Frame{{Frame: &wire.DatagramFrame{}}},
+			}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, SendTime: now.Add(-time.Second)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.Stats().PacketsLost).To(BeEquivalentTo(1))
+			Expect(handler.Stats().PacketsRetransmitted).To(BeZero())
+		})
+	})
+
 	Context("probe packets", func() {
 		It("queues a probe packet", func() {
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: 10}))
@@ -984,7 +1027,7 @@ var _ = Describe("SentPacketHandler", func() {
 	Context("amplification limit, for the server, with validated address", func() {
 		JustBeforeEach(func() {
 			var rttStats utils.RTTStats
-			handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, true, false, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, true, false, perspective, nil, utils.DefaultLogger, nil)
 		})
 
 		It("do not limits the window", func() {
@@ -1208,6 +1251,25 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("path migration", func() {
+		It("starts over on the new path", func() {
+			setHandshakeConfirmed()
+			updateRTT(time.Second)
+			for i := protocol.PacketNumber(1); i <= 3; i++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: i, Length: 1000}))
+			}
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(3000)))
+			oldCongestion := handler.congestion
+			handler.MigratedPath(time.Now(), protocol.InitialPacketSize)
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			expectInPacketHistory(nil, protocol.Encryption1RTT)
+			Expect(handler.bytesInFlight).To(BeZero())
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.congestion).ToNot(BeIdenticalTo(oldCongestion))
+			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
+		})
+	})
+
 	Context("crypto packets", func() {
 		It("rejects an ACK that acks packets with a higher encryption level", func() {
 			sentPacket(ackElicitingPacket(&packet{
@@ -1436,6 +1498,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 		JustBeforeEach(func() {
 			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().GetCongestionWindow().AnyTimes() // for Stats
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
@@ -1443,7 +1506,7 @@ var _ = Describe("SentPacketHandler", func() {
 			lostPackets = nil
 			var rttStats utils.RTTStats
 			rttStats.UpdateRTT(time.Hour, 0, time.Now())
-			handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, false, false, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSize, &rttStats, false, false, perspective, nil, utils.DefaultLogger, nil)
 			handler.ecnTracker = ecnHandler
 			handler.congestion = cong
 		})
@@ -0,0 +1,64 @@
+package ackhandler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// Stats are the transport statistics of a path.
+type Stats struct {
+	SmoothedRTT   time.Duration
+	MeanDeviation time.Duration
+	MinRTT        time.Duration
+	LatestRTT     time.Duration
+
+	CongestionWindow protocol.ByteCount
+	BytesInFlight    protocol.ByteCount
+
+	PacketsSent uint64
+	PacketsLost uint64
+	// PacketsRetransmitted counts the lost packets and PTO probes whose frames were queued for retransmission.
+	PacketsRetransmitted uint64
+}
+
+// statsPublisher makes the statistics of the sentPacketHandler available to other goroutines.
+// The sentPacketHandler updates it from the run loop, Stats can be called concurrently.
+type statsPublisher struct {
+	smoothedRTT   atomic.Int64
+	meanDeviation atomic.Int64
+	minRTT        atomic.Int64
+	latestRTT     atomic.Int64
+
+	congestionWindow atomic.Int64
+	bytesInFlight    atomic.Int64
+
+	packetsSent          atomic.Uint64
+	packetsLost          atomic.Uint64
+	packetsRetransmitted atomic.Uint64
+}
+
+func (s *statsPublisher) publish(rttStats *utils.RTTStats, cwnd, bytesInFlight protocol.ByteCount) {
+	s.smoothedRTT.Store(int64(rttStats.SmoothedRTT()))
+	s.meanDeviation.Store(int64(rttStats.MeanDeviation()))
+	s.minRTT.Store(int64(rttStats.MinRTT()))
+	s.latestRTT.Store(int64(rttStats.LatestRTT()))
+	s.congestionWindow.Store(int64(cwnd))
+	s.bytesInFlight.Store(int64(bytesInFlight))
+}
+
+func (s *statsPublisher) stats() Stats {
+	return Stats{
+		SmoothedRTT:          time.Duration(s.smoothedRTT.Load()),
+		MeanDeviation:        time.Duration(s.meanDeviation.Load()),
+		MinRTT:               time.Duration(s.minRTT.Load()),
+		LatestRTT:            time.Duration(s.latestRTT.Load()),
+		CongestionWindow:     protocol.ByteCount(s.congestionWindow.Load()),
+		BytesInFlight:        protocol.ByteCount(s.bytesInFlight.Load()),
+		PacketsSent:          s.packetsSent.Load(),
+		PacketsLost:          s.packetsLost.Load(),
+		PacketsRetransmitted: s.packetsRetransmitted.Load(),
+	}
+}
@@ -0,0 +1,1111 @@
+package congestion
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// BBRv3 congestion control implementation for QUIC.
+// Based on:
+//   - https://datatracker.ietf.org/doc/html/draft-ietf-ccwg-bbr
+//   - https://github.com/google/bbr/tree/v3 (Linux kernel reference)
+//
+// Unlike BBRv1 (bbr_sender.go), BBRv3 reacts to loss and ECN: it keeps
+// short-term lower bounds (bw_lo, inflight_lo) that shrink when a round
+// sees congestion, and a long-term upper bound (inflight_hi) set from the
+// inflight at which loss exceeded 2% (or ECN marks exceeded 50%) while
+// probing. Loss below the threshold never lowers max_bw or inflight_hi, and
+// the short-term bounds don't drop below what was delivered in the last
+// round, which keeps throughput up on lossy wireless paths.
+//
+// The SendAlgorithm interface only reports ECN-CE as a congestion event
+// without the number of marked packets; each such event is counted as one
+// CE-marked packet when estimating the fraction of marked data.
+
+// BBRv3 operating modes
+type bbr3Mode int
+
+const (
+	bbr3Startup       bbr3Mode = iota // exponential bandwidth probing
+	bbr3Drain                         // drain the queue built during startup
+	bbr3ProbeBWDown                   // ProbeBW: drain the queue left by probing
+	bbr3ProbeBWCruise                 // ProbeBW: cruise at the estimated bandwidth
+	bbr3ProbeBWRefill                 // ProbeBW: refill the pipe before probing
+	bbr3ProbeBWUp                     // ProbeBW: probe for more bandwidth
+	bbr3ProbeRTT                      // reduce inflight to probe for a lower RTT
+)
+
+func (m bbr3Mode) String() string {
+	switch m {
+	case bbr3Startup:
+		return "startup"
+	case bbr3Drain:
+		return "drain"
+	case bbr3ProbeBWDown:
+		return "probe_bw_down"
+	case bbr3ProbeBWCruise:
+		return "probe_bw_cruise"
+	case bbr3ProbeBWRefill:
+		return "probe_bw_refill"
+	case bbr3ProbeBWUp:
+		return "probe_bw_up"
+	case bbr3ProbeRTT:
+		return "probe_rtt"
+	default:
+		return fmt.Sprintf("unknown mode: %d", int(m))
+	}
+}
+
+// ack phases of a ProbeBW cycle, used to decide when to advance the max
+// bandwidth filter and to collect inflight_hi feedback
+type bbr3AckPhase int
+
+const (
+	bbr3AcksInit bbr3AckPhase = iota
+	bbr3AcksRefilling
+	bbr3AcksProbeStarting
+	bbr3AcksProbeFeedback
+	bbr3AcksProbeStopping
+)
+
+const (
+	// startup pacing gain: 4*ln(2)
+	bbr3StartupPacingGain = 2.77
+	// startup cwnd gain
+	bbr3StartupCwndGain = 2.0
+	// drain pacing gain: 1/2.885
+	bbr3DrainPacingGain = 0.35
+	// default cwnd gain
+	bbr3CwndGain = 2.0
+	// ProbeBW_DOWN pacing gain
+	bbr3ProbeBWDownPacingGain = 0.9
+	// ProbeBW_UP pacing and cwnd gains
+	bbr3ProbeBWUpPacingGain = 1.25
+	bbr3ProbeBWUpCwndGain   = 2.25
+	// pace 1% below the estimated bandwidth, to drain queues
+	bbr3PacingMarginPercent = 1
+	// maximum tolerated loss rate per round while probing
+	bbr3LossThresh = 0.02
+	// number of loss events in a round that end startup, if the loss rate is too high
+	bbr3StartupFullLossCount = 6
+	// multiplicative decrease of the lower bounds on congestion
+	bbr3Beta = 0.7
+	// fraction of inflight_hi left free for other flows when cruising
+	bbr3Headroom = 0.15
+	// gain of the EWMA of the fraction of CE-marked data
+	bbr3ECNAlphaGain = 1.0 / 16
+	// fraction of CE-marked data that counts as inflight too high
+	bbr3ECNThresh = 0.5
+	// inflight_lo reduction per unit of ecn_alpha
+	bbr3ECNFactor = 1.0 / 3
+	// bandwidth growth that keeps startup going
+	bbr3FullBandwidthThreshold = 1.25
+	// rounds without enough growth that end startup
+	bbr3FullBandwidthCount = 3
+	// minimum congestion window in packets
+	bbr3MinPipeCwndPackets = 4
+	// cwnd gain in PROBE_RTT
+	bbr3ProbeRTTCwndGain = 0.5
+	// minimum duration of PROBE_RTT
+	bbr3ProbeRTTDuration = 200 * time.Millisecond
+	// PROBE_RTT is entered if the RTT didn't hit a new minimum for this long
+	bbr3ProbeRTTInterval = 5 * time.Second
+	// window of the min_rtt filter
+	bbr3MinRTTFilterLen = 10 * time.Second
+	// rounds per slot of the two-slot extra_acked filter
+	bbr3ExtraAckedFilterRounds = 5
+	// base wait between two bandwidth probes, plus up to 1s at random
+	bbr3ProbeWaitBase = 2 * time.Second
+	// bound of the round count between two probes for coexistence with Reno/CUBIC
+	bbr3MaxRenoRounds = 63
+)
+
+// bbr3SentPacket is the delivery state recorded when a packet is sent,
+// used for delivery rate samples.
+type bbr3SentPacket struct {
+	size          protocol.ByteCount
+	sendTime      time.Time
+	firstSentTime time.Time
+	deliveredTime time.Time
+	delivered     protocol.ByteCount
+	lost          protocol.ByteCount
+	txInFlight    protocol.ByteCount
+	isAppLimited  bool
+}
+
+// bbr3RateSample is a delivery rate sample taken on an ACK.
+type bbr3RateSample struct {
+	deliveryRate   Bandwidth
+	delivered      protocol.ByteCount
+	priorDelivered protocol.ByteCount
+	newlyAcked     protocol.ByteCount
+	lost           protocol.ByteCount
+	txInFlight     protocol.ByteCount
+	isAppLimited   bool
+}
+
+// bbr3Sender implements BBRv3 congestion control.
+type bbr3Sender struct {
+	rttStats *utils.RTTStats
+	clock    Clock
+	pacer    *pacer
+	tracer   *logging.ConnectionTracer
+	rand     *rand.Rand
+
+	mode       bbr3Mode
+	pacingGain float64
+	cwndGain   float64
+	pacingRate Bandwidth
+
+	congestionWindow protocol.ByteCount
+	priorCwnd        protocol.ByteCount
+	initialCwnd      protocol.ByteCount
+	maxDatagramSize  protocol.ByteCount
+	bytesInFlight    protocol.ByteCount
+	cwndLimited      bool
+
+	// delivery rate estimation
+	sentPackets     map[protocol.PacketNumber]*bbr3SentPacket
+	delivered       protocol.ByteCount
+	deliveredTime   time.Time
+	firstSentTime   time.Time
+	lost            protocol.ByteCount
+	appLimitedUntil protocol.ByteCount // delivered count up to which samples are app-limited
+	largestAcked    protocol.PacketNumber
+	lastPrune       uint64
+
+	// round counting
+	roundCount         uint64
+	roundStart         bool
+	nextRoundDelivered protocol.ByteCount
+
+	// model: bandwidth
+	maxBwFilter [2]Bandwidth // max delivery rate of the previous and the current ProbeBW cycle
+	maxBw       Bandwidth
+	bwLo        Bandwidth
+	bw          Bandwidth
+	bwLatest    Bandwidth
+
+	// model: inflight
+	inflightHi     protocol.ByteCount
+	inflightLo     protocol.ByteCount
+	inflightLatest protocol.ByteCount
+
+	// model: RTT
+	minRTT           time.Duration
+	minRTTStamp      time.Time
+	probeRTTMinDelay time.Duration
+	probeRTTMinStamp time.Time
+	probeRTTExpired  bool
+
+	// ACK aggregation
+	extraAcked              [2]protocol.ByteCount
+	extraAckedIdx           int
+	extraAckedRoundStart    uint64
+	extraAckedIntervalStart time.Time
+	extraAckedDelivered     protocol.ByteCount
+
+	// congestion signals of the current loss round
+	lossRoundStart     bool
+	lossRoundDelivered protocol.ByteCount
+	lossInRound        bool
+	lossEventsInRound  int
+	lostInRound        protocol.ByteCount
+	deliveredInRound   protocol.ByteCount
+	ceInRound          protocol.ByteCount
+	ecnAlpha           float64
+
+	// startup
+	filledPipe         bool
+	fullBw             Bandwidth
+	fullBwCount        int
+	fullBwNow          bool
+	roundStartLost     protocol.ByteCount
+	roundStartInFlight protocol.ByteCount
+
+	// ProbeBW
+	ackPhase           bbr3AckPhase
+	cycleStamp         time.Time
+	bwProbeWait        time.Duration
+	roundsSinceBwProbe uint64
+	bwProbeSamples     bool
+	bwProbeUpRounds    int
+	bwProbeUpCnt       protocol.ByteCount
+	bwProbeUpAcks      protocol.ByteCount
+
+	// ProbeRTT
+	probeRTTDoneStamp time.Time
+	probeRTTRoundDone bool
+
+	// Previous state for tracer
+	lastState logging.CongestionState
+}
+
+var (
+	_ SendAlgorithm               = &bbr3Sender{}
+	_ SendAlgorithmWithDebugInfos = &bbr3Sender{}
+)
+
+// NewBBR3Sender creates a new BBRv3 congestion controller.
+func NewBBR3Sender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	tracer *logging.ConnectionTracer,
+) *bbr3Sender {
+	b := &bbr3Sender{
+		rttStats:         rttStats,
+		clock:            clock,
+		tracer:           tracer,
+		rand:             rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		maxDatagramSize:  initialMaxDatagramSize,
+		congestionWindow: initialCongestionWindow * initialMaxDatagramSize,
+		initialCwnd:      initialCongestionWindow * initialMaxDatagramSize,
+		sentPackets:      make(map[protocol.PacketNumber]*bbr3SentPacket),
+		largestAcked:     protocol.InvalidPacketNumber,
+		bwLo:             infBandwidth,
+		bw:               infBandwidth,
+		inflightHi:       protocol.MaxByteCount,
+		inflightLo:       protocol.MaxByteCount,
+		lastState:        logging.CongestionStateSlowStart,
+	}
+	b.probeRTTMinStamp = clock.Now()
+	b.minRTTStamp = b.probeRTTMinStamp
+	b.pacer = newPacer(b.bandwidthEstimate)
+	b.enterStartup()
+	if b.tracer != nil && b.tracer.UpdatedCongestionState != nil {
+		b.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
+	}
+	return b
+}
+
+// --- SendAlgorithm interface ---
+
+func (b *bbr3Sender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *bbr3Sender) HasPacingBudget(now time.Time) bool {
+	return b.pacer.Budget(now) >= b.maxDatagramSize
+}
+
+func (b *bbr3Sender) OnPacketSent(
+	sentTime time.Time,
+	bytesInFlight protocol.ByteCount,
+	packetNumber protocol.PacketNumber,
+	bytes protocol.ByteCount,
+	isRetransmittable bool,
+) {
+	b.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	// bytesInFlight includes this packet
+	if bytesInFlight == bytes {
+		// the pipe was empty: restart the delivery rate interval
+		b.firstSentTime = sentTime
+		b.deliveredTime = sentTime
+	}
+	b.bytesInFlight = bytesInFlight
+	b.cwndLimited = bytesInFlight >= b.congestionWindow
+	// Sending less than a BDP means the application doesn't keep the pipe
+	// full: samples taken until this packet is acked underestimate the bandwidth.
+	if bytesInFlight < b.bdpMultiple(b.maxBw, 1.0) && !b.cwndLimited {
+		b.appLimitedUntil = max(b.delivered+bytesInFlight, 1)
+	}
+	b.sentPackets[packetNumber] = &bbr3SentPacket{
+		size:          bytes,
+		sendTime:      sentTime,
+		firstSentTime: b.firstSentTime,
+		deliveredTime: b.deliveredTime,
+		delivered:     b.delivered,
+		lost:          b.lost,
+		txInFlight:    bytesInFlight,
+		isAppLimited:  b.appLimitedUntil != 0,
+	}
+}
+
+func (b *bbr3Sender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	return bytesInFlight < b.GetCongestionWindow()
+}
+
+func (b *bbr3Sender) MaybeExitSlowStart() {
+	// BBR exits STARTUP when the bandwidth stops growing or loss is too high.
+}
+
+func (b *bbr3Sender) OnPacketAcked(
+	ackedPacketNumber protocol.PacketNumber,
+	ackedBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+	eventTime time.Time,
+) {
+	p, ok := b.sentPackets[ackedPacketNumber]
+	if !ok {
+		return
+	}
+	delete(b.sentPackets, ackedPacketNumber)
+	b.largestAcked = max(b.largestAcked, ackedPacketNumber)
+	b.bytesInFlight = min(b.bytesInFlight, priorInFlight)
+	b.bytesInFlight -= min(b.bytesInFlight, ackedBytes)
+
+	b.delivered += ackedBytes
+	b.deliveredTime = eventTime
+	if b.appLimitedUntil != 0 && b.delivered > b.appLimitedUntil {
+		b.appLimitedUntil = 0
+	}
+	rs := b.rateSample(p, ackedBytes)
+
+	b.updateRound(p)
+	b.deliveredInRound += ackedBytes
+	b.updateModelAndState(rs, eventTime)
+	b.updateControlParameters(rs)
+
+	if b.roundStart && b.roundCount-b.lastPrune >= 8 {
+		b.pruneSentPackets(eventTime)
+	}
+}
+
+func (b *bbr3Sender) OnCongestionEvent(
+	packetNumber protocol.PacketNumber,
+	lostBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+) {
+	if lostBytes == 0 {
+		// ECN-CE: the ack handler doesn't report how many packets were marked
+		b.ceInRound += b.maxDatagramSize
+		b.lossInRound = true
+		if b.bwProbeSamples && b.ceRatio() > bbr3ECNThresh {
+			b.handleInflightTooHigh(bbr3RateSample{txInFlight: priorInFlight}, false)
+		}
+		return
+	}
+	b.bytesInFlight = min(b.bytesInFlight, priorInFlight)
+	b.bytesInFlight -= min(b.bytesInFlight, lostBytes)
+	b.lost += lostBytes
+	b.lostInRound += lostBytes
+	b.lossInRound = true
+	b.lossEventsInRound++
+
+	p, ok := b.sentPackets[packetNumber]
+	if !ok {
+		return
+	}
+	delete(b.sentPackets, packetNumber)
+	if !b.bwProbeSamples {
+		return
+	}
+	rs := bbr3RateSample{
+		txInFlight:   p.txInFlight,
+		lost:         b.lost - p.lost,
+		isAppLimited: p.isAppLimited,
+	}
+	if b.isInflightTooHigh(rs) {
+		rs.txInFlight = b.inflightHiFromLostPacket(rs, p)
+		b.handleInflightTooHigh(rs, true)
+	}
+}
+
+// OnRetransmissionTimeout is not called by the ack handler: quic-go uses PTOs,
+// and packets declared lost after a PTO are reported via OnCongestionEvent.
+func (b *bbr3Sender) OnRetransmissionTimeout(bool) {}
+
+func (b *bbr3Sender) SetMaxDatagramSize(s protocol.ByteCount) {
+	if s < b.maxDatagramSize {
+		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", b.maxDatagramSize, s))
+	}
+	cwndIsMin := b.congestionWindow == b.minPipeCwnd()
+	b.maxDatagramSize = s
+	if cwndIsMin {
+		b.congestionWindow = b.minPipeCwnd()
+	}
+	b.pacer.SetMaxDatagramSize(s)
+}
+
+// --- SendAlgorithmWithDebugInfos interface ---
+
+func (b *bbr3Sender) InSlowStart() bool {
+	return b.mode == bbr3Startup
+}
+
+// InRecovery reports whether the lower bounds currently limit the
+// sending rate after congestion.
+func (b *bbr3Sender) InRecovery() bool {
+	return b.inflightLo != protocol.MaxByteCount || b.bwLo != infBandwidth
+}
+
+func (b *bbr3Sender) GetCongestionWindow() protocol.ByteCount {
+	return b.congestionWindow
+}
+
+// --- Delivery rate estimation ---
+
+// rateSample computes the delivery rate sample for the ACK of packet p.
+func (b *bbr3Sender) rateSample(p *bbr3SentPacket, ackedBytes protocol.ByteCount) bbr3RateSample {
+	rs := bbr3RateSample{
+		delivered:      b.delivered - p.delivered,
+		priorDelivered: p.delivered,
+		newlyAcked:     ackedBytes,
+		lost:           b.lost - p.lost,
+		txInFlight:     p.txInFlight,
+		isAppLimited:   p.isAppLimited,
+	}
+	b.firstSentTime = p.sendTime
+	// The delivery rate is bounded by both the send and the ACK rate.
+	sendElapsed := p.sendTime.Sub(p.firstSentTime)
+	ackElapsed := b.deliveredTime.Sub(p.deliveredTime)
+	interval := max(sendElapsed, ackElapsed)
+	// Intervals shorter than min_rtt come from ACK compression.
+	if interval <= 0 || (b.minRTT > 0 && interval < b.minRTT) {
+		return rs
+	}
+	rs.deliveryRate = BandwidthFromDelta(rs.delivered, interval)
+	return rs
+}
+
+func (b *bbr3Sender) updateRound(p *bbr3SentPacket) {
+	b.roundStart = false
+	if p.delivered >= b.nextRoundDelivered {
+		b.startRound()
+		b.roundCount++
+		b.roundsSinceBwProbe++
+		b.roundStart = true
+	}
+}
+
+func (b *bbr3Sender) startRound() {
+	b.nextRoundDelivered = b.delivered
+}
+
+// pruneSentPackets drops the state of packets that were neither acked nor
+// declared lost (e.g. dropped Initial and Handshake packets).
+func (b *bbr3Sender) pruneSentPackets(now time.Time) {
+	b.lastPrune = b.roundCount
+	horizon := 4 * max(b.rttStats.SmoothedRTT(), b.minRTT, 100*time.Millisecond)
+	for pn, p := range b.sentPackets {
+		if pn < b.largestAcked && now.Sub(p.sendTime) > horizon {
+			delete(b.sentPackets, pn)
+		}
+	}
+}
+
+// --- Model and state machine ---
+
+func (b *bbr3Sender) updateModelAndState(rs bbr3RateSample, now time.Time) {
+	b.updateLatestDeliverySignals(rs)
+	b.updateCongestionSignals(rs)
+	b.updateACKAggregation(rs, now)
+	b.checkFullBwReached(rs)
+	b.checkStartupHighLoss(rs)
+	b.checkStartupDone()
+	b.checkDrain(now)
+	b.updateProbeBWCyclePhase(rs, now)
+	b.updateMinRTT(now)
+	b.checkProbeRTT(now)
+	b.advanceLatestDeliverySignals(rs)
+	b.boundBwForModel()
+}
+
+func (b *bbr3Sender) updateLatestDeliverySignals(rs bbr3RateSample) {
+	b.lossRoundStart = false
+	b.bwLatest = max(b.bwLatest, rs.deliveryRate)
+	b.inflightLatest = max(b.inflightLatest, rs.delivered)
+	if rs.priorDelivered >= b.lossRoundDelivered {
+		b.lossRoundDelivered = b.delivered
+		b.lossRoundStart = true
+	}
+}
+
+func (b *bbr3Sender) advanceLatestDeliverySignals(rs bbr3RateSample) {
+	if b.lossRoundStart {
+		b.bwLatest = rs.deliveryRate
+		b.inflightLatest = rs.delivered
+	}
+}
+
+func (b *bbr3Sender) updateCongestionSignals(rs bbr3RateSample) {
+	b.updateMaxBw(rs)
+	if !b.lossRoundStart {
+		return
+	}
+	b.updateECNAlpha()
+	b.adaptLowerBoundsFromCongestion()
+	b.lossInRound = false
+	b.lossEventsInRound = 0
+	b.lostInRound = 0
+	b.deliveredInRound = 0
+	b.ceInRound = 0
+}
+
+func (b *bbr3Sender) updateMaxBw(rs bbr3RateSample) {
+	if rs.deliveryRate == 0 {
+		return
+	}
+	if rs.deliveryRate >= b.maxBw || !rs.isAppLimited {
+		b.maxBwFilter[1] = max(b.maxBwFilter[1], rs.deliveryRate)
+		b.maxBw = max(b.maxBwFilter[0], b.maxBwFilter[1])
+	}
+}
+
+// advanceMaxBwFilter starts a new slot of the max bandwidth filter:
+// max_bw is the maximum over the last two ProbeBW cycles.
+func (b *bbr3Sender) advanceMaxBwFilter() {
+	b.maxBwFilter[0] = b.maxBwFilter[1]
+	b.maxBwFilter[1] = 0
+	b.maxBw = b.maxBwFilter[0]
+}
+
+func (b *bbr3Sender) ceRatio() float64 {
+	if b.deliveredInRound == 0 {
+		return 0
+	}
+	return min(1, float64(b.ceInRound)/float64(b.deliveredInRound))
+}
+
+func (b *bbr3Sender) updateECNAlpha() {
+	b.ecnAlpha = (1-bbr3ECNAlphaGain)*b.ecnAlpha + bbr3ECNAlphaGain*b.ceRatio()
+}
+
+// isProbingBw reports whether the sender is deliberately probing for more
+// bandwidth, in which case congestion doesn't lower the short-term bounds.
+func (b *bbr3Sender) isProbingBw() bool {
+	return b.mode == bbr3Startup || b.mode == bbr3ProbeBWRefill || b.mode == bbr3ProbeBWUp
+}
+
+func (b *bbr3Sender) adaptLowerBoundsFromCongestion() {
+	if b.isProbingBw() || !b.lossInRound {
+		return
+	}
+	// init the lower bounds
+	if b.bwLo == infBandwidth {
+		b.bwLo = b.maxBw
+	}
+	if b.inflightLo == protocol.MaxByteCount {
+		b.inflightLo = b.congestionWindow
+	}
+	if b.lostInRound > 0 {
+		b.bwLo = max(b.bwLatest, Bandwidth(bbr3Beta*float64(b.bwLo)))
+		b.inflightLo = max(b.inflightLatest, protocol.ByteCount(bbr3Beta*float64(b.inflightLo)))
+	}
+	if b.ceInRound > 0 {
+		cut := protocol.ByteCount(float64(b.inflightLo) * (1 - b.ecnAlpha*bbr3ECNFactor))
+		b.inflightLo = max(cut, b.minPipeCwnd())
+	}
+}
+
+func (b *bbr3Sender) resetLowerBounds() {
+	b.bwLo = infBandwidth
+	b.inflightLo = protocol.MaxByteCount
+}
+
+func (b *bbr3Sender) resetCongestionSignals() {
+	b.lossInRound = false
+	b.lossEventsInRound = 0
+	b.lostInRound = 0
+	b.deliveredInRound = 0
+	b.ceInRound = 0
+	b.bwLatest = 0
+	b.inflightLatest = 0
+}
+
+func (b *bbr3Sender) boundBwForModel() {
+	b.bw = min(b.maxBw, b.bwLo)
+}
+
+func (b *bbr3Sender) updateACKAggregation(rs bbr3RateSample, now time.Time) {
+	if b.roundStart && b.roundCount-b.extraAckedRoundStart >= bbr3ExtraAckedFilterRounds {
+		b.extraAckedRoundStart = b.roundCount
+		b.extraAckedIdx = 1 - b.extraAckedIdx
+		b.extraAcked[b.extraAckedIdx] = 0
+	}
+	if b.extraAckedIntervalStart.IsZero() {
+		b.extraAckedIntervalStart = now
+	}
+	expected := protocol.ByteCount(uint64(b.bw/BytesPerSecond) * uint64(now.Sub(b.extraAckedIntervalStart)) / uint64(time.Second))
+	if b.bw == infBandwidth {
+		expected = 0
+	}
+	// Reset the interval if the ACK rate is below the expected rate.
+	if b.extraAckedDelivered <= expected {
+		b.extraAckedDelivered = 0
+		b.extraAckedIntervalStart = now
+		expected = 0
+	}
+	b.extraAckedDelivered += rs.newlyAcked
+	extra := min(b.extraAckedDelivered-expected, b.congestionWindow)
+	b.extraAcked[b.extraAckedIdx] = max(b.extraAcked[b.extraAckedIdx], extra)
+}
+
+func (b *bbr3Sender) extraAckedMax() protocol.ByteCount {
+	return max(b.extraAcked[0], b.extraAcked[1])
+}
+
+// --- Startup and Drain ---
+
+func (b *bbr3Sender) enterStartup() {
+	b.mode = bbr3Startup
+	b.pacingGain = bbr3StartupPacingGain
+	b.cwndGain = bbr3StartupCwndGain
+	b.maybeTraceStateChange(logging.CongestionStateSlowStart)
+}
+
+func (b *bbr3Sender) resetFullBw() {
+	b.fullBw = 0
+	b.fullBwCount = 0
+	b.fullBwNow = false
+}
+
+// checkFullBwReached detects a bandwidth plateau: three rounds without
+// 25% growth.
+func (b *bbr3Sender) checkFullBwReached(rs bbr3RateSample) {
+	if b.fullBwNow || !b.roundStart || rs.isAppLimited {
+		return
+	}
+	if rs.deliveryRate >= Bandwidth(float64(b.fullBw)*bbr3FullBandwidthThreshold) {
+		b.fullBw = rs.deliveryRate
+		b.fullBwCount = 0
+		return
+	}
+	b.fullBwCount++
+	b.fullBwNow = b.fullBwCount >= bbr3FullBandwidthCount
+	if b.fullBwNow {
+		b.filledPipe = true
+	}
+}
+
+// checkStartupHighLoss ends startup if a round saw too much loss.
+func (b *bbr3Sender) checkStartupHighLoss(rs bbr3RateSample) {
+	if b.mode != bbr3Startup || b.filledPipe || !b.lossRoundStart {
+		return
+	}
+	if b.lossEventsInRound < bbr3StartupFullLossCount {
+		return
+	}
+	if !b.isInflightTooHigh(bbr3RateSample{txInFlight: rs.txInFlight, lost: b.lostInRound}) {
+		return
+	}
+	b.filledPipe = true
+	b.inflightHi = max(b.bdpMultiple(b.maxBw, 1.0), b.inflightLatest)
+}
+
+func (b *bbr3Sender) checkStartupDone() {
+	if b.mode == bbr3Startup && b.filledPipe {
+		b.enterDrain()
+	}
+}
+
+func (b *bbr3Sender) enterDrain() {
+	b.mode = bbr3Drain
+	b.pacingGain = bbr3DrainPacingGain
+	b.cwndGain = bbr3StartupCwndGain
+	b.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+}
+
+func (b *bbr3Sender) checkDrain(now time.Time) {
+	if b.mode == bbr3Drain && b.bytesInFlight <= b.bdpMultiple(b.maxBw, 1.0) {
+		b.startProbeBWDown(now)
+	}
+}
+
+// --- ProbeBW ---
+
+func (b *bbr3Sender) isInProbeBW() bool {
+	switch b.mode {
+	case bbr3ProbeBWDown, bbr3ProbeBWCruise, bbr3ProbeBWRefill, bbr3ProbeBWUp:
+		return true
+	}
+	return false
+}
+
+func (b *bbr3Sender) startProbeBWDown(now time.Time) {
+	b.resetCongestionSignals()
+	b.bwProbeUpCnt = protocol.MaxByteCount
+	b.pickProbeWait()
+	b.cycleStamp = now
+	b.ackPhase = bbr3AcksProbeStopping
+	b.startRound()
+	b.mode = bbr3ProbeBWDown
+	b.pacingGain = bbr3ProbeBWDownPacingGain
+	b.cwndGain = bbr3CwndGain
+	b.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+}
+
+func (b *bbr3Sender) startProbeBWCruise() {
+	b.mode = bbr3ProbeBWCruise
+	b.pacingGain = 1.0
+	b.cwndGain = bbr3CwndGain
+}
+
+func (b *bbr3Sender) startProbeBWRefill() {
+	b.resetLowerBounds()
+	b.bwProbeUpRounds = 0
+	b.bwProbeUpAcks = 0
+	b.ackPhase = bbr3AcksRefilling
+	b.startRound()
+	b.mode = bbr3ProbeBWRefill
+	b.pacingGain = 1.0
+	b.cwndGain = bbr3CwndGain
+}
+
+func (b *bbr3Sender) startProbeBWUp(rs bbr3RateSample, now time.Time) {
+	b.ackPhase = bbr3AcksProbeStarting
+	b.startRound()
+	b.resetFullBw()
+	b.fullBw = rs.deliveryRate
+	b.cycleStamp = now
+	b.mode = bbr3ProbeBWUp
+	b.pacingGain = bbr3ProbeBWUpPacingGain
+	b.cwndGain = bbr3ProbeBWUpCwndGain
+	b.raiseInflightHiSlope()
+}
+
+// pickProbeWait randomizes the time to the next bandwidth probe, so that
+// flows sharing a bottleneck don't probe in sync.
+func (b *bbr3Sender) pickProbeWait() {
+	b.roundsSinceBwProbe = uint64(b.rand.IntN(2))
+	b.bwProbeWait = bbr3ProbeWaitBase + time.Duration(b.rand.Int64N(int64(time.Second)))
+}
+
+func (b *bbr3Sender) updateProbeBWCyclePhase(rs bbr3RateSample, now time.Time) {
+	if !b.filledPipe {
+		return
+	}
+	b.adaptUpperBounds(rs, now)
+	if !b.isInProbeBW() {
+		return
+	}
+	switch b.mode {
+	case bbr3ProbeBWDown:
+		if b.isTimeToProbeBw(now) {
+			return
+		}
+		if b.isTimeToCruise() {
+			b.startProbeBWCruise()
+		}
+	case bbr3ProbeBWCruise:
+		b.isTimeToProbeBw(now)
+	case bbr3ProbeBWRefill:
+		// After one round of refill, start probing.
+		if b.roundStart {
+			b.bwProbeSamples = true
+			b.startProbeBWUp(rs, now)
+		}
+	case bbr3ProbeBWUp:
+		if b.isTimeToGoDown(rs, now) {
+			b.startProbeBWDown(now)
+		}
+	}
+}
+
+// isTimeToProbeBw starts refilling the pipe if it's time to probe for
+// bandwidth: after the randomized wait, or after as many rounds as Reno
+// would take to probe the same BDP.
+func (b *bbr3Sender) isTimeToProbeBw(now time.Time) bool {
+	if now.Sub(b.cycleStamp) > b.bwProbeWait || b.isRenoCoexistenceProbeTime() {
+		b.startProbeBWRefill()
+		return true
+	}
+	return false
+}
+
+func (b *bbr3Sender) isRenoCoexistenceProbeTime() bool {
+	renoRounds := uint64(b.targetInflight() / b.maxDatagramSize)
+	return b.roundsSinceBwProbe >= min(renoRounds, bbr3MaxRenoRounds)
+}
+
+func (b *bbr3Sender) isTimeToCruise() bool {
+	if b.bytesInFlight > b.inflightWithHeadroom() {
+		return false // not enough headroom
+	}
+	return b.bytesInFlight <= b.bdpMultiple(b.maxBw, 1.0)
+}
+
+func (b *bbr3Sender) isTimeToGoDown(rs bbr3RateSample, now time.Time) bool {
+	if b.cwndLimited && b.congestionWindow >= b.inflightHi {
+		// inflight_hi limits the probe, the bandwidth plateau can't be seen
+		b.resetFullBw()
+		b.fullBw = rs.deliveryRate
+		return false
+	}
+	if b.fullBwNow {
+		return true
+	}
+	// probed long enough to fill the pipe at the higher rate
+	return now.Sub(b.cycleStamp) > b.minRTT && b.bytesInFlight >= b.bdpMultiple(b.maxBw, bbr3ProbeBWUpPacingGain)
+}
+
+func (b *bbr3Sender) raiseInflightHiSlope() {
+	growth := b.maxDatagramSize << b.bwProbeUpRounds
+	b.bwProbeUpRounds = min(b.bwProbeUpRounds+1, 30)
+	b.bwProbeUpCnt = max(b.congestionWindow/growth, 1) * b.maxDatagramSize
+}
+
+// probeInflightHiUpward grows inflight_hi while probing, by an amount that
+// doubles every round.
+func (b *bbr3Sender) probeInflightHiUpward(rs bbr3RateSample) {
+	if !b.cwndLimited || b.congestionWindow < b.inflightHi {
+		return // not fully using inflight_hi, no need to raise it
+	}
+	b.bwProbeUpAcks += rs.newlyAcked
+	if b.bwProbeUpAcks >= b.bwProbeUpCnt {
+		delta := b.bwProbeUpAcks / b.bwProbeUpCnt
+		b.bwProbeUpAcks -= delta * b.bwProbeUpCnt
+		b.inflightHi += delta * b.maxDatagramSize
+	}
+	if b.roundStart {
+		b.raiseInflightHiSlope()
+	}
+}
+
+func (b *bbr3Sender) adaptUpperBounds(rs bbr3RateSample, now time.Time) {
+	if b.ackPhase == bbr3AcksProbeStarting && b.roundStart {
+		// starting to get feedback for the probe
+		b.ackPhase = bbr3AcksProbeFeedback
+	}
+	if b.ackPhase == bbr3AcksProbeStopping && b.roundStart {
+		// end of the samples of the previous probe
+		b.bwProbeSamples = false
+		b.ackPhase = bbr3AcksInit
+		if b.isInProbeBW() && !rs.isAppLimited {
+			b.advanceMaxBwFilter()
+		}
+	}
+	if b.checkInflightTooHigh(rs, now) {
+		return
+	}
+	if b.inflightHi == protocol.MaxByteCount {
+		return
+	}
+	if rs.txInFlight > b.inflightHi {
+		b.inflightHi = rs.txInFlight
+	}
+	if b.mode == bbr3ProbeBWUp {
+		b.probeInflightHiUpward(rs)
+	}
+}
+
+func (b *bbr3Sender) checkInflightTooHigh(rs bbr3RateSample, _ time.Time) bool {
+	if !b.isInflightTooHigh(rs) {
+		return false
+	}
+	if b.bwProbeSamples {
+		b.handleInflightTooHigh(rs, true)
+	}
+	return true
+}
+
+// isInflightTooHigh reports whether the loss rate or the fraction of
+// CE-marked data of a sample is above the tolerated level.
+func (b *bbr3Sender) isInflightTooHigh(rs bbr3RateSample) bool {
+	if rs.lost > 0 && rs.txInFlight > 0 && float64(rs.lost) > float64(rs.txInFlight)*bbr3LossThresh {
+		return true
+	}
+	return b.ceInRound > 0 && b.ceRatio() > bbr3ECNThresh
+}
+
+func (b *bbr3Sender) handleInflightTooHigh(rs bbr3RateSample, useSample bool) {
+	b.bwProbeSamples = false
+	if !rs.isAppLimited || !useSample {
+		b.inflightHi = max(rs.txInFlight, protocol.ByteCount(float64(b.targetInflight())*bbr3Beta))
+	}
+	if b.mode == bbr3ProbeBWUp {
+		b.startProbeBWDown(b.clock.Now())
+	}
+}
+
+// inflightHiFromLostPacket estimates the inflight at which the loss rate
+// crossed bbr3LossThresh, assuming loss grew linearly up to the lost packet.
+func (b *bbr3Sender) inflightHiFromLostPacket(rs bbr3RateSample, p *bbr3SentPacket) protocol.ByteCount {
+	inflightPrev := float64(rs.txInFlight) - float64(p.size)
+	lostPrev := float64(rs.lost) - float64(p.size)
+	if inflightPrev <= 0 {
+		return rs.txInFlight
+	}
+	lostPrefix := (bbr3LossThresh*inflightPrev - lostPrev) / (1 - bbr3LossThresh)
+	return protocol.ByteCount(max(inflightPrev+lostPrefix, 0))
+}
+
+// --- ProbeRTT ---
+
+func (b *bbr3Sender) updateMinRTT(now time.Time) {
+	rtt := b.rttStats.LatestRTT()
+	if rtt <= 0 {
+		return
+	}
+	b.probeRTTExpired = now.Sub(b.probeRTTMinStamp) > bbr3ProbeRTTInterval
+	if b.probeRTTMinDelay == 0 || rtt < b.probeRTTMinDelay || b.probeRTTExpired {
+		b.probeRTTMinDelay = rtt
+		b.probeRTTMinStamp = now
+	}
+	minRTTExpired := now.Sub(b.minRTTStamp) > bbr3MinRTTFilterLen
+	if b.minRTT == 0 || b.probeRTTMinDelay < b.minRTT || minRTTExpired {
+		b.minRTT = b.probeRTTMinDelay
+		b.minRTTStamp = b.probeRTTMinStamp
+	}
+}
+
+func (b *bbr3Sender) checkProbeRTT(now time.Time) {
+	if b.mode != bbr3ProbeRTT && b.probeRTTExpired {
+		b.enterProbeRTT()
+	}
+	if b.mode == bbr3ProbeRTT {
+		b.handleProbeRTT(now)
+	}
+}
+
+func (b *bbr3Sender) enterProbeRTT() {
+	b.priorCwnd = b.saveCwnd()
+	b.probeRTTDoneStamp = time.Time{}
+	b.ackPhase = bbr3AcksProbeStopping
+	b.startRound()
+	b.mode = bbr3ProbeRTT
+	b.pacingGain = 1.0
+	b.cwndGain = bbr3ProbeRTTCwndGain
+	b.maybeTraceStateChange(logging.CongestionStateApplicationLimited)
+}
+
+func (b *bbr3Sender) handleProbeRTT(now time.Time) {
+	if b.probeRTTDoneStamp.IsZero() {
+		if b.bytesInFlight <= b.probeRTTCwnd() {
+			// inflight drained: hold it for ProbeRTTDuration and one round
+			b.probeRTTDoneStamp = now.Add(bbr3ProbeRTTDuration)
+			b.probeRTTRoundDone = false
+			b.startRound()
+		}
+		return
+	}
+	if b.roundStart {
+		b.probeRTTRoundDone = true
+	}
+	if b.probeRTTRoundDone && now.After(b.probeRTTDoneStamp) {
+		b.probeRTTMinStamp = now
+		b.restoreCwnd()
+		b.exitProbeRTT(now)
+	}
+}
+
+func (b *bbr3Sender) exitProbeRTT(now time.Time) {
+	b.resetLowerBounds()
+	if b.filledPipe {
+		b.startProbeBWDown(now)
+		b.startProbeBWCruise()
+	} else {
+		b.enterStartup()
+	}
+}
+
+func (b *bbr3Sender) saveCwnd() protocol.ByteCount {
+	if b.mode != bbr3ProbeRTT {
+		return b.congestionWindow
+	}
+	return max(b.priorCwnd, b.congestionWindow)
+}
+
+func (b *bbr3Sender) restoreCwnd() {
+	b.congestionWindow = max(b.congestionWindow, b.priorCwnd)
+}
+
+func (b *bbr3Sender) probeRTTCwnd() protocol.ByteCount {
+	return max(b.bdpMultiple(b.bw, bbr3ProbeRTTCwndGain), b.minPipeCwnd())
+}
+
+// --- Control parameters ---
+
+func (b *bbr3Sender) updateControlParameters(rs bbr3RateSample) {
+	b.setPacingRate()
+	b.setCwnd(rs)
+}
+
+func (b *bbr3Sender) setPacingRate() {
+	bw := b.bw
+	if bw == infBandwidth || bw == 0 {
+		return
+	}
+	rate := Bandwidth(b.pacingGain * float64(bw) * (100 - bbr3PacingMarginPercent) / 100)
+	if b.filledPipe || rate > b.pacingRate {
+		b.pacingRate = rate
+	}
+}
+
+func (b *bbr3Sender) setCwnd(rs bbr3RateSample) {
+	maxInflight := b.bdpMultiple(b.bw, b.cwndGain) + b.extraAckedMax()
+	if b.mode == bbr3ProbeBWUp {
+		maxInflight += 2 * b.maxDatagramSize
+	}
+	if b.filledPipe {
+		b.congestionWindow = min(b.congestionWindow+rs.newlyAcked, maxInflight)
+	} else if b.congestionWindow < maxInflight || b.delivered < b.initialCwnd {
+		b.congestionWindow += rs.newlyAcked
+	}
+	b.congestionWindow = max(b.congestionWindow, b.minPipeCwnd())
+	if b.mode == bbr3ProbeRTT {
+		b.congestionWindow = min(b.congestionWindow, b.probeRTTCwnd())
+	}
+	b.boundCwndForModel()
+	b.congestionWindow = min(b.congestionWindow, b.maxDatagramSize*protocol.MaxCongestionWindowPackets)
+}
+
+func (b *bbr3Sender) boundCwndForModel() {
+	limit := protocol.MaxByteCount
+	switch {
+	case b.isInProbeBW() && b.mode != bbr3ProbeBWCruise:
+		limit = b.inflightHi
+	case b.mode == bbr3ProbeRTT || b.mode == bbr3ProbeBWCruise:
+		limit = b.inflightWithHeadroom()
+	}
+	limit = min(limit, b.inflightLo)
+	limit = max(limit, b.minPipeCwnd())
+	b.congestionWindow = min(b.congestionWindow, limit)
+}
+
+// bdpMultiple returns gain times the estimated BDP at bandwidth bw.
+func (b *bbr3Sender) bdpMultiple(bw Bandwidth, gain float64) protocol.ByteCount {
+	if b.minRTT == 0 || bw == 0 || bw == infBandwidth {
+		return b.initialCwnd
+	}
+	bdp := float64(bw/BytesPerSecond) * b.minRTT.Seconds()
+	return protocol.ByteCount(math.Ceil(gain * bdp))
+}
+
+func (b *bbr3Sender) targetInflight() protocol.ByteCount {
+	return min(b.bdpMultiple(b.bw, 1.0), b.congestionWindow)
+}
+
+// inflightWithHeadroom leaves some of inflight_hi free for other flows.
+func (b *bbr3Sender) inflightWithHeadroom() protocol.ByteCount {
+	if b.inflightHi == protocol.MaxByteCount {
+		return protocol.MaxByteCount
+	}
+	headroom := max(b.maxDatagramSize, protocol.ByteCount(bbr3Headroom*float64(b.inflightHi)))
+	return max(b.inflightHi-min(headroom, b.inflightHi), b.minPipeCwnd())
+}
+
+func (b *bbr3Sender) minPipeCwnd() protocol.ByteCount {
+	return bbr3MinPipeCwndPackets * b.maxDatagramSize
+}
+
+// bandwidthEstimate returns the pacing rate for the pacer.
+func (b *bbr3Sender) bandwidthEstimate() Bandwidth {
+	if b.pacingRate != 0 {
+		return b.pacingRate
+	}
+	// Before the first bandwidth sample: pace the initial window over the smoothed RTT.
+	srtt := b.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		return infBandwidth
+	}
+	return Bandwidth(bbr3StartupPacingGain * float64(BandwidthFromDelta(b.congestionWindow, srtt)))
+}
+
+func (b *bbr3Sender) maybeTraceStateChange(new logging.CongestionState) {
+	if b.tracer == nil || b.tracer.UpdatedCongestionState == nil || new == b.lastState {
+		return
+	}
+	b.tracer.UpdatedCongestionState(new)
+	b.lastState = new
+}
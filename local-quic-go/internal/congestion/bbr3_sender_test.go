@@ -0,0 +1,281 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// bbr3LinkPacket is a packet in flight on the simulated bottleneck link.
+type bbr3LinkPacket struct {
+	pn     protocol.PacketNumber
+	size   protocol.ByteCount
+	sentAt time.Time
+	ackAt  time.Time
+	lost   bool
+	ce     bool
+}
+
+var _ = Describe("BBRv3 Sender", func() {
+	var (
+		sender            *bbr3Sender
+		clock             mockClock
+		bytesInFlight     protocol.ByteCount
+		packetNumber      protocol.PacketNumber
+		ackedPacketNumber protocol.PacketNumber
+		rttStats          utils.RTTStats
+	)
+
+	BeforeEach(func() {
+		bytesInFlight = 0
+		packetNumber = 1
+		ackedPacketNumber = 0
+		clock = mockClock{}
+		clock.Advance(time.Hour)
+		rttStats = utils.RTTStats{}
+		sender = NewBBR3Sender(&clock, &rttStats, maxDatagramSize, nil)
+	})
+
+	SendAvailableSendWindow := func() int {
+		var packetsSent int
+		for sender.CanSend(bytesInFlight) {
+			// the ack handler passes the bytes in flight including the packet
+			bytesInFlight += maxDatagramSize
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			packetNumber++
+			packetsSent++
+		}
+		return packetsSent
+	}
+
+	AckNPackets := func(n int) {
+		rttStats.UpdateRTT(60*time.Millisecond, 0, clock.Now())
+		for i := 0; i < n; i++ {
+			ackedPacketNumber++
+			sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+			bytesInFlight -= maxDatagramSize
+		}
+	}
+
+	LoseNPackets := func(n int) {
+		for i := 0; i < n; i++ {
+			ackedPacketNumber++
+			sender.OnCongestionEvent(ackedPacketNumber, maxDatagramSize, bytesInFlight)
+			bytesInFlight -= maxDatagramSize
+		}
+	}
+
+	// RunLink runs the sender over a bottleneck link with bandwidth bw,
+	// base round-trip time rtt and a buffer of bufferPackets, for duration d.
+	// drop and mark decide which packets are lost and CE-marked on top of
+	// buffer overflows. onTick is called every millisecond.
+	RunLink := func(
+		bw Bandwidth,
+		rtt time.Duration,
+		bufferPackets int,
+		d time.Duration,
+		drop, mark func(protocol.PacketNumber) bool,
+		onTick func(),
+	) {
+		var (
+			inFlight []bbr3LinkPacket
+			linkFree time.Time
+		)
+		txTime := func(size protocol.ByteCount) time.Duration {
+			return time.Duration(uint64(size) * uint64(time.Second) / uint64(bw/BytesPerSecond))
+		}
+		end := clock.Now().Add(d)
+		for clock.Now().Before(end) {
+			now := clock.Now()
+			for len(inFlight) > 0 && !inFlight[0].ackAt.After(now) {
+				p := inFlight[0]
+				inFlight = inFlight[1:]
+				if p.lost {
+					sender.OnCongestionEvent(p.pn, p.size, bytesInFlight)
+					bytesInFlight -= p.size
+					continue
+				}
+				rttStats.UpdateRTT(now.Sub(p.sentAt), 0, now)
+				if p.ce {
+					sender.OnCongestionEvent(p.pn, 0, bytesInFlight)
+				}
+				sender.OnPacketAcked(p.pn, p.size, bytesInFlight, now)
+				bytesInFlight -= p.size
+			}
+			for sender.CanSend(bytesInFlight) && sender.HasPacingBudget(now) {
+				pn := packetNumber
+				packetNumber++
+				bytesInFlight += maxDatagramSize
+				sender.OnPacketSent(now, bytesInFlight, pn, maxDatagramSize, true)
+				p := bbr3LinkPacket{pn: pn, size: maxDatagramSize, sentAt: now}
+				if linkFree.Before(now) {
+					linkFree = now
+				}
+				queued := int(linkFree.Sub(now) / txTime(maxDatagramSize))
+				if queued >= bufferPackets || (drop != nil && drop(pn)) {
+					// reported lost when the next packet is acked
+					p.lost = true
+					p.ackAt = linkFree.Add(txTime(maxDatagramSize) + rtt)
+				} else {
+					linkFree = linkFree.Add(txTime(maxDatagramSize))
+					p.ackAt = linkFree.Add(rtt)
+					p.ce = mark != nil && mark(pn)
+				}
+				inFlight = append(inFlight, p)
+			}
+			if onTick != nil {
+				onTick()
+			}
+			clock.Advance(time.Millisecond)
+		}
+	}
+
+	const (
+		linkBandwidth = 10 * 1000 * 1000 * BitsPerSecond
+		linkRTT       = 40 * time.Millisecond
+	)
+	linkBDP := protocol.ByteCount(linkBandwidth / BytesPerSecond * Bandwidth(linkRTT) / Bandwidth(time.Second))
+
+	It("has the right values at startup", func() {
+		// At startup make sure we are at the default.
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindow * maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		// Make sure we can send.
+		Expect(sender.TimeUntilSend(0)).To(BeZero())
+		Expect(sender.CanSend(bytesInFlight)).To(BeTrue())
+
+		// Fill the send window with data, then verify that we can't send.
+		Expect(SendAvailableSendWindow()).To(Equal(initialCongestionWindow))
+		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
+	})
+
+	It("paces", func() {
+		rttStats.UpdateRTT(10*time.Millisecond, 0, clock.Now())
+		// Fill the send window with data, then verify that we can't send right away.
+		SendAvailableSendWindow()
+		AckNPackets(1)
+		delay := sender.TimeUntilSend(bytesInFlight)
+		Expect(delay).ToNot(BeZero())
+		Expect(delay.Sub(clock.Now())).To(BeNumerically("<", time.Hour))
+	})
+
+	It("doubles the congestion window every round in startup", func() {
+		for round := 1; round <= 3; round++ {
+			n := SendAvailableSendWindow()
+			clock.Advance(60 * time.Millisecond)
+			AckNPackets(n)
+			Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindow * maxDatagramSize << round))
+		}
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("doesn't reduce the congestion window on loss in startup", func() {
+		n := SendAvailableSendWindow()
+		clock.Advance(60 * time.Millisecond)
+		LoseNPackets(1)
+		AckNPackets(n - 1)
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindow*maxDatagramSize + protocol.ByteCount(n-1)*maxDatagramSize))
+	})
+
+	It("exits startup when the bandwidth stops growing", func() {
+		RunLink(linkBandwidth, linkRTT, 1000, 3*time.Second, nil, nil, nil)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.isInProbeBW()).To(BeTrue())
+		Expect(sender.maxBw).To(BeNumerically("~", linkBandwidth, linkBandwidth/10))
+		Expect(sender.minRTT).To(BeNumerically("~", linkRTT, 2*time.Millisecond))
+		// the queue built in startup was drained
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", 3*linkBDP))
+	})
+
+	It("cycles through the ProbeBW phases", func() {
+		seen := make(map[bbr3Mode]bool)
+		RunLink(linkBandwidth, linkRTT, 1000, 10*time.Second, nil, nil, func() { seen[sender.mode] = true })
+		for _, mode := range []bbr3Mode{bbr3Startup, bbr3Drain, bbr3ProbeBWDown, bbr3ProbeBWCruise, bbr3ProbeBWRefill, bbr3ProbeBWUp} {
+			Expect(seen).To(HaveKey(mode), mode.String())
+		}
+	})
+
+	It("sets inflight_hi when probing causes too much loss", func() {
+		RunLink(linkBandwidth, linkRTT, 5, 5*time.Second, nil, nil, nil)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.inflightHi).To(BeNumerically("<", 3*linkBDP))
+		Expect(sender.maxBw).To(BeNumerically("~", linkBandwidth, linkBandwidth/5))
+	})
+
+	It("tolerates random loss below the loss threshold", func() {
+		RunLink(linkBandwidth, linkRTT, 1000, 10*time.Second, func(pn protocol.PacketNumber) bool { return pn%100 == 0 }, nil, nil)
+		Expect(sender.isInProbeBW()).To(BeTrue())
+		Expect(sender.inflightHi).To(Equal(protocol.MaxByteCount))
+		Expect(sender.maxBw).To(BeNumerically("~", linkBandwidth, linkBandwidth/10))
+	})
+
+	It("reduces inflight_lo on ECN-CE marks", func() {
+		var (
+			marking bool
+			cwnd    protocol.ByteCount
+			reduced bool
+		)
+		start := clock.Now()
+		RunLink(linkBandwidth, linkRTT, 1000, 7*time.Second, nil, func(pn protocol.PacketNumber) bool {
+			return marking && pn%3 != 0
+		}, func() {
+			if !marking && clock.Now().Sub(start) >= 3*time.Second {
+				Expect(sender.isInProbeBW()).To(BeTrue())
+				Expect(sender.inflightLo).To(Equal(protocol.MaxByteCount))
+				marking = true
+				cwnd = sender.GetCongestionWindow()
+			}
+			if marking && sender.inflightLo < cwnd {
+				reduced = true
+			}
+		})
+		Expect(reduced).To(BeTrue())
+		Expect(sender.ecnAlpha).To(BeNumerically(">", 0))
+		Expect(sender.inflightHi).To(BeNumerically("<", protocol.MaxByteCount))
+	})
+
+	It("probes for a lower RTT", func() {
+		var probeRTTCwnd protocol.ByteCount
+		RunLink(linkBandwidth, linkRTT, 1000, 7*time.Second, nil, nil, func() {
+			if sender.mode == bbr3ProbeRTT && probeRTTCwnd == 0 && !sender.probeRTTDoneStamp.IsZero() {
+				probeRTTCwnd = sender.GetCongestionWindow()
+			}
+		})
+		Expect(probeRTTCwnd).ToNot(BeZero())
+		Expect(probeRTTCwnd).To(BeNumerically("<=", max(linkBDP, bbr3MinPipeCwndPackets*maxDatagramSize)))
+		// ProbeRTT ended
+		Expect(sender.mode).ToNot(Equal(bbr3ProbeRTT))
+	})
+
+	It("estimates the inflight at which the loss threshold was crossed", func() {
+		p := &bbr3SentPacket{size: maxDatagramSize}
+		rs := bbr3RateSample{txInFlight: 100 * maxDatagramSize, lost: 3 * maxDatagramSize}
+		// loss crossed 2% before this packet was lost
+		inflightHi := sender.inflightHiFromLostPacket(rs, p)
+		Expect(inflightHi).To(BeNumerically("<", rs.txInFlight))
+		lostAtInflightHi := 2*maxDatagramSize + inflightHi - 99*maxDatagramSize
+		Expect(float64(lostAtInflightHi) / float64(inflightHi)).To(BeNumerically("~", bbr3LossThresh, 0.0001))
+	})
+
+	It("limits the congestion window to the minimum in ProbeRTT", func() {
+		sender.enterProbeRTT()
+		Expect(sender.probeRTTCwnd()).To(BeNumerically(">=", bbr3MinPipeCwndPackets*maxDatagramSize))
+		SendAvailableSendWindow()
+		clock.Advance(60 * time.Millisecond)
+		AckNPackets(1)
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.probeRTTCwnd()))
+	})
+
+	It("keeps the minimum congestion window when the max datagram size grows", func() {
+		sender.congestionWindow = sender.minPipeCwnd()
+		sender.SetMaxDatagramSize(maxDatagramSize + 100)
+		Expect(sender.GetCongestionWindow()).To(Equal(bbr3MinPipeCwndPackets * (maxDatagramSize + 100)))
+		Expect(func() { sender.SetMaxDatagramSize(maxDatagramSize) }).To(Panic())
+	})
+})
@@ -82,3 +82,11 @@ func (p *pacer) TimeUntilSend() time.Time {
 func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
 	p.maxDatagramSize = s
 }
+
+// Pacer is the pacer of the built-in congestion controllers, exported for the public congestion package.
+type Pacer = pacer
+
+// NewPacer creates a pacer that paces at 5/4 of the bandwidth returned by getBandwidth.
+func NewPacer(getBandwidth func() Bandwidth) *Pacer {
+	return newPacer(getBandwidth)
+}
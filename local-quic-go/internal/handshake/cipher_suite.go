@@ -79,25 +79,30 @@ func (f *xorNonceAEAD) NonceSize() int        { return 8 } // 64-bit sequence nu
 func (f *xorNonceAEAD) Overhead() int         { return f.aead.Overhead() }
 func (f *xorNonceAEAD) explicitNonceLen() int { return 0 }
 
+// Seal XORs the nonce into the low bytes of the nonce mask.
+// The nonce is usually the 64-bit packet number,
+// multipath packets use a 96-bit nonce that also contains the path ID.
 func (f *xorNonceAEAD) Seal(out, nonce, plaintext, additionalData []byte) []byte {
+	offset := aeadNonceLength - len(nonce)
 	for i, b := range nonce {
-		f.nonceMask[4+i] ^= b
+		f.nonceMask[offset+i] ^= b
 	}
 	result := f.aead.Seal(out, f.nonceMask[:], plaintext, additionalData)
 	for i, b := range nonce {
-		f.nonceMask[4+i] ^= b
+		f.nonceMask[offset+i] ^= b
 	}
 
 	return result
 }
 
 func (f *xorNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	offset := aeadNonceLength - len(nonce)
 	for i, b := range nonce {
-		f.nonceMask[4+i] ^= b
+		f.nonceMask[offset+i] ^= b
 	}
 	result, err := f.aead.Open(out, f.nonceMask[:], ciphertext, additionalData)
 	for i, b := range nonce {
-		f.nonceMask[4+i] ^= b
+		f.nonceMask[offset+i] ^= b
 	}
 
 	return result, err
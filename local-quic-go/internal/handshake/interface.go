@@ -54,6 +54,17 @@ type ShortHeaderSealer interface {
 	KeyPhase() protocol.KeyPhaseBit
 }
 
+// PathAEAD seals and opens short header packets sent on a multipath path other than the initial one.
+// The nonce includes the path ID (draft-ietf-quic-multipath), so the packet number spaces of
+// different paths can share the 1-RTT keys.
+type PathAEAD interface {
+	SealPath(dst, src []byte, pathID protocol.PathID, pn protocol.PacketNumber, associatedData []byte) []byte
+	OpenPath(dst, src []byte, rcvTime time.Time, pathID protocol.PathID, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte) ([]byte, error)
+	// SetLargestPathAcked is called when a packet sent on the path was acknowledged.
+	// The key phase is shared by all paths.
+	SetLargestPathAcked(pathID protocol.PathID, pn protocol.PacketNumber) error
+}
+
 type ConnectionState struct {
 	tls.ConnectionState
 	Used0RTT bool
@@ -42,6 +42,11 @@ type updatableAEAD struct {
 	highestRcvdPN           protocol.PacketNumber // highest packet number received (which could be successfully unprotected)
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
+	// the same for the multipath paths other than the initial path, by path ID
+	firstRcvdOnPath map[protocol.PathID]protocol.PacketNumber
+	firstSentOnPath map[protocol.PathID]protocol.PacketNumber
+	// a packet sent with the current key phase was acknowledged on a path other than the initial path
+	pathAckedWithCurrentKey bool
 	rcvAEAD                 cipher.AEAD
 	sendAEAD                cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
@@ -63,11 +68,14 @@ type updatableAEAD struct {
 
 	// use a single slice to avoid allocations
 	nonceBuf []byte
+	// the nonce of multipath packets, see putPathNonce
+	pathNonceBuf []byte
 }
 
 var (
 	_ ShortHeaderOpener = &updatableAEAD{}
 	_ ShortHeaderSealer = &updatableAEAD{}
+	_ PathAEAD          = &updatableAEAD{}
 )
 
 func newUpdatableAEAD(rttStats *utils.RTTStats, tracer *logging.ConnectionTracer, logger utils.Logger, version protocol.Version) *updatableAEAD {
@@ -76,6 +84,8 @@ func newUpdatableAEAD(rttStats *utils.RTTStats, tracer *logging.ConnectionTracer
 		largestAcked:            protocol.InvalidPacketNumber,
 		firstRcvdWithCurrentKey: protocol.InvalidPacketNumber,
 		firstSentWithCurrentKey: protocol.InvalidPacketNumber,
+		firstRcvdOnPath:         make(map[protocol.PathID]protocol.PacketNumber),
+		firstSentOnPath:         make(map[protocol.PathID]protocol.PacketNumber),
 		rttStats:                rttStats,
 		tracer:                  tracer,
 		logger:                  logger,
@@ -97,6 +107,9 @@ func (a *updatableAEAD) rollKeys() {
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	clear(a.firstRcvdOnPath)
+	clear(a.firstSentOnPath)
+	a.pathAckedWithCurrentKey = false
 	a.prevRcvAEAD = a.rcvAEAD
 	a.rcvAEAD = a.nextRcvAEAD
 	a.sendAEAD = a.nextSendAEAD
@@ -177,7 +190,7 @@ func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 	return dec, err
 }
 
-func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+func (a *updatableAEAD) dropPrevKeys(rcvTime time.Time) {
 	if a.prevRcvAEAD != nil && !a.prevRcvAEADExpiry.IsZero() && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
 		a.logger.Debugf("Dropping key phase %d", a.keyPhase-1)
@@ -186,9 +199,16 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 			a.tracer.DroppedKey(a.keyPhase - 1)
 		}
 	}
+}
+
+func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	a.dropPrevKeys(rcvTime)
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	if kp != a.keyPhase.Bit() {
-		if a.keyPhase > 0 && a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < a.firstRcvdWithCurrentKey {
+		// On a multipath connection, the peer might have used the current key phase on other paths only.
+		// Once the previous keys are dropped, the packet can only be protected with the next key phase.
+		rcvdOnPaths := a.prevRcvAEAD == nil && len(a.firstRcvdOnPath) > 0
+		if a.keyPhase > 0 && a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber && !rcvdOnPaths || pn < a.firstRcvdWithCurrentKey {
 			if a.prevRcvAEAD == nil {
 				return nil, ErrKeysDropped
 			}
@@ -204,20 +224,8 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 		if err != nil {
 			return nil, ErrDecryptionFailed
 		}
-		// Opening succeeded. Check if the peer was allowed to update.
-		if a.keyPhase > 0 && a.firstSentWithCurrentKey == protocol.InvalidPacketNumber {
-			return nil, &qerr.TransportError{
-				ErrorCode:    qerr.KeyUpdateError,
-				ErrorMessage: "keys updated too quickly",
-			}
-		}
-		a.rollKeys()
-		a.logger.Debugf("Peer updated keys to %d", a.keyPhase)
-		// The peer initiated this key update. It's safe to drop the keys for the previous generation now.
-		// Start a timer to drop the previous key generation.
-		a.startKeyDropTimer(rcvTime)
-		if a.tracer != nil && a.tracer.UpdatedKey != nil {
-			a.tracer.UpdatedKey(a.keyPhase, true)
+		if err := a.peerUpdatedKeys(rcvTime); err != nil {
+			return nil, err
 		}
 		a.firstRcvdWithCurrentKey = pn
 		return dec, err
@@ -230,17 +238,47 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 	}
 	a.numRcvdWithCurrentKey++
 	if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
-		// We initiated the key updated, and now we received the first packet protected with the new key phase.
-		// Therefore, we are certain that the peer rolled its keys as well. Start a timer to drop the old keys.
-		if a.keyPhase > 0 {
-			a.logger.Debugf("Peer confirmed key update to phase %d", a.keyPhase)
-			a.startKeyDropTimer(rcvTime)
-		}
+		a.firstRcvdWithNewKey(rcvTime)
 		a.firstRcvdWithCurrentKey = pn
 	}
 	return dec, err
 }
 
+// peerUpdatedKeys rolls the keys after a packet protected with the next key phase was opened.
+func (a *updatableAEAD) peerUpdatedKeys(rcvTime time.Time) error {
+	// Check if the peer was allowed to update.
+	if a.keyPhase > 0 && !a.sentWithCurrentKey() {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.KeyUpdateError,
+			ErrorMessage: "keys updated too quickly",
+		}
+	}
+	a.rollKeys()
+	a.logger.Debugf("Peer updated keys to %d", a.keyPhase)
+	// The peer initiated this key update. It's safe to drop the keys for the previous generation now.
+	// Start a timer to drop the previous key generation.
+	a.startKeyDropTimer(rcvTime)
+	if a.tracer != nil && a.tracer.UpdatedKey != nil {
+		a.tracer.UpdatedKey(a.keyPhase, true)
+	}
+	return nil
+}
+
+// firstRcvdWithNewKey is called for the first packet of a path protected with the current key phase.
+func (a *updatableAEAD) firstRcvdWithNewKey(rcvTime time.Time) {
+	if a.keyPhase == 0 || a.firstRcvdWithCurrentKey != protocol.InvalidPacketNumber || len(a.firstRcvdOnPath) > 0 {
+		return
+	}
+	// We initiated the key updated, and now we received the first packet protected with the new key phase.
+	// Therefore, we are certain that the peer rolled its keys as well. Start a timer to drop the old keys.
+	a.logger.Debugf("Peer confirmed key update to phase %d", a.keyPhase)
+	a.startKeyDropTimer(rcvTime)
+}
+
+func (a *updatableAEAD) sentWithCurrentKey() bool {
+	return a.firstSentWithCurrentKey != protocol.InvalidPacketNumber || len(a.firstSentOnPath) > 0
+}
+
 func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
 	if a.firstSentWithCurrentKey == protocol.InvalidPacketNumber {
 		a.firstSentWithCurrentKey = pn
@@ -255,6 +293,76 @@ func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byt
 	return a.sendAEAD.Seal(dst, a.nonceBuf, src, ad)
 }
 
+// SealPath seals a packet sent on a multipath path.
+// The 32 bit path ID is placed in front of the 64 bit packet number in the nonce.
+// The key phase is shared by all paths: packets sent on any path count towards the key update.
+func (a *updatableAEAD) SealPath(dst, src []byte, pathID protocol.PathID, pn protocol.PacketNumber, ad []byte) []byte {
+	if _, ok := a.firstSentOnPath[pathID]; !ok {
+		a.firstSentOnPath[pathID] = pn
+	}
+	a.numSentWithCurrentKey++
+	a.putPathNonce(pathID, pn)
+	return a.sendAEAD.Seal(dst, a.pathNonceBuf, src, ad)
+}
+
+// OpenPath opens a packet received on a multipath path.
+// The packet number is decoded by the caller, who tracks the highest packet number of the path.
+func (a *updatableAEAD) OpenPath(dst, src []byte, rcvTime time.Time, pathID protocol.PathID, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	dec, err := a.openPath(dst, src, rcvTime, pathID, pn, kp, ad)
+	if err == ErrDecryptionFailed {
+		a.invalidPacketCount++
+		if a.invalidPacketCount >= a.invalidPacketLimit {
+			return nil, &qerr.TransportError{ErrorCode: qerr.AEADLimitReached}
+		}
+	}
+	return dec, err
+}
+
+func (a *updatableAEAD) openPath(dst, src []byte, rcvTime time.Time, pathID protocol.PathID, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	a.dropPrevKeys(rcvTime)
+	a.putPathNonce(pathID, pn)
+	if kp != a.keyPhase.Bit() {
+		// Packet numbers are only comparable within a path: a packet that is older
+		// than the first one received on this path with the current key phase,
+		// or received before any, was sent before the peer updated.
+		// Once the previous keys are dropped, it can only be the next key phase.
+		if first, ok := a.firstRcvdOnPath[pathID]; a.keyPhase > 0 && a.prevRcvAEAD != nil && (!ok || pn < first) {
+			dec, err := a.prevRcvAEAD.Open(dst, a.pathNonceBuf, src, ad)
+			if err != nil {
+				err = ErrDecryptionFailed
+			}
+			return dec, err
+		}
+		dec, err := a.nextRcvAEAD.Open(dst, a.pathNonceBuf, src, ad)
+		if err != nil {
+			return nil, ErrDecryptionFailed
+		}
+		if err := a.peerUpdatedKeys(rcvTime); err != nil {
+			return nil, err
+		}
+		a.firstRcvdOnPath[pathID] = pn
+		return dec, nil
+	}
+	dec, err := a.rcvAEAD.Open(dst, a.pathNonceBuf, src, ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	a.numRcvdWithCurrentKey++
+	if _, ok := a.firstRcvdOnPath[pathID]; !ok {
+		a.firstRcvdWithNewKey(rcvTime)
+		a.firstRcvdOnPath[pathID] = pn
+	}
+	return dec, nil
+}
+
+func (a *updatableAEAD) putPathNonce(pathID protocol.PathID, pn protocol.PacketNumber) {
+	if a.pathNonceBuf == nil {
+		a.pathNonceBuf = make([]byte, aeadNonceLength)
+	}
+	binary.BigEndian.PutUint32(a.pathNonceBuf[:4], uint32(pathID))
+	binary.BigEndian.PutUint64(a.pathNonceBuf[4:], uint64(pn))
+}
+
 func (a *updatableAEAD) SetLargestAcked(pn protocol.PacketNumber) error {
 	if a.firstSentWithCurrentKey != protocol.InvalidPacketNumber &&
 		pn >= a.firstSentWithCurrentKey && a.numRcvdWithCurrentKey == 0 {
@@ -267,6 +375,22 @@ func (a *updatableAEAD) SetLargestAcked(pn protocol.PacketNumber) error {
 	return nil
 }
 
+// SetLargestPathAcked is SetLargestAcked for the multipath paths other than the initial path.
+func (a *updatableAEAD) SetLargestPathAcked(pathID protocol.PathID, pn protocol.PacketNumber) error {
+	first, ok := a.firstSentOnPath[pathID]
+	if !ok || pn < first {
+		return nil
+	}
+	if a.numRcvdWithCurrentKey == 0 {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.KeyUpdateError,
+			ErrorMessage: fmt.Sprintf("received ACK for key phase %d on path %d, but peer didn't update keys", a.keyPhase, pathID),
+		}
+	}
+	a.pathAckedWithCurrentKey = true
+	return nil
+}
+
 func (a *updatableAEAD) SetHandshakeConfirmed() {
 	a.handshakeConfirmed = true
 }
@@ -280,7 +404,8 @@ func (a *updatableAEAD) updateAllowed() bool {
 		// subsequent key updates as soon as a packet sent with that key phase has been acknowledged
 		(a.firstSentWithCurrentKey != protocol.InvalidPacketNumber &&
 			a.largestAcked != protocol.InvalidPacketNumber &&
-			a.largestAcked >= a.firstSentWithCurrentKey)
+			a.largestAcked >= a.firstSentWithCurrentKey) ||
+		a.pathAckedWithCurrentKey
 }
 
 func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
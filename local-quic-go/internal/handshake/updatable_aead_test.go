@@ -209,6 +209,106 @@ func TestKeyUpdates(t *testing.T) {
 	require.Equal(t, msg, string(decrypted))
 }
 
+func TestUpdatableAEADMultipathNonce(t *testing.T) {
+	client, server, _ := setupEndpoints(t, &utils.RTTStats{})
+
+	encrypted := server.SealPath(nil, []byte(msg), 1, 0x1337, []byte(ad))
+	// the same packet number on path 0 and on path 2 uses a different nonce
+	require.NotEqual(t, encrypted, server.Seal(nil, []byte(msg), 0x1337, []byte(ad)))
+	require.NotEqual(t, encrypted, server.SealPath(nil, []byte(msg), 2, 0x1337, []byte(ad)))
+
+	_, err := client.OpenPath(nil, encrypted, time.Now(), 2, 0x1337, protocol.KeyPhaseZero, []byte(ad))
+	require.Equal(t, ErrDecryptionFailed, err)
+	decrypted, err := client.OpenPath(nil, encrypted, time.Now(), 1, 0x1337, protocol.KeyPhaseZero, []byte(ad))
+	require.NoError(t, err)
+	require.Equal(t, msg, string(decrypted))
+	// path packets don't move the packet number decoding of path 0
+	require.Equal(t, protocol.PacketNumber(0x38), client.DecodePacketNumber(0x38, protocol.PacketNumberLen1))
+
+	// sealing on a path doesn't change the nonce used on path 0
+	encrypted0 := server.Seal(nil, []byte(msg), 0x42, []byte(ad))
+	decrypted, err = client.Open(nil, encrypted0, time.Now(), 0x42, protocol.KeyPhaseZero, []byte(ad))
+	require.NoError(t, err)
+	require.Equal(t, msg, string(decrypted))
+}
+
+func TestUpdatableAEADMultipathKeyUpdate(t *testing.T) {
+	const firstKeyUpdateInterval = 5
+	setKeyUpdateIntervals(t, firstKeyUpdateInterval, KeyUpdateInterval)
+
+	client, server, serverTracer := setupEndpoints(t, &utils.RTTStats{})
+	client.SetHandshakeConfirmed()
+
+	now := time.Now()
+	// packets sent on any path count towards the key update
+	for i := 0; i < firstKeyUpdateInterval; i++ {
+		require.Equal(t, protocol.KeyPhaseZero, client.KeyPhase())
+		client.SealPath(nil, []byte(msg), protocol.PathID(1+i%2), protocol.PacketNumber(i), []byte(ad))
+	}
+	delayed := client.SealPath(nil, []byte(msg), 2, 0x10, []byte(ad))
+	require.Equal(t, protocol.KeyPhaseOne, client.KeyPhase())
+	encrypted1 := client.SealPath(nil, []byte(msg), 1, 0x11, []byte(ad))
+
+	// the server updates when receiving the next key phase on a path...
+	serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), true)
+	decrypted, err := server.OpenPath(nil, encrypted1, now, 1, 0x11, protocol.KeyPhaseOne, []byte(ad))
+	require.NoError(t, err)
+	require.Equal(t, msg, string(decrypted))
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	// ... and still opens packets sent before the update on the other paths
+	decrypted, err = server.OpenPath(nil, delayed, now, 2, 0x10, protocol.KeyPhaseZero, []byte(ad))
+	require.NoError(t, err)
+	require.Equal(t, msg, string(decrypted))
+
+	// the key phase of a path is independent of the packet numbers of the other paths
+	encrypted2 := server.SealPath(nil, []byte(msg), 2, 0x1, []byte(ad))
+	decrypted, err = client.OpenPath(nil, encrypted2, now, 2, 0x1, protocol.KeyPhaseOne, []byte(ad))
+	require.NoError(t, err)
+	require.Equal(t, msg, string(decrypted))
+}
+
+func TestUpdatableAEADMultipathKeyUpdateACK(t *testing.T) {
+	const firstKeyUpdateInterval = 5
+	const keyUpdateInterval = 20
+	setKeyUpdateIntervals(t, firstKeyUpdateInterval, keyUpdateInterval)
+
+	client, server, serverTracer := setupEndpoints(t, &utils.RTTStats{})
+	server.SetHandshakeConfirmed()
+
+	var pn protocol.PacketNumber
+	for i := 0; i < firstKeyUpdateInterval; i++ {
+		server.SealPath(nil, []byte(msg), 1, pn, []byte(ad))
+		pn++
+	}
+	serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false)
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	firstPN := pn
+	for i := 0; i < 2*keyUpdateInterval; i++ {
+		server.SealPath(nil, []byte(msg), 1, pn, []byte(ad))
+		pn++
+	}
+	// no update allowed before receiving an acknowledgement for the current key phase
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	// an ACK for a packet sent with the previous key phase, or on another path, doesn't count
+	require.NoError(t, server.SetLargestPathAcked(1, firstPN-1))
+	require.NoError(t, server.SetLargestPathAcked(2, pn))
+	// the ACK must be sent in the current key phase
+	err := server.SetLargestPathAcked(1, firstPN)
+	var transportErr *qerr.TransportError
+	require.ErrorAs(t, err, &transportErr)
+	require.Equal(t, qerr.KeyUpdateError, transportErr.ErrorCode)
+
+	client.rollKeys()
+	b := client.SealPath(nil, []byte("foobar"), 2, 1, []byte("ad"))
+	_, err = server.OpenPath(nil, b, time.Now(), 2, 1, protocol.KeyPhaseOne, []byte("ad"))
+	require.NoError(t, err)
+	require.NoError(t, server.SetLargestPathAcked(1, firstPN))
+
+	serverTracer.EXPECT().DroppedKey(protocol.KeyPhase(0))
+	serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(2), false)
+	require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+}
+
 // func TestUpdatesKeysWhenReceivingPacketWithNextKeyPhase(t *testing.T) {
 // 	rttStats := utils.RTTStats{}
 // 	mockCtrl := gomock.NewController(t)
@@ -619,3 +619,41 @@ func (c *MockEarlyConnectionSendDatagramCall) DoAndReturn(f func([]byte) error)
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
+
+// Stats mocks base method.
+func (m *MockEarlyConnection) Stats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockEarlyConnectionMockRecorder) Stats() *MockEarlyConnectionStatsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockEarlyConnection)(nil).Stats))
+	return &MockEarlyConnectionStatsCall{Call: call}
+}
+
+// MockEarlyConnectionStatsCall wrap *gomock.Call
+type MockEarlyConnectionStatsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockEarlyConnectionStatsCall) Return(arg0 quic.ConnectionStats) *MockEarlyConnectionStatsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockEarlyConnectionStatsCall) Do(f func() quic.ConnectionStats) *MockEarlyConnectionStatsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockEarlyConnectionStatsCall) DoAndReturn(f func() quic.ConnectionStats) *MockEarlyConnectionStatsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
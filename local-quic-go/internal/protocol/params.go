@@ -146,6 +146,17 @@ const MaxActiveConnectionIDs = 4
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
+// MaxPaths is the maximum value of Config.MaxPaths.
+const MaxPaths = 16
+
+// MaxPathChallenges is the number of PATH_CHALLENGE frames sent on a new multipath path
+// before it is abandoned.
+const MaxPathChallenges = 5
+
+// AmplificationFactor limits the bytes sent to an unvalidated address,
+// to this factor times the bytes received from it (RFC 9000, section 8).
+const AmplificationFactor = 3
+
 // PacketsPerConnectionID is the number of packets we send using one connection ID.
 // If the peer provices us with enough new connection IDs, we switch to a new connection ID.
 const PacketsPerConnectionID = 10000
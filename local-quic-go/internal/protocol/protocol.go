@@ -34,6 +34,13 @@ func (t PacketType) String() string {
 	}
 }
 
+// A PathID identifies a path of a multipath connection (draft-ietf-quic-multipath).
+// Path 0 is the path the handshake was performed on.
+type PathID uint32
+
+// InitialPathID is the ID of the path the handshake was performed on.
+const InitialPathID PathID = 0
+
 type ECN uint8
 
 const (
@@ -95,6 +95,17 @@ func (r *RTTStats) SetMaxAckDelay(mad time.Duration) {
 	r.maxAckDelay = mad
 }
 
+// ResetForPathMigration is called when the connection migrates to a new path.
+// The RTT measured on the old path says nothing about the new one, so all measurements are discarded.
+// The max_ack_delay is a property of the peer and is kept.
+func (r *RTTStats) ResetForPathMigration() {
+	r.hasMeasurement = false
+	r.minRTT = 0
+	r.latestRTT = 0
+	r.smoothedRTT = 0
+	r.meanDeviation = 0
+}
+
 // SetInitialRTT sets the initial RTT.
 // It is used during the 0-RTT handshake when restoring the RTT stats from the session state.
 func (r *RTTStats) SetInitialRTT(t time.Duration) {
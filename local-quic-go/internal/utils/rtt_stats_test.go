@@ -116,3 +116,22 @@ func TestRTTMeasurementAfterRestore(t *testing.T) {
 	require.Equal(t, rtt, rttStats.LatestRTT())
 	require.Equal(t, rtt, rttStats.SmoothedRTT())
 }
+
+func TestRTTStatsResetForPathMigration(t *testing.T) {
+	var rttStats RTTStats
+	rttStats.SetMaxAckDelay(25 * time.Millisecond)
+	rttStats.UpdateRTT(200*time.Millisecond, 0, time.Time{})
+	rttStats.UpdateRTT(300*time.Millisecond, 0, time.Time{})
+	require.NotZero(t, rttStats.SmoothedRTT())
+
+	rttStats.ResetForPathMigration()
+	require.Zero(t, rttStats.MinRTT())
+	require.Zero(t, rttStats.LatestRTT())
+	require.Zero(t, rttStats.SmoothedRTT())
+	require.Zero(t, rttStats.MeanDeviation())
+	require.Equal(t, 25*time.Millisecond, rttStats.MaxAckDelay())
+	// the first measurement on the new path is taken as is
+	rttStats.UpdateRTT(50*time.Millisecond, 0, time.Time{})
+	require.Equal(t, 50*time.Millisecond, rttStats.MinRTT())
+	require.Equal(t, 50*time.Millisecond, rttStats.SmoothedRTT())
+}
@@ -12,8 +12,10 @@ import (
 
 var errInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK ranges")
 
-// An AckFrame is an ACK frame
+// An AckFrame is an ACK frame.
+// With multipath, an AckFrame for a path other than the initial path is a PATH_ACK frame.
 type AckFrame struct {
+	PathID    protocol.PathID
 	AckRanges []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 	DelayTime time.Duration
 
@@ -23,7 +25,19 @@ type AckFrame struct {
 // parseAckFrame reads an ACK frame
 func parseAckFrame(frame *AckFrame, b []byte, typ uint64, ackDelayExponent uint8, _ protocol.Version) (int, error) {
 	startLen := len(b)
-	ecn := typ == ackECNFrameType
+	ecn := typ == ackECNFrameType || typ == pathAckECNFrameType
+
+	if typ == pathAckFrameType || typ == pathAckECNFrameType {
+		pathID, l, err := quicvarint.Parse(b)
+		if err != nil {
+			return 0, replaceUnexpectedEOF(err)
+		}
+		if pathID > math.MaxUint32 {
+			return 0, errors.New("invalid path ID")
+		}
+		b = b[l:]
+		frame.PathID = protocol.PathID(pathID)
+	}
 
 	la, l, err := quicvarint.Parse(b)
 	if err != nil {
@@ -121,9 +135,16 @@ func parseAckFrame(frame *AckFrame, b []byte, typ uint64, ackDelayExponent uint8
 // Append appends an ACK frame.
 func (f *AckFrame) Append(b []byte, _ protocol.Version) ([]byte, error) {
 	hasECN := f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0
-	if hasECN {
+	switch {
+	case f.PathID != 0 && hasECN:
+		b = quicvarint.Append(b, pathAckECNFrameType)
+		b = quicvarint.Append(b, uint64(f.PathID))
+	case f.PathID != 0:
+		b = quicvarint.Append(b, pathAckFrameType)
+		b = quicvarint.Append(b, uint64(f.PathID))
+	case hasECN:
 		b = append(b, ackECNFrameType)
-	} else {
+	default:
 		b = append(b, ackFrameType)
 	}
 	b = quicvarint.Append(b, uint64(f.LargestAcked()))
@@ -157,6 +178,10 @@ func (f *AckFrame) Length(_ protocol.Version) protocol.ByteCount {
 	numRanges := f.numEncodableAckRanges()
 
 	length := 1 + quicvarint.Len(uint64(largestAcked)) + quicvarint.Len(encodeAckDelay(f.DelayTime))
+	if f.PathID != 0 {
+		// PATH_ACK and PATH_ACK_ECN use the same frame type length
+		length += quicvarint.Len(pathAckFrameType) - 1 + quicvarint.Len(uint64(f.PathID))
+	}
 
 	length += quicvarint.Len(uint64(numRanges - 1))
 	lowestInFirstRange := f.AckRanges[0].Smallest
@@ -259,6 +284,7 @@ func (f *AckFrame) AcksPacket(p protocol.PacketNumber) bool {
 }
 
 func (f *AckFrame) Reset() {
+	f.PathID = 0
 	f.DelayTime = 0
 	f.ECT0 = 0
 	f.ECT1 = 0
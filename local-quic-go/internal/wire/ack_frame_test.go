@@ -340,6 +340,28 @@ func TestWriteACKLimitMaxSize(t *testing.T) {
 	require.Less(t, len(frame.AckRanges), numRanges) // make sure we dropped some ranges
 }
 
+func TestWritePathACKFrame(t *testing.T) {
+	f := &AckFrame{
+		PathID:    5,
+		AckRanges: []AckRange{{Smallest: 100, Largest: 1337}},
+		ECT1:      3,
+	}
+	b, err := f.Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	require.Len(t, b, int(f.Length(protocol.Version1)))
+	typ, l, err := quicvarint.Parse(b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(pathAckECNFrameType), typ)
+	var frame AckFrame
+	n, err := parseAckFrame(&frame, b[l:], typ, protocol.AckDelayExponent, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, len(b)-l, n)
+	require.Equal(t, f, &frame)
+
+	frame.Reset()
+	require.Zero(t, frame.PathID)
+}
+
 func TestAckRangeValidator(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -36,10 +36,21 @@ const (
 	handshakeDoneFrameType      = 0x1e
 )
 
+// Frame types of the multipath extension (draft-ietf-quic-multipath-10).
+const (
+	pathAckFrameType                = 0x15228c00
+	pathAckECNFrameType             = 0x15228c01
+	pathAbandonFrameType            = 0x15228c05
+	pathNewConnectionIDFrameType    = 0x15228c09
+	pathRetireConnectionIDFrameType = 0x15228c0a
+	maxPathIDFrameType              = 0x15228c0c
+)
+
 // The FrameParser parses QUIC frames, one by one.
 type FrameParser struct {
 	ackDelayExponent  uint8
 	supportsDatagrams bool
+	supportsMultipath bool
 
 	// To avoid allocating when parsing, keep a single ACK frame struct.
 	// It is used over and over again.
@@ -95,7 +106,7 @@ func (p *FrameParser) parseFrame(b []byte, typ uint64, encLevel protocol.Encrypt
 	var frame Frame
 	var err error
 	var l int
-	if typ&0xf8 == 0x8 {
+	if typ&^0x7 == 0x8 {
 		frame, l, err = parseStreamFrame(b, typ, v)
 	} else {
 		switch typ {
@@ -146,7 +157,39 @@ func (p *FrameParser) parseFrame(b []byte, typ uint64, encLevel protocol.Encrypt
 				frame, l, err = parseDatagramFrame(b, typ, v)
 				break
 			}
-			fallthrough
+			err = errors.New("unknown frame type")
+		case pathAckFrameType, pathAckECNFrameType:
+			if !p.supportsMultipath {
+				err = errors.New("unknown frame type")
+				break
+			}
+			p.ackFrame.Reset()
+			l, err = parseAckFrame(p.ackFrame, b, typ, p.ackDelayExponent, v)
+			frame = p.ackFrame
+		case pathAbandonFrameType:
+			if !p.supportsMultipath {
+				err = errors.New("unknown frame type")
+				break
+			}
+			frame, l, err = parsePathAbandonFrame(b, v)
+		case pathNewConnectionIDFrameType:
+			if !p.supportsMultipath {
+				err = errors.New("unknown frame type")
+				break
+			}
+			frame, l, err = parsePathNewConnectionIDFrame(b, v)
+		case pathRetireConnectionIDFrameType:
+			if !p.supportsMultipath {
+				err = errors.New("unknown frame type")
+				break
+			}
+			frame, l, err = parsePathRetireConnectionIDFrame(b, v)
+		case maxPathIDFrameType:
+			if !p.supportsMultipath {
+				err = errors.New("unknown frame type")
+				break
+			}
+			frame, l, err = parseMaxPathIDFrame(b, v)
 		default:
 			err = errors.New("unknown frame type")
 		}
@@ -163,15 +206,18 @@ func (p *FrameParser) parseFrame(b []byte, typ uint64, encLevel protocol.Encrypt
 func (p *FrameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionLevel) bool {
 	switch encLevel {
 	case protocol.EncryptionInitial, protocol.EncryptionHandshake:
-		switch f.(type) {
-		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *PingFrame:
+		switch f := f.(type) {
+		case *AckFrame:
+			return f.PathID == 0
+		case *CryptoFrame, *ConnectionCloseFrame, *PingFrame:
 			return true
 		default:
 			return false
 		}
 	case protocol.Encryption0RTT:
 		switch f.(type) {
-		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame,
+			*PathAbandonFrame, *PathNewConnectionIDFrame, *PathRetireConnectionIDFrame, *MaxPathIDFrame:
 			return false
 		default:
 			return true
@@ -183,6 +229,12 @@ func (p *FrameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 	}
 }
 
+// EnableMultipath enables parsing of the multipath extension frames.
+// It is called once the peer announced support in its transport parameters.
+func (p *FrameParser) EnableMultipath() {
+	p.supportsMultipath = true
+}
+
 // SetAckDelayExponent sets the acknowledgment delay exponent (sent in the transport parameters).
 // This value is used to scale the ACK Delay field in the ACK frame.
 func (p *FrameParser) SetAckDelayExponent(exp uint8) {
@@ -345,6 +345,52 @@ func TestFrameParsingErrorsWhenDatagramFramesAreNotSupported(t *testing.T) {
 	require.Equal(t, "unknown frame type", transportErr.ErrorMessage)
 }
 
+func TestFrameParsingUnpacksMultipathFrames(t *testing.T) {
+	parser := NewFrameParser(true)
+	parser.EnableMultipath()
+	for _, f := range []Frame{
+		&AckFrame{PathID: 3, AckRanges: []AckRange{{Smallest: 1, Largest: 0x13}}},
+		&AckFrame{PathID: 1, AckRanges: []AckRange{{Smallest: 1, Largest: 5}}, ECT0: 2, ECNCE: 1},
+		&PathAbandonFrame{PathID: 2, ErrorCode: 0x42},
+		&PathNewConnectionIDFrame{
+			PathID:              1,
+			SequenceNumber:      3,
+			RetirePriorTo:       1,
+			ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			StatelessResetToken: protocol.StatelessResetToken{0xe, 0xa, 0xd},
+		},
+		&PathRetireConnectionIDFrame{PathID: 7, SequenceNumber: 2},
+		&MaxPathIDFrame{MaxPathID: 9},
+	} {
+		b, err := f.Append(nil, protocol.Version1)
+		require.NoError(t, err)
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		require.NoError(t, err)
+		require.Equal(t, f, frame)
+		require.Equal(t, len(b), l)
+	}
+}
+
+func TestFrameParsingErrorsWhenMultipathIsNotEnabled(t *testing.T) {
+	parser := NewFrameParser(true)
+	b, err := (&PathAbandonFrame{PathID: 1}).Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	_, _, err = parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+	var transportErr *qerr.TransportError
+	require.ErrorAs(t, err, &transportErr)
+	require.Equal(t, uint64(pathAbandonFrameType), transportErr.FrameType)
+	require.Equal(t, "unknown frame type", transportErr.ErrorMessage)
+}
+
+func TestFrameParsingRejectsPathAckBefore1RTT(t *testing.T) {
+	parser := NewFrameParser(true)
+	parser.EnableMultipath()
+	b, err := (&AckFrame{PathID: 1, AckRanges: []AckRange{{Smallest: 1, Largest: 2}}}).Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	_, _, err = parser.ParseNext(b, protocol.EncryptionHandshake, protocol.Version1)
+	require.ErrorContains(t, err, "not allowed at encryption level Handshake")
+}
+
 func TestFrameParsingErrorsOnInvalidType(t *testing.T) {
 	parser := NewFrameParser(true)
 	_, _, err := parser.ParseNext(encodeVarInt(0x42), protocol.Encryption1RTT, protocol.Version1)
@@ -31,6 +31,9 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		if hasECN {
 			ecn = fmt.Sprintf(", ECT0: %d, ECT1: %d, CE: %d", f.ECT0, f.ECT1, f.ECNCE)
 		}
+		if f.PathID != 0 {
+			ecn += fmt.Sprintf(", PathID: %d", f.PathID)
+		}
 		if len(f.AckRanges) > 1 {
 			ackRanges := make([]string, len(f.AckRanges))
 			for i, r := range f.AckRanges {
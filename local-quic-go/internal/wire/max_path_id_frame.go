@@ -0,0 +1,30 @@
+package wire
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// A MaxPathIDFrame is a MAX_PATH_ID frame (draft-ietf-quic-multipath)
+type MaxPathIDFrame struct {
+	MaxPathID protocol.PathID
+}
+
+func parseMaxPathIDFrame(b []byte, _ protocol.Version) (*MaxPathIDFrame, int, error) {
+	pathID, l, err := parsePathID(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &MaxPathIDFrame{MaxPathID: pathID}, l, nil
+}
+
+func (f *MaxPathIDFrame) Append(b []byte, _ protocol.Version) ([]byte, error) {
+	b = quicvarint.Append(b, maxPathIDFrameType)
+	b = quicvarint.Append(b, uint64(f.MaxPathID))
+	return b, nil
+}
+
+// Length of a written frame
+func (f *MaxPathIDFrame) Length(protocol.Version) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(maxPathIDFrameType) + quicvarint.Len(uint64(f.MaxPathID)))
+}
@@ -0,0 +1,38 @@
+package wire
+
+import (
+	"io"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaxPathIDFrame(t *testing.T) {
+	data := encodeVarInt(0xdecafbad)
+	frame, l, err := parseMaxPathIDFrame(data, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, protocol.PathID(0xdecafbad), frame.MaxPathID)
+	require.Equal(t, len(data), l)
+}
+
+func TestParseMaxPathIDFrameErrorsOnEOFs(t *testing.T) {
+	data := encodeVarInt(0xdecafbad)
+	for i := range data {
+		_, _, err := parseMaxPathIDFrame(data[:i], protocol.Version1)
+		require.Equal(t, io.EOF, err)
+	}
+	_, _, err := parseMaxPathIDFrame(encodeVarInt(1<<32), protocol.Version1)
+	require.EqualError(t, err, "invalid path ID")
+}
+
+func TestWriteMaxPathIDFrame(t *testing.T) {
+	frame := &MaxPathIDFrame{MaxPathID: 12}
+	b, err := frame.Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	expected := encodeVarInt(maxPathIDFrameType)
+	expected = append(expected, encodeVarInt(12)...)
+	require.Equal(t, expected, b)
+	require.Len(t, b, int(frame.Length(protocol.Version1)))
+}
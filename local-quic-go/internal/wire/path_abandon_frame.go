@@ -0,0 +1,53 @@
+package wire
+
+import (
+	"errors"
+	"math"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// A PathAbandonFrame is a PATH_ABANDON frame (draft-ietf-quic-multipath)
+type PathAbandonFrame struct {
+	PathID    protocol.PathID
+	ErrorCode uint64
+}
+
+func parsePathAbandonFrame(b []byte, _ protocol.Version) (*PathAbandonFrame, int, error) {
+	startLen := len(b)
+	pathID, l, err := parsePathID(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	b = b[l:]
+	ec, l, err := quicvarint.Parse(b)
+	if err != nil {
+		return nil, 0, replaceUnexpectedEOF(err)
+	}
+	b = b[l:]
+	return &PathAbandonFrame{PathID: pathID, ErrorCode: ec}, startLen - len(b), nil
+}
+
+func (f *PathAbandonFrame) Append(b []byte, _ protocol.Version) ([]byte, error) {
+	b = quicvarint.Append(b, pathAbandonFrameType)
+	b = quicvarint.Append(b, uint64(f.PathID))
+	b = quicvarint.Append(b, f.ErrorCode)
+	return b, nil
+}
+
+// Length of a written frame
+func (f *PathAbandonFrame) Length(protocol.Version) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(pathAbandonFrameType) + quicvarint.Len(uint64(f.PathID)) + quicvarint.Len(f.ErrorCode))
+}
+
+func parsePathID(b []byte) (protocol.PathID, int, error) {
+	id, l, err := quicvarint.Parse(b)
+	if err != nil {
+		return 0, 0, replaceUnexpectedEOF(err)
+	}
+	if id > math.MaxUint32 {
+		return 0, 0, errors.New("invalid path ID")
+	}
+	return protocol.PathID(id), l, nil
+}
@@ -0,0 +1,47 @@
+package wire
+
+import (
+	"io"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathAbandonFrame(t *testing.T) {
+	data := encodeVarInt(3)                    // path ID
+	data = append(data, encodeVarInt(0x42)...) // error code
+	frame, l, err := parsePathAbandonFrame(data, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, protocol.PathID(3), frame.PathID)
+	require.Equal(t, uint64(0x42), frame.ErrorCode)
+	require.Equal(t, len(data), l)
+}
+
+func TestParsePathAbandonFrameInvalidPathID(t *testing.T) {
+	data := encodeVarInt(1 << 32)           // path ID
+	data = append(data, encodeVarInt(0)...) // error code
+	_, _, err := parsePathAbandonFrame(data, protocol.Version1)
+	require.EqualError(t, err, "invalid path ID")
+}
+
+func TestParsePathAbandonFrameErrorsOnEOFs(t *testing.T) {
+	data := encodeVarInt(0x1337)                 // path ID
+	data = append(data, encodeVarInt(0xcafe)...) // error code
+	for i := range data {
+		_, _, err := parsePathAbandonFrame(data[:i], protocol.Version1)
+		require.Equal(t, io.EOF, err)
+	}
+}
+
+func TestWritePathAbandonFrame(t *testing.T) {
+	frame := &PathAbandonFrame{PathID: 0x1337, ErrorCode: 7}
+	b, err := frame.Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	expected := encodeVarInt(pathAbandonFrameType)
+	expected = append(expected, encodeVarInt(0x1337)...)
+	expected = append(expected, encodeVarInt(7)...)
+	require.Equal(t, expected, b)
+	require.Len(t, b, int(frame.Length(protocol.Version1)))
+}
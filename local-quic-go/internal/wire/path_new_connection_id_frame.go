@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"fmt"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// A PathNewConnectionIDFrame is a PATH_NEW_CONNECTION_ID frame (draft-ietf-quic-multipath).
+// Apart from the path ID, its fields are the ones of the NEW_CONNECTION_ID frame.
+type PathNewConnectionIDFrame struct {
+	PathID              protocol.PathID
+	SequenceNumber      uint64
+	RetirePriorTo       uint64
+	ConnectionID        protocol.ConnectionID
+	StatelessResetToken protocol.StatelessResetToken
+}
+
+func parsePathNewConnectionIDFrame(b []byte, v protocol.Version) (*PathNewConnectionIDFrame, int, error) {
+	pathID, l, err := parsePathID(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, fl, err := parseNewConnectionIDFrame(b[l:], v)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &PathNewConnectionIDFrame{
+		PathID:              pathID,
+		SequenceNumber:      f.SequenceNumber,
+		RetirePriorTo:       f.RetirePriorTo,
+		ConnectionID:        f.ConnectionID,
+		StatelessResetToken: f.StatelessResetToken,
+	}, l + fl, nil
+}
+
+func (f *PathNewConnectionIDFrame) Append(b []byte, _ protocol.Version) ([]byte, error) {
+	b = quicvarint.Append(b, pathNewConnectionIDFrameType)
+	b = quicvarint.Append(b, uint64(f.PathID))
+	b = quicvarint.Append(b, f.SequenceNumber)
+	b = quicvarint.Append(b, f.RetirePriorTo)
+	connIDLen := f.ConnectionID.Len()
+	if connIDLen > protocol.MaxConnIDLen {
+		return nil, fmt.Errorf("invalid connection ID length: %d", connIDLen)
+	}
+	b = append(b, uint8(connIDLen))
+	b = append(b, f.ConnectionID.Bytes()...)
+	b = append(b, f.StatelessResetToken[:]...)
+	return b, nil
+}
+
+// Length of a written frame
+func (f *PathNewConnectionIDFrame) Length(protocol.Version) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(pathNewConnectionIDFrameType)+quicvarint.Len(uint64(f.PathID))+
+		quicvarint.Len(f.SequenceNumber)+quicvarint.Len(f.RetirePriorTo)+1 /* connection ID length */ +f.ConnectionID.Len()) + 16
+}
@@ -0,0 +1,61 @@
+package wire
+
+import (
+	"io"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathNewConnectionIDFrame(t *testing.T) {
+	data := encodeVarInt(4)                            // path ID
+	data = append(data, encodeVarInt(0xdeadbeef)...)   // sequence number
+	data = append(data, encodeVarInt(0xcafe)...)       // retire prior to
+	data = append(data, 4)                             // connection ID length
+	data = append(data, []byte{1, 2, 3, 4}...)         // connection ID
+	data = append(data, []byte("deadbeefdecafbad")...) // stateless reset token
+	frame, l, err := parsePathNewConnectionIDFrame(data, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, protocol.PathID(4), frame.PathID)
+	require.Equal(t, uint64(0xdeadbeef), frame.SequenceNumber)
+	require.Equal(t, uint64(0xcafe), frame.RetirePriorTo)
+	require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4}), frame.ConnectionID)
+	require.Equal(t, "deadbeefdecafbad", string(frame.StatelessResetToken[:]))
+	require.Equal(t, len(data), l)
+}
+
+func TestParsePathNewConnectionIDErrorsOnEOFs(t *testing.T) {
+	data := encodeVarInt(4)                            // path ID
+	data = append(data, encodeVarInt(0xdeadbeef)...)   // sequence number
+	data = append(data, encodeVarInt(0xcafe)...)       // retire prior to
+	data = append(data, 4)                             // connection ID length
+	data = append(data, []byte{1, 2, 3, 4}...)         // connection ID
+	data = append(data, []byte("deadbeefdecafbad")...) // stateless reset token
+	for i := range data {
+		_, _, err := parsePathNewConnectionIDFrame(data[:i], protocol.Version1)
+		require.Equal(t, io.EOF, err)
+	}
+}
+
+func TestWritePathNewConnectionIDFrame(t *testing.T) {
+	frame := &PathNewConnectionIDFrame{
+		PathID:              2,
+		SequenceNumber:      0x1337,
+		RetirePriorTo:       0x42,
+		ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6}),
+		StatelessResetToken: protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+	b, err := frame.Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	expected := encodeVarInt(pathNewConnectionIDFrameType)
+	expected = append(expected, encodeVarInt(2)...)
+	expected = append(expected, encodeVarInt(0x1337)...)
+	expected = append(expected, encodeVarInt(0x42)...)
+	expected = append(expected, 6)
+	expected = append(expected, []byte{1, 2, 3, 4, 5, 6}...)
+	expected = append(expected, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}...)
+	require.Equal(t, expected, b)
+	require.Len(t, b, int(frame.Length(protocol.Version1)))
+}
@@ -0,0 +1,40 @@
+package wire
+
+import (
+	"io"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathRetireConnectionID(t *testing.T) {
+	data := encodeVarInt(2)                          // path ID
+	data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+	frame, l, err := parsePathRetireConnectionIDFrame(data, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, protocol.PathID(2), frame.PathID)
+	require.Equal(t, uint64(0xdeadbeef), frame.SequenceNumber)
+	require.Equal(t, len(data), l)
+}
+
+func TestParsePathRetireConnectionIDErrorsOnEOFs(t *testing.T) {
+	data := encodeVarInt(2)                          // path ID
+	data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+	for i := range data {
+		_, _, err := parsePathRetireConnectionIDFrame(data[:i], protocol.Version1)
+		require.Equal(t, io.EOF, err)
+	}
+}
+
+func TestWritePathRetireConnectionID(t *testing.T) {
+	frame := &PathRetireConnectionIDFrame{PathID: 1, SequenceNumber: 0x1337}
+	b, err := frame.Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	expected := encodeVarInt(pathRetireConnectionIDFrameType)
+	expected = append(expected, encodeVarInt(1)...)
+	expected = append(expected, encodeVarInt(0x1337)...)
+	require.Equal(t, expected, b)
+	require.Len(t, b, int(frame.Length(protocol.Version1)))
+}
@@ -99,6 +99,7 @@ func TestMarshalAndUnmarshalTransportParameters(t *testing.T) {
 		ActiveConnectionIDLimit:         2 + getRandomValueUpTo(quicvarint.Max-2),
 		MaxUDPPayloadSize:               1200 + protocol.ByteCount(getRandomValueUpTo(quicvarint.Max-1200)),
 		MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+		MaxPathID:                       protocol.PathID(getRandomValueUpTo(math.MaxUint32)),
 	}
 	data := params.Marshal(protocol.PerspectiveServer)
 
@@ -121,6 +122,18 @@ func TestMarshalAndUnmarshalTransportParameters(t *testing.T) {
 	require.Equal(t, params.ActiveConnectionIDLimit, p.ActiveConnectionIDLimit)
 	require.Equal(t, params.MaxUDPPayloadSize, p.MaxUDPPayloadSize)
 	require.Equal(t, params.MaxDatagramFrameSize, p.MaxDatagramFrameSize)
+	require.Equal(t, params.MaxPathID, p.MaxPathID)
+}
+
+func TestMarshalWithoutMaxPathID(t *testing.T) {
+	data := (&TransportParameters{
+		StatelessResetToken:     &protocol.StatelessResetToken{},
+		ActiveConnectionIDLimit: 2,
+	}).Marshal(protocol.PerspectiveServer)
+	require.False(t, bytes.Contains(data, quicvarint.Append(nil, uint64(initialMaxPathIDParameterID))))
+	p := &TransportParameters{}
+	require.NoError(t, p.Unmarshal(data, protocol.PerspectiveServer))
+	require.Zero(t, p.MaxPathID)
 }
 
 func TestMarshalAdditionalTransportParameters(t *testing.T) {
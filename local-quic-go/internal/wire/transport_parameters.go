@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/netip"
 	"slices"
 	"time"
@@ -45,6 +46,8 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// draft-ietf-quic-multipath-10
+	initialMaxPathIDParameterID transportParameterID = 0x0f739bbc1b666d0c
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -83,6 +86,10 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	// MaxPathID is the initial_max_path_id of the multipath extension.
+	// It is only sent if larger than 0: a peer that doesn't send it doesn't use multipath.
+	MaxPathID protocol.PathID
 }
 
 // Unmarshal the transport parameters
@@ -140,6 +147,7 @@ func (p *TransportParameters) unmarshal(b []byte, sentBy protocol.Perspective, f
 			initialMaxStreamsUniParameterID,
 			maxAckDelayParameterID,
 			maxDatagramFrameSizeParameterID,
+			initialMaxPathIDParameterID,
 			ackDelayExponentParameterID:
 			if err := p.readNumericTransportParameter(b, paramID, int(paramLen)); err != nil {
 				return err
@@ -322,6 +330,11 @@ func (p *TransportParameters) readNumericTransportParameter(b []byte, paramID tr
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case initialMaxPathIDParameterID:
+		if val > math.MaxUint32 {
+			return fmt.Errorf("invalid value for initial_max_path_id: %d", val)
+		}
+		p.MaxPathID = protocol.PathID(val)
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -419,6 +432,10 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		b = p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// initial_max_path_id
+	if p.MaxPathID > 0 {
+		b = p.marshalVarintParam(b, initialMaxPathIDParameterID, uint64(p.MaxPathID))
+	}
 
 	if pers == protocol.PerspectiveClient && len(AdditionalTransportParametersClient) > 0 {
 		for k, v := range AdditionalTransportParametersClient {
@@ -527,6 +544,10 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.MaxPathID > 0 {
+		logString += ", MaxPathID: %d"
+		logParams = append(logParams, p.MaxPathID)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -44,6 +44,14 @@ type (
 	StreamsBlockedFrame = wire.StreamsBlockedFrame
 	// A StreamDataBlockedFrame is a STREAM_DATA_BLOCKED frame.
 	StreamDataBlockedFrame = wire.StreamDataBlockedFrame
+	// A PathAbandonFrame is a PATH_ABANDON frame (multipath).
+	PathAbandonFrame = wire.PathAbandonFrame
+	// A PathNewConnectionIDFrame is a PATH_NEW_CONNECTION_ID frame (multipath).
+	PathNewConnectionIDFrame = wire.PathNewConnectionIDFrame
+	// A PathRetireConnectionIDFrame is a PATH_RETIRE_CONNECTION_ID frame (multipath).
+	PathRetireConnectionIDFrame = wire.PathRetireConnectionIDFrame
+	// A MaxPathIDFrame is a MAX_PATH_ID frame (multipath).
+	MaxPathIDFrame = wire.MaxPathIDFrame
 )
 
 // A CryptoFrame is a CRYPTO frame.
@@ -187,6 +187,42 @@ func (c *MockConnRunnerRemoveCall) DoAndReturn(f func(protocol.ConnectionID)) *M
 	return c
 }
 
+// RemoveHandler mocks base method.
+func (m *MockConnRunner) RemoveHandler(arg0 packetHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveHandler", arg0)
+}
+
+// RemoveHandler indicates an expected call of RemoveHandler.
+func (mr *MockConnRunnerMockRecorder) RemoveHandler(arg0 any) *MockConnRunnerRemoveHandlerCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveHandler", reflect.TypeOf((*MockConnRunner)(nil).RemoveHandler), arg0)
+	return &MockConnRunnerRemoveHandlerCall{Call: call}
+}
+
+// MockConnRunnerRemoveHandlerCall wrap *gomock.Call
+type MockConnRunnerRemoveHandlerCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnRunnerRemoveHandlerCall) Return() *MockConnRunnerRemoveHandlerCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnRunnerRemoveHandlerCall) Do(f func(packetHandler)) *MockConnRunnerRemoveHandlerCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnRunnerRemoveHandlerCall) DoAndReturn(f func(packetHandler)) *MockConnRunnerRemoveHandlerCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // RemoveResetToken mocks base method.
 func (m *MockConnRunner) RemoveResetToken(arg0 protocol.StatelessResetToken) {
 	m.ctrl.T.Helper()
@@ -339,6 +339,42 @@ func (c *MockPacketHandlerManagerRemoveCall) DoAndReturn(f func(protocol.Connect
 	return c
 }
 
+// RemoveHandler mocks base method.
+func (m *MockPacketHandlerManager) RemoveHandler(arg0 packetHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveHandler", arg0)
+}
+
+// RemoveHandler indicates an expected call of RemoveHandler.
+func (mr *MockPacketHandlerManagerMockRecorder) RemoveHandler(arg0 any) *MockPacketHandlerManagerRemoveHandlerCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveHandler", reflect.TypeOf((*MockPacketHandlerManager)(nil).RemoveHandler), arg0)
+	return &MockPacketHandlerManagerRemoveHandlerCall{Call: call}
+}
+
+// MockPacketHandlerManagerRemoveHandlerCall wrap *gomock.Call
+type MockPacketHandlerManagerRemoveHandlerCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPacketHandlerManagerRemoveHandlerCall) Return() *MockPacketHandlerManagerRemoveHandlerCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPacketHandlerManagerRemoveHandlerCall) Do(f func(packetHandler)) *MockPacketHandlerManagerRemoveHandlerCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPacketHandlerManagerRemoveHandlerCall) DoAndReturn(f func(packetHandler)) *MockPacketHandlerManagerRemoveHandlerCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // RemoveResetToken mocks base method.
 func (m *MockPacketHandlerManager) RemoveResetToken(arg0 protocol.StatelessResetToken) {
 	m.ctrl.T.Helper()
@@ -619,6 +619,44 @@ func (c *MockQUICConnSendDatagramCall) DoAndReturn(f func([]byte) error) *MockQU
 	return c
 }
 
+// Stats mocks base method.
+func (m *MockQUICConn) Stats() ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockQUICConnMockRecorder) Stats() *MockQUICConnStatsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockQUICConn)(nil).Stats))
+	return &MockQUICConnStatsCall{Call: call}
+}
+
+// MockQUICConnStatsCall wrap *gomock.Call
+type MockQUICConnStatsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockQUICConnStatsCall) Return(arg0 ConnectionStats) *MockQUICConnStatsCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockQUICConnStatsCall) Do(f func() ConnectionStats) *MockQUICConnStatsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockQUICConnStatsCall) DoAndReturn(f func() ConnectionStats) *MockQUICConnStatsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // closeWithTransportError mocks base method.
 func (m *MockQUICConn) closeWithTransportError(arg0 qerr.TransportErrorCode) {
 	m.ctrl.T.Helper()
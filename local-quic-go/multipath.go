@@ -0,0 +1,984 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// This file implements a subset of the multipath extension (draft-ietf-quic-multipath-10):
+//   - Paths are opened by the client, each on its own Transport (i.e. its own local socket).
+//     All paths of a connection reach the same server socket.
+//   - Every path has its own packet number space, RTT estimate and congestion controller,
+//     and is acknowledged with PATH_ACK frames sent on the path itself.
+//   - Additional paths only carry DATAGRAM frames and path-bound frames (PATH_CHALLENGE, PATH_RESPONSE, PING).
+//     Stream data and all other control frames are sent on the initial path.
+//   - One connection ID is issued per path ID, and path IDs are never reused:
+//     when a path is abandoned, MAX_PATH_ID makes room for a new one.
+//   - The key phase is shared by all paths: packets sent and received on any path count towards
+//     the next key update, and a key update initiated on one path applies to all of them.
+
+// ErrMultipathNotNegotiated is returned by OpenPath and AcceptPath if multipath wasn't
+// enabled using Config.MaxPaths, or if the peer doesn't support it.
+var ErrMultipathNotNegotiated = errors.New("multipath not negotiated")
+
+var (
+	errPathClosed           = errors.New("path closed")
+	errPathAbandonedByPeer  = errors.New("path abandoned by peer")
+	errPathValidationFailed = errors.New("path validation failed")
+	errPathIdleTimeout      = errors.New("path idle timeout")
+)
+
+// A Path is an additional path of a multipath connection.
+// It is obtained from MultipathConnection.OpenPath on the client and MultipathConnection.AcceptPath on the server.
+type Path struct {
+	id   PathID
+	conn *connection
+
+	sendConn  sendConn
+	sendQueue sender
+	// blocked is set when the send queue is full, so the run loop is woken up once it drains
+	blocked atomic.Bool
+	// only set for paths opened by the client
+	transport *Transport
+
+	localConnID protocol.ConnectionID
+	destConnID  protocol.ConnectionID
+
+	rttStats              *utils.RTTStats
+	sentPacketHandler     ackhandler.SentPacketHandler
+	receivedPacketHandler ackhandler.ReceivedPacketHandler
+	retransmissionQueue   *retransmissionQueue
+	frames                pathFrameSource
+	packer                *packetPacker
+	datagramQueue         *datagramQueue
+	maxPacketSize         protocol.ByteCount
+
+	highestRcvdPN     protocol.PacketNumber
+	lastPacketRcvd    time.Time
+	keepAlivePingSent bool
+	pacingDeadline    time.Time
+
+	challenges        [][8]byte
+	challengeDeadline time.Time
+	validated         bool
+	validatedChan     chan struct{} // closed once the path is validated
+
+	smoothedRTT atomic.Int64
+
+	ctx       context.Context
+	ctxCancel context.CancelCauseFunc
+}
+
+// ID returns the path ID.
+func (p *Path) ID() PathID { return p.id }
+
+// LocalAddr returns the local address of the path.
+func (p *Path) LocalAddr() net.Addr { return p.sendConn.LocalAddr() }
+
+// RemoteAddr returns the address of the peer on this path.
+func (p *Path) RemoteAddr() net.Addr { return p.sendConn.RemoteAddr() }
+
+// SmoothedRTT returns the smoothed RTT of the path.
+// It is 0 until the first PATH_ACK was received.
+func (p *Path) SmoothedRTT() time.Duration { return time.Duration(p.smoothedRTT.Load()) }
+
+// Stats returns the transport statistics of the path.
+func (p *Path) Stats() ConnectionStats { return newConnectionStats(p.sentPacketHandler.Stats()) }
+
+// Context returns a context that is cancelled when the path is closed.
+func (p *Path) Context() context.Context { return p.ctx }
+
+// SendDatagram sends a datagram on this path, see Connection.SendDatagram.
+func (p *Path) SendDatagram(b []byte) error {
+	if !p.conn.supportsDatagrams() {
+		return errors.New("datagram support disabled")
+	}
+	if p.ctx.Err() != nil {
+		return context.Cause(p.ctx)
+	}
+	f := &wire.DatagramFrame{DataLenPresent: true}
+	maxDataLen := min(
+		f.MaxDataLen(p.conn.peerParams.MaxDatagramFrameSize, p.conn.version),
+		estimateMaxPayloadSize(p.maxPacketSize),
+	)
+	if protocol.ByteCount(len(b)) > maxDataLen {
+		return &DatagramTooLargeError{MaxDatagramPayloadSize: int64(maxDataLen)}
+	}
+	f.Data = make([]byte, len(b))
+	copy(f.Data, b)
+	return p.datagramQueue.Add(f)
+}
+
+// ReceiveDatagram gets a datagram received on this path.
+func (p *Path) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return p.datagramQueue.Receive(ctx)
+}
+
+// Close abandons the path.
+// The connection stays open.
+func (p *Path) Close() error {
+	m := p.conn.multipath
+	m.queueOp(func() { m.closePath(p.id, errPathClosed) })
+	return nil
+}
+
+func (p *Path) queueChallenge(now time.Time) {
+	var data [8]byte
+	_, _ = rand.Read(data[:])
+	p.challenges = append(p.challenges, data)
+	p.frames.queue(&wire.PathChallengeFrame{Data: data})
+	// back off exponentially, the path might be lossy
+	p.challengeDeadline = now.Add(p.rttStats.PTO(false) << (len(p.challenges) - 1))
+}
+
+func (p *Path) idleTimeout() time.Duration {
+	return max(p.conn.idleTimeout, p.rttStats.PTO(true)*3)
+}
+
+// Time when the next keep-alive PING should be sent on the path.
+// It returns a zero time if no keep-alive should be sent.
+func (p *Path) nextKeepAliveTime() time.Time {
+	if p.conn.config.KeepAlivePeriod == 0 || p.keepAlivePingSent || !p.validated {
+		return time.Time{}
+	}
+	return p.lastPacketRcvd.Add(max(p.conn.keepAliveInterval, p.rttStats.PTO(true)*3/2))
+}
+
+func (p *Path) close(e error) {
+	p.ctxCancel(e)
+	p.sendQueue.Close()
+	p.datagramQueue.CloseWithError(e)
+	if p.transport != nil {
+		p.transport.handlerMap.Remove(p.localConnID)
+	}
+}
+
+// pathFrameSource holds the frames that have to be sent on a particular path.
+type pathFrameSource struct {
+	frames []wire.Frame
+}
+
+var _ frameSource = &pathFrameSource{}
+
+func (q *pathFrameSource) queue(f wire.Frame) { q.frames = append(q.frames, f) }
+
+func (q *pathFrameSource) HasData() bool { return len(q.frames) > 0 }
+
+func (q *pathFrameSource) AppendStreamFrames(frames []ackhandler.StreamFrame, _ protocol.ByteCount, _ protocol.Version) ([]ackhandler.StreamFrame, protocol.ByteCount) {
+	return frames, 0
+}
+
+func (q *pathFrameSource) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount, v protocol.Version) ([]ackhandler.Frame, protocol.ByteCount) {
+	var length protocol.ByteCount
+	for len(q.frames) > 0 {
+		l := q.frames[0].Length(v)
+		if length+l > maxLen {
+			break
+		}
+		frames = append(frames, ackhandler.Frame{Frame: q.frames[0]})
+		length += l
+		q.frames = q.frames[1:]
+	}
+	return frames, length
+}
+
+// pathAckSource turns the ACK frames of a path into PATH_ACK frames.
+type pathAckSource struct {
+	id  PathID
+	rph ackhandler.ReceivedPacketHandler
+}
+
+func (s *pathAckSource) GetAckFrame(encLevel protocol.EncryptionLevel, onlyIfQueued bool) *wire.AckFrame {
+	ack := s.rph.GetAckFrame(encLevel, onlyIfQueued)
+	if ack != nil {
+		ack.PathID = s.id
+	}
+	return ack
+}
+
+// pathSealingManager only provides the 1-RTT sealer, using the path ID in the nonce.
+type pathSealingManager struct {
+	id     PathID
+	cs     handshake.CryptoSetup
+	sealer *pathSealer
+}
+
+var _ sealingManager = &pathSealingManager{}
+
+func (m *pathSealingManager) GetInitialSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *pathSealingManager) GetHandshakeSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *pathSealingManager) Get0RTTSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *pathSealingManager) Get1RTTSealer() (handshake.ShortHeaderSealer, error) {
+	if m.sealer != nil {
+		return m.sealer, nil
+	}
+	s, err := m.cs.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := s.(handshake.PathAEAD)
+	if !ok {
+		return nil, errors.New("multipath not supported by the 1-RTT sealer")
+	}
+	m.sealer = &pathSealer{ShortHeaderSealer: s, aead: aead, id: m.id}
+	return m.sealer, nil
+}
+
+type pathSealer struct {
+	handshake.ShortHeaderSealer
+	aead handshake.PathAEAD
+	id   PathID
+}
+
+func (s *pathSealer) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
+	return s.aead.SealPath(dst, src, s.id, pn, ad)
+}
+
+// pathPacketHandler receives the packets of a path opened on a Transport other than the connection's.
+// Closing that Transport only abandons the path, not the connection.
+type pathPacketHandler struct {
+	conn *connection
+	id   PathID
+}
+
+var _ packetHandler = &pathPacketHandler{}
+
+func (h *pathPacketHandler) handlePacket(p receivedPacket) { h.conn.handlePacket(p) }
+
+func (h *pathPacketHandler) destroy(e error) {
+	m := h.conn.multipath
+	m.queueOp(func() { m.closePath(h.id, e) })
+}
+
+func (h *pathPacketHandler) closeWithTransportError(code qerr.TransportErrorCode) {
+	h.destroy(&qerr.TransportError{ErrorCode: code})
+}
+
+type pathOpenRequest struct {
+	tr       *Transport
+	result   chan *Path
+	path     *Path
+	canceled bool
+}
+
+// multipathState is the multipath state of a connection.
+// Apart from queueOp and the accept queue, it is only accessed from the connection's run loop.
+type multipathState struct {
+	conn    *connection
+	enabled bool
+
+	cs       handshake.CryptoSetup
+	unpacker *packetUnpacker
+
+	localMaxPathID PathID
+	peerMaxPathID  PathID
+	issuedUpTo     PathID
+
+	localConnIDs map[PathID]protocol.ConnectionID
+	byConnID     map[protocol.ConnectionID]PathID
+	peerConnIDs  map[PathID]protocol.ConnectionID
+	paths        map[PathID]*Path
+	abandoned    map[PathID]struct{}
+
+	// the path a packet is currently being handled for
+	rcvPath *Path
+
+	pendingOpens []*pathOpenRequest
+	acceptQueue  chan *Path
+
+	opsMutex sync.Mutex
+	ops      []func()
+}
+
+func newMultipathState(s *connection) *multipathState {
+	return &multipathState{
+		conn:           s,
+		localMaxPathID: PathID(s.config.MaxPaths - 1),
+		localConnIDs:   make(map[PathID]protocol.ConnectionID),
+		byConnID:       make(map[protocol.ConnectionID]PathID),
+		peerConnIDs:    make(map[PathID]protocol.ConnectionID),
+		paths:          make(map[PathID]*Path),
+		abandoned:      make(map[PathID]struct{}),
+		acceptQueue:    make(chan *Path, protocol.MaxPaths),
+	}
+}
+
+func (m *multipathState) active() bool { return m != nil && m.enabled }
+
+// receivingPath returns the additional path the packet currently being handled was received on.
+// It returns nil for packets received on the initial path.
+func (m *multipathState) receivingPath() *Path {
+	if m == nil {
+		return nil
+	}
+	return m.rcvPath
+}
+
+func (m *multipathState) pathForConnID(connID protocol.ConnectionID) (PathID, bool) {
+	if !m.active() {
+		return 0, false
+	}
+	id, ok := m.byConnID[connID]
+	return id, ok
+}
+
+// negotiate is called when the peer's transport parameters are applied.
+func (m *multipathState) negotiate(params *wire.TransportParameters) {
+	s := m.conn
+	if params.MaxPathID == 0 || s.srcConnIDLen == 0 || params.InitialSourceConnectionID.Len() == 0 {
+		return
+	}
+	cs, ok := s.cryptoStreamHandler.(handshake.CryptoSetup)
+	if !ok {
+		return
+	}
+	m.enabled = true
+	m.cs = cs
+	m.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
+	m.peerMaxPathID = params.MaxPathID
+}
+
+// handshakeConfirmed issues the connection IDs for the additional paths.
+func (m *multipathState) handshakeConfirmed() error {
+	if !m.active() {
+		return nil
+	}
+	return m.issueConnIDs()
+}
+
+func (m *multipathState) issueConnIDs() error {
+	g := m.conn.connIDGenerator
+	for m.issuedUpTo < min(m.localMaxPathID, m.peerMaxPathID) {
+		id := m.issuedUpTo + 1
+		connID, err := g.generator.GenerateConnectionID()
+		if err != nil {
+			return err
+		}
+		g.addConnectionID(connID)
+		m.localConnIDs[id] = connID
+		m.byConnID[connID] = id
+		m.issuedUpTo = id
+		m.conn.queueControlFrame(&wire.PathNewConnectionIDFrame{
+			PathID:              id,
+			ConnectionID:        connID,
+			StatelessResetToken: g.getStatelessResetToken(connID),
+		})
+	}
+	return nil
+}
+
+func (m *multipathState) queueOp(op func()) {
+	m.opsMutex.Lock()
+	m.ops = append(m.ops, op)
+	m.opsMutex.Unlock()
+	m.conn.scheduleSending()
+}
+
+func (m *multipathState) runOps() {
+	m.opsMutex.Lock()
+	ops := m.ops
+	m.ops = nil
+	m.opsMutex.Unlock()
+	for _, op := range ops {
+		op()
+	}
+}
+
+func (m *multipathState) newPath(id PathID, conn sendConn, tr *Transport, now time.Time) *Path {
+	s := m.conn
+	p := &Path{
+		id:             id,
+		conn:           s,
+		sendConn:       conn,
+		sendQueue:      newSendQueue(conn),
+		transport:      tr,
+		localConnID:    m.localConnIDs[id],
+		destConnID:     m.peerConnIDs[id],
+		rttStats:       &utils.RTTStats{},
+		maxPacketSize:  protocol.ByteCount(s.config.InitialPacketSize),
+		highestRcvdPN:  protocol.InvalidPacketNumber,
+		lastPacketRcvd: now,
+		validatedChan:  make(chan struct{}),
+	}
+	if s.peerParams.MaxUDPPayloadSize > 0 && s.peerParams.MaxUDPPayloadSize < p.maxPacketSize {
+		p.maxPacketSize = s.peerParams.MaxUDPPayloadSize
+	}
+	p.ctx, p.ctxCancel = context.WithCancelCause(s.ctx)
+	p.rttStats.SetMaxAckDelay(s.peerParams.MaxAckDelay)
+	p.sentPacketHandler, p.receivedPacketHandler = ackhandler.NewPathAckHandler(
+		p.maxPacketSize,
+		p.rttStats,
+		s.perspective,
+		s.logger,
+		s.config.congestionControl(conn.RemoteAddr()),
+	)
+	p.retransmissionQueue = newRetransmissionQueue()
+	p.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
+	p.packer = newPacketPacker(
+		p.localConnID,
+		func() protocol.ConnectionID { return p.destConnID },
+		nil,
+		nil,
+		p.sentPacketHandler,
+		p.retransmissionQueue,
+		&pathSealingManager{id: id, cs: m.cs},
+		&p.frames,
+		&pathAckSource{id: id, rph: p.receivedPacketHandler},
+		p.datagramQueue,
+		s.perspective,
+	)
+	go func() {
+		if err := p.sendQueue.Run(); err != nil {
+			m.queueOp(func() { m.closePath(id, err) })
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-p.sendQueue.Available():
+				if p.blocked.CompareAndSwap(true, false) {
+					s.scheduleSending()
+				}
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+	m.paths[id] = p
+	p.queueChallenge(now)
+	s.logger.Debugf("Opened path %d from %s to %s", id, conn.LocalAddr(), conn.RemoteAddr())
+	return p
+}
+
+// freePathID returns the lowest path ID that can be used for a new path.
+// It returns 0 if there's none.
+func (m *multipathState) freePathID() PathID {
+	for id := PathID(1); id <= m.issuedUpTo; id++ {
+		if _, ok := m.abandoned[id]; ok {
+			continue
+		}
+		if _, ok := m.peerConnIDs[id]; !ok {
+			continue
+		}
+		if _, ok := m.paths[id]; !ok {
+			return id
+		}
+	}
+	return 0
+}
+
+func (m *multipathState) open(req *pathOpenRequest) {
+	if req.canceled {
+		return
+	}
+	id := m.freePathID()
+	if id == 0 {
+		// wait for the peer to provide a connection ID, or to raise the path limit
+		m.pendingOpens = append(m.pendingOpens, req)
+		return
+	}
+	s := m.conn
+	conn := newSendConn(req.tr.conn, s.conn.RemoteAddr(), packetInfo{}, s.logger)
+	p := m.newPath(id, conn, req.tr, time.Now())
+	req.path = p
+	if !req.tr.handlerMap.Add(p.localConnID, &pathPacketHandler{conn: s, id: id}) {
+		m.closePath(id, fmt.Errorf("connection ID %s already in use on the transport", p.localConnID))
+	}
+	req.result <- p
+}
+
+func (m *multipathState) cancelOpen(req *pathOpenRequest) {
+	req.canceled = true
+	m.pendingOpens = slices.DeleteFunc(m.pendingOpens, func(r *pathOpenRequest) bool { return r == req })
+	if req.path != nil && !req.path.validated {
+		m.closePath(req.path.id, context.Canceled)
+	}
+}
+
+func (m *multipathState) servePendingOpens() {
+	for len(m.pendingOpens) > 0 && m.freePathID() != 0 {
+		req := m.pendingOpens[0]
+		m.pendingOpens = m.pendingOpens[1:]
+		m.open(req)
+	}
+}
+
+// closePath abandons a path, and makes room for a new path.
+// It is a no-op if the path was already abandoned.
+func (m *multipathState) closePath(id PathID, e error) {
+	if _, ok := m.abandoned[id]; ok || id == 0 || id > m.issuedUpTo {
+		return
+	}
+	s := m.conn
+	s.logger.Debugf("Abandoning path %d: %s", id, e)
+	m.abandoned[id] = struct{}{}
+	s.queueControlFrame(&wire.PathAbandonFrame{PathID: id})
+	if p, ok := m.paths[id]; ok {
+		p.close(e)
+		delete(m.paths, id)
+	}
+	if connID, ok := m.localConnIDs[id]; ok {
+		s.connIDGenerator.removeConnectionID(connID)
+		delete(m.byConnID, connID)
+		delete(m.localConnIDs, id)
+	}
+	delete(m.peerConnIDs, id)
+	// path IDs are never reused
+	m.localMaxPathID++
+	s.queueControlFrame(&wire.MaxPathIDFrame{MaxPathID: m.localMaxPathID})
+	if err := m.issueConnIDs(); err != nil {
+		s.closeLocal(err)
+	}
+}
+
+func (m *multipathState) handlePacket(rp receivedPacket, destConnID protocol.ConnectionID, id PathID) bool /* was the packet successfully processed */ {
+	s := m.conn
+	p := m.paths[id]
+	highestRcvdPN := protocol.InvalidPacketNumber
+	if p != nil {
+		highestRcvdPN = p.highestRcvdPN
+	}
+	pn, _, _, data, err := m.unpacker.UnpackPathShortHeader(rp.rcvTime, id, highestRcvdPN, rp.data)
+	if err != nil {
+		var transportErr *qerr.TransportError
+		if errors.As(err, &transportErr) {
+			s.closeLocal(err)
+		} else {
+			s.logger.Debugf("Dropping packet on path %d that could not be unpacked: %s", id, err)
+		}
+		return false
+	}
+	if p == nil {
+		// The server learns about a new path when it receives the first packet on it.
+		sc, ok := s.migration.pathConn.get().(*sconn)
+		if s.perspective != protocol.PerspectiveServer || !ok {
+			return false
+		}
+		if _, ok := m.peerConnIDs[id]; !ok {
+			s.logger.Debugf("Dropping packet on path %d: no connection ID to reply with", id)
+			return false
+		}
+		p = m.newPath(id, newSendConn(sc.rawConn, rp.remoteAddr, rp.info, s.logger), nil, rp.rcvTime)
+	}
+	if s.logger.Debug() {
+		s.logger.Debugf("<- Reading packet %d (%d bytes) on path %d", pn, rp.Size(), id)
+	}
+	if p.receivedPacketHandler.IsPotentiallyDuplicate(pn, protocol.Encryption1RTT) {
+		s.logger.Debugf("Dropping (potentially) duplicate packet.")
+		return false
+	}
+	p.highestRcvdPN = max(p.highestRcvdPN, pn)
+	p.lastPacketRcvd = rp.rcvTime
+	p.keepAlivePingSent = false
+	s.lastPacketReceivedTime = rp.rcvTime
+	s.keepAlivePingSent = false
+
+	m.rcvPath = p
+	isAckEliciting, _, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, nil)
+	m.rcvPath = nil
+	if err == nil {
+		err = p.receivedPacketHandler.ReceivedPacket(pn, rp.ecn, protocol.Encryption1RTT, rp.rcvTime, isAckEliciting)
+	}
+	if err != nil {
+		s.closeLocal(err)
+		return false
+	}
+	return true
+}
+
+var errMultipathFrame = &qerr.TransportError{
+	ErrorCode:    qerr.ProtocolViolation,
+	ErrorMessage: "received a multipath frame, but multipath was not negotiated",
+}
+
+func (m *multipathState) handlePathAckFrame(f *wire.AckFrame, rcvTime time.Time) error {
+	if !m.active() {
+		return errMultipathFrame
+	}
+	p, ok := m.paths[f.PathID]
+	if !ok {
+		// the path was abandoned
+		return nil
+	}
+	acked1RTTPacket, err := p.sentPacketHandler.ReceivedAck(f, protocol.Encryption1RTT, rcvTime)
+	if err != nil {
+		return err
+	}
+	p.smoothedRTT.Store(int64(p.rttStats.SmoothedRTT()))
+	if !acked1RTTPacket {
+		return nil
+	}
+	// the key phase is shared by all paths, see updatableAEAD.SetLargestPathAcked
+	sealer, err := m.cs.Get1RTTSealer()
+	if err != nil {
+		return err
+	}
+	aead, ok := sealer.(handshake.PathAEAD)
+	if !ok {
+		return nil
+	}
+	return aead.SetLargestPathAcked(f.PathID, f.LargestAcked())
+}
+
+func (m *multipathState) handlePathResponseFrame(f *wire.PathResponseFrame) {
+	for _, p := range m.paths {
+		if p.validated || !slices.Contains(p.challenges, f.Data) {
+			continue
+		}
+		p.validated = true
+		p.challenges = nil
+		close(p.validatedChan)
+		m.conn.logger.Debugf("Validated path %d", p.id)
+		if p.transport == nil {
+			select {
+			case m.acceptQueue <- p:
+			default:
+				m.conn.logger.Debugf("Accept queue full, path %d not delivered to AcceptPath", p.id)
+			}
+		}
+		return
+	}
+}
+
+func (m *multipathState) handlePathNewConnectionIDFrame(f *wire.PathNewConnectionIDFrame) error {
+	if !m.active() {
+		return errMultipathFrame
+	}
+	if f.PathID == 0 || f.PathID > m.localMaxPathID {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("PATH_NEW_CONNECTION_ID for path %d exceeds the path limit %d", f.PathID, m.localMaxPathID),
+		}
+	}
+	if f.ConnectionID.Len() == 0 {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "zero-length connection ID on a multipath connection",
+		}
+	}
+	if _, ok := m.abandoned[f.PathID]; ok {
+		return nil
+	}
+	// Connection IDs are not rotated on additional paths: only the first one is used.
+	if _, ok := m.peerConnIDs[f.PathID]; !ok {
+		m.peerConnIDs[f.PathID] = f.ConnectionID
+		m.servePendingOpens()
+	}
+	return nil
+}
+
+func (m *multipathState) handlePathRetireConnectionIDFrame(f *wire.PathRetireConnectionIDFrame) error {
+	if !m.active() {
+		return errMultipathFrame
+	}
+	// We only issue a single connection ID per path.
+	// Once it's retired, the path can't be used anymore.
+	m.closePath(f.PathID, errPathAbandonedByPeer)
+	return nil
+}
+
+func (m *multipathState) handlePathAbandonFrame(f *wire.PathAbandonFrame) error {
+	if !m.active() {
+		return errMultipathFrame
+	}
+	m.closePath(f.PathID, errPathAbandonedByPeer)
+	return nil
+}
+
+func (m *multipathState) handleMaxPathIDFrame(f *wire.MaxPathIDFrame) error {
+	if !m.active() {
+		return errMultipathFrame
+	}
+	if f.MaxPathID <= m.peerMaxPathID {
+		return nil
+	}
+	m.peerMaxPathID = f.MaxPathID
+	if err := m.issueConnIDs(); err != nil {
+		return err
+	}
+	m.servePendingOpens()
+	return nil
+}
+
+// onTimers runs pending operations, and handles the timers of all paths.
+func (m *multipathState) onTimers(now time.Time) {
+	m.runOps()
+	if !m.active() {
+		return
+	}
+	for id, p := range m.paths {
+		if t := p.sentPacketHandler.GetLossDetectionTimeout(); !t.IsZero() && !now.Before(t) {
+			if err := p.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
+				m.closePath(id, err)
+				continue
+			}
+		}
+		if !p.validated {
+			if !now.Before(p.challengeDeadline) {
+				if len(p.challenges) >= protocol.MaxPathChallenges {
+					m.closePath(id, errPathValidationFailed)
+					continue
+				}
+				p.queueChallenge(now)
+			}
+			continue
+		}
+		if now.Sub(p.lastPacketRcvd) >= p.idleTimeout() {
+			m.closePath(id, errPathIdleTimeout)
+			continue
+		}
+		if t := p.nextKeepAliveTime(); !t.IsZero() && !now.Before(t) {
+			p.frames.queue(&wire.PingFrame{})
+			p.keepAlivePingSent = true
+		}
+	}
+}
+
+// addDeadlines adds the timers and the pacing deadlines of all paths
+// to the deadlines of the initial path.
+func (m *multipathState) addDeadlines(timer, pacing time.Time) (time.Time, time.Time) {
+	earliest := func(deadline, t time.Time) time.Time {
+		if !t.IsZero() && (deadline.IsZero() || t.Before(deadline)) {
+			return t
+		}
+		return deadline
+	}
+	for _, p := range m.paths {
+		timer = earliest(timer, p.sentPacketHandler.GetLossDetectionTimeout())
+		timer = earliest(timer, p.receivedPacketHandler.GetAlarmTimeout())
+		if !p.validated {
+			timer = earliest(timer, p.challengeDeadline)
+		} else {
+			timer = earliest(timer, p.lastPacketRcvd.Add(p.idleTimeout()))
+			timer = earliest(timer, p.nextKeepAliveTime())
+		}
+		pacing = earliest(pacing, p.pacingDeadline)
+	}
+	return timer, pacing
+}
+
+func (m *multipathState) sendPackets(now time.Time) {
+	for id, p := range m.paths {
+		if err := m.sendPacketsOnPath(p, now); err != nil {
+			m.closePath(id, err)
+		}
+	}
+}
+
+func (m *multipathState) sendPacketsOnPath(p *Path, now time.Time) error {
+	p.pacingDeadline = time.Time{}
+	for {
+		if p.sendQueue.WouldBlock() {
+			p.blocked.Store(true)
+			return nil
+		}
+		sendMode := p.sentPacketHandler.SendMode(now)
+		//nolint:exhaustive // Initial and Handshake packets are never sent on additional paths.
+		switch sendMode {
+		case ackhandler.SendAny:
+			sent, err := m.sendPacket(p, false, now)
+			if err != nil || !sent {
+				return err
+			}
+			// Prioritize receiving of packets over sending out more packets.
+			if len(m.conn.receivedPackets) > 0 {
+				p.pacingDeadline = deadlineSendImmediately
+				return nil
+			}
+		case ackhandler.SendPacingLimited:
+			p.pacingDeadline = p.sentPacketHandler.TimeUntilSend()
+			if p.pacingDeadline.IsZero() {
+				p.pacingDeadline = deadlineSendImmediately
+			}
+			_, err := m.sendPacket(p, true, now)
+			return err
+		case ackhandler.SendAck:
+			_, err := m.sendPacket(p, true, now)
+			return err
+		case ackhandler.SendPTOAppData:
+			if err := m.sendProbePacket(p, now); err != nil {
+				return err
+			}
+		case ackhandler.SendNone:
+			return nil
+		default:
+			return fmt.Errorf("BUG: invalid send mode %d on path %d", sendMode, p.id)
+		}
+	}
+}
+
+func (m *multipathState) sendPacket(p *Path, onlyAck bool, now time.Time) (bool, error) {
+	var (
+		sp  shortHeaderPacket
+		buf *packetBuffer
+		err error
+	)
+	if onlyAck {
+		sp, buf, err = p.packer.PackAckOnlyPacket(p.maxPacketSize, m.conn.version)
+	} else {
+		buf = getPacketBuffer()
+		sp, err = p.packer.AppendPacket(buf, p.maxPacketSize, m.conn.version)
+	}
+	if err != nil {
+		buf.Release()
+		if err == errNothingToPack {
+			return false, nil
+		}
+		return false, err
+	}
+	ecn := p.sentPacketHandler.ECNMode(true)
+	m.registerPacket(p, sp, ecn, now)
+	p.sendQueue.Send(buf, 0, ecn)
+	return true, nil
+}
+
+func (m *multipathState) sendProbePacket(p *Path, now time.Time) error {
+	// Queue probe packets until we actually send out a packet,
+	// or until there are no more packets to queue.
+	var packet *coalescedPacket
+	for {
+		if wasQueued := p.sentPacketHandler.QueueProbePacket(protocol.Encryption1RTT); !wasQueued {
+			break
+		}
+		var err error
+		packet, err = p.packer.MaybePackProbePacket(protocol.Encryption1RTT, p.maxPacketSize, m.conn.version)
+		if err != nil {
+			return err
+		}
+		if packet != nil {
+			break
+		}
+	}
+	if packet == nil {
+		p.retransmissionQueue.AddPing(protocol.Encryption1RTT)
+		var err error
+		packet, err = p.packer.MaybePackProbePacket(protocol.Encryption1RTT, p.maxPacketSize, m.conn.version)
+		if err != nil {
+			return err
+		}
+	}
+	if packet == nil || packet.shortHdrPacket == nil {
+		return fmt.Errorf("connection BUG: couldn't pack probe packet on path %d", p.id)
+	}
+	ecn := p.sentPacketHandler.ECNMode(true)
+	m.registerPacket(p, *packet.shortHdrPacket, ecn, now)
+	p.sendQueue.Send(packet.buffer, 0, ecn)
+	return nil
+}
+
+func (m *multipathState) registerPacket(p *Path, sp shortHeaderPacket, ecn protocol.ECN, now time.Time) {
+	if logger := m.conn.logger; logger.Debug() {
+		logger.Debugf("-> Sending packet %d (%d bytes) on path %d", sp.PacketNumber, sp.Length, p.id)
+		if sp.Ack != nil {
+			wire.LogFrame(logger, sp.Ack, true)
+		}
+		for _, f := range sp.Frames {
+			wire.LogFrame(logger, f.Frame, true)
+		}
+	}
+	largestAcked := protocol.InvalidPacketNumber
+	if sp.Ack != nil {
+		largestAcked = sp.Ack.LargestAcked()
+	}
+	p.sentPacketHandler.SentPacket(now, sp.PacketNumber, largestAcked, sp.StreamFrames, sp.Frames, protocol.Encryption1RTT, ecn, sp.Length, false)
+}
+
+func (m *multipathState) close(e error) {
+	for id, p := range m.paths {
+		p.close(e)
+		delete(m.paths, id)
+	}
+	for _, connID := range m.localConnIDs {
+		m.conn.connIDGenerator.removeConnectionID(connID)
+	}
+	m.enabled = false
+}
+
+func (s *connection) OpenPath(ctx context.Context, tr *Transport) (*Path, error) {
+	if s.perspective == protocol.PerspectiveServer {
+		return nil, errors.New("only the client can open paths")
+	}
+	if s.multipath == nil {
+		return nil, ErrMultipathNotNegotiated
+	}
+	select {
+	case <-s.HandshakeComplete():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, context.Cause(s.ctx)
+	}
+	if err := tr.init(false); err != nil {
+		return nil, err
+	}
+	if tr.connIDLen != s.srcConnIDLen {
+		return nil, fmt.Errorf("transport uses %d byte connection IDs, the connection %d byte connection IDs", tr.connIDLen, s.srcConnIDLen)
+	}
+	m := s.multipath
+	req := &pathOpenRequest{tr: tr, result: make(chan *Path, 1)}
+	errChan := make(chan error, 1)
+	m.queueOp(func() {
+		if !m.active() {
+			errChan <- ErrMultipathNotNegotiated
+			return
+		}
+		m.open(req)
+	})
+	var p *Path
+	select {
+	case p = <-req.result:
+	case err := <-errChan:
+		return nil, err
+	case <-ctx.Done():
+		m.queueOp(func() { m.cancelOpen(req) })
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, context.Cause(s.ctx)
+	}
+	select {
+	case <-p.validatedChan:
+		return p, nil
+	case <-p.ctx.Done():
+		return nil, context.Cause(p.ctx)
+	case <-ctx.Done():
+		m.queueOp(func() { m.cancelOpen(req) })
+		return nil, ctx.Err()
+	}
+}
+
+func (s *connection) AcceptPath(ctx context.Context) (*Path, error) {
+	if s.multipath == nil {
+		return nil, ErrMultipathNotNegotiated
+	}
+	select {
+	case p := <-s.multipath.acceptQueue:
+		return p, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, context.Cause(s.ctx)
+	}
+}
@@ -150,6 +150,22 @@ func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
 	h.logger.Debugf("Removing connection ID %s.", id)
 }
 
+func (h *packetHandlerMap) RemoveHandler(handler packetHandler) {
+	h.mutex.Lock()
+	for id, hdlr := range h.handlers {
+		if hdlr == handler {
+			delete(h.handlers, id)
+		}
+	}
+	for token, hdlr := range h.resetTokens {
+		if hdlr == handler {
+			delete(h.resetTokens, token)
+		}
+	}
+	h.mutex.Unlock()
+	h.logger.Debugf("Removing all connection IDs of a connection.")
+}
+
 func (h *packetHandlerMap) Retire(id protocol.ConnectionID) {
 	h.logger.Debugf("Retiring connection ID %s in %s.", id, h.deleteRetiredConnsAfter)
 	time.AfterFunc(h.deleteRetiredConnsAfter, func() {
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -124,6 +125,38 @@ func (u *packetUnpacker) UnpackShortHeader(rcvTime time.Time, data []byte) (prot
 	return pn, pnLen, kp, decrypted, nil
 }
 
+// UnpackPathShortHeader unpacks a short header packet received on a multipath path other than the initial path.
+// The packet number is decoded relative to the highest packet number received on that path.
+func (u *packetUnpacker) UnpackPathShortHeader(rcvTime time.Time, pathID protocol.PathID, highestRcvdPN protocol.PacketNumber, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+	opener, err := u.cs.Get1RTTOpener()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	aead, ok := opener.(handshake.PathAEAD)
+	if !ok {
+		return 0, 0, 0, nil, errors.New("multipath not supported by the 1-RTT opener")
+	}
+	l, pn, pnLen, kp, parseErr := u.unpackShortHeader(opener, data)
+	if parseErr != nil && parseErr != wire.ErrInvalidReservedBits {
+		return 0, 0, 0, nil, &headerParseError{parseErr}
+	}
+	pn = protocol.DecodePacketNumber(pnLen, highestRcvdPN, pn)
+	decrypted, err := aead.OpenPath(data[l:l], data[l:], rcvTime, pathID, pn, kp, data[:l])
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	if parseErr != nil {
+		return 0, 0, 0, nil, parseErr
+	}
+	if len(decrypted) == 0 {
+		return 0, 0, 0, nil, &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "empty packet",
+		}
+	}
+	return pn, pnLen, kp, decrypted, nil
+}
+
 func (u *packetUnpacker) unpackLongHeaderPacket(opener handshake.LongHeaderOpener, hdr *wire.Header, data []byte) (*wire.ExtendedHeader, []byte, error) {
 	extHdr, parseErr := u.unpackLongHeader(opener, hdr, data)
 	// If the reserved bits are set incorrectly, we still need to continue unpacking.
@@ -59,6 +59,14 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalHandshakeDoneFrame(enc, frame)
 	case *logging.DatagramFrame:
 		marshalDatagramFrame(enc, frame)
+	case *logging.PathAbandonFrame:
+		marshalPathAbandonFrame(enc, frame)
+	case *logging.PathNewConnectionIDFrame:
+		marshalPathNewConnectionIDFrame(enc, frame)
+	case *logging.PathRetireConnectionIDFrame:
+		marshalPathRetireConnectionIDFrame(enc, frame)
+	case *logging.MaxPathIDFrame:
+		marshalMaxPathIDFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -101,7 +109,12 @@ func (ar ackRange) MarshalJSONArray(enc *gojay.Encoder) {
 func (ar ackRange) IsNil() bool { return false }
 
 func marshalAckFrame(enc *gojay.Encoder, f *logging.AckFrame) {
-	enc.StringKey("frame_type", "ack")
+	if f.PathID != 0 {
+		enc.StringKey("frame_type", "path_ack")
+		enc.Uint32Key("path_id", uint32(f.PathID))
+	} else {
+		enc.StringKey("frame_type", "ack")
+	}
 	enc.FloatKeyOmitEmpty("ack_delay", milliseconds(f.DelayTime))
 	enc.ArrayKey("acked_ranges", ackRanges(f.AckRanges))
 	if hasECN := f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0; hasECN {
@@ -201,6 +214,33 @@ func marshalPathResponseFrame(enc *gojay.Encoder, f *logging.PathResponseFrame)
 	enc.StringKey("data", fmt.Sprintf("%x", f.Data[:]))
 }
 
+func marshalPathAbandonFrame(enc *gojay.Encoder, f *logging.PathAbandonFrame) {
+	enc.StringKey("frame_type", "path_abandon")
+	enc.Uint32Key("path_id", uint32(f.PathID))
+	enc.Uint64Key("error_code", f.ErrorCode)
+}
+
+func marshalPathNewConnectionIDFrame(enc *gojay.Encoder, f *logging.PathNewConnectionIDFrame) {
+	enc.StringKey("frame_type", "path_new_connection_id")
+	enc.Uint32Key("path_id", uint32(f.PathID))
+	enc.Int64Key("sequence_number", int64(f.SequenceNumber))
+	enc.Int64Key("retire_prior_to", int64(f.RetirePriorTo))
+	enc.IntKey("length", f.ConnectionID.Len())
+	enc.StringKey("connection_id", f.ConnectionID.String())
+	enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", f.StatelessResetToken))
+}
+
+func marshalPathRetireConnectionIDFrame(enc *gojay.Encoder, f *logging.PathRetireConnectionIDFrame) {
+	enc.StringKey("frame_type", "path_retire_connection_id")
+	enc.Uint32Key("path_id", uint32(f.PathID))
+	enc.Int64Key("sequence_number", int64(f.SequenceNumber))
+}
+
+func marshalMaxPathIDFrame(enc *gojay.Encoder, f *logging.MaxPathIDFrame) {
+	enc.StringKey("frame_type", "max_path_id")
+	enc.Uint32Key("maximum_path_id", uint32(f.MaxPathID))
+}
+
 func marshalConnectionCloseFrame(enc *gojay.Encoder, f *logging.ConnectionCloseFrame) {
 	errorSpace := "transport"
 	if f.IsApplicationError {
@@ -401,3 +401,70 @@ func TestDatagramFrame(t *testing.T) {
 		},
 	)
 }
+
+func TestPathAckFrame(t *testing.T) {
+	check(t,
+		&logging.AckFrame{
+			PathID:    2,
+			AckRanges: []logging.AckRange{{Smallest: 42, Largest: 1337}},
+		},
+		map[string]interface{}{
+			"frame_type":   "path_ack",
+			"path_id":      2,
+			"acked_ranges": [][]float64{{42, 1337}},
+		},
+	)
+}
+
+func TestPathAbandonFrame(t *testing.T) {
+	check(t,
+		&logging.PathAbandonFrame{PathID: 3, ErrorCode: 7},
+		map[string]interface{}{
+			"frame_type": "path_abandon",
+			"path_id":    3,
+			"error_code": 7,
+		},
+	)
+}
+
+func TestPathNewConnectionIDFrame(t *testing.T) {
+	check(t,
+		&logging.PathNewConnectionIDFrame{
+			PathID:              1,
+			SequenceNumber:      42,
+			RetirePriorTo:       24,
+			ConnectionID:        protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			StatelessResetToken: protocol.StatelessResetToken{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf},
+		},
+		map[string]interface{}{
+			"frame_type":            "path_new_connection_id",
+			"path_id":               1,
+			"sequence_number":       42,
+			"retire_prior_to":       24,
+			"length":                4,
+			"connection_id":         "deadbeef",
+			"stateless_reset_token": "000102030405060708090a0b0c0d0e0f",
+		},
+	)
+}
+
+func TestPathRetireConnectionIDFrame(t *testing.T) {
+	check(t,
+		&logging.PathRetireConnectionIDFrame{PathID: 1, SequenceNumber: 1337},
+		map[string]interface{}{
+			"frame_type":      "path_retire_connection_id",
+			"path_id":         1,
+			"sequence_number": 1337,
+		},
+	)
+}
+
+func TestMaxPathIDFrame(t *testing.T) {
+	check(t,
+		&logging.MaxPathIDFrame{MaxPathID: 4},
+		map[string]interface{}{
+			"frame_type":      "max_path_id",
+			"maximum_path_id": 4,
+		},
+	)
+}